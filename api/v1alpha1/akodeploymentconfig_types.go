@@ -80,6 +80,16 @@ type AKODeploymentConfigSpec struct {
 	// +optional
 	Tenant AVITenant `json:"tenant,omitempty"`
 
+	// RolePermissionProfileRef points to a ConfigMap which overrides the permissions
+	// of the AKO user role. Its keys are Avi permission resources, e.g.
+	// PERMISSION_VIRTUALSERVICE, and its values are NO_ACCESS, READ_ACCESS or
+	// WRITE_ACCESS. The profile is merged with the default AKO permissions, and a
+	// role named ako-essential-role-<AKODeploymentConfig name> is created and kept
+	// exactly in sync with the result instead of the shared ako-essential-role.
+	// This field is optional.
+	// +optional
+	RolePermissionProfileRef *ConfigMapRef `json:"rolePermissionProfileRef,omitempty"`
+
 	// DataNetworks describes the Data Networks the AKO will be deployed
 	// with.
 	// This field is immutable.
@@ -90,6 +100,33 @@ type AKODeploymentConfigSpec struct {
 	// +optional
 	ControlPlaneNetwork ControlPlaneNetwork `json:"controlPlaneNetwork,omitempty"`
 
+	// ControlPlaneDNS publishes the control plane VIP of the clusters using NSX
+	// Advanced Load Balancer as HA provider as <cluster name>.<domain>, which
+	// becomes their control plane endpoint. It applies to the clusters whose HA
+	// service is created once it is set and which have no control plane
+	// endpoint of their own.
+	//
+	// +optional
+	ControlPlaneDNS *ControlPlaneDNS `json:"controlPlaneDNS,omitempty"`
+
+	// ControlPlaneHealthMonitor is the name of an Avi health monitor probing the
+	// API server of the control plane machines behind the control plane VIP of
+	// the clusters using NSX Advanced Load Balancer as HA provider. It is
+	// attached through an AKO L4Rule, which needs AKO 1.11 or later in the
+	// management cluster.
+	//
+	// +optional
+	ControlPlaneHealthMonitor string `json:"controlPlaneHealthMonitor,omitempty"`
+
+	// ControlPlaneAviInfraSetting gives each cluster using NSX Advanced Load
+	// Balancer as HA provider an AviInfraSetting of its own, named
+	// <HA service name>-ais, for its control plane VIP. It is referenced by the
+	// HA service instead of the AviInfraSetting of the control plane network
+	// and deleted with the cluster.
+	//
+	// +optional
+	ControlPlaneAviInfraSetting *ControlPlaneAviInfraSetting `json:"controlPlaneAviInfraSetting,omitempty"`
+
 	// ExtraConfigs contains extra configurations for AKO Deployment
 	//
 	// +optional
@@ -369,8 +406,36 @@ type AVITenant struct {
 	// +kubebuilder:validation:Enum=Provider;Tenant
 	Context string `json:"context,omitempty"`
 
-	// Name is the name of the tenant. This field is immutable.
-	Name string `json:"name"`
+	// Name is the name of the tenant, used when Mode is Static. This field is immutable.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Mode decides which tenant each Cluster's AKO user and AKO instance live in. Defaults to Static.
+	//
+	// * Static                     every Cluster uses the tenant set in Name
+	// * Namespace                  every Cluster uses the tenant named after its namespace
+	// * LabelTemplate              every Cluster uses the tenant rendered from NameTemplate
+	//
+	// This field is immutable.
+	// +kubebuilder:validation:Enum=Static;Namespace;LabelTemplate
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// NameTemplate is a Go template rendered with the Cluster's .Name, .Namespace
+	// and .Labels into the tenant name, e.g. "{{ index .Labels \"team\" }}".
+	// It is required when Mode is LabelTemplate.
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	// CreateIfMissing creates the tenant in the AVI Controller when it doesn't
+	// exist yet. Only used when Mode is Namespace or LabelTemplate.
+	// +optional
+	CreateIfMissing bool `json:"createIfMissing,omitempty"`
+
+	// DeleteWhenUnused deletes a tenant created by the operator once the last
+	// Cluster using it is deleted. Only used when Mode is Namespace or LabelTemplate.
+	// +optional
+	DeleteWhenUnused bool `json:"deleteWhenUnused,omitempty"`
 }
 
 // DataNetwork describes one AVI Data Network
@@ -386,6 +451,46 @@ type ControlPlaneNetwork struct {
 	CIDR string `json:"cidr"`
 }
 
+// ControlPlaneDNS describes how the control plane FQDNs of the clusters are published
+type ControlPlaneDNS struct {
+	// Domain is the DNS domain the control plane FQDNs are published in
+	Domain string `json:"domain"`
+
+	// Provider publishes the DNS records, both read the external-dns hostname
+	// annotation of the HA service. AviDNS serves the domain from the DNS profile
+	// of the cloud, ExternalDNS leaves the records to an external-dns deployment.
+	// Default value: ExternalDNS
+	// +kubebuilder:validation:Enum=AviDNS;ExternalDNS
+	// +optional
+	Provider string `json:"provider,omitempty"`
+}
+
+// ControlPlaneAviInfraSetting describes the AviInfraSetting of the control
+// plane VIP of each cluster. The VIP is placed in the control plane network,
+// the data network when it's not set.
+type ControlPlaneAviInfraSetting struct {
+	// ServiceEngineGroup hosts the control plane VIPs, it isolates them from
+	// the workloads of the clusters. The ServiceEngineGroup of the
+	// AKODeploymentConfig by default
+	// +optional
+	ServiceEngineGroup string `json:"serviceEngineGroup,omitempty"`
+
+	// EnableRHI advertises the control plane VIPs with BGP route health
+	// injection
+	// +optional
+	EnableRHI *bool `json:"enableRhi,omitempty"`
+
+	// BGPPeerLabels selects the BGP peers the control plane VIPs are
+	// advertised to, all of them by default
+	// +optional
+	BGPPeerLabels []string `json:"bgpPeerLabels,omitempty"`
+
+	// EnablePublicIP allocates a public IP for the control plane VIPs in the
+	// public clouds
+	// +optional
+	EnablePublicIP *bool `json:"enablePublicIP,omitempty"`
+}
+
 // VIPNetwork describes a VIPNetwork in the adc file
 type VIPNetwork struct {
 	NetworkName string `json:"networkName"`
@@ -416,6 +521,15 @@ type SecretRef struct {
 	Namespace string `json:"namespace"`
 }
 
+// ConfigMapRef references a Kind ConfigMap object in the same kubernetes
+// cluster
+type ConfigMapRef struct {
+	// Name is the name of resource being referenced.
+	Name string `json:"name"`
+	// Namespace of the resource being referenced.
+	Namespace string `json:"namespace"`
+}
+
 // AKODeploymentConfigStatus defines the observed state of AKODeploymentConfig
 type AKODeploymentConfigStatus struct {
 	// ObservedGeneration reflects the generation of the most recently
@@ -426,11 +540,36 @@ type AKODeploymentConfigStatus struct {
 	// Conditions defines current state of the AKODeploymentConfig.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// Clusters counts the clusters running AKO with this AKODeploymentConfig
+	// by the status of their AKOReady condition.
+	// +optional
+	Clusters AKOClustersStatus `json:"clusters,omitempty"`
+}
+
+// AKOClustersStatus aggregates the AKO health of the clusters of an
+// AKODeploymentConfig
+type AKOClustersStatus struct {
+	// Total is the number of clusters running AKO with this AKODeploymentConfig.
+	Total int32 `json:"total"`
+
+	// Ready is the number of clusters where AKO is ready.
+	Ready int32 `json:"ready"`
+
+	// NotReady is the number of clusters where AKO is not ready.
+	NotReady int32 `json:"notReady"`
+
+	// Unknown is the number of clusters where the AKO health is unknown, either
+	// because it wasn't probed yet or the probe failed.
+	Unknown int32 `json:"unknown"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:shortName=adc,path=akodeploymentconfigs,scope=Cluster
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Clusters",type="integer",JSONPath=".status.clusters.total",description="Clusters running AKO"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.clusters.ready",description="Clusters where AKO is ready"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // AKODeploymentConfig is the Schema for the akodeploymentconfigs API
 type AKODeploymentConfig struct {
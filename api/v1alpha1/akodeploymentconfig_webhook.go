@@ -9,11 +9,13 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,6 +34,27 @@ var runTest bool
 
 const controllerVersionRegex = `^\d+(\.\d+)*$`
 
+const (
+	// ValidationModeOnline validates AKODeploymentConfig objects against the avi controller
+	ValidationModeOnline = "online"
+	// ValidationModeOffline only validates the structure of AKODeploymentConfig objects,
+	// the avi controller and the referenced secrets are not looked up
+	ValidationModeOffline = "offline"
+)
+
+// validationMode is the webhook validation mode used when an AKODeploymentConfig
+// object doesn't carry the validation mode annotation
+var validationMode = ValidationModeOnline
+
+// SetValidationMode sets the default webhook validation mode
+func SetValidationMode(mode string) error {
+	if mode != ValidationModeOnline && mode != ValidationModeOffline {
+		return fmt.Errorf("invalid validation mode %q, valid values are %s and %s", mode, ValidationModeOnline, ValidationModeOffline)
+	}
+	validationMode = mode
+	return nil
+}
+
 func (r *AKODeploymentConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	kclient = mgr.GetClient()
 	return ctrl.NewWebhookManagedBy(mgr).
@@ -49,8 +72,12 @@ func (r *AKODeploymentConfig) ValidateCreate() (admission.Warnings, error) {
 	akoDeploymentConfigLog.Info("validate create", "name", r.Name)
 
 	var allErrs field.ErrorList
-	allErrs = append(allErrs, r.validateClusterSelector(nil)...)
-	allErrs = append(allErrs, r.validateAVI(nil)...)
+	if r.isOfflineValidation(true) {
+		allErrs = append(allErrs, r.ValidateStructure(nil)...)
+	} else {
+		allErrs = append(allErrs, r.validateClusterSelector(nil)...)
+		allErrs = append(allErrs, r.validateAVI(nil)...)
+	}
 	if len(allErrs) == 0 {
 		return nil, nil
 	}
@@ -66,8 +93,12 @@ func (r *AKODeploymentConfig) ValidateUpdate(old runtime.Object) (admission.Warn
 	}
 	var allErrs field.ErrorList
 	if oldADC != nil {
-		allErrs = append(allErrs, r.validateClusterSelector(oldADC)...)
-		allErrs = append(allErrs, r.validateAVI(oldADC)...)
+		if r.isOfflineValidation(false) {
+			allErrs = append(allErrs, r.ValidateStructure(oldADC)...)
+		} else {
+			allErrs = append(allErrs, r.validateClusterSelector(oldADC)...)
+			allErrs = append(allErrs, r.validateAVI(oldADC)...)
+		}
 	}
 	if len(allErrs) == 0 {
 		return nil, nil
@@ -144,11 +175,8 @@ func (r *AKODeploymentConfig) validateAVI(old *AKODeploymentConfig) field.ErrorL
 		return allErrs
 	}
 
-	// check avi controller version format
-	_, err := r.validateAviControllerVersion()
-	if err != nil {
-		allErrs = append(allErrs, err)
-	}
+	// run all the checks which don't need the avi controller first
+	allErrs = append(allErrs, r.validateAVIStructure(old)...)
 
 	if !runTest {
 		username := string(adminCredential.Data["username"][:])
@@ -178,55 +206,155 @@ func (r *AKODeploymentConfig) validateAVI(old *AKODeploymentConfig) field.ErrorL
 		aviClient = client
 	}
 
+	// when old is nil, it is creating a new AKODeploymentConfig object and all the avi
+	// references are checked, otherwise only the changed references and the ones
+	// depending on them are checked
+	allErrs = append(allErrs, r.validateAviReferences(old)...)
+	return allErrs
+}
+
+// ValidateStructure runs every AKODeploymentConfig check which needs neither the
+// management cluster nor the NSX Advanced Load Balancer controller, it is what the
+// webhook runs in offline validation mode and what `akoo validate` runs against
+// AKODeploymentConfig files. When old is nil the object is validated as a new one,
+// otherwise the update rules are applied as well.
+func (r *AKODeploymentConfig) ValidateStructure(old *AKODeploymentConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, r.validateClusterSelector(old)...)
+	allErrs = append(allErrs, r.validateAviSecretReference(r.Spec.AdminCredentialRef, "adminCredentialRef")...)
+	allErrs = append(allErrs, r.validateAviSecretReference(r.Spec.CertificateAuthorityRef, "certificateAuthorityRef")...)
+	allErrs = append(allErrs, r.validateAVIStructure(old)...)
+	return allErrs
+}
+
+// validateAVIStructure checks the NSX Advanced Load Balancer related fields whose
+// correctness doesn't depend on the objects in avi controller
+func (r *AKODeploymentConfig) validateAVIStructure(old *AKODeploymentConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// check avi controller version format
+	if _, err := r.validateAviControllerVersion(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
 	if err := r.validateReplicaCount(); err != nil {
 		allErrs = append(allErrs, err)
 	}
 
+	if err := r.validateTenantNameTemplate(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	allErrs = append(allErrs, r.validateRolePermissionProfileRef()...)
+	allErrs = append(allErrs, r.validateControlPlaneDNS()...)
+
 	if old == nil {
-		// when old is nil, it is creating a new AKODeploymentConfig object, check following fields
-		if err := r.validateAviCloud(); err != nil {
-			allErrs = append(allErrs, err)
-		}
-		if err := r.validateAviServiceEngineGroup(); err != nil {
-			allErrs = append(allErrs, err)
-		}
-		if err := r.validateAviControlPlaneNetworks(); err != nil {
-			allErrs = append(allErrs, err...)
-		}
-		if err := r.validateAviDataNetworks(); err != nil {
-			allErrs = append(allErrs, err...)
-		}
-	} else {
-		// when old is not nil, it is updating an existing AKODeploymentConfig object,
-		// only check changed fields
-		if old.Spec.CloudName != r.Spec.CloudName {
-			if err := r.validateAviCloud(); err != nil {
-				allErrs = append(allErrs, err)
-			}
-		}
-		if old.Spec.ServiceEngineGroup != r.Spec.ServiceEngineGroup {
-			if err := r.validateAviServiceEngineGroup(); err != nil {
-				allErrs = append(allErrs, err)
-			}
-		}
-		// control plane network should be immutable since cluster control plane endpoint
-		// can't be updated
-		if (old.Spec.ControlPlaneNetwork.Name != r.Spec.ControlPlaneNetwork.Name) ||
-			(old.Spec.ControlPlaneNetwork.CIDR != r.Spec.ControlPlaneNetwork.CIDR) {
-			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "ControlPlaneNetwork"),
-				r.Spec.ControlPlaneNetwork,
-				"field should not be changed"))
-		}
-		if (old.Spec.DataNetwork.Name != r.Spec.DataNetwork.Name) ||
-			(old.Spec.DataNetwork.CIDR != r.Spec.DataNetwork.CIDR) {
-			if err := r.validateAviDataNetworks(); err != nil {
-				allErrs = append(allErrs, err...)
-			}
-		}
+		allErrs = append(allErrs, r.validateControlPlaneNetworkCIDR()...)
+		allErrs = append(allErrs, r.validateDataNetworkCIDR()...)
+		return allErrs
+	}
+
+	// control plane network should be immutable since cluster control plane endpoint
+	// can't be updated
+	if (old.Spec.ControlPlaneNetwork.Name != r.Spec.ControlPlaneNetwork.Name) ||
+		(old.Spec.ControlPlaneNetwork.CIDR != r.Spec.ControlPlaneNetwork.CIDR) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "ControlPlaneNetwork"),
+			r.Spec.ControlPlaneNetwork,
+			"field should not be changed"))
+	}
+	if dataNetworkChanged(old, r) {
+		allErrs = append(allErrs, r.validateDataNetworkCIDR()...)
+	}
+	// existing avi users live in the tenants derived with the old mode
+	if tenantMode(old.Spec.Tenant) != tenantMode(r.Spec.Tenant) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "tenant", "mode"),
+			r.Spec.Tenant.Mode,
+			"field should not be changed"))
+	}
+	return allErrs
+}
+
+// tenantMode returns the tenant mode, Static when it isn't set
+func tenantMode(tenant AVITenant) string {
+	if tenant.Mode == "" {
+		return AVITenantModeStatic
+	}
+	return tenant.Mode
+}
+
+// validateTenantNameTemplate checks the tenant name template is set and can be parsed
+// when the tenant is rendered from the cluster labels
+func (r *AKODeploymentConfig) validateTenantNameTemplate() *field.Error {
+	if r.Spec.Tenant.Mode != AVITenantModeLabelTemplate {
+		return nil
+	}
+	if r.Spec.Tenant.NameTemplate == "" {
+		return field.Required(field.NewPath("spec", "tenant", "nameTemplate"),
+			"tenant name template should be set when tenant mode is "+AVITenantModeLabelTemplate)
+	}
+	if _, err := template.New("tenant").Parse(r.Spec.Tenant.NameTemplate); err != nil {
+		return field.Invalid(field.NewPath("spec", "tenant", "nameTemplate"), r.Spec.Tenant.NameTemplate,
+			"invalid tenant name template:"+err.Error())
+	}
+	return nil
+}
+
+// validateRolePermissionProfileRef checks the role permission profile reference is
+// complete when it is set
+func (r *AKODeploymentConfig) validateRolePermissionProfileRef() field.ErrorList {
+	var allErrs field.ErrorList
+	ref := r.Spec.RolePermissionProfileRef
+	if ref == nil {
+		return allErrs
+	}
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "rolePermissionProfileRef", "name"), "configmap name should be set"))
+	}
+	if ref.Namespace == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "rolePermissionProfileRef", "namespace"), "configmap namespace should be set"))
 	}
 	return allErrs
 }
 
+// validateControlPlaneDNS checks the control plane FQDNs can be built from the
+// domain and the provider is known
+func (r *AKODeploymentConfig) validateControlPlaneDNS() field.ErrorList {
+	var allErrs field.ErrorList
+	dns := r.Spec.ControlPlaneDNS
+	if dns == nil {
+		return allErrs
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(dns.Domain) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "controlPlaneDNS", "domain"), dns.Domain, msg))
+	}
+	switch dns.Provider {
+	case "", ControlPlaneDNSProviderAviDNS, ControlPlaneDNSProviderExternalDNS:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("spec", "controlPlaneDNS", "provider"), dns.Provider,
+			[]string{ControlPlaneDNSProviderAviDNS, ControlPlaneDNSProviderExternalDNS}))
+	}
+	return allErrs
+}
+
+// dataNetworkChanged checks if the data network name or cidr is updated
+func dataNetworkChanged(old, r *AKODeploymentConfig) bool {
+	return (old.Spec.DataNetwork.Name != r.Spec.DataNetwork.Name) ||
+		(old.Spec.DataNetwork.CIDR != r.Spec.DataNetwork.CIDR)
+}
+
+// isOfflineValidation checks if the webhook should skip all the validations which need
+// to talk to the avi controller. The per object annotation can always ask for the
+// online validation, but it only skips it on create: an object in use is changed
+// with the global validation mode, so the annotation can't bypass the checks of
+// the avi references it switches to.
+func (r *AKODeploymentConfig) isOfflineValidation(create bool) bool {
+	mode := validationMode
+	if m, ok := r.Annotations[AKODeploymentConfigValidationModeAnnotation]; ok && (create || m == ValidationModeOnline) {
+		mode = m
+	}
+	return mode == ValidationModeOffline
+}
+
 // validateReplicaCount checks replica count is between 1 and 2 or is unset
 func (r *AKODeploymentConfig) validateReplicaCount() *field.Error {
 	if r.Spec.ExtraConfigs.ReplicaCount == nil {
@@ -259,6 +387,22 @@ func (r *AKODeploymentConfig) validateAviSecret(secret *corev1.Secret, secretRef
 	return nil
 }
 
+// validateAviSecretReference checks NSX Advanced Load Balancer related credentials or certificate secret
+// reference is set, without looking up the secret
+func (r *AKODeploymentConfig) validateAviSecretReference(secretRef SecretReference, name string) field.ErrorList {
+	var allErrs field.ErrorList
+	if secretRef == nil {
+		return append(allErrs, field.Required(field.NewPath("spec", name), "secret reference should be set"))
+	}
+	if secretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", name, "name"), "secret name should be set"))
+	}
+	if secretRef.Namespace == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", name, "namespace"), "secret namespace should be set"))
+	}
+	return allErrs
+}
+
 // validateAviControllerVersion checks NSX Advanced Load Balancer controller version valid or not
 func (r *AKODeploymentConfig) validateAviControllerVersion() (string, *field.Error) {
 	controllerVersion := ""
@@ -288,18 +432,6 @@ func (r *AKODeploymentConfig) validateAviAccount(username, password, certificate
 	return aviClient, nil
 }
 
-// validateAviCloud checks input Cloud Name field valid or not
-func (r *AKODeploymentConfig) validateAviCloud() *field.Error {
-	if cloud, err := aviClient.CloudGetByName(r.Spec.CloudName); err != nil {
-		return field.Invalid(field.NewPath("spec", "cloudName"), r.Spec.CloudName,
-			"failed to get cloud from avi controller:"+err.Error())
-	} else if cloud.IPAMProviderRef == nil {
-		return field.Invalid(field.NewPath("spec", "cloudName"), r.Spec.CloudName,
-			"this cloud doesn't have any ipam profile configured")
-	}
-	return nil
-}
-
 // validateAviServiceEngineGroup checks input Servcie Engine Group valid or not
 func (r *AKODeploymentConfig) validateAviServiceEngineGroup() *field.Error {
 	if _, err := aviClient.ServiceEngineGroupGetByName(r.Spec.ServiceEngineGroup, r.Spec.CloudName); err != nil {
@@ -309,7 +441,7 @@ func (r *AKODeploymentConfig) validateAviServiceEngineGroup() *field.Error {
 	return nil
 }
 
-// validateAviControlPlaneNetworks checks input Control Plane Network name existing or not, CIDR format valid or not
+// validateAviControlPlaneNetworks checks input Control Plane Network name existing or not
 func (r *AKODeploymentConfig) validateAviControlPlaneNetworks() field.ErrorList {
 	var allErrs field.ErrorList
 	if r.Spec.ControlPlaneNetwork.Name == "" || r.Spec.ControlPlaneNetwork.CIDR == "" {
@@ -321,6 +453,15 @@ func (r *AKODeploymentConfig) validateAviControlPlaneNetworks() field.ErrorList
 			r.Spec.ControlPlaneNetwork.Name,
 			"failed to get control plane network "+r.Spec.ControlPlaneNetwork.Name+" from avi controller:"+err.Error()))
 	}
+	return allErrs
+}
+
+// validateControlPlaneNetworkCIDR checks input Control Plane Network CIDR format valid or not
+func (r *AKODeploymentConfig) validateControlPlaneNetworkCIDR() field.ErrorList {
+	var allErrs field.ErrorList
+	if r.Spec.ControlPlaneNetwork.Name == "" || r.Spec.ControlPlaneNetwork.CIDR == "" {
+		return allErrs
+	}
 	// check network cidr validate or not
 	_, _, err := net.ParseCIDR(r.Spec.ControlPlaneNetwork.CIDR)
 	if err != nil {
@@ -331,10 +472,7 @@ func (r *AKODeploymentConfig) validateAviControlPlaneNetworks() field.ErrorList
 	return allErrs
 }
 
-// validateAviDataNetworks checks input
-// Data Plane Network name existing or not
-// CIDR format valid or not
-// IPPools format valid or not
+// validateAviDataNetworks checks input Data Plane Network name existing or not
 func (r *AKODeploymentConfig) validateAviDataNetworks() field.ErrorList {
 	var allErrs field.ErrorList
 	// check data network name
@@ -343,10 +481,18 @@ func (r *AKODeploymentConfig) validateAviDataNetworks() field.ErrorList {
 			r.Spec.DataNetwork.Name,
 			"failed to get data plane network "+r.Spec.DataNetwork.Name+" from avi controller:"+err.Error()))
 	}
+	return allErrs
+}
+
+// validateDataNetworkCIDR checks input
+// CIDR format valid or not
+// IPPools format valid or not
+func (r *AKODeploymentConfig) validateDataNetworkCIDR() field.ErrorList {
+	var allErrs field.ErrorList
 	// check network cidr
 	addr, cidr, err := net.ParseCIDR(r.Spec.DataNetwork.CIDR)
 	if err != nil {
-		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "dataNetwork", "cidr"),
+		return append(allErrs, field.Invalid(field.NewPath("spec", "dataNetwork", "cidr"),
 			r.Spec.DataNetwork.CIDR,
 			"data plane network cidr "+r.Spec.DataNetwork.CIDR+" is not valid:"+err.Error()))
 	}
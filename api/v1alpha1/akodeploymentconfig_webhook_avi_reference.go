@@ -0,0 +1,244 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"github.com/vmware/alb-sdk/go/models"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+// aviReference is an object in the avi controller which an AKODeploymentConfig
+// refers to, either directly by name or indirectly through another object
+type aviReference string
+
+const (
+	aviReferenceTenant              aviReference = "tenant"
+	aviReferenceRole                aviReference = "role"
+	aviReferenceCloud               aviReference = "cloud"
+	aviReferenceIPAMProfile         aviReference = "ipamProfile"
+	aviReferenceServiceEngineGroup  aviReference = "serviceEngineGroup"
+	aviReferenceControlPlaneNetwork aviReference = "controlPlaneNetwork"
+	aviReferenceDataNetwork         aviReference = "dataNetwork"
+)
+
+// aviReferenceOrder is the order avi references are validated in, every reference
+// comes after the references it depends on
+var aviReferenceOrder = []aviReference{
+	aviReferenceTenant,
+	aviReferenceRole,
+	aviReferenceCloud,
+	aviReferenceIPAMProfile,
+	aviReferenceServiceEngineGroup,
+	aviReferenceControlPlaneNetwork,
+	aviReferenceDataNetwork,
+}
+
+// aviReferenceDependents is the dependency graph of avi references. Objects looked
+// up within a cloud or a tenant have to be revalidated when the cloud or the tenant
+// changes, even if their own name stays the same.
+var aviReferenceDependents = map[aviReference][]aviReference{
+	aviReferenceTenant: {aviReferenceRole},
+	aviReferenceCloud: {
+		aviReferenceIPAMProfile,
+		aviReferenceServiceEngineGroup,
+		aviReferenceControlPlaneNetwork,
+		aviReferenceDataNetwork,
+	},
+}
+
+// aviReferenceValidator carries the avi objects resolved so far, so dependent
+// references can be checked against their parents
+type aviReferenceValidator struct {
+	adc    *AKODeploymentConfig
+	tenant *models.Tenant
+	cloud  *models.Cloud
+}
+
+// validateAviReferences validates every avi reference affected by the change from
+// old to r. When old is nil all the references are validated. A reference whose
+// parent failed validation is skipped, since it can't be looked up anyway.
+func (r *AKODeploymentConfig) validateAviReferences(old *AKODeploymentConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	affected := affectedAviReferences(old, r)
+	failed := map[aviReference]bool{}
+	v := &aviReferenceValidator{adc: r}
+	for _, ref := range aviReferenceOrder {
+		if !affected[ref] {
+			continue
+		}
+		if parent, ok := aviReferenceParent(ref); ok && failed[parent] {
+			failed[ref] = true
+			continue
+		}
+		if errs := v.validate(ref); len(errs) != 0 {
+			failed[ref] = true
+			allErrs = append(allErrs, errs...)
+		}
+	}
+	return allErrs
+}
+
+// affectedAviReferences returns the avi references which changed from old to r
+// together with all their dependents
+func affectedAviReferences(old, r *AKODeploymentConfig) map[aviReference]bool {
+	affected := map[aviReference]bool{}
+	var visit func(ref aviReference)
+	visit = func(ref aviReference) {
+		if affected[ref] {
+			return
+		}
+		affected[ref] = true
+		for _, dependent := range aviReferenceDependents[ref] {
+			visit(dependent)
+		}
+	}
+	for _, ref := range aviReferenceOrder {
+		if old == nil || aviReferenceChanged(old, r, ref) {
+			visit(ref)
+		}
+	}
+	return affected
+}
+
+// aviReferenceParent returns the avi reference ref depends on, if any
+func aviReferenceParent(ref aviReference) (aviReference, bool) {
+	for parent, dependents := range aviReferenceDependents {
+		for _, dependent := range dependents {
+			if dependent == ref {
+				return parent, true
+			}
+		}
+	}
+	return "", false
+}
+
+// aviReferenceChanged checks if the AKODeploymentConfig fields naming ref are updated,
+// references which are only resolved through their parent never change by themselves
+func aviReferenceChanged(old, r *AKODeploymentConfig, ref aviReference) bool {
+	switch ref {
+	case aviReferenceTenant:
+		// the role is checked against the tenant, which is looked up again
+		// whenever the role changes
+		return old.Spec.Tenant.Name != r.Spec.Tenant.Name || aviReferenceChanged(old, r, aviReferenceRole)
+	case aviReferenceRole:
+		return (old.Spec.RolePermissionProfileRef == nil) != (r.Spec.RolePermissionProfileRef == nil)
+	case aviReferenceCloud:
+		return old.Spec.CloudName != r.Spec.CloudName
+	case aviReferenceServiceEngineGroup:
+		return old.Spec.ServiceEngineGroup != r.Spec.ServiceEngineGroup
+	case aviReferenceControlPlaneNetwork:
+		return old.Spec.ControlPlaneNetwork.Name != r.Spec.ControlPlaneNetwork.Name
+	case aviReferenceDataNetwork:
+		return dataNetworkChanged(old, r)
+	}
+	return false
+}
+
+func (v *aviReferenceValidator) validate(ref aviReference) field.ErrorList {
+	switch ref {
+	case aviReferenceTenant:
+		return toErrorList(v.validateTenant())
+	case aviReferenceRole:
+		return toErrorList(v.validateRole())
+	case aviReferenceCloud:
+		return toErrorList(v.validateCloud())
+	case aviReferenceIPAMProfile:
+		return toErrorList(v.validateIPAMProfile())
+	case aviReferenceServiceEngineGroup:
+		return toErrorList(v.adc.validateAviServiceEngineGroup())
+	case aviReferenceControlPlaneNetwork:
+		return v.adc.validateAviControlPlaneNetworks()
+	case aviReferenceDataNetwork:
+		return v.adc.validateAviDataNetworks()
+	}
+	return nil
+}
+
+// validateTenant checks the tenant the ako user will be created in exists. Tenants
+// derived from each cluster can't be checked before the clusters exist.
+func (v *aviReferenceValidator) validateTenant() *field.Error {
+	if v.isPerClusterTenant() {
+		return nil
+	}
+	tenantName := v.adc.Spec.Tenant.Name
+	if tenantName == "" {
+		tenantName = AviDefaultTenant
+	}
+	tenant, err := aviClient.TenantGet(tenantName)
+	if err != nil {
+		return field.Invalid(field.NewPath("spec", "tenant", "name"), v.adc.Spec.Tenant.Name,
+			"failed to get tenant "+tenantName+" from avi controller:"+err.Error())
+	}
+	v.tenant = tenant
+	return nil
+}
+
+// validateRole checks the ako user role can be assigned in the tenant. The role is
+// created by the operator when it doesn't exist, otherwise it has to belong to the
+// tenant itself or to the admin tenant whose objects are shared with all tenants.
+func (v *aviReferenceValidator) validateRole() *field.Error {
+	// the role is created in each cluster's own tenant
+	if v.isPerClusterTenant() {
+		return nil
+	}
+	// an AKODeploymentConfig with its own role permission profile gets its own role
+	roleName := AkoUserRoleName
+	if v.adc.Spec.RolePermissionProfileRef != nil {
+		roleName = AkoUserRoleName + "-" + v.adc.Name
+	}
+	role, err := aviClient.RoleGetByName(roleName)
+	if aviclient.IsAviRoleNonExistentError(err) {
+		return nil
+	}
+	if err != nil {
+		return field.Invalid(field.NewPath("spec", "tenant", "name"), v.adc.Spec.Tenant.Name,
+			"failed to get role "+roleName+" from avi controller:"+err.Error())
+	}
+	if role == nil || role.TenantRef == nil || v.tenant == nil || v.tenant.URL == nil {
+		return nil
+	}
+	roleTenant := aviclient.GetUUIDFromRef(*role.TenantRef)
+	if roleTenant != AviDefaultTenant && roleTenant != aviclient.GetUUIDFromRef(*v.tenant.URL) {
+		return field.Invalid(field.NewPath("spec", "tenant", "name"), v.adc.Spec.Tenant.Name,
+			"role "+roleName+" belongs to tenant "+roleTenant+" and can't be assigned to users in this tenant")
+	}
+	return nil
+}
+
+// validateCloud checks input Cloud Name field valid or not
+func (v *aviReferenceValidator) validateCloud() *field.Error {
+	cloud, err := aviClient.CloudGetByName(v.adc.Spec.CloudName)
+	if err != nil {
+		return field.Invalid(field.NewPath("spec", "cloudName"), v.adc.Spec.CloudName,
+			"failed to get cloud from avi controller:"+err.Error())
+	}
+	v.cloud = cloud
+	return nil
+}
+
+// validateIPAMProfile checks the cloud has an ipam profile configured and it exists
+func (v *aviReferenceValidator) validateIPAMProfile() *field.Error {
+	if v.cloud == nil || v.cloud.IPAMProviderRef == nil {
+		return field.Invalid(field.NewPath("spec", "cloudName"), v.adc.Spec.CloudName,
+			"this cloud doesn't have any ipam profile configured")
+	}
+	if _, err := aviClient.IPAMDNSProviderProfileGet(aviclient.GetUUIDFromRef(*v.cloud.IPAMProviderRef)); err != nil {
+		return field.Invalid(field.NewPath("spec", "cloudName"), v.adc.Spec.CloudName,
+			"failed to get ipam profile of this cloud from avi controller:"+err.Error())
+	}
+	return nil
+}
+
+func (v *aviReferenceValidator) isPerClusterTenant() bool {
+	return v.adc.Spec.Tenant.Mode == AVITenantModeNamespace || v.adc.Spec.Tenant.Mode == AVITenantModeLabelTemplate
+}
+
+func toErrorList(err *field.Error) field.ErrorList {
+	if err == nil {
+		return nil
+	}
+	return field.ErrorList{err}
+}
@@ -5,11 +5,13 @@ package v1alpha1
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
 	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
@@ -115,6 +117,16 @@ func configureAVIController() {
 	aviClient.NetworkCreate(&models.Network{
 		Name: ptr.To("fake-data-plane"),
 	})
+	fakeAviClient := aviClient.(*aviclient.FakeAviClient)
+	fakeAviClient.IPAMDNSProviderProfile.SetGetIPAMFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error) {
+		return &models.IPAMDNSProviderProfile{Name: ptr.To(uuid)}, nil
+	})
+	fakeAviClient.Tenant.SetGetTenantFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+		return &models.Tenant{Name: ptr.To(uuid), URL: ptr.To("https://10.0.0.x/api/tenant/" + uuid)}, nil
+	})
+	fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+		return nil, errors.New("No object of type role with name " + name + " is found")
+	})
 }
 
 func TestCreateNewAKODeploymentConfig(t *testing.T) {
@@ -148,6 +160,20 @@ func TestCreateNewAKODeploymentConfig(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name:              "per cluster tenant mode should not look up the tenant",
+			adminSecret:       staticAdminSecret.DeepCopy(),
+			certificateSecret: staticCASecret.DeepCopy(),
+			adc:               staticADC.DeepCopy(),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				aviClient.(*aviclient.FakeAviClient).Tenant.SetGetTenantFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+					return nil, errors.New("can't find tenant")
+				})
+				adc.Spec.Tenant.Mode = AVITenantModeNamespace
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: false,
+		},
 		{
 			name:              "wrong controller version formt should return error",
 			adminSecret:       staticAdminSecret.DeepCopy(),
@@ -414,6 +440,38 @@ func TestCreateNewAKODeploymentConfig(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name:              "control plane dns with a valid domain should pass webhook validation",
+			adminSecret:       staticAdminSecret.DeepCopy(),
+			certificateSecret: staticCASecret.DeepCopy(),
+			adc:               staticADC.DeepCopy(),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.ControlPlaneDNS = &ControlPlaneDNS{Domain: "k8s.example.com", Provider: ControlPlaneDNSProviderAviDNS}
+				return adminSecret, certificateSecret, adc
+			},
+		},
+		{
+			name:              "should throw error if control plane dns domain is invalid",
+			adminSecret:       staticAdminSecret.DeepCopy(),
+			certificateSecret: staticCASecret.DeepCopy(),
+			adc:               staticADC.DeepCopy(),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.ControlPlaneDNS = &ControlPlaneDNS{Domain: "K8s_example"}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name:              "should throw error if control plane dns provider is unknown",
+			adminSecret:       staticAdminSecret.DeepCopy(),
+			certificateSecret: staticCASecret.DeepCopy(),
+			adc:               staticADC.DeepCopy(),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.ControlPlaneDNS = &ControlPlaneDNS{Domain: "k8s.example.com", Provider: "Route53"}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -585,6 +643,359 @@ func TestUpdateExistingAKODeploymentConfig(t *testing.T) {
 	}
 }
 
+func TestUpdateAviReferencesOfAKODeploymentConfig(t *testing.T) {
+	staticAdminSecret, staticCASecret, staticADC, g := beforeAll(t)
+	fakeAviClient := aviClient.(*aviclient.FakeAviClient)
+	testcases := []struct {
+		name           string
+		customizeInput func(adc *AKODeploymentConfig) *AKODeploymentConfig
+		expectErr      bool
+	}{
+		{
+			name: "unchanged avi references should not be looked up again",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				aviClient.CloudCreate(nil)
+				aviClient.ServiceEngineGroupCreate(nil)
+				aviClient.NetworkCreate(nil)
+				return adc
+			},
+			expectErr: false,
+		},
+		{
+			name: "cloud change should revalidate the unchanged service engine group",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				aviClient.ServiceEngineGroupCreate(nil)
+				adc.Spec.CloudName = "fake-new-cloud"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "cloud change should revalidate the unchanged networks",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				aviClient.NetworkCreate(nil)
+				adc.Spec.CloudName = "fake-new-cloud"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "cloud change should revalidate the ipam profile",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				fakeAviClient.IPAMDNSProviderProfile.SetGetIPAMFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error) {
+					return nil, errors.New("can't find ipam profile")
+				})
+				adc.Spec.CloudName = "fake-new-cloud"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "cloud change to a cloud without ipam profile should be rejected",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				aviClient.CloudCreate(&models.Cloud{Name: ptr.To("fake-new-cloud")})
+				adc.Spec.CloudName = "fake-new-cloud"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "tenant change should validate the tenant",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				fakeAviClient.Tenant.SetGetTenantFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+					return nil, errors.New("can't find tenant")
+				})
+				adc.Spec.Tenant.Name = "fake-tenant"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "tenant change should revalidate the ako user role",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+					return &models.Role{Name: ptr.To(name), TenantRef: ptr.To("https://10.0.0.x/api/tenant/other-tenant")}, nil
+				})
+				adc.Spec.Tenant.Name = "fake-tenant"
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "role change should check the ako user role against the unchanged tenant",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+					return &models.Role{Name: ptr.To(name), TenantRef: ptr.To("https://10.0.0.x/api/tenant/other-tenant")}, nil
+				})
+				adc.Spec.RolePermissionProfileRef = &ConfigMapRef{Name: "ako-role-profile", Namespace: "default"}
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "tenant mode should not be changed",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				adc.Spec.Tenant.Mode = AVITenantModeNamespace
+				return adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "tenant mode set to its default is not changed",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				adc.Spec.Tenant.Mode = AVITenantModeStatic
+				return adc
+			},
+			expectErr: false,
+		},
+		{
+			name: "ako user role in the admin tenant can be used by any tenant",
+			customizeInput: func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+				fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+					return &models.Role{Name: ptr.To(name), TenantRef: ptr.To("https://10.0.0.x/api/tenant/admin")}, nil
+				})
+				adc.Spec.Tenant.Name = "fake-tenant"
+				return adc
+			},
+			expectErr: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			adminSecret, certificateSecret := staticAdminSecret.DeepCopy(), staticCASecret.DeepCopy()
+			g.Expect(kclient.Create(context.Background(), adminSecret)).ShouldNot(HaveOccurred())
+			g.Expect(kclient.Create(context.Background(), certificateSecret)).ShouldNot(HaveOccurred())
+
+			_, err := tc.customizeInput(staticADC.DeepCopy()).ValidateUpdate(staticADC.DeepCopy())
+			if !tc.expectErr {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(err).Should(HaveOccurred())
+			}
+
+			afterEach(adminSecret, certificateSecret, g)
+		})
+	}
+}
+
+func TestAffectedAviReferences(t *testing.T) {
+	g := NewWithT(t)
+	old := &AKODeploymentConfig{Spec: AKODeploymentConfigSpec{CloudName: "cloud", ServiceEngineGroup: "seg"}}
+
+	g.Expect(affectedAviReferences(nil, old)).To(HaveLen(len(aviReferenceOrder)))
+	g.Expect(affectedAviReferences(old, old.DeepCopy())).To(BeEmpty())
+
+	seg := old.DeepCopy()
+	seg.Spec.ServiceEngineGroup = "new-seg"
+	g.Expect(affectedAviReferences(old, seg)).To(Equal(map[aviReference]bool{
+		aviReferenceServiceEngineGroup: true,
+	}))
+
+	cloud := old.DeepCopy()
+	cloud.Spec.CloudName = "new-cloud"
+	g.Expect(affectedAviReferences(old, cloud)).To(Equal(map[aviReference]bool{
+		aviReferenceCloud:               true,
+		aviReferenceIPAMProfile:         true,
+		aviReferenceServiceEngineGroup:  true,
+		aviReferenceControlPlaneNetwork: true,
+		aviReferenceDataNetwork:         true,
+	}))
+
+	tenant := old.DeepCopy()
+	tenant.Spec.Tenant.Name = "new-tenant"
+	g.Expect(affectedAviReferences(old, tenant)).To(Equal(map[aviReference]bool{
+		aviReferenceTenant: true,
+		aviReferenceRole:   true,
+	}))
+
+	profile := old.DeepCopy()
+	profile.Spec.RolePermissionProfileRef = &ConfigMapRef{Name: "profile", Namespace: "default"}
+	g.Expect(affectedAviReferences(old, profile)).To(Equal(map[aviReference]bool{
+		aviReferenceTenant: true,
+		aviReferenceRole:   true,
+	}))
+}
+
+func TestOfflineValidateAKODeploymentConfig(t *testing.T) {
+	staticAdminSecret, staticCASecret, staticADC, g := beforeAll(t)
+	offline := func(adc *AKODeploymentConfig) *AKODeploymentConfig {
+		adc.Annotations = map[string]string{AKODeploymentConfigValidationModeAnnotation: ValidationModeOffline}
+		return adc
+	}
+
+	testcases := []struct {
+		name              string
+		adminSecret       *corev1.Secret
+		certificateSecret *corev1.Secret
+		adc               *AKODeploymentConfig
+		customizeInput    ModifyTestCaseInputFunc
+		expectErr         bool
+	}{
+		{
+			name: "offline validation should not look up secrets",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: false,
+		},
+		{
+			name:              "offline validation should not look up avi objects",
+			adminSecret:       staticAdminSecret.DeepCopy(),
+			certificateSecret: staticCASecret.DeepCopy(),
+			adc:               offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				aviClient.CloudCreate(nil)
+				aviClient.ServiceEngineGroupCreate(nil)
+				aviClient.NetworkCreate(nil)
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: false,
+		},
+		{
+			name: "offline validation should throw error if secret reference namespace is empty",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.AdminCredentialRef = &SecretRef{Name: "test-avi-credentials"}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should throw error if cluster selector is empty",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.ClusterSelector = v1.LabelSelector{}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should throw error if invalid data plane network ip pools",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.DataNetwork.IPPools[0].Start = "12.0.0.1"
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should throw error if tenant name template is missing",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.Tenant.Mode = AVITenantModeLabelTemplate
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should throw error if tenant name template is invalid",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.Tenant.Mode = AVITenantModeLabelTemplate
+				adc.Spec.Tenant.NameTemplate = "{{ .Labels.team"
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should pass with valid tenant name template",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.Tenant.Mode = AVITenantModeLabelTemplate
+				adc.Spec.Tenant.NameTemplate = "{{ .Labels.team }}"
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: false,
+		},
+		{
+			name: "offline validation should throw error if role permission profile namespace is missing",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.RolePermissionProfileRef = &ConfigMapRef{Name: "ako-role-profile"}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+		{
+			name: "offline validation should pass with complete role permission profile",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.RolePermissionProfileRef = &ConfigMapRef{Name: "ako-role-profile", Namespace: "default"}
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: false,
+		},
+		{
+			name: "offline validation should throw error if invalid controller version",
+			adc:  offline(staticADC.DeepCopy()),
+			customizeInput: func(adminSecret, certificateSecret *corev1.Secret, adc *AKODeploymentConfig) (*corev1.Secret, *corev1.Secret, *AKODeploymentConfig) {
+				adc.Spec.ControllerVersion = "v20.1"
+				return adminSecret, certificateSecret, adc
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.adminSecret, tc.certificateSecret, tc.adc = tc.customizeInput(tc.adminSecret, tc.certificateSecret, tc.adc)
+			if tc.adminSecret != nil {
+				err := kclient.Create(context.Background(), tc.adminSecret)
+				g.Expect(err).ShouldNot(HaveOccurred())
+			}
+			if tc.certificateSecret != nil {
+				err := kclient.Create(context.Background(), tc.certificateSecret)
+				g.Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			_, err := tc.adc.ValidateCreate()
+			if !tc.expectErr {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(err).Should(HaveOccurred())
+			}
+
+			afterEach(tc.adminSecret, tc.certificateSecret, g)
+		})
+	}
+
+	t.Run("global offline validation mode applies to objects without annotation", func(t *testing.T) {
+		g.Expect(SetValidationMode(ValidationModeOffline)).ShouldNot(HaveOccurred())
+		defer func() {
+			g.Expect(SetValidationMode(ValidationModeOnline)).ShouldNot(HaveOccurred())
+		}()
+		// no secrets created, online validation would fail
+		_, err := staticADC.DeepCopy().ValidateCreate()
+		g.Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	t.Run("offline annotation should not skip online validation on update", func(t *testing.T) {
+		old := staticADC.DeepCopy()
+		adc := offline(staticADC.DeepCopy())
+		adc.Spec.ServiceEngineGroup = "another-se-group"
+		// no secrets created, online validation fails
+		_, err := adc.ValidateUpdate(old)
+		g.Expect(err).Should(HaveOccurred())
+	})
+
+	t.Run("online annotation should override global offline validation mode", func(t *testing.T) {
+		g.Expect(SetValidationMode(ValidationModeOffline)).ShouldNot(HaveOccurred())
+		defer func() {
+			g.Expect(SetValidationMode(ValidationModeOnline)).ShouldNot(HaveOccurred())
+		}()
+		adc := staticADC.DeepCopy()
+		adc.Annotations = map[string]string{AKODeploymentConfigValidationModeAnnotation: ValidationModeOnline}
+		g.Expect(adc.isOfflineValidation(false)).Should(BeFalse())
+		g.Expect(adc.isOfflineValidation(true)).Should(BeFalse())
+	})
+
+	t.Run("invalid validation mode should be rejected", func(t *testing.T) {
+		g.Expect(SetValidationMode("dry-run")).Should(HaveOccurred())
+	})
+}
+
 func TestDeleteAKODeploymentConfig(t *testing.T) {
 	staticAdminSecret, staticCASecret, staticADC, g := beforeAll(t)
 
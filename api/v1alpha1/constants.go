@@ -35,6 +35,7 @@ const (
 	AkoClusterBootstrapRefNamePrefix = "load-balancer-and-ingress-service.tanzu.vmware.com"
 	AkoPackageInstallName            = "load-balancer-and-ingress-service"
 	AkoPreferredIPAnnotation         = "ako.vmware.com/load-balancer-ip"
+	AkoVirtualServiceAnnotation      = "ako.vmware.com/host-fqdn-vs-uuid-map"
 
 	AviClusterLabel                                                     = "networking.tkg.tanzu.vmware.com/avi"
 	AviClusterDeleteConfigLabel                                         = "networking.tkg.tanzu.vmware.com/avi-config-delete"
@@ -43,18 +44,69 @@ const (
 	AviCredentialName                                                   = "avi-controller-credentials"
 	AviCAName                                                           = "avi-controller-ca"
 	AviCertificateKey                                                   = "certificateAuthorityData"
+	AviTenantAnnotation                                                 = "networking.tkg.tanzu.vmware.com/avi-tenant"
+	AviPreviousTenantAnnotation                                         = "networking.tkg.tanzu.vmware.com/avi-previous-tenant"
+	AviDNSServiceDomainAnnotation                                       = "networking.tkg.tanzu.vmware.com/avi-dns-service-domain"
+	AviTenantDescription                                                = "Created by ako-operator"
+	AviDefaultTenant                                                    = "admin"
 	AviResourceCleanupReason                                            = "AviResourceCleanup"
 	AviResourceCleanupSucceededCondition        clusterv1.ConditionType = "AviResourceCleanupSucceeded"
 	AviUserCleanupSucceededCondition            clusterv1.ConditionType = "AviUserCleanupSucceeded"
 	ClusterIpFamilyValidationSucceededCondition clusterv1.ConditionType = "ClusterIpFamilyValidationSucceeded"
+	AKOConfigurationInSyncCondition             clusterv1.ConditionType = "AKOConfigurationInSync"
+	AKOConfigurationDriftedReason                                       = "AKOConfigurationDrifted"
+	AKOConfigurationCheckFailedReason                                   = "AKOConfigurationCheckFailed"
+	AKOConfigMapName                                                    = "avi-k8s-config"
+	AKOReadyCondition                           clusterv1.ConditionType = "AKOReady"
+	AKONotDeployedReason                                                = "AKONotDeployed"
+	AKOPodNotReadyReason                                                = "AKOPodNotReady"
+	AKODisabledReason                                                   = "AKODisabled"
+	AKOObjectDeletionReason                                             = "AKOObjectDeletion"
+	AKOHealthCheckFailedReason                                          = "AKOHealthCheckFailed"
+	AKODeploymentConfigSelectedCondition        clusterv1.ConditionType = "AKODeploymentConfigSelected"
+	AKODeploymentConfigNotSelectedReason                                = "NoAKODeploymentConfigSelected"
+	AKOSelectionClusterNotReadyReason                                   = "ClusterNotReady"
+	AKOSelectionAnnotation                                              = "networking.tkg.tanzu.vmware.com/ako-selection"
 	PreTerminateAnnotation                                              = clusterv1.PreTerminateDeleteHookAnnotationPrefix + "/avi-cleanup"
+	PreDrainAnnotation                                                  = clusterv1.PreDrainDeleteHookAnnotationPrefix + "/avi-drain"
 
 	HAServiceName                      = "control-plane"
 	HAServiceBootstrapClusterFinalizer = "ako-operator.networking.tkg.tanzu.vmware.com/ha"
+	HAServiceFinalizer                 = "ako-operator.networking.tkg.tanzu.vmware.com/ha-service"
 	HAServiceAnnotationsKey            = "skipnodeport.ako.vmware.com/enabled"
 	HAAVIInfraSettingAnnotationsKey    = "aviinfrasetting.ako.vmware.com/name"
+	HAExternalDNSHostnameAnnotation    = "external-dns.alpha.kubernetes.io/hostname"
+	HAL4RuleAnnotation                 = "ako.vmware.com/l4rule"
+
+	// HAReservedVIPAnnotation records on the cluster the control plane VIP Avi
+	// serves, it is requested again when the HA service is recreated so that
+	// the control plane endpoint doesn't change
+	HAReservedVIPAnnotation = "networking.tkg.tanzu.vmware.com/avi-control-plane-vip"
+
+	// HAMigrationAnnotation moves a running cluster from kube-vip to an Avi
+	// control plane VIP, it is set to HAMigrationRequested on the cluster and
+	// then follows the progress of the migration
+	HAMigrationAnnotation        = "networking.tkg.tanzu.vmware.com/avi-ha-migration"
+	HAMigrationStartedAnnotation = "networking.tkg.tanzu.vmware.com/avi-ha-migration-started"
+	HAMigrationRequested         = "requested"
+	HAMigrationInProgress        = "in-progress"
+	HAMigrationCompleted         = "completed"
+	HAMigrationRolledBack        = "rolled-back"
+
+	AviControlPlaneHAMigratedCondition clusterv1.ConditionType = "AviControlPlaneHAMigrated"
+	HAMigrationInProgressReason                                = "HAMigrationInProgress"
+	HAMigrationRolledBackReason                                = "HAMigrationRolledBack"
 
 	AKODeploymentConfigControllerName = "akodeploymentconfig-controller"
 
+	AKODeploymentConfigValidationModeAnnotation = "networking.tkg.tanzu.vmware.com/validation-mode"
+
 	AVIControllerEnterpriseOnlyVersion = "v30.0.0"
+
+	AVITenantModeStatic        = "Static"
+	AVITenantModeNamespace     = "Namespace"
+	AVITenantModeLabelTemplate = "LabelTemplate"
+
+	ControlPlaneDNSProviderAviDNS      = "AviDNS"
+	ControlPlaneDNSProviderExternalDNS = "ExternalDNS"
 )
@@ -12,6 +12,21 @@ import (
 	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AKOClustersStatus) DeepCopyInto(out *AKOClustersStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AKOClustersStatus.
+func (in *AKOClustersStatus) DeepCopy() *AKOClustersStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AKOClustersStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AKODeploymentConfig) DeepCopyInto(out *AKODeploymentConfig) {
 	*out = *in
@@ -91,8 +106,23 @@ func (in *AKODeploymentConfigSpec) DeepCopyInto(out *AKODeploymentConfigSpec) {
 		**out = **in
 	}
 	out.Tenant = in.Tenant
+	if in.RolePermissionProfileRef != nil {
+		in, out := &in.RolePermissionProfileRef, &out.RolePermissionProfileRef
+		*out = new(ConfigMapRef)
+		**out = **in
+	}
 	in.DataNetwork.DeepCopyInto(&out.DataNetwork)
 	out.ControlPlaneNetwork = in.ControlPlaneNetwork
+	if in.ControlPlaneDNS != nil {
+		in, out := &in.ControlPlaneDNS, &out.ControlPlaneDNS
+		*out = new(ControlPlaneDNS)
+		**out = **in
+	}
+	if in.ControlPlaneAviInfraSetting != nil {
+		in, out := &in.ControlPlaneAviInfraSetting, &out.ControlPlaneAviInfraSetting
+		*out = new(ControlPlaneAviInfraSetting)
+		(*in).DeepCopyInto(*out)
+	}
 	in.ExtraConfigs.DeepCopyInto(&out.ExtraConfigs)
 }
 
@@ -116,6 +146,7 @@ func (in *AKODeploymentConfigStatus) DeepCopyInto(out *AKODeploymentConfigStatus
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	out.Clusters = in.Clusters
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AKODeploymentConfigStatus.
@@ -235,6 +266,66 @@ func (in *AVITenant) DeepCopy() *AVITenant {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapRef) DeepCopyInto(out *ConfigMapRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapRef.
+func (in *ConfigMapRef) DeepCopy() *ConfigMapRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneAviInfraSetting) DeepCopyInto(out *ControlPlaneAviInfraSetting) {
+	*out = *in
+	if in.EnableRHI != nil {
+		in, out := &in.EnableRHI, &out.EnableRHI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BGPPeerLabels != nil {
+		in, out := &in.BGPPeerLabels, &out.BGPPeerLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnablePublicIP != nil {
+		in, out := &in.EnablePublicIP, &out.EnablePublicIP
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneAviInfraSetting.
+func (in *ControlPlaneAviInfraSetting) DeepCopy() *ControlPlaneAviInfraSetting {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneAviInfraSetting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneDNS) DeepCopyInto(out *ControlPlaneDNS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneDNS.
+func (in *ControlPlaneDNS) DeepCopy() *ControlPlaneDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ControlPlaneNetwork) DeepCopyInto(out *ControlPlaneNetwork) {
 	*out = *in
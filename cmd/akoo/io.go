@@ -0,0 +1,79 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// readObjects reads all the kubernetes objects from a yaml or json file, "-" reads
+// from stdin
+func readObjects(path string) ([]*unstructured.Unstructured, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrapf(err, "failed to decode %s", path)
+		}
+		// skip empty documents
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// readObjectsOfKind reads all the objects of the given kind from the files and
+// converts them to typed objects with newObj
+func readObjectsOfKind[T runtime.Object](paths []string, kind string, newObj func() T) ([]T, error) {
+	var res []T
+	for _, path := range paths {
+		objs, err := readObjects(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if obj.GetKind() != kind {
+				continue
+			}
+			// round trip through json instead of using the unstructured converter,
+			// so type errors point to the offending field
+			raw, err := obj.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			typed := newObj()
+			if err := json.Unmarshal(raw, typed); err != nil {
+				return nil, errors.Wrapf(err, "failed to convert %s %s in %s", kind, obj.GetName(), path)
+			}
+			res = append(res, typed)
+		}
+	}
+	return res, nil
+}
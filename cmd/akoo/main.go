@@ -0,0 +1,30 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// akoo is the command line companion of AKO Operator, it runs the operator logic
+// against local files so it can be used without a management cluster.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newRootCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "akoo",
+		Short:         "akoo runs AKO Operator logic against local files",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+	cmd.AddCommand(newValidateCommand())
+	cmd.AddCommand(newRenderCommand())
+	return cmd
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
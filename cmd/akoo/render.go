@@ -0,0 +1,187 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/cmd/internal/kubeclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/cluster"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
+)
+
+type renderOptions struct {
+	files       []string
+	secretFiles []string
+	adcName     string
+	clusterName string
+	diff        bool
+	kubeconfig  string
+	context     string
+}
+
+func newRenderCommand() *cobra.Command {
+	opts := &renderOptions{}
+	cmd := &cobra.Command{
+		Use:   "render -f akodeploymentconfig.yaml -f cluster.yaml",
+		Short: "Render the AKO add-on values AKO Operator generates for a cluster",
+		Long: `Render prints the values.yaml of the AKO add-on secret AKO Operator generates for a
+Cluster from an AKODeploymentConfig. Legacy clusters get the ytt data values header,
+ClusterClass based clusters get plain values.
+
+The Avi user credentials are read from the <cluster>-avi-credentials secret in --user-secret,
+they are left empty when no secret is given.
+
+When --diff is set, the rendered values are compared with the ones of the
+<cluster>-load-balancer-and-ingress-service-addon secret in the management cluster of the
+kubeconfig. The Avi user secret is read from the management cluster as well unless
+--user-secret is set. The command fails when the values differ.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var c client.Client
+			if opts.diff {
+				var err error
+				if c, err = kubeclient.New(opts.kubeconfig, opts.context, corev1.AddToScheme); err != nil {
+					return err
+				}
+			}
+			return runRender(cmd.Context(), cmd.OutOrStdout(), opts, c)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&opts.files, "filename", "f", nil, "AKODeploymentConfig and Cluster files, - reads from stdin")
+	cmd.Flags().StringSliceVar(&opts.secretFiles, "user-secret", nil, "Files with the Avi user secret of the cluster")
+	cmd.Flags().StringVar(&opts.adcName, "akodeploymentconfig", "", "Name of the AKODeploymentConfig to render, required when the files have several")
+	cmd.Flags().StringVar(&opts.clusterName, "cluster", "", "Name of the Cluster to render, required when the files have several")
+	cmd.Flags().BoolVar(&opts.diff, "diff", false, "Compare the rendered values with the add-on secret in the management cluster")
+	cmd.Flags().StringVar(&opts.kubeconfig, "kubeconfig", "", "Kubeconfig of the management cluster, defaults to the KUBECONFIG rules")
+	cmd.Flags().StringVar(&opts.context, "context", "", "Kubeconfig context of the management cluster")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+func runRender(ctx context.Context, out io.Writer, opts *renderOptions, c client.Client) error {
+	adcs, err := readAKODeploymentConfigs(opts.files)
+	if err != nil {
+		return err
+	}
+	adc, err := pickObject(adcs, akoov1alpha1.AkoDeploymentConfigKind, opts.adcName)
+	if err != nil {
+		return err
+	}
+	clusters, err := readObjectsOfKind(opts.files, "Cluster", func() *clusterv1.Cluster {
+		return &clusterv1.Cluster{}
+	})
+	if err != nil {
+		return err
+	}
+	cl, err := pickObject(clusters, "Cluster", opts.clusterName)
+	if err != nil {
+		return err
+	}
+	if cl.Namespace == "" {
+		cl.Namespace = "default"
+	}
+
+	userSecret, err := renderUserSecret(ctx, opts, c, cl)
+	if err != nil {
+		return err
+	}
+	values, err := cluster.AkoAddonSecretDataYaml(cl, adc, userSecret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to render AKO values of cluster %s/%s", cl.Namespace, cl.Name)
+	}
+	if !opts.diff {
+		_, err = io.WriteString(out, values)
+		return err
+	}
+
+	key := client.ObjectKey{Name: utils.AKOAddonSecretName(cl), Namespace: cl.Namespace}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return errors.Wrapf(err, "failed to get AKO add-on secret %s", key)
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(secret.Data[akoov1alpha1.TKGAddOnSecretDataKey])),
+		B:        difflib.SplitLines(values),
+		FromFile: "secret/" + key.String(),
+		ToFile:   "rendered",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Fprintf(out, "AKO add-on secret %s is up to date\n", key)
+		return nil
+	}
+	fmt.Fprint(out, diff)
+	return errors.Errorf("AKO add-on secret %s differs from the rendered values", key)
+}
+
+// renderUserSecret returns the Avi user secret of the cluster, from the
+// --user-secret files when set, from the management cluster in diff mode, or
+// an empty one
+func renderUserSecret(ctx context.Context, opts *renderOptions, c client.Client, cl *clusterv1.Cluster) (*corev1.Secret, error) {
+	name := utils.AVIUserSecretName(cl)
+	if len(opts.secretFiles) != 0 {
+		secrets, err := readObjectsOfKind(opts.secretFiles, "Secret", func() *corev1.Secret {
+			return &corev1.Secret{}
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range secrets {
+			if s.Name == name {
+				// stringData is only merged by the API server
+				for k, v := range s.StringData {
+					if s.Data == nil {
+						s.Data = map[string][]byte{}
+					}
+					s.Data[k] = []byte(v)
+				}
+				return s, nil
+			}
+		}
+		return nil, errors.Errorf("no Secret %s found", name)
+	}
+	if opts.diff {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: cl.Namespace}, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to get Avi user secret %s/%s", cl.Namespace, name)
+		}
+		return secret, nil
+	}
+	return &corev1.Secret{}, nil
+}
+
+// pickObject returns the object with the given name, or the only object when
+// name is empty
+func pickObject[T client.Object](objs []T, kind, name string) (T, error) {
+	var zero T
+	if name == "" {
+		switch len(objs) {
+		case 0:
+			return zero, errors.Errorf("no %s found", kind)
+		case 1:
+			return objs[0], nil
+		default:
+			return zero, errors.Errorf("%d %ss found, select one by name", len(objs), kind)
+		}
+	}
+	for _, obj := range objs {
+		if obj.GetName() == name {
+			return obj, nil
+		}
+	}
+	return zero, errors.Errorf("no %s %s found", kind, name)
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+const testCluster = `apiVersion: cluster.x-k8s.io/v1beta1
+kind: Cluster
+metadata:
+  name: test-cluster
+  namespace: default
+`
+
+const testClusterClassCluster = `apiVersion: cluster.x-k8s.io/v1beta1
+kind: Cluster
+metadata:
+  name: test-cluster
+  namespace: default
+spec:
+  topology:
+    class: tkg-vsphere-default
+    version: v1.28.0
+`
+
+const testUserSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-cluster-avi-credentials
+  namespace: default
+stringData:
+  username: test-cluster
+data:
+  password: QWRtaW4hMjM=
+`
+
+func TestRender(t *testing.T) {
+	testcases := []struct {
+		name        string
+		files       []string
+		secretFiles []string
+		expectErr   bool
+		expectOut   []string
+		unexpectOut []string
+	}{
+		{
+			name:      "legacy cluster should get the ytt data values header",
+			files:     []string{writeFile(t, validADC), writeFile(t, testCluster)},
+			expectOut: []string{akoov1alpha1.TKGDataValueFormatString, "cluster_name: default-test-cluster", "cloud_name: fake-cloud"},
+		},
+		{
+			name:        "clusterclass based cluster should get plain values",
+			files:       []string{writeFile(t, validADC+"---\n"+testClusterClassCluster)},
+			expectOut:   []string{"loadBalancerAndIngressService:", "tkg_cluster_role: workload"},
+			unexpectOut: []string{"#@data/values"},
+		},
+		{
+			name:        "user secret should fill the avi credentials",
+			files:       []string{writeFile(t, validADC), writeFile(t, testCluster)},
+			secretFiles: []string{writeFile(t, testUserSecret)},
+			expectOut:   []string{"username: test-cluster", "password: Admin!23"},
+		},
+		{
+			name:        "missing user secret should fail",
+			files:       []string{writeFile(t, validADC), writeFile(t, testCluster)},
+			secretFiles: []string{writeFile(t, strings.Replace(testUserSecret, "test-cluster-avi", "other-avi", 1))},
+			expectErr:   true,
+		},
+		{
+			name:      "file without cluster should fail",
+			files:     []string{writeFile(t, validADC)},
+			expectErr: true,
+		},
+		{
+			name:      "several clusters without a name should fail",
+			files:     []string{writeFile(t, validADC), writeFile(t, testCluster), writeFile(t, strings.Replace(testCluster, "test-cluster", "other", 1))},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			out := &bytes.Buffer{}
+			err := runRender(context.Background(), out, &renderOptions{files: tc.files, secretFiles: tc.secretFiles}, nil)
+			if tc.expectErr {
+				g.Expect(err).Should(HaveOccurred())
+				return
+			}
+			g.Expect(err).ShouldNot(HaveOccurred())
+			for _, s := range tc.expectOut {
+				g.Expect(out.String()).Should(ContainSubstring(s))
+			}
+			for _, s := range tc.unexpectOut {
+				g.Expect(out.String()).ShouldNot(ContainSubstring(s))
+			}
+		})
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	g := NewWithT(t)
+
+	opts := &renderOptions{
+		files: []string{writeFile(t, validADC), writeFile(t, testCluster)},
+		diff:  true,
+	}
+	userSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-avi-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("test-cluster"), "password": []byte("Admin!23")},
+	}
+	rendered := &bytes.Buffer{}
+	g.Expect(runRender(context.Background(), rendered, &renderOptions{files: opts.files, secretFiles: []string{writeFile(t, testUserSecret)}}, nil)).Should(Succeed())
+
+	newAddonSecret := func(values string) client.Object {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-load-balancer-and-ingress-service-addon", Namespace: "default"},
+			Data:       map[string][]byte{akoov1alpha1.TKGAddOnSecretDataKey: []byte(values)},
+		}
+	}
+
+	t.Run("up to date secret should pass", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithObjects(userSecret, newAddonSecret(rendered.String())).Build()
+		out := &bytes.Buffer{}
+		g.Expect(runRender(context.Background(), out, opts, c)).Should(Succeed())
+		g.Expect(out.String()).Should(ContainSubstring("is up to date"))
+	})
+
+	t.Run("outdated secret should print the diff and fail", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithObjects(userSecret,
+			newAddonSecret(strings.Replace(rendered.String(), "cloud_name: fake-cloud", "cloud_name: old-cloud", 1))).Build()
+		out := &bytes.Buffer{}
+		g.Expect(runRender(context.Background(), out, opts, c)).ShouldNot(Succeed())
+		g.Expect(out.String()).Should(ContainSubstring("-            cloud_name: old-cloud"))
+		g.Expect(out.String()).Should(ContainSubstring("+            cloud_name: fake-cloud"))
+	})
+
+	t.Run("missing secret should fail", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithObjects(userSecret).Build()
+		g.Expect(runRender(context.Background(), &bytes.Buffer{}, opts, c)).ShouldNot(Succeed())
+	})
+}
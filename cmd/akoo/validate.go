@@ -0,0 +1,82 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+type validateOptions struct {
+	files    []string
+	oldFiles []string
+}
+
+func newValidateCommand() *cobra.Command {
+	opts := &validateOptions{}
+	cmd := &cobra.Command{
+		Use:   "validate -f akodeploymentconfig.yaml",
+		Short: "Validate AKODeploymentConfig files without a management cluster or NSX Advanced Load Balancer controller",
+		Long: `Validate runs the structural checks of the AKODeploymentConfig webhook, the same checks
+the webhook runs in offline validation mode. Objects referenced by the AKODeploymentConfig,
+like secrets, clouds, service engine groups and networks are not looked up.
+
+When --old is set, AKODeploymentConfigs in --filename are validated as updates of the
+AKODeploymentConfigs with the same name in --old.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runValidate(cmd.OutOrStdout(), opts)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&opts.files, "filename", "f", nil, "AKODeploymentConfig files to validate, - reads from stdin")
+	cmd.Flags().StringSliceVar(&opts.oldFiles, "old", nil, "Current AKODeploymentConfig files, used to validate update rules")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+func runValidate(out io.Writer, opts *validateOptions) error {
+	adcs, err := readAKODeploymentConfigs(opts.files)
+	if err != nil {
+		return err
+	}
+	if len(adcs) == 0 {
+		return errors.New("no AKODeploymentConfig found")
+	}
+	oldADCs, err := readAKODeploymentConfigs(opts.oldFiles)
+	if err != nil {
+		return err
+	}
+	oldByName := map[string]*akoov1alpha1.AKODeploymentConfig{}
+	for _, adc := range oldADCs {
+		oldByName[adc.Name] = adc
+	}
+
+	invalid := 0
+	for _, adc := range adcs {
+		allErrs := adc.ValidateStructure(oldByName[adc.Name])
+		if len(allErrs) == 0 {
+			fmt.Fprintf(out, "AKODeploymentConfig %s is valid\n", adc.Name)
+			continue
+		}
+		invalid++
+		fmt.Fprintf(out, "AKODeploymentConfig %s is invalid:\n", adc.Name)
+		for _, e := range allErrs {
+			fmt.Fprintf(out, "  - %s\n", e.Error())
+		}
+	}
+	if invalid != 0 {
+		return errors.Errorf("%d of %d AKODeploymentConfigs are invalid", invalid, len(adcs))
+	}
+	return nil
+}
+
+func readAKODeploymentConfigs(paths []string) ([]*akoov1alpha1.AKODeploymentConfig, error) {
+	return readObjectsOfKind(paths, akoov1alpha1.AkoDeploymentConfigKind, func() *akoov1alpha1.AKODeploymentConfig {
+		return &akoov1alpha1.AKODeploymentConfig{}
+	})
+}
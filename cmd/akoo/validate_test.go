@@ -0,0 +1,103 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const validADC = `apiVersion: networking.tkg.tanzu.vmware.com/v1alpha1
+kind: AKODeploymentConfig
+metadata:
+  name: test
+spec:
+  cloudName: fake-cloud
+  controller: 1.1.1.1
+  serviceEngineGroup: fake-seg
+  clusterSelector:
+    matchLabels:
+      foo: bar
+  adminCredentialRef:
+    name: avi-controller-credentials
+    namespace: default
+  certificateAuthorityRef:
+    name: avi-controller-ca
+    namespace: default
+  dataNetwork:
+    name: fake-data-plane
+    cidr: 10.0.0.0/24
+    ipPools:
+    - start: 10.0.0.1
+      end: 10.0.0.10
+      type: V4
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: avi-controller-credentials
+  namespace: default
+`
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "adc.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	testcases := []struct {
+		name      string
+		files     []string
+		oldFiles  []string
+		expectErr bool
+		expectOut string
+	}{
+		{
+			name:      "valid akodeploymentconfig should pass validation",
+			files:     []string{writeFile(t, validADC)},
+			expectOut: "AKODeploymentConfig test is valid",
+		},
+		{
+			name:      "invalid data network cidr should fail validation",
+			files:     []string{writeFile(t, strings.Replace(validADC, "cidr: 10.0.0.0/24", "cidr: 10.0.0.0", 1))},
+			expectErr: true,
+			expectOut: "data plane network cidr 10.0.0.0 is not valid",
+		},
+		{
+			name:      "cluster selector update should fail validation",
+			files:     []string{writeFile(t, strings.Replace(validADC, "foo: bar", "foo: baz", 1))},
+			oldFiles:  []string{writeFile(t, validADC)},
+			expectErr: true,
+			expectOut: "field should not be changed",
+		},
+		{
+			name:      "file without akodeploymentconfig should fail",
+			files:     []string{writeFile(t, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: foo\n")},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := runValidate(out, &validateOptions{files: tc.files, oldFiles: tc.oldFiles})
+			if tc.expectErr {
+				g.Expect(err).Should(HaveOccurred())
+			} else {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			}
+			g.Expect(out.String()).Should(ContainSubstring(tc.expectOut))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kubeclient builds the clients the command line tools use to reach a
+// management cluster.
+package kubeclient
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// New builds a client for the cluster of the kubeconfig context, an empty
+// kubeconfig follows the KUBECONFIG rules and an empty context uses the
+// current one
+func New(kubeconfig, kubeContext string, addToScheme ...func(*runtime.Scheme) error) (client.Client, error) {
+	restConfig, err := RESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return NewForConfig(restConfig, addToScheme...)
+}
+
+// RESTConfig loads the rest config of the kubeconfig context
+func RESTConfig(kubeconfig, kubeContext string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kubeconfig")
+	}
+	return restConfig, nil
+}
+
+// NewForConfig builds a client knowing the given types
+func NewForConfig(restConfig *rest.Config, addToScheme ...func(*runtime.Scheme) error) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	for _, add := range addToScheme {
+		if err := add(scheme); err != nil {
+			return nil, err
+		}
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
@@ -0,0 +1,472 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	yamlv3 "gopkg.in/yaml.v3"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/cmd/internal/kubeclient"
+	akocluster "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/cluster"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
+	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
+)
+
+const (
+	// redacted replaces the credentials in the bundle
+	redacted = "REDACTED"
+	// lastAppliedAnnotation holds a copy of applied objects, secret data included
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// credentialKey matches the keys of the AKO data values holding credentials,
+// besides the avi_credentials which are redacted as a whole
+var credentialKey = regexp.MustCompile(`(?i)password|username|token|secret|key|cert|crt`)
+
+// podLogsFunc returns the logs of a container
+type podLogsFunc func(ctx context.Context, namespace, pod, container string) ([]byte, error)
+
+type bundleOptions struct {
+	output            string
+	operatorNamespace string
+	workload          bool
+	avi               bool
+}
+
+func newSupportBundleCommand(opts *rootOptions) *cobra.Command {
+	bundleOpts := &bundleOptions{}
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect the AKO Operator state of the management cluster into a tarball",
+		Long: `Support-bundle collects the AKODeploymentConfigs, the clusters they select with their
+labels and conditions, the AKO add-on secrets, the AviInfraSettings, the control plane HA
+services and endpoints and the AKO Operator logs of the management cluster.
+
+With --workload, the AKO StatefulSet, ConfigMap, data values, pods and logs of every
+workload cluster are collected as well, using the kubeconfig secrets of the clusters.
+With --avi, the clouds, service engine groups, networks and users of the NSX Advanced Load
+Balancer controllers are read with the credentials of the AKODeploymentConfigs.
+
+Credentials are redacted, and collection errors are recorded in errors.txt.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			restConfig, err := kubeclient.RESTConfig(opts.kubeconfig, opts.context)
+			if err != nil {
+				return err
+			}
+			c, err := kubeclient.NewForConfig(restConfig, bundleScheme...)
+			if err != nil {
+				return err
+			}
+			logs, err := newPodLogs(restConfig)
+			if err != nil {
+				return err
+			}
+			collector := &bundleCollector{
+				client:            c,
+				logs:              logs,
+				operatorNamespace: bundleOpts.operatorNamespace,
+			}
+			if bundleOpts.workload {
+				collector.workload = workloadClients
+			}
+			if bundleOpts.avi {
+				collector.avi = aviClient
+			}
+			if bundleOpts.output == "" {
+				bundleOpts.output = fmt.Sprintf("ako-support-bundle-%s.tar.gz", time.Now().Format("20060102-150405"))
+			}
+			f, err := os.Create(bundleOpts.output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := collector.write(cmd.Context(), f); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Support bundle written to %s\n", bundleOpts.output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&bundleOpts.output, "output", "o", "", "Tarball to write, defaults to ako-support-bundle-<time>.tar.gz")
+	cmd.Flags().StringVar(&bundleOpts.operatorNamespace, "operator-namespace", "tkg-system-networking", "Namespace AKO Operator runs in")
+	cmd.Flags().BoolVar(&bundleOpts.workload, "workload", false, "Collect AKO from the workload clusters")
+	cmd.Flags().BoolVar(&bundleOpts.avi, "avi", false, "Read the NSX Advanced Load Balancer objects")
+	return cmd
+}
+
+// bundleScheme are the types collected by the support bundle
+var bundleScheme = []func(*runtime.Scheme) error{
+	corev1.AddToScheme, appv1.AddToScheme, clusterv1.AddToScheme, akoov1alpha1.AddToScheme, akov1beta1.AddToScheme,
+}
+
+// bundleCollector writes the support bundle, the optional collectors are nil
+// when disabled
+type bundleCollector struct {
+	client            client.Client
+	logs              podLogsFunc
+	operatorNamespace string
+	workload          func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster) (client.Client, podLogsFunc, error)
+	avi               func(ctx context.Context, c client.Client, adc *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error)
+
+	tw   *tar.Writer
+	errs []string
+}
+
+// write collects the bundle as a gzipped tarball
+func (b *bundleCollector) write(ctx context.Context, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	b.tw = tar.NewWriter(gz)
+	b.errs = nil
+
+	b.collect(ctx)
+	if len(b.errs) != 0 {
+		b.add("errors.txt", []byte(strings.Join(b.errs, "\n")+"\n"))
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (b *bundleCollector) collect(ctx context.Context) {
+	var adcs akoov1alpha1.AKODeploymentConfigList
+	if err := b.client.List(ctx, &adcs); err != nil {
+		b.fail("list AKODeploymentConfigs", err)
+	}
+	for i := range adcs.Items {
+		adc := &adcs.Items[i]
+		b.addObject(path.Join("management", "akodeploymentconfigs", adc.Name+".yaml"), adc)
+		if b.avi != nil {
+			b.collectAvi(ctx, adc)
+		}
+	}
+
+	var aviInfraSettings akov1beta1.AviInfraSettingList
+	if err := b.client.List(ctx, &aviInfraSettings); err != nil {
+		b.fail("list AviInfraSettings", err)
+	}
+	for i := range aviInfraSettings.Items {
+		b.addObject(path.Join("management", "aviinfrasettings", aviInfraSettings.Items[i].Name+".yaml"), &aviInfraSettings.Items[i])
+	}
+
+	var clusters clusterv1.ClusterList
+	if err := b.client.List(ctx, &clusters, client.HasLabels{akoov1alpha1.AviClusterLabel}); err != nil {
+		b.fail("list clusters", err)
+	}
+	for i := range clusters.Items {
+		b.collectCluster(ctx, &clusters.Items[i])
+	}
+
+	b.collectPods(ctx, b.client, b.logs, path.Join("management", "operator"), b.operatorNamespace)
+}
+
+// collectCluster collects the objects AKO Operator manages for the cluster
+func (b *bundleCollector) collectCluster(ctx context.Context, cluster *clusterv1.Cluster) {
+	dir := path.Join("management", "clusters", cluster.Namespace, cluster.Name)
+	b.addObject(path.Join(dir, "cluster.yaml"), cluster)
+
+	secret := &corev1.Secret{}
+	if b.get(ctx, b.client, client.ObjectKey{Namespace: cluster.Namespace, Name: utils.AKOAddonSecretName(cluster)}, secret) {
+		b.addObject(path.Join(dir, "addon-secret.yaml"), redactSecret(secret))
+	}
+	haKey, err := haServiceKey(ctx, b.client, cluster)
+	if err != nil {
+		b.fail("find the HA service of cluster "+cluster.Name, err)
+	}
+	svc := &corev1.Service{}
+	if b.get(ctx, b.client, haKey, svc) {
+		b.addObject(path.Join(dir, "ha-service.yaml"), svc)
+	}
+	endpoints := &corev1.Endpoints{}
+	if b.get(ctx, b.client, haKey, endpoints) {
+		b.addObject(path.Join(dir, "ha-endpoints.yaml"), endpoints)
+	}
+
+	if b.workload == nil {
+		return
+	}
+	remoteClient, remoteLogs, err := b.workload(ctx, b.client, cluster)
+	if err != nil {
+		b.fail("connect to cluster "+cluster.Namespace+"/"+cluster.Name, err)
+		return
+	}
+	dir = path.Join("workload", cluster.Namespace, cluster.Name)
+	dataValues := &corev1.Secret{}
+	if b.get(ctx, remoteClient, client.ObjectKey{Namespace: akoov1alpha1.TKGSystemNamespace, Name: akocluster.AKODataValuesSecretName(cluster)}, dataValues) {
+		b.addObject(path.Join(dir, "data-values.yaml"), redactSecret(dataValues))
+	}
+	statefulSet := &appv1.StatefulSet{}
+	if b.get(ctx, remoteClient, client.ObjectKey{Namespace: akoov1alpha1.AviNamespace, Name: akoov1alpha1.AkoStatefulSetName}, statefulSet) {
+		b.addObject(path.Join(dir, "statefulset.yaml"), statefulSet)
+	}
+	configMap := &corev1.ConfigMap{}
+	if b.get(ctx, remoteClient, client.ObjectKey{Namespace: akoov1alpha1.AviNamespace, Name: akoov1alpha1.AKOConfigMapName}, configMap) {
+		b.addObject(path.Join(dir, "configmap.yaml"), configMap)
+	}
+	b.collectPods(ctx, remoteClient, remoteLogs, dir, akoov1alpha1.AviNamespace)
+}
+
+// collectPods collects the pods of a namespace with the logs of their containers
+func (b *bundleCollector) collectPods(ctx context.Context, c client.Client, logs podLogsFunc, dir, namespace string) {
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		b.fail("list pods in "+namespace, err)
+		return
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		b.addObject(path.Join(dir, "pods", pod.Name+".yaml"), pod)
+		for _, container := range pod.Spec.Containers {
+			out, err := logs(ctx, namespace, pod.Name, container.Name)
+			if err != nil {
+				b.fail("get logs of "+namespace+"/"+pod.Name+"/"+container.Name, err)
+				continue
+			}
+			b.add(path.Join(dir, "logs", pod.Name, container.Name+".log"), out)
+		}
+	}
+}
+
+// collectAvi reads the NSX Advanced Load Balancer objects the
+// AKODeploymentConfig refers to, and the users of its clusters
+func (b *bundleCollector) collectAvi(ctx context.Context, adc *akoov1alpha1.AKODeploymentConfig) {
+	aviClient, err := b.avi(ctx, b.client, adc)
+	if err != nil {
+		b.fail("connect to the NSX Advanced Load Balancer controller of "+adc.Name, err)
+		return
+	}
+	dir := path.Join("avi", adc.Name)
+	if version, err := aviClient.GetControllerVersion(); err != nil {
+		b.fail("get controller version of "+adc.Name, err)
+	} else {
+		b.add(path.Join(dir, "version.txt"), []byte(version+"\n"))
+	}
+	if cloud, err := aviClient.CloudGetByName(adc.Spec.CloudName); err != nil {
+		b.fail("get cloud "+adc.Spec.CloudName, err)
+	} else {
+		b.addJSON(path.Join(dir, "cloud.json"), cloud)
+	}
+	if seg, err := aviClient.ServiceEngineGroupGetByName(adc.Spec.ServiceEngineGroup, adc.Spec.CloudName); err != nil {
+		b.fail("get service engine group "+adc.Spec.ServiceEngineGroup, err)
+	} else {
+		b.addJSON(path.Join(dir, "service-engine-group.json"), seg)
+	}
+	networks := []string{adc.Spec.DataNetwork.Name}
+	if adc.Spec.ControlPlaneNetwork.Name != "" && adc.Spec.ControlPlaneNetwork.Name != adc.Spec.DataNetwork.Name {
+		networks = append(networks, adc.Spec.ControlPlaneNetwork.Name)
+	}
+	for _, name := range networks {
+		if network, err := aviClient.NetworkGetByName(name, adc.Spec.CloudName); err != nil {
+			b.fail("get network "+name, err)
+		} else {
+			b.addJSON(path.Join(dir, "networks", name+".json"), network)
+		}
+	}
+
+	var clusters clusterv1.ClusterList
+	if err := b.client.List(ctx, &clusters, client.MatchingLabels{akoov1alpha1.AviClusterLabel: adc.Name}); err != nil {
+		b.fail("list clusters of "+adc.Name, err)
+		return
+	}
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		secret := &corev1.Secret{}
+		if !b.get(ctx, b.client, client.ObjectKey{Namespace: cluster.Namespace, Name: utils.AVIUserSecretName(cluster)}, secret) {
+			continue
+		}
+		username := string(secret.Data["username"])
+		user, err := aviClient.UserGetByName(username)
+		if err != nil {
+			b.fail("get avi user "+username, err)
+			continue
+		}
+		user.Password = nil
+		b.addJSON(path.Join(dir, "users", username+".json"), user)
+	}
+}
+
+// get reads an object, missing objects and errors are recorded
+func (b *bundleCollector) get(ctx context.Context, c client.Client, key client.ObjectKey, obj client.Object) bool {
+	if err := c.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			b.errs = append(b.errs, fmt.Sprintf("%T %s not found", obj, key))
+		} else {
+			b.fail(fmt.Sprintf("get %T %s", obj, key), err)
+		}
+		return false
+	}
+	return true
+}
+
+func (b *bundleCollector) fail(what string, err error) {
+	b.errs = append(b.errs, fmt.Sprintf("failed to %s: %v", what, err))
+}
+
+// addObject adds a kubernetes object as yaml, without its managed fields
+func (b *bundleCollector) addObject(name string, obj client.Object) {
+	if gvk, err := apiutil.GVKForObject(obj, b.client.Scheme()); err == nil {
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	obj.SetManagedFields(nil)
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		b.fail("marshal "+name, err)
+		return
+	}
+	b.add(name, out)
+}
+
+func (b *bundleCollector) addJSON(name string, obj interface{}) {
+	out, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		b.fail("marshal "+name, err)
+		return
+	}
+	b.add(name, out)
+}
+
+func (b *bundleCollector) add(name string, data []byte) {
+	if err := b.tw.WriteHeader(&tar.Header{
+		Name:    path.Join("ako-support-bundle", name),
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		b.fail("write "+name, err)
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.fail("write "+name, err)
+	}
+}
+
+// redactSecret returns a copy of the secret without credentials. The AKO data
+// values keep everything but their credential fields.
+func redactSecret(secret *corev1.Secret) *corev1.Secret {
+	s := secret.DeepCopy()
+	delete(s.Annotations, lastAppliedAnnotation)
+	s.StringData = nil
+	for key, value := range s.Data {
+		if key == akoov1alpha1.TKGAddOnSecretDataKey {
+			s.Data[key] = redactDataValues(value)
+		} else {
+			s.Data[key] = []byte(redacted)
+		}
+	}
+	return s
+}
+
+// redactDataValues parses the yaml documents of the AKO data values and
+// redacts the avi_credentials and the values of the credential keys, whatever
+// their yaml style. Data values which can't be parsed are redacted as a whole.
+func redactDataValues(data []byte) []byte {
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+	out := &bytes.Buffer{}
+	encoder := yamlv3.NewEncoder(out)
+	encoder.SetIndent(2)
+	for {
+		doc := &yamlv3.Node{}
+		if err := decoder.Decode(doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return []byte(redacted)
+		}
+		redactNode(doc, false)
+		if err := encoder.Encode(doc); err != nil {
+			return []byte(redacted)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return []byte(redacted)
+	}
+	return out.Bytes()
+}
+
+// redactNode replaces the scalars under the node which are credentials
+func redactNode(node *yamlv3.Node, credential bool) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			redactNode(node.Content[i+1], credential || key == "avi_credentials" || credentialKey.MatchString(key))
+		}
+	case yamlv3.DocumentNode, yamlv3.SequenceNode:
+		for _, child := range node.Content {
+			redactNode(child, credential)
+		}
+	case yamlv3.ScalarNode:
+		if credential {
+			node.Value, node.Tag, node.Style = redacted, "!!str", 0
+		}
+	}
+}
+
+func newPodLogs(restConfig *rest.Config) (podLogsFunc, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, namespace, pod, container string) ([]byte, error) {
+		return clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container}).DoRaw(ctx)
+	}, nil
+}
+
+// workloadClients connects to the workload cluster with its kubeconfig secret
+func workloadClients(ctx context.Context, c client.Client, cluster *clusterv1.Cluster) (client.Client, podLogsFunc, error) {
+	data, err := kubeconfig.FromSecret(ctx, c, client.ObjectKeyFromObject(cluster))
+	if err != nil {
+		return nil, nil, err
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteClient, err := kubeclient.NewForConfig(restConfig, bundleScheme...)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, err := newPodLogs(restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return remoteClient, logs, nil
+}
+
+// aviClient connects to the NSX Advanced Load Balancer controller of the
+// AKODeploymentConfig with its admin credentials
+func aviClient(ctx context.Context, c client.Client, adc *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+	return aviclient.NewAviClientFromSecrets(c, ctx, logr.Discard(), adc.Spec.Controller,
+		adc.Spec.AdminCredentialRef.Name, adc.Spec.AdminCredentialRef.Namespace,
+		adc.Spec.CertificateAuthorityRef.Name, adc.Spec.CertificateAuthorityRef.Namespace,
+		adc.Spec.ControllerVersion)
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+const testDataValues = `#@data/values
+---
+loadBalancerAndIngressService:
+  config:
+    controller_settings:
+      cloud_name: fake-cloud
+    avi_credentials:
+      username: test-cluster
+      password: Admin!23
+      certificate_authority_data: LS0tLS1CRUdJTg==
+`
+
+func newBundleClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	for _, add := range bundleScheme {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+// readBundle returns the files of a support bundle
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[strings.TrimPrefix(h.Name, "ako-support-bundle/")] = string(content)
+	}
+}
+
+func TestSupportBundle(t *testing.T) {
+	g := NewWithT(t)
+
+	adc := newADC("adc-foo", map[string]string{"foo": "bar"})
+	adc.Spec.CloudName = "fake-cloud"
+	adc.Spec.ServiceEngineGroup = "fake-seg"
+	adc.Spec.DataNetwork.Name = "fake-network"
+	cluster := newCluster("default", "test-cluster", map[string]string{"foo": "bar", akoov1alpha1.AviClusterLabel: "adc-foo"})
+	mgmtClient := newBundleClient(t, adc, cluster,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "test-cluster-load-balancer-and-ingress-service-addon",
+				Annotations: map[string]string{lastAppliedAnnotation: `{"stringData":{"values.yaml":"password: Admin!23"}}`},
+			},
+			Data: map[string][]byte{akoov1alpha1.TKGAddOnSecretDataKey: []byte(testDataValues)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster-avi-credentials"},
+			Data:       map[string][]byte{"username": []byte("test-cluster-default-ako-user"), "password": []byte("Admin!23")},
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "default-test-cluster-control-plane"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tkg-system-networking", Name: "ako-operator-controller-manager-0"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "manager"}}},
+		},
+	)
+	workloadClient := newBundleClient(t,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: akoov1alpha1.TKGSystemNamespace, Name: "load-balancer-and-ingress-service-data-values"},
+			Data:       map[string][]byte{akoov1alpha1.TKGAddOnSecretDataKey: []byte(testDataValues)},
+		},
+		&appv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: akoov1alpha1.AviNamespace, Name: akoov1alpha1.AkoStatefulSetName},
+			Spec:       appv1.StatefulSetSpec{Replicas: ptr.To(int32(1))},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: akoov1alpha1.AviNamespace, Name: "ako-0"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "ako"}}},
+		},
+	)
+	logs := func(_ context.Context, namespace, pod, container string) ([]byte, error) {
+		return []byte("logs of " + namespace + "/" + pod + "/" + container), nil
+	}
+
+	aviClient := aviclient.NewFakeAviClient()
+	aviClient.Cloud.SetGetByNameCloudFunc(func(name string, _ ...session.ApiOptionsParams) (*models.Cloud, error) {
+		return &models.Cloud{Name: ptr.To(name)}, nil
+	})
+	aviClient.ServiceEngineGroup.SetGetByNameFn(func(name string, _ ...session.ApiOptionsParams) (*models.ServiceEngineGroup, error) {
+		return &models.ServiceEngineGroup{Name: ptr.To(name)}, nil
+	})
+	aviClient.Network.SetGetByNameFn(func(name string, _ ...session.ApiOptionsParams) (*models.Network, error) {
+		return nil, errors.New("network not found")
+	})
+	aviClient.User.SetGetByNameUserFunc(func(name string, _ ...session.ApiOptionsParams) (*models.User, error) {
+		return &models.User{Name: ptr.To(name), Password: ptr.To("Admin!23")}, nil
+	})
+
+	collector := &bundleCollector{
+		client:            mgmtClient,
+		logs:              logs,
+		operatorNamespace: "tkg-system-networking",
+		workload: func(_ context.Context, _ client.Client, _ *clusterv1.Cluster) (client.Client, podLogsFunc, error) {
+			return workloadClient, logs, nil
+		},
+		avi: func(_ context.Context, _ client.Client, _ *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+			return aviClient, nil
+		},
+	}
+	out := &bytes.Buffer{}
+	g.Expect(collector.write(context.Background(), out)).Should(Succeed())
+	files := readBundle(t, out.Bytes())
+
+	g.Expect(files).Should(HaveKey("management/akodeploymentconfigs/adc-foo.yaml"))
+	g.Expect(files["management/akodeploymentconfigs/adc-foo.yaml"]).Should(ContainSubstring("kind: AKODeploymentConfig"))
+	g.Expect(files["management/clusters/default/test-cluster/cluster.yaml"]).Should(ContainSubstring("networking.tkg.tanzu.vmware.com/avi: adc-foo"))
+	g.Expect(files).Should(HaveKey("management/clusters/default/test-cluster/ha-service.yaml"))
+	g.Expect(files["management/operator/logs/ako-operator-controller-manager-0/manager.log"]).Should(Equal("logs of tkg-system-networking/ako-operator-controller-manager-0/manager"))
+	g.Expect(files).Should(HaveKey("workload/default/test-cluster/statefulset.yaml"))
+	g.Expect(files["workload/default/test-cluster/logs/ako-0/ako.log"]).Should(Equal("logs of avi-system/ako-0/ako"))
+	g.Expect(files["avi/adc-foo/cloud.json"]).Should(ContainSubstring(`"name": "fake-cloud"`))
+	g.Expect(files["avi/adc-foo/users/test-cluster-default-ako-user.json"]).Should(ContainSubstring("test-cluster-default-ako-user"))
+
+	for name, content := range files {
+		g.Expect(content).ShouldNot(ContainSubstring("Admin!23"), name)
+		g.Expect(content).ShouldNot(ContainSubstring("LS0tLS1CRUdJTg=="), name)
+	}
+	for _, name := range []string{"management/clusters/default/test-cluster/addon-secret.yaml", "workload/default/test-cluster/data-values.yaml"} {
+		g.Expect(files[name]).ShouldNot(ContainSubstring(lastAppliedAnnotation))
+	}
+
+	g.Expect(files["errors.txt"]).Should(ContainSubstring("failed to get network fake-network: network not found"))
+	g.Expect(files["errors.txt"]).Should(ContainSubstring("default/default-test-cluster-control-plane not found"))
+}
+
+func TestRedactSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			akoov1alpha1.TKGAddOnSecretDataKey: []byte(testDataValues),
+			"password":                         []byte("Admin!23"),
+		},
+	}
+	s := redactSecret(secret)
+	g.Expect(string(s.Data["password"])).Should(Equal(redacted))
+	values := string(s.Data[akoov1alpha1.TKGAddOnSecretDataKey])
+	g.Expect(values).Should(ContainSubstring("cloud_name: fake-cloud"))
+	g.Expect(values).Should(ContainSubstring("      username: REDACTED\n"))
+	g.Expect(values).Should(ContainSubstring("      password: REDACTED\n"))
+	g.Expect(values).Should(ContainSubstring("      certificate_authority_data: REDACTED\n"))
+	g.Expect(string(secret.Data["password"])).Should(Equal("Admin!23"))
+}
+
+func TestRedactMultiLineCredentials(t *testing.T) {
+	g := NewWithT(t)
+
+	values := `#@data/values
+---
+loadBalancerAndIngressService:
+  config:
+    controller_settings:
+      cloud_name: fake-cloud
+    avi_credentials:
+      username: test-cluster
+      password: "Admin!23
+        continued"
+      certificate_authority_data: |
+        -----BEGIN CERTIFICATE-----
+        MIIBszCCAVmgAwIBAgIU
+        -----END CERTIFICATE-----
+    tls:
+      ca.crt: >
+        folded
+        certificate
+`
+	s := redactSecret(&corev1.Secret{Data: map[string][]byte{akoov1alpha1.TKGAddOnSecretDataKey: []byte(values)}})
+	redactedValues := string(s.Data[akoov1alpha1.TKGAddOnSecretDataKey])
+	for _, leaked := range []string{"test-cluster", "Admin!23", "continued", "BEGIN CERTIFICATE", "MIIBszCCAVmgAwIBAgIU", "folded"} {
+		g.Expect(redactedValues).ShouldNot(ContainSubstring(leaked))
+	}
+	g.Expect(redactedValues).Should(ContainSubstring("cloud_name: fake-cloud"))
+	g.Expect(redactedValues).Should(ContainSubstring("      certificate_authority_data: REDACTED\n"))
+	g.Expect(redactedValues).Should(ContainSubstring("      ca.crt: REDACTED\n"))
+
+	s = redactSecret(&corev1.Secret{Data: map[string][]byte{akoov1alpha1.TKGAddOnSecretDataKey: []byte("password: [unterminated")}})
+	g.Expect(string(s.Data[akoov1alpha1.TKGAddOnSecretDataKey])).Should(Equal(redacted))
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+)
+
+func newExplainCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain CLUSTER",
+		Short: "Show which AKODeploymentConfig selects a cluster and why",
+		Long: `Explain shows the AKODeploymentConfig which selects the cluster and walks through the
+selection rules:
+
+- the AKODeploymentConfig named in the networking.tkg.tanzu.vmware.com/avi label keeps
+  the cluster as long as its non empty selector matches it
+- otherwise the first AKODeploymentConfig with a non empty selector matching the cluster
+  labels selects it
+- otherwise the install-ako-for-all AKODeploymentConfig selects it when its selector is empty
+- clusters which are not ready are skipped, and the management cluster is only managed by
+  install-ako-for-management-cluster`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+			return runExplain(cmd.Context(), cmd.OutOrStdout(), c, client.ObjectKey{Namespace: opts.namespace, Name: args[0]})
+		},
+	}
+}
+
+func runExplain(ctx context.Context, out io.Writer, c client.Client, key client.ObjectKey) error {
+	cluster := &clusterv1.Cluster{}
+	if err := c.Get(ctx, key, cluster); err != nil {
+		return err
+	}
+	// the api server can't look up the AKODeploymentConfigs by the cache index
+	// of the operator, every one of them is read instead
+	var adcs akoov1alpha1.AKODeploymentConfigList
+	if err := c.List(ctx, &adcs); err != nil {
+		return err
+	}
+	selection := ako_operator.SelectAKODeploymentConfig(cluster, adcs.Items)
+	adc := selection.AKODeploymentConfig
+	if adc == nil {
+		fmt.Fprintf(out, "Cluster %s is not selected by any AKODeploymentConfig\n", key)
+	} else {
+		fmt.Fprintf(out, "Cluster %s is selected by AKODeploymentConfig %s\n", key, adc.Name)
+	}
+
+	fmt.Fprintln(out, "\nSelection:")
+	if label, ok := cluster.Labels[akoov1alpha1.AviClusterLabel]; ok {
+		fmt.Fprintf(out, "  - the cluster is labeled %s=%s\n", akoov1alpha1.AviClusterLabel, label)
+		labeled := &akoov1alpha1.AKODeploymentConfig{}
+		if err := c.Get(ctx, client.ObjectKey{Name: label}, labeled); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			fmt.Fprintf(out, "  - AKODeploymentConfig %s doesn't exist anymore\n", label)
+		} else {
+			fmt.Fprintf(out, "  - AKODeploymentConfig %s %s\n", label, explainSelector(labeled, cluster))
+		}
+	} else {
+		fmt.Fprintf(out, "  - the cluster has no %s label\n", akoov1alpha1.AviClusterLabel)
+	}
+
+	for i := range adcs.Items {
+		fmt.Fprintf(out, "  - AKODeploymentConfig %s %s\n", adcs.Items[i].Name, explainSelector(&adcs.Items[i], cluster))
+	}
+
+	fmt.Fprintln(out, "\nRules:")
+	for _, step := range selection.Trace {
+		fmt.Fprintf(out, "  - %s: %s\n", step.Rule, step.Message)
+	}
+
+	if adc == nil {
+		return nil
+	}
+	fmt.Fprintln(out, "\nManagement:")
+	switch {
+	case selection.Skipped:
+		fmt.Fprintln(out, "  - the cluster is skipped until it is ready")
+	case selection.Manages(adc):
+		fmt.Fprintf(out, "  - AKODeploymentConfig %s manages the cluster\n", adc.Name)
+	default:
+		fmt.Fprintf(out, "  - AKODeploymentConfig %s doesn't manage the cluster\n", adc.Name)
+	}
+	return nil
+}
+
+// explainSelector tells how the selector of the AKODeploymentConfig applies to
+// the cluster
+func explainSelector(adc *akoov1alpha1.AKODeploymentConfig, cluster *clusterv1.Cluster) string {
+	selector, err := metav1.LabelSelectorAsSelector(&adc.Spec.ClusterSelector)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("has an invalid selector: %v", err)
+	case selector.Empty() && adc.Name == akoov1alpha1.WorkloadClusterAkoDeploymentConfig:
+		return "has an empty selector and selects the clusters no other AKODeploymentConfig selects"
+	case selector.Empty():
+		return "has an empty selector, only " + akoov1alpha1.WorkloadClusterAkoDeploymentConfig + " selects clusters with it"
+	case selector.Matches(labels.Set(cluster.Labels)):
+		return fmt.Sprintf("selector %s matches the cluster labels", selector)
+	default:
+		return fmt.Sprintf("selector %s doesn't match the cluster labels", selector)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+func TestExplain(t *testing.T) {
+	notReady := newCluster("default", "not-ready", map[string]string{"foo": "bar"})
+	conditions.MarkFalse(notReady, clusterv1.ReadyCondition, "Provisioning", clusterv1.ConditionSeverityInfo, "")
+
+	testcases := []struct {
+		name      string
+		cluster   *clusterv1.Cluster
+		expectOut []string
+	}{
+		{
+			name:    "cluster matching a selector should be selected by its akodeploymentconfig",
+			cluster: newCluster("default", "foo-cluster", map[string]string{"foo": "bar"}),
+			expectOut: []string{
+				"is selected by AKODeploymentConfig adc-foo",
+				"the cluster has no networking.tkg.tanzu.vmware.com/avi label",
+				"AKODeploymentConfig adc-foo selector foo=bar matches the cluster labels",
+				"Selector: the selector foo=bar matches the cluster labels",
+				"AKODeploymentConfig adc-foo manages the cluster",
+			},
+		},
+		{
+			name:    "cluster matching no selector should be selected by the default akodeploymentconfig",
+			cluster: newCluster("default", "other-cluster", nil),
+			expectOut: []string{
+				"is selected by AKODeploymentConfig install-ako-for-all",
+				"AKODeploymentConfig adc-foo selector foo=bar doesn't match the cluster labels",
+				"AKODeploymentConfig install-ako-for-all has an empty selector and selects the clusters no other AKODeploymentConfig selects",
+				"Default: the default AKODeploymentConfig has an empty selector and selects every cluster",
+			},
+		},
+		{
+			name:    "labeled cluster should report its label",
+			cluster: newCluster("default", "labeled", map[string]string{akoov1alpha1.AviClusterLabel: "deleted-adc"}),
+			expectOut: []string{
+				"the cluster is labeled networking.tkg.tanzu.vmware.com/avi=deleted-adc",
+				"AKODeploymentConfig deleted-adc doesn't exist anymore",
+			},
+		},
+		{
+			name:    "not ready cluster should be skipped",
+			cluster: notReady,
+			expectOut: []string{
+				"is selected by AKODeploymentConfig adc-foo",
+				"the cluster is skipped until it is ready",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := newFakeClient(t,
+				newADC(akoov1alpha1.WorkloadClusterAkoDeploymentConfig, nil),
+				newADC("adc-foo", map[string]string{"foo": "bar"}),
+				tc.cluster,
+			)
+			out := &bytes.Buffer{}
+			g.Expect(runExplain(context.Background(), out, c, client.ObjectKeyFromObject(tc.cluster))).Should(Succeed())
+			for _, s := range tc.expectOut {
+				g.Expect(out.String()).Should(ContainSubstring(s))
+			}
+		})
+	}
+}
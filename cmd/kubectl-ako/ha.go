@@ -0,0 +1,138 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+func newHACommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ha CLUSTER",
+		Short: "Show the control plane VIP and endpoints of a cluster using NSX Advanced Load Balancer as HA provider",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+			return runHA(cmd.Context(), cmd.OutOrStdout(), c, client.ObjectKey{Namespace: opts.namespace, Name: args[0]})
+		},
+	}
+}
+
+func runHA(ctx context.Context, out io.Writer, c client.Client, key client.ObjectKey) error {
+	cluster := &clusterv1.Cluster{}
+	if err := c.Get(ctx, key, cluster); err != nil {
+		return err
+	}
+	// legacy clusters follow the operator configuration, which the plugin
+	// can't see, so they are only told apart by their service
+	isVIPProvider, err := ako_operator.IsControlPlaneVIPProvider(cluster)
+	if err != nil {
+		return err
+	}
+	if ako_operator.IsClusterClassBasedCluster(cluster) && !isVIPProvider {
+		fmt.Fprintf(out, "Cluster %s doesn't use NSX Advanced Load Balancer as control plane HA provider\n", key)
+		return nil
+	}
+	fmt.Fprintf(out, "Cluster:          %s\n", key)
+	fmt.Fprintf(out, "Control plane:    %s\n", orNone(endpointString(cluster.Spec.ControlPlaneEndpoint)))
+
+	svcKey, err := haServiceKey(ctx, c, cluster)
+	if err != nil {
+		return err
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, svcKey, svc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		fmt.Fprintf(out, "Service:          %s not found, the cluster doesn't use NSX Advanced Load Balancer as control plane HA provider or it isn't created yet\n", svcKey)
+		return nil
+	}
+	var vips []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		vips = append(vips, ingress.IP)
+	}
+	fmt.Fprintf(out, "Service:          %s\n", svcKey)
+	fmt.Fprintf(out, "Preferred IP:     %s\n", orNone(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]))
+	fmt.Fprintf(out, "VIP:              %s\n", orNone(strings.Join(vips, ",")))
+
+	endpoints := &corev1.Endpoints{}
+	if err := c.Get(ctx, svcKey, endpoints); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		fmt.Fprintln(out, "Endpoints:        not created yet")
+		return nil
+	}
+	var ready, notReady, ports []string
+	for _, subset := range endpoints.Subsets {
+		for _, port := range subset.Ports {
+			ports = append(ports, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		}
+		for _, address := range subset.Addresses {
+			ready = append(ready, addressString(address))
+		}
+		for _, address := range subset.NotReadyAddresses {
+			notReady = append(notReady, addressString(address))
+		}
+	}
+	fmt.Fprintf(out, "Endpoint ports:   %s\n", orNone(strings.Join(ports, ",")))
+	fmt.Fprintf(out, "Ready endpoints:  %s\n", orNone(strings.Join(ready, ", ")))
+	fmt.Fprintf(out, "Not ready:        %s\n", orNone(strings.Join(notReady, ", ")))
+	return nil
+}
+
+// haServiceKey returns where the HA service of the cluster is. The namespace
+// of the HA services is set in the operator configuration, which the plugin
+// can't see, so the service is looked up by its cluster annotations when it's
+// not in the cluster namespace.
+func haServiceKey(ctx context.Context, c client.Client, cluster *clusterv1.Cluster) (client.ObjectKey, error) {
+	key := client.ObjectKey{Namespace: cluster.Namespace, Name: haprovider.HAServiceName(cluster)}
+	if err := c.Get(ctx, key, &corev1.Service{}); !apierrors.IsNotFound(err) {
+		return key, client.IgnoreNotFound(err)
+	}
+	services := &corev1.ServiceList{}
+	if err := c.List(ctx, services); err != nil {
+		return key, err
+	}
+	for _, service := range services.Items {
+		keys := index.ServiceByCluster(&service)
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(keys) == 1 && keys[0] == index.Key(cluster.Namespace, cluster.Name) {
+			return client.ObjectKeyFromObject(&service), nil
+		}
+	}
+	return key, nil
+}
+
+func endpointString(endpoint clusterv1.APIEndpoint) string {
+	if endpoint.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+}
+
+// addressString prints an endpoint address with the machine it belongs to
+func addressString(address corev1.EndpointAddress) string {
+	if address.NodeName == nil {
+		return address.IP
+	}
+	return fmt.Sprintf("%s (%s)", address.IP, *address.NodeName)
+}
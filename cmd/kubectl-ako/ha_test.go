@@ -0,0 +1,85 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+func TestHA(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := newCluster("default", "test-cluster", nil)
+	cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "10.1.1.10", Port: 6443}
+	meta := metav1.ObjectMeta{Namespace: "default", Name: "default-test-cluster-control-plane"}
+
+	t.Run("cluster without ha service should be reported", func(t *testing.T) {
+		g := NewWithT(t)
+		out := &bytes.Buffer{}
+		g.Expect(runHA(context.Background(), out, newFakeClient(t, cluster), client.ObjectKeyFromObject(cluster))).Should(Succeed())
+		g.Expect(out.String()).Should(ContainSubstring("not found, the cluster doesn't use NSX Advanced Load Balancer"))
+	})
+
+	c := newFakeClient(t, cluster,
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   meta.Namespace,
+				Name:        meta.Name,
+				Annotations: map[string]string{akoov1alpha1.AkoPreferredIPAnnotation: "10.1.1.10"},
+			},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "10.1.1.10"}},
+			}},
+		},
+		&corev1.Endpoints{
+			ObjectMeta: meta,
+			Subsets: []corev1.EndpointSubset{{
+				Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.2", NodeName: ptr.To("cp-1")}},
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.3", NodeName: ptr.To("cp-2")}},
+				Ports:             []corev1.EndpointPort{{Port: 6443, Protocol: corev1.ProtocolTCP}},
+			}},
+		},
+	)
+	out := &bytes.Buffer{}
+	g.Expect(runHA(context.Background(), out, c, client.ObjectKeyFromObject(cluster))).Should(Succeed())
+	g.Expect(out.String()).Should(ContainSubstring("Control plane:    10.1.1.10:6443"))
+	g.Expect(out.String()).Should(ContainSubstring("Service:          default/default-test-cluster-control-plane"))
+	g.Expect(out.String()).Should(ContainSubstring("VIP:              10.1.1.10"))
+	g.Expect(out.String()).Should(ContainSubstring("Endpoint ports:   6443/TCP"))
+	g.Expect(out.String()).Should(ContainSubstring("Ready endpoints:  10.0.0.2 (cp-1)"))
+	g.Expect(out.String()).Should(ContainSubstring("Not ready:        10.0.0.3 (cp-2)"))
+}
+
+func TestHAServiceInDedicatedNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := newCluster("default", "test-cluster", nil)
+	c := newFakeClient(t, cluster,
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "tkg-ha-services",
+				Name:      "default-test-cluster-control-plane",
+				Annotations: map[string]string{
+					akoov1alpha1.TKGClusterNameLabel:      "test-cluster",
+					akoov1alpha1.TKGClusterNameSpaceLabel: "default",
+				},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		},
+	)
+	out := &bytes.Buffer{}
+	g.Expect(runHA(context.Background(), out, c, client.ObjectKeyFromObject(cluster))).Should(Succeed())
+	g.Expect(out.String()).Should(ContainSubstring("Service:          tkg-ha-services/default-test-cluster-control-plane"))
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+)
+
+func newListCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the AKODeploymentConfigs with the clusters they select",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+			return runList(cmd.Context(), cmd.OutOrStdout(), c)
+		},
+	}
+}
+
+func runList(ctx context.Context, out io.Writer, c client.Client) error {
+	var adcs akoov1alpha1.AKODeploymentConfigList
+	if err := c.List(ctx, &adcs); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSELECTOR\tCLUSTERS")
+	for i := range adcs.Items {
+		adc := &adcs.Items[i]
+		clusters, err := ako_operator.ListAkoDeploymentConfigSelectClusters(ctx, c, logr.Discard(), adc)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(clusters.Items))
+		for _, cluster := range clusters.Items {
+			names = append(names, cluster.Namespace+"/"+cluster.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", adc.Name, selectorString(&adc.Spec.ClusterSelector), orNone(strings.Join(names, ",")))
+	}
+	return w.Flush()
+}
+
+// selectorString prints a label selector the way kubectl does, an empty
+// selector selects every cluster
+func selectorString(s *metav1.LabelSelector) string {
+	selector, err := metav1.LabelSelectorAsSelector(s)
+	if err != nil {
+		return "<invalid>"
+	}
+	if selector.Empty() {
+		return "<all>"
+	}
+	return selector.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
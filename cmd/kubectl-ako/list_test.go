@@ -0,0 +1,31 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+func TestList(t *testing.T) {
+	g := NewWithT(t)
+
+	c := newFakeClient(t,
+		newADC(akoov1alpha1.WorkloadClusterAkoDeploymentConfig, nil),
+		newADC("adc-foo", map[string]string{"foo": "bar"}),
+		newCluster("default", "foo-cluster", map[string]string{"foo": "bar", akoov1alpha1.AviClusterLabel: "adc-foo"}),
+		newCluster("default", "other-cluster", nil),
+		newCluster(akoov1alpha1.TKGSystemNamespace, "management", nil),
+	)
+	out := &bytes.Buffer{}
+	g.Expect(runList(context.Background(), out, c)).Should(Succeed())
+	g.Expect(out.String()).Should(ContainSubstring("adc-foo              foo=bar   default/foo-cluster\n"))
+	g.Expect(out.String()).Should(ContainSubstring("install-ako-for-all  <all>     default/other-cluster\n"))
+	g.Expect(out.String()).ShouldNot(ContainSubstring("tkg-system/management"))
+}
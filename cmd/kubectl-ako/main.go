@@ -0,0 +1,54 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// kubectl-ako is a kubectl plugin to inspect how AKO Operator manages the
+// clusters of a management cluster. Install it in the PATH and run it as
+// kubectl ako.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/cmd/internal/kubeclient"
+)
+
+type rootOptions struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+// client builds a read only client of the management cluster
+func (o *rootOptions) client() (client.Client, error) {
+	return kubeclient.New(o.kubeconfig, o.context, corev1.AddToScheme, clusterv1.AddToScheme, akoov1alpha1.AddToScheme)
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &rootOptions{}
+	cmd := &cobra.Command{
+		Use:          "kubectl ako",
+		Short:        "Inspect how AKO Operator manages the clusters of a management cluster",
+		SilenceUsage: true,
+	}
+	cmd.PersistentFlags().StringVar(&opts.kubeconfig, "kubeconfig", "", "Kubeconfig of the management cluster, defaults to the KUBECONFIG rules")
+	cmd.PersistentFlags().StringVar(&opts.context, "context", "", "Kubeconfig context of the management cluster")
+	cmd.PersistentFlags().StringVarP(&opts.namespace, "namespace", "n", "default", "Namespace of the clusters")
+	cmd.AddCommand(newListCommand(opts))
+	cmd.AddCommand(newExplainCommand(opts))
+	cmd.AddCommand(newHACommand(opts))
+	cmd.AddCommand(newStatusCommand(opts))
+	cmd.AddCommand(newSupportBundleCommand(opts))
+	return cmd
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
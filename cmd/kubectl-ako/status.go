@@ -0,0 +1,101 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+// statusConditions are the cluster conditions AKO Operator maintains, in the
+// order they are printed
+var statusConditions = []clusterv1.ConditionType{
+	akoov1alpha1.AKODeploymentConfigSelectedCondition,
+	akoov1alpha1.AKOReadyCondition,
+	akoov1alpha1.AKOConfigurationInSyncCondition,
+	akoov1alpha1.ClusterIpFamilyValidationSucceededCondition,
+	akoov1alpha1.AviResourceCleanupSucceededCondition,
+	akoov1alpha1.AviUserCleanupSucceededCondition,
+}
+
+type statusOptions struct {
+	allNamespaces bool
+}
+
+func newStatusCommand(opts *rootOptions) *cobra.Command {
+	statusOpts := &statusOptions{}
+	cmd := &cobra.Command{
+		Use:   "status [CLUSTER]",
+		Short: "Summarize the AKO, Avi resource cleanup and Avi user conditions of the clusters",
+		Long: `Status prints the conditions AKO Operator sets on the cluster, or on every cluster
+selected by an AKODeploymentConfig when no cluster is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+			var clusters []clusterv1.Cluster
+			if len(args) == 1 {
+				cluster := clusterv1.Cluster{}
+				if err := c.Get(cmd.Context(), client.ObjectKey{Namespace: opts.namespace, Name: args[0]}, &cluster); err != nil {
+					return err
+				}
+				clusters = append(clusters, cluster)
+			} else {
+				listOpts := []client.ListOption{client.HasLabels{akoov1alpha1.AviClusterLabel}}
+				if !statusOpts.allNamespaces {
+					listOpts = append(listOpts, client.InNamespace(opts.namespace))
+				}
+				var list clusterv1.ClusterList
+				if err := c.List(cmd.Context(), &list, listOpts...); err != nil {
+					return err
+				}
+				clusters = list.Items
+			}
+			return runStatus(cmd.OutOrStdout(), clusters)
+		},
+	}
+	cmd.Flags().BoolVarP(&statusOpts.allNamespaces, "all-namespaces", "A", false, "List the clusters of all namespaces")
+	return cmd
+}
+
+func runStatus(out io.Writer, clusters []clusterv1.Cluster) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tAKODEPLOYMENTCONFIG\tCONDITION\tSTATUS\tREASON\tMESSAGE")
+	for i := range clusters {
+		cluster := &clusters[i]
+		name := cluster.Namespace + "/" + cluster.Name
+		adc := orNone(cluster.Labels[akoov1alpha1.AviClusterLabel])
+		for _, t := range statusConditions {
+			c := conditions.Get(cluster, t)
+			if c == nil {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, adc, t, c.Status, c.Reason, c.Message)
+		}
+		if !cluster.DeletionTimestamp.IsZero() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, adc, "Deleting", "True", "", deletionMessage(cluster))
+		}
+	}
+	return w.Flush()
+}
+
+// deletionMessage tells what the deletion of the cluster waits for
+func deletionMessage(cluster *clusterv1.Cluster) string {
+	for _, f := range cluster.Finalizers {
+		if f == akoov1alpha1.ClusterFinalizer {
+			return "waiting for the Avi resources and user cleanup"
+		}
+	}
+	return "AKO Operator cleanup finished"
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+func TestStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	ready := newCluster("default", "ready", map[string]string{akoov1alpha1.AviClusterLabel: "adc-foo"})
+	conditions.MarkTrue(ready, akoov1alpha1.AKOReadyCondition)
+	conditions.MarkTrue(ready, clusterv1.ReadyCondition)
+
+	deleting := newCluster("default", "deleting", map[string]string{akoov1alpha1.AviClusterLabel: "adc-foo"})
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleting.Finalizers = []string{akoov1alpha1.ClusterFinalizer}
+	conditions.MarkFalse(deleting, akoov1alpha1.AviResourceCleanupSucceededCondition, akoov1alpha1.AviResourceCleanupReason,
+		clusterv1.ConditionSeverityInfo, "waiting for AKO")
+
+	out := &bytes.Buffer{}
+	g.Expect(runStatus(out, []clusterv1.Cluster{*ready, *deleting})).Should(Succeed())
+	g.Expect(out.String()).Should(MatchRegexp(`default/ready +adc-foo +AKOReady +True`))
+	g.Expect(out.String()).ShouldNot(ContainSubstring("default/ready  adc-foo  Ready"))
+	g.Expect(out.String()).Should(MatchRegexp(`default/deleting +adc-foo +AviResourceCleanupSucceeded +False +AviResourceCleanup +waiting for AKO`))
+	g.Expect(out.String()).Should(MatchRegexp(`default/deleting +adc-foo +Deleting +True +waiting for the Avi resources and user cleanup`))
+}
@@ -0,0 +1,357 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package akodeploymentconfig
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/phases"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/predicates"
+)
+
+// clusterEventsBufferSize is the number of clusters the AKODeploymentConfig
+// controller can enqueue before it waits for the AKO cluster controller
+const clusterEventsBufferSize = 1024
+
+// AKOClusterReconciler reconciles AKO in a single workload cluster: the avi user,
+// the AKO add-on secret and the cleanup when the cluster is deleted. It shares the
+// avi clients of the AKODeploymentConfigReconciler, which enqueues the selected
+// clusters once the avi resources of their AKODeploymentConfig are reconciled.
+type AKOClusterReconciler struct {
+	*AKODeploymentConfigReconciler
+}
+
+// SetupWithManager adds this reconciler to a new controller then to the
+// provided manager.
+func (r *AKOClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.clusterEvents = make(chan event.GenericEvent, clusterEventsBufferSize)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("akocluster").
+		For(&clusterv1.Cluster{}, builder.WithPredicates(predicates.ClusterChanged())).
+		WatchesRawSource(
+			&source.Channel{Source: r.clusterEvents},
+			&handler.EnqueueRequestForObject{},
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: config.Get().Concurrency.Cluster}).
+		Complete(r)
+}
+
+func (r *AKOClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Cluster", req.NamespacedName)
+	res := ctrl.Result{}
+
+	// Get the resource for this request.
+	cluster := &clusterv1.Cluster{}
+	if err := r.Client.Get(ctx, req.NamespacedName, cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Cluster not found, will not reconcile")
+			return res, nil
+		}
+		return res, err
+	}
+
+	obj, selection, err := r.getAKODeploymentConfig(ctx, log, cluster)
+	if err != nil {
+		log.Error(err, "failed to get cluster matched akodeploymentconfig")
+		return res, err
+	}
+	if err := r.recordSelection(ctx, log, cluster, selection); err != nil {
+		log.Error(err, "failed to record the akodeploymentconfig selection")
+		return res, err
+	}
+	if obj == nil && selection.AKODeploymentConfig != nil {
+		log.V(3).Info("akodeploymentconfig doesn't manage the cluster now, skip", "adc", selection.AKODeploymentConfig.Name)
+		return res, nil
+	}
+	if obj == nil {
+		adcName, labeled := cluster.Labels[akoov1alpha1.AviClusterLabel]
+		if err := r.releaseCluster(ctx, log, cluster); err != nil || !labeled {
+			return res, err
+		}
+		return res, r.updateClustersStatus(ctx, log, adcName, cluster)
+	}
+	log = log.WithValues("AKODeploymentConfig", obj.Name)
+
+	// the clusters of a deleted AKODeploymentConfig are cleaned up by the
+	// AKODeploymentConfig controller before its finalizer is removed, and the
+	// clusters of a new one are enqueued once its avi resources are ready
+	if !obj.GetDeletionTimestamp().IsZero() || !ctrlutil.ContainsFinalizer(obj, akoov1alpha1.AkoDeploymentConfigFinalizer) {
+		log.V(3).Info("akodeploymentconfig is not ready to reconcile clusters, skip")
+		return res, nil
+	}
+
+	clients, err := r.initAVI(ctx, log, obj)
+	if err != nil {
+		log.Error(err, "Failed to initialize avi related clients")
+		return res, err
+	}
+	r.initCluster(log)
+
+	akoReady := akoReadyStatus(cluster)
+	res, err = phases.ReconcileClusterPhases(ctx, r.Client, log, cluster, obj,
+		[]phases.ReconcileClusterPhase{
+			clients.skipUnchangedAviUser(clients.userReconciler.ReconcileAviUser),
+			r.addClusterFinalizer,
+			r.ClusterReconciler.ReconcileAddonSecret,
+			r.ClusterReconciler.ReconcileAKODrift,
+			r.ClusterReconciler.ReconcileAKOHealth,
+		},
+		[]phases.ReconcileClusterPhase{
+			clients.userReconciler.ReconcileAviUserDelete,
+			r.ClusterReconciler.ReconcileAddonSecretDelete,
+			r.ClusterReconciler.ReconcileDelete,
+		},
+	)
+	if err != nil {
+		// a later phase may need the avi user phase to run again, like the add-on
+		// secret when the avi user secret is gone
+		clients.forgetAviUser(cluster)
+	}
+	// the AKODeploymentConfig counts follow the AKOReady transitions and deletions
+	if akoReady != akoReadyStatus(cluster) || !cluster.GetDeletionTimestamp().IsZero() {
+		if statusErr := r.updateClustersStatus(ctx, log, obj.Name, cluster); statusErr != nil && err == nil {
+			err = statusErr
+		}
+	}
+	return res, err
+}
+
+// getAKODeploymentConfig returns the AKODeploymentConfig managing the cluster, nil
+// if the selected one doesn't manage it or there is none, together with the
+// selection which decided it
+func (r *AKOClusterReconciler) getAKODeploymentConfig(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+) (*akoov1alpha1.AKODeploymentConfig, *ako_operator.ClusterSelection, error) {
+	selection, err := ako_operator.GetAKODeploymentConfigSelection(ctx, r.Client, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	if selection.AKODeploymentConfig == nil || !selection.Manages(selection.AKODeploymentConfig) {
+		return nil, selection, nil
+	}
+	log.V(3).Info("cluster is selected by akodeploymentconfig", "adc", selection.AKODeploymentConfig.Name, "rule", selection.Rule)
+	return selection.AKODeploymentConfig, selection, nil
+}
+
+// recordSelection records the selection trace in the cluster annotation and its
+// outcome in the AKODeploymentConfigSelected condition. The clusters no
+// AKODeploymentConfig ever selected are left untouched.
+func (r *AKOClusterReconciler) recordSelection(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	selection *ako_operator.ClusterSelection,
+) error {
+	if _, recorded := cluster.Annotations[akoov1alpha1.AKOSelectionAnnotation]; !recorded && selection.AKODeploymentConfig == nil {
+		return nil
+	}
+	patchHelper, err := patch.NewHelper(cluster, r.Client)
+	if err != nil {
+		return err
+	}
+	if cluster.Annotations == nil {
+		cluster.Annotations = make(map[string]string)
+	}
+	cluster.Annotations[akoov1alpha1.AKOSelectionAnnotation] = selection.TraceJSON()
+	switch {
+	case selection.AKODeploymentConfig == nil:
+		conditions.MarkFalse(cluster, akoov1alpha1.AKODeploymentConfigSelectedCondition,
+			akoov1alpha1.AKODeploymentConfigNotSelectedReason, clusterv1.ConditionSeverityInfo,
+			"no AKODeploymentConfig selects the cluster")
+	case selection.Skipped:
+		conditions.MarkFalse(cluster, akoov1alpha1.AKODeploymentConfigSelectedCondition,
+			akoov1alpha1.AKOSelectionClusterNotReadyReason, clusterv1.ConditionSeverityInfo,
+			"AKODeploymentConfig %s selects the cluster once it is ready", selection.AKODeploymentConfig.Name)
+	case !selection.Manages(selection.AKODeploymentConfig):
+		conditions.MarkFalse(cluster, akoov1alpha1.AKODeploymentConfigSelectedCondition,
+			akoov1alpha1.AKODeploymentConfigNotSelectedReason, clusterv1.ConditionSeverityInfo,
+			"%s", selection.Trace[len(selection.Trace)-1].Message)
+	default:
+		conditions.Set(cluster, &clusterv1.Condition{
+			Type:    akoov1alpha1.AKODeploymentConfigSelectedCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  string(selection.Rule),
+			Message: "selected by AKODeploymentConfig " + selection.AKODeploymentConfig.Name,
+		})
+	}
+	log.V(3).Info("Recording the akodeploymentconfig selection", "rule", selection.Rule)
+	return patchHelper.Patch(ctx, cluster)
+}
+
+// releaseCluster removes the avi label and finalizer from a cluster which is no
+// longer managed by the AKODeploymentConfig in its label
+func (r *AKOClusterReconciler) releaseCluster(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster) error {
+	if _, ok := cluster.Labels[akoov1alpha1.AviClusterLabel]; !ok || ako_operator.SkipCluster(cluster) {
+		log.V(3).Info("cluster is not managed by any akodeploymentconfig, skip")
+		return nil
+	}
+	patchHelper, err := patch.NewHelper(cluster, r.Client)
+	if err != nil {
+		return err
+	}
+	log.Info("cluster is no longer selected by any akodeploymentconfig, removing finalizer and avi labels", "finalizer", akoov1alpha1.ClusterFinalizer)
+	ako_operator.RemoveClusterLabel(log, cluster)
+	ctrlutil.RemoveFinalizer(cluster, akoov1alpha1.ClusterFinalizer)
+	return patchHelper.Patch(ctx, cluster)
+}
+
+// enqueueClusters is a reconcilePhase. It hands every cluster selected by the
+// AKODeploymentConfig over to the AKOClusterReconciler, together with the clusters
+// still labeled with it which it no longer selects.
+func (r *AKODeploymentConfigReconciler) enqueueClusters(
+	ctx context.Context,
+	log logr.Logger,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	res := ctrl.Result{}
+	if r.clusterEvents == nil {
+		return res, errors.New("AKO cluster controller is not set up")
+	}
+
+	clusters, err := ako_operator.ListAkoDeploymentConfigSelectClusters(ctx, r.Client, log, obj)
+	if err != nil {
+		log.Error(err, "Fail to list clusters deployed by current AKODeploymentConfig")
+		return res, err
+	}
+	// the AKODeploymentConfig is reconciled when its secrets or ConfigMaps change
+	r.resyncAviUsers(obj.Name)
+
+	labeled := &clusterv1.ClusterList{}
+	if err := r.Client.List(ctx, labeled, client.MatchingFields{index.ClusterAviLabelField: obj.Name}); err != nil {
+		log.Error(err, "Fail to list clusters labeled with current AKODeploymentConfig")
+		return res, err
+	}
+
+	enqueued := sets.New[types.NamespacedName]()
+	for _, items := range [][]clusterv1.Cluster{clusters.Items, labeled.Items} {
+		for i := range items {
+			key := client.ObjectKeyFromObject(&items[i])
+			if enqueued.Has(key) {
+				continue
+			}
+			select {
+			case r.clusterEvents <- event.GenericEvent{Object: &items[i]}:
+			case <-ctx.Done():
+				return res, ctx.Err()
+			}
+			enqueued.Insert(key)
+		}
+	}
+	log.Info("Enqueued the selected clusters", "count", enqueued.Len())
+	return res, nil
+}
+
+// reconcileClustersStatus is a reconcilePhase. It counts the clusters of the
+// AKODeploymentConfig by their AKOReady condition.
+func (r *AKODeploymentConfigReconciler) reconcileClustersStatus(
+	ctx context.Context,
+	log logr.Logger,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	res := ctrl.Result{}
+	status, err := r.clustersStatus(ctx, obj.Name, nil)
+	if err != nil {
+		log.Error(err, "Fail to count the clusters of current AKODeploymentConfig")
+		return res, err
+	}
+	obj.Status.Clusters = status
+	return res, nil
+}
+
+// updateClustersStatus updates the cluster counts in the status of the
+// AKODeploymentConfig. The cluster just reconciled, if any, is counted as it is
+// now rather than as it is in the cache.
+func (r *AKODeploymentConfigReconciler) updateClustersStatus(
+	ctx context.Context,
+	log logr.Logger,
+	name string,
+	current *clusterv1.Cluster,
+) error {
+	obj := &akoov1alpha1.AKODeploymentConfig{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	status, err := r.clustersStatus(ctx, name, current)
+	if err != nil {
+		return err
+	}
+	if obj.Status.Clusters == status {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopy())
+	obj.Status.Clusters = status
+	log.V(3).Info("Updating cluster counts", "AKODeploymentConfig", name, "clusters", status)
+	return r.Client.Status().Patch(ctx, obj, patch)
+}
+
+// clustersStatus counts the clusters labeled with the AKODeploymentConfig by
+// their AKOReady condition, the clusters being deleted are left out
+func (r *AKODeploymentConfigReconciler) clustersStatus(
+	ctx context.Context,
+	name string,
+	current *clusterv1.Cluster,
+) (akoov1alpha1.AKOClustersStatus, error) {
+	status := akoov1alpha1.AKOClustersStatus{}
+	labeled := &clusterv1.ClusterList{}
+	if err := r.Client.List(ctx, labeled, client.MatchingFields{index.ClusterAviLabelField: name}); err != nil {
+		return status, err
+	}
+
+	clusters := make([]*clusterv1.Cluster, 0, len(labeled.Items)+1)
+	for i := range labeled.Items {
+		if current == nil || client.ObjectKeyFromObject(current) != client.ObjectKeyFromObject(&labeled.Items[i]) {
+			clusters = append(clusters, &labeled.Items[i])
+		}
+	}
+	if current != nil && current.Labels[akoov1alpha1.AviClusterLabel] == name {
+		clusters = append(clusters, current)
+	}
+
+	for _, cluster := range clusters {
+		if !cluster.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		status.Total++
+		switch akoReadyStatus(cluster) {
+		case corev1.ConditionTrue:
+			status.Ready++
+		case corev1.ConditionFalse:
+			status.NotReady++
+		default:
+			status.Unknown++
+		}
+	}
+	return status, nil
+}
+
+func akoReadyStatus(cluster *clusterv1.Cluster) corev1.ConditionStatus {
+	if c := conditions.Get(cluster, akoov1alpha1.AKOReadyCondition); c != nil {
+		return c.Status
+	}
+	return corev1.ConditionUnknown
+}
@@ -0,0 +1,328 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package akodeploymentconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+func newTestADC(name string, selector map[string]string) *akoov1alpha1.AKODeploymentConfig {
+	return &akoov1alpha1.AKODeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Finalizers: []string{akoov1alpha1.AkoDeploymentConfigFinalizer},
+		},
+		Spec: akoov1alpha1.AKODeploymentConfigSpec{
+			ClusterSelector: metav1.LabelSelector{MatchLabels: selector},
+			CertificateAuthorityRef: &akoov1alpha1.SecretRef{
+				Name:      "controller-ca",
+				Namespace: "default",
+			},
+		},
+	}
+}
+
+func newTestCluster(name string, labels map[string]string) *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    labels,
+		},
+	}
+}
+
+func newTestAKOClusterReconciler(t *testing.T, objs ...client.Object) *AKOClusterReconciler {
+	scheme := runtime.NewScheme()
+	if err := akoov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &AKOClusterReconciler{
+		AKODeploymentConfigReconciler: &AKODeploymentConfigReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).WithObjects(objs...).
+				WithIndex(&clusterv1.Cluster{}, index.ClusterAviLabelField, index.ClusterByAviLabel).
+				WithStatusSubresource(&akoov1alpha1.AKODeploymentConfig{}, &clusterv1.Cluster{}).
+				Build(),
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			clusterEvents: make(chan event.GenericEvent, clusterEventsBufferSize),
+		},
+	}
+}
+
+func TestAKOClusterReconcilerSkip(t *testing.T) {
+	deletedADC := newTestADC("deleted", map[string]string{"team": "deleted"})
+	deletedADC.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	newADC := newTestADC("new", map[string]string{"team": "new"})
+	newADC.Finalizers = nil
+
+	for _, tc := range []struct {
+		name    string
+		cluster *clusterv1.Cluster
+	}{
+		{
+			name:    "cluster not selected by any akodeploymentconfig",
+			cluster: newTestCluster("unselected", map[string]string{"team": "none"}),
+		},
+		{
+			name:    "akodeploymentconfig being deleted",
+			cluster: newTestCluster("deleted", map[string]string{"team": "deleted"}),
+		},
+		{
+			name:    "akodeploymentconfig not reconciled yet",
+			cluster: newTestCluster("new", map[string]string{"team": "new"}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the avi client can't be initialized, the cluster must be skipped before
+			r := newTestAKOClusterReconciler(t, deletedADC, newADC, tc.cluster)
+			res, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: tc.cluster.Namespace, Name: tc.cluster.Name},
+			})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if !res.IsZero() {
+				t.Errorf("Reconcile() result = %v", res)
+			}
+		})
+	}
+}
+
+func TestAKOClusterReconcilerReleaseCluster(t *testing.T) {
+	cluster := newTestCluster("b", map[string]string{"team": "b", akoov1alpha1.AviClusterLabel: "team-a"})
+	cluster.Finalizers = []string{akoov1alpha1.ClusterFinalizer}
+	r := newTestAKOClusterReconciler(t, newTestADC("team-a", map[string]string{"team": "a"}), cluster)
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cluster)}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	released := &clusterv1.Cluster{}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(cluster), released); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := released.Labels[akoov1alpha1.AviClusterLabel]; ok {
+		t.Error("avi label should be removed from the cluster")
+	}
+	if len(released.Finalizers) != 0 {
+		t.Errorf("cluster finalizers = %v", released.Finalizers)
+	}
+}
+
+func TestAKOClusterReconcilerKeepUnmanagedCluster(t *testing.T) {
+	cluster := newTestCluster("b", map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: "team-b"})
+	cluster.Finalizers = []string{akoov1alpha1.ClusterFinalizer}
+	r := newTestAKOClusterReconciler(t,
+		newTestADC("team-a", map[string]string{"team": "a"}),
+		newTestADC("team-b", map[string]string{"team": "b"}),
+		cluster,
+	)
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cluster)}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	kept := &clusterv1.Cluster{}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(cluster), kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept.Labels[akoov1alpha1.AviClusterLabel] != "team-b" {
+		t.Errorf("avi label = %q, the cluster controller relabels the cluster", kept.Labels[akoov1alpha1.AviClusterLabel])
+	}
+	if len(kept.Finalizers) != 1 {
+		t.Errorf("cluster finalizers = %v", kept.Finalizers)
+	}
+}
+
+func TestUpdateClustersStatus(t *testing.T) {
+	adc := newTestADC("team-a", map[string]string{"team": "a"})
+	labels := map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: "team-a"}
+	ready := newTestCluster("ready", labels)
+	conditions.MarkTrue(ready, akoov1alpha1.AKOReadyCondition)
+	notReady := newTestCluster("not-ready", labels)
+	conditions.MarkFalse(notReady, akoov1alpha1.AKOReadyCondition, akoov1alpha1.AKOPodNotReadyReason, clusterv1.ConditionSeverityWarning, "")
+	deleting := newTestCluster("deleting", labels)
+	deleting.Finalizers = []string{akoov1alpha1.ClusterFinalizer}
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	r := newTestAKOClusterReconciler(t, adc, ready, notReady, deleting,
+		newTestCluster("unknown", labels),
+		newTestCluster("other", map[string]string{"team": "b", akoov1alpha1.AviClusterLabel: "team-b"}),
+	)
+
+	// the cluster just reconciled is counted as it is now
+	recovered := notReady.DeepCopy()
+	conditions.MarkTrue(recovered, akoov1alpha1.AKOReadyCondition)
+	if err := r.updateClustersStatus(context.Background(), logr.Discard(), adc.Name, recovered); err != nil {
+		t.Fatalf("updateClustersStatus() error = %v", err)
+	}
+	updated := &akoov1alpha1.AKODeploymentConfig{}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(adc), updated); err != nil {
+		t.Fatal(err)
+	}
+	expected := akoov1alpha1.AKOClustersStatus{Total: 3, Ready: 2, Unknown: 1}
+	if updated.Status.Clusters != expected {
+		t.Errorf("status.clusters = %+v, expected %+v", updated.Status.Clusters, expected)
+	}
+
+	if err := r.updateClustersStatus(context.Background(), logr.Discard(), "deleted", nil); err != nil {
+		t.Errorf("updateClustersStatus() of a deleted akodeploymentconfig error = %v", err)
+	}
+}
+
+func TestAKOClusterReconcilerGetAKODeploymentConfig(t *testing.T) {
+	teamA := newTestADC("team-a", map[string]string{"team": "a"})
+	teamB := newTestADC("team-b", map[string]string{"env": "dev"})
+
+	for _, tc := range []struct {
+		name     string
+		cluster  *clusterv1.Cluster
+		expected string
+	}{
+		{
+			name:     "selected by its labels",
+			cluster:  newTestCluster("a", map[string]string{"team": "a"}),
+			expected: "team-a",
+		},
+		{
+			name: "labeled by the akodeploymentconfig selecting it",
+			cluster: newTestCluster("b", map[string]string{
+				"team": "a", "env": "dev", akoov1alpha1.AviClusterLabel: "team-b",
+			}),
+			expected: "team-b",
+		},
+		{
+			// the cluster controller relabels it with team-a first
+			name: "labeled by an akodeploymentconfig no longer selecting it",
+			cluster: newTestCluster("c", map[string]string{
+				"team": "a", akoov1alpha1.AviClusterLabel: "team-b",
+			}),
+		},
+		{
+			name:    "not selected",
+			cluster: newTestCluster("d", map[string]string{"team": "c"}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestAKOClusterReconciler(t, teamA, teamB, tc.cluster)
+			obj, _, err := r.getAKODeploymentConfig(context.Background(), logr.Discard(), tc.cluster)
+			if err != nil {
+				t.Fatalf("getAKODeploymentConfig() error = %v", err)
+			}
+			name := ""
+			if obj != nil {
+				name = obj.Name
+			}
+			if name != tc.expected {
+				t.Errorf("getAKODeploymentConfig() = %q, expected %q", name, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAKOClusterReconcilerRecordSelection(t *testing.T) {
+	notReady := newTestCluster("not-ready", map[string]string{"team": "a"})
+	conditions.MarkFalse(notReady, clusterv1.ReadyCondition, "Provisioning", clusterv1.ConditionSeverityInfo, "")
+	moved := newTestCluster("moved", map[string]string{"team": "c"})
+	moved.Annotations = map[string]string{akoov1alpha1.AKOSelectionAnnotation: "[]"}
+
+	for _, tc := range []struct {
+		name      string
+		cluster   *clusterv1.Cluster
+		recorded  bool
+		reason    string
+		traceRule string
+	}{
+		{
+			name:      "selected cluster not ready",
+			cluster:   notReady,
+			recorded:  true,
+			reason:    akoov1alpha1.AKOSelectionClusterNotReadyReason,
+			traceRule: `"rule":"ClusterNotReady"`,
+		},
+		{
+			name:      "cluster no longer selected",
+			cluster:   moved,
+			recorded:  true,
+			reason:    akoov1alpha1.AKODeploymentConfigNotSelectedReason,
+			traceRule: `"rule":"Selector"`,
+		},
+		{
+			name:    "cluster never selected",
+			cluster: newTestCluster("unselected", map[string]string{"team": "c"}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestAKOClusterReconciler(t, newTestADC("team-a", map[string]string{"team": "a"}), tc.cluster)
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tc.cluster)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			cluster := &clusterv1.Cluster{}
+			if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(tc.cluster), cluster); err != nil {
+				t.Fatal(err)
+			}
+			trace, ok := cluster.Annotations[akoov1alpha1.AKOSelectionAnnotation]
+			c := conditions.Get(cluster, akoov1alpha1.AKODeploymentConfigSelectedCondition)
+			if !tc.recorded {
+				if ok || c != nil {
+					t.Errorf("selection should not be recorded, annotation = %q, condition = %v", trace, c)
+				}
+				return
+			}
+			if !strings.Contains(trace, tc.traceRule) {
+				t.Errorf("selection annotation = %s, expected it to contain %s", trace, tc.traceRule)
+			}
+			if c == nil || c.Status != corev1.ConditionFalse || c.Reason != tc.reason {
+				t.Errorf("%s condition = %v, expected reason %s", akoov1alpha1.AKODeploymentConfigSelectedCondition, c, tc.reason)
+			}
+		})
+	}
+}
+
+func TestEnqueueClusters(t *testing.T) {
+	adc := newTestADC("team-a", map[string]string{"team": "a"})
+	r := newTestAKOClusterReconciler(t, adc,
+		newTestCluster("a-1", map[string]string{"team": "a"}),
+		newTestCluster("a-2", map[string]string{"team": "a"}),
+		newTestCluster("b-1", map[string]string{"team": "b"}),
+		newTestCluster("b-2", map[string]string{"team": "b", akoov1alpha1.AviClusterLabel: "team-a"}),
+	)
+
+	if _, err := r.enqueueClusters(context.Background(), logr.Discard(), adc); err != nil {
+		t.Fatalf("enqueueClusters() error = %v", err)
+	}
+	close(r.clusterEvents)
+	var enqueued []string
+	for e := range r.clusterEvents {
+		enqueued = append(enqueued, e.Object.GetName())
+	}
+	if len(enqueued) != 3 || enqueued[0] != "a-1" || enqueued[1] != "a-2" || enqueued[2] != "b-2" {
+		t.Errorf("enqueueClusters() enqueued %v", enqueued)
+	}
+
+	r.clusterEvents = nil
+	if _, err := r.enqueueClusters(context.Background(), logr.Discard(), adc); err == nil {
+		t.Error("enqueueClusters() should fail without the AKO cluster controller")
+	}
+}
@@ -20,45 +20,64 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
-	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/handlers"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/predicates"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 func (r *AKODeploymentConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&akoov1alpha1.AKODeploymentConfig{}).
-		Watches(
-			&clusterv1.Cluster{},
-			handler.EnqueueRequestsFromMapFunc(handlers.AkoDeploymentConfigForCluster(r.Client, r.Log)),
-		).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.secretToAKODeploymentConfig(r.Client, r.Log)),
+			builder.WithPredicates(predicates.SecretDataChanged()),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.configMapToAKODeploymentConfig(r.Client, r.Log)),
+			builder.WithPredicates(predicates.ConfigMapReferenced(r.Client, r.PermissionMatrix.ConfigMap())),
 		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: config.Get().Concurrency.AKODeploymentConfig}).
 		Complete(r)
 }
 
 type AKODeploymentConfigReconciler struct {
 	client.Client
-	aviClient         aviclient.Client
 	Log               logr.Logger
 	Scheme            *runtime.Scheme
-	userReconciler    *user.AkoUserReconciler
 	ClusterReconciler *cluster.ClusterReconciler
 	netprovider.UsableNetworkProvider
+
+	// PermissionMatrix reads the permission matrix of the ako user roles, the
+	// default one is used when it's nil
+	PermissionMatrix *user.PermissionMatrixSource
+
+	// aviClients are the avi clients of the AKODeploymentConfigs, shared with the
+	// AKOClusterReconciler
+	aviClients aviClientCache
+
+	// clusterEvents enqueues the selected clusters to the AKOClusterReconciler
+	clusterEvents chan event.GenericEvent
 }
 
+// SetAviClient makes every AKODeploymentConfig use client instead of a client
+// built from its avi controller secrets, it must be called before the controllers
+// start
 func (r *AKODeploymentConfigReconciler) SetAviClient(client aviclient.Client) {
-	r.aviClient = client
+	r.aviClients.override = client
 }
 
 // AKODeploymentConfigReconciler reconciles a AKODeploymentConfig object
@@ -67,6 +86,7 @@ func (r *AKODeploymentConfigReconciler) SetAviClient(client aviclient.Client) {
 // +kubebuilder:rbac:groups=networking.tkg.tanzu.vmware.com,resources=akodeploymentconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.tkg.tanzu.vmware.com,resources=akodeploymentconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;list;watch;update;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=ako.vmware.com,resources=aviinfrasettings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=run.tanzu.vmware.com,resources=clusterbootstraps;clusterbootstraps/status,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=run.tanzu.vmware.com,resources=tanzukubernetesreleases;tanzukubernetesreleases/status,verbs=get;list;watch
@@ -131,9 +151,12 @@ func (r *AKODeploymentConfigReconciler) reconcileNormal(
 		// resources are released when the interface is destroyed. Return immediately after here to let the
 		// patcher helper update the object, and then proceed on the next reconciliation.
 		ctrlutil.AddFinalizer(obj, akoov1alpha1.AkoDeploymentConfigFinalizer)
+		// the AKO cluster controller only reconciles the clusters of an
+		// AKODeploymentConfig with the finalizer
+		return ctrl.Result{Requeue: true}, nil
 	}
 	return phases.ReconcilePhases(ctx, log, obj,
-		[]phases.ReconcilePhase{r.reconcileAVI, r.reconcileClusters})
+		[]phases.ReconcilePhase{r.reconcileAVI, r.enqueueClusters, r.reconcileClustersStatus})
 }
 
 func (r *AKODeploymentConfigReconciler) reconcileDelete(
@@ -153,6 +176,7 @@ func (r *AKODeploymentConfigReconciler) reconcileDelete(
 			// remove finalizer when clean up finishes successfully
 			log.Info("Removing finalizer", "finalizer", akoov1alpha1.AkoDeploymentConfigFinalizer)
 			ctrlutil.RemoveFinalizer(obj, akoov1alpha1.AkoDeploymentConfigFinalizer)
+			r.forgetAVI(obj.Name)
 		}
 	}()
 	return phases.ReconcilePhases(ctx, log, obj,
@@ -171,34 +195,72 @@ func (r *AKODeploymentConfigReconciler) secretToAKODeploymentConfig(c client.Cli
 		}
 		logger := log.WithValues("Secret", secret.Namespace+"/"+secret.Name)
 
-		if secret.Name != akoov1alpha1.AviCredentialName && secret.Name != akoov1alpha1.AviCAName {
+		// enqueue if credentials or certificate of akoo is updated
+		var akoDeploymentConfigs akoov1alpha1.AKODeploymentConfigList
+		if err := c.List(ctx, &akoDeploymentConfigs, client.MatchingFields{
+			index.AKODeploymentConfigSecretField: index.Key(secret.Namespace, secret.Name),
+		}); err != nil {
+			logger.Error(err, "Couldn't read ADCs")
 			return []reconcile.Request{}
 		}
 
+		var requests []ctrl.Request
+		for _, akoDeploymentConfig := range akoDeploymentConfigs.Items {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: akoDeploymentConfig.Namespace,
+					Name:      akoDeploymentConfig.Name,
+				},
+			})
+		}
+
+		if len(requests) != 0 {
+			logger.Info("Generating requests", "requests", requests)
+		}
+		// Return reconcile requests for the AKODeploymentConfig resources.
+		return requests
+	}
+}
+
+// configMapToAKODeploymentConfig enqueues the AKODeploymentConfigs whose role
+// permission profile is the ConfigMap, or all of them when it's the permission
+// matrix override, so the ako user roles follow its changes
+func (r *AKODeploymentConfigReconciler) configMapToAKODeploymentConfig(c client.Client, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		configMap, ok := o.(*corev1.ConfigMap)
+		if !ok {
+			log.Error(errors.New("invalid type"),
+				"Expected to receive ConfigMap resource",
+				"actualType", fmt.Sprintf("%T", o))
+			return nil
+		}
+		logger := log.WithValues("ConfigMap", configMap.Namespace+"/"+configMap.Name)
+
+		// the permission matrix override applies to every ADC
+		var opts []client.ListOption
+		if r.PermissionMatrix.ConfigMap() != client.ObjectKeyFromObject(configMap) {
+			opts = append(opts, client.MatchingFields{
+				index.AKODeploymentConfigConfigMapField: index.Key(configMap.Namespace, configMap.Name),
+			})
+		}
 		var akoDeploymentConfigs akoov1alpha1.AKODeploymentConfigList
-		if err := c.List(ctx, &akoDeploymentConfigs, []client.ListOption{}...); err != nil {
+		if err := c.List(ctx, &akoDeploymentConfigs, opts...); err != nil {
 			logger.Error(err, "Couldn't read ADCs")
 			return []reconcile.Request{}
 		}
 
 		var requests []ctrl.Request
-		// enqueue if credentials or certificate of akoo is updated
 		for _, akoDeploymentConfig := range akoDeploymentConfigs.Items {
-			if akoDeploymentConfig.Spec.CertificateAuthorityRef.Name == secret.Name &&
-				akoDeploymentConfig.Spec.CertificateAuthorityRef.Namespace == secret.Namespace ||
-				akoDeploymentConfig.Spec.AdminCredentialRef.Name == secret.Name &&
-					akoDeploymentConfig.Spec.AdminCredentialRef.Namespace == secret.Namespace {
-				requests = append(requests, ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: akoDeploymentConfig.Namespace,
-						Name:      akoDeploymentConfig.Name,
-					},
-				})
-			}
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name: akoDeploymentConfig.Name,
+				},
+			})
 		}
 
-		logger.Info("Generating requests", "requests", requests)
-		// Return reconcile requests for the AKODeploymentConfig resources.
+		if len(requests) != 0 {
+			logger.Info("Generating requests", "requests", requests)
+		}
 		return requests
 	}
 }
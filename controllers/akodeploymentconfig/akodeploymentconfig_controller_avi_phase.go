@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
@@ -17,10 +18,10 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/vmware/alb-sdk/go/models"
 
-	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,114 +38,193 @@ import (
 
 var lock = &sync.Mutex{}
 
-func getAviCAFromADC(c client.Client, ctx context.Context,
-	log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (string, error) {
-	aviControllerCA := &corev1.Secret{}
-	if err := c.Get(ctx, client.ObjectKey{
-		Name:      obj.Spec.CertificateAuthorityRef.Name,
-		Namespace: obj.Spec.CertificateAuthorityRef.Namespace,
-	}, aviControllerCA); err != nil {
-		if apierrors.IsNotFound(err) {
-			log.Info("Cannot find referenced secret " + obj.Spec.CertificateAuthorityRef.Name + "/" + obj.Spec.CertificateAuthorityRef.Namespace + " requeue the request")
-		} else {
-			log.Error(err, "Failed to find referenced "+obj.Spec.CertificateAuthorityRef.Name+"/"+obj.Spec.CertificateAuthorityRef.Namespace+" Secret")
+// aviClientCache holds the avi client and the ako user reconciler of every
+// AKODeploymentConfig, keyed by its name. The AKODeploymentConfig and the AKO
+// cluster controllers share it, so the concurrent reconciles of
+// AKODeploymentConfigs pointing to different avi controllers never use the
+// client of another one.
+type aviClientCache struct {
+	lock    sync.Mutex
+	entries map[string]*aviClients
+	// override is the avi client of every AKODeploymentConfig when it's set,
+	// it's set before the controllers start
+	override aviclient.Client
+}
+
+// aviClients are the clients an AKODeploymentConfig talks to its avi controller with
+type aviClients struct {
+	// fingerprint identifies the avi controller and the credentials the clients
+	// are built for
+	fingerprint    string
+	aviClient      aviclient.Client
+	userReconciler *user.AkoUserReconciler
+	// syncedUsers remembers, by cluster, the state the avi user of the cluster
+	// was last reconciled for
+	syncedUsers sync.Map
+}
+
+// skipUnchangedAviUser runs the avi user phase only when the cluster or its
+// AKODeploymentConfig changed since the phase last succeeded, so the periodic
+// AKO health and drift checks requeue the clusters without calling the avi
+// controller
+func (c *aviClients) skipUnchangedAviUser(phase phases.ReconcileClusterPhase) phases.ReconcileClusterPhase {
+	return func(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+		key := client.ObjectKeyFromObject(cluster)
+		if !cluster.GetDeletionTimestamp().IsZero() {
+			c.syncedUsers.Delete(key)
+			return phase(ctx, log, cluster, obj)
+		}
+		state := aviUserState(cluster, obj)
+		if synced, ok := c.syncedUsers.Load(key); ok && synced == state {
+			log.V(3).Info("AVI user is up to date, skip")
+			return ctrl.Result{}, nil
 		}
-		return "", err
+		res, err := phase(ctx, log, cluster, obj)
+		if err == nil && res.IsZero() {
+			c.syncedUsers.Store(key, state)
+		}
+		return res, err
 	}
+}
+
+// forgetAviUser makes the next reconcile of the cluster reconcile its avi user
+func (c *aviClients) forgetAviUser(cluster *clusterv1.Cluster) {
+	c.syncedUsers.Delete(client.ObjectKeyFromObject(cluster))
+}
 
-	return string(aviControllerCA.Data["certificateAuthorityData"][:]), nil
+// aviUserState is what the avi user of a cluster depends on: the cluster, its
+// labels which may name its tenant, and the spec of the AKODeploymentConfig.
+// The secrets and ConfigMaps of the AKODeploymentConfig are covered by
+// resyncAviUsers.
+func aviUserState(cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig) string {
+	return fmt.Sprintf("%s/%d/%v/%s/%d", cluster.UID, cluster.Generation, cluster.Labels, obj.UID, obj.Generation)
+}
+
+// aviPhase is a reconcilePhase function talking to the avi controller of the
+// AKODeploymentConfig
+type aviPhase func(ctx context.Context, log logr.Logger, aviClient aviclient.Client, obj *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error)
+
+// withAviClient turns an aviPhase into a reconcilePhase using aviClient
+func withAviClient(aviClient aviclient.Client, phase aviPhase) phases.ReconcilePhase {
+	return func(ctx context.Context, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+		return phase(ctx, log, aviClient, obj)
+	}
 }
 
+// initAVI returns the avi clients of the AKODeploymentConfig. They are built
+// lazily and again once its avi controller, credentials or certificate authority
+// change.
 func (r *AKODeploymentConfigReconciler) initAVI(
 	ctx context.Context,
 	log logr.Logger,
 	obj *akoov1alpha1.AKODeploymentConfig,
-) (ctrl.Result, error) {
-	res := ctrl.Result{}
-
-	var currentCa, newCa string
-
-	if r.aviClient != nil {
-		currentCa, _ = r.aviClient.AviCertificateConfig()
+) (*aviClients, error) {
+	var fingerprint string
+	if r.aviClients.override == nil {
+		var err error
+		if fingerprint, err = ako_operator.AviClientFingerprint(ctx, r.Client, log, obj); err != nil {
+			return nil, err
+		}
 	}
-	newCa, err := getAviCAFromADC(r.Client, ctx, log, obj)
-	if err != nil {
-		return res, err
+
+	r.aviClients.lock.Lock()
+	defer r.aviClients.lock.Unlock()
+	if clients, ok := r.aviClients.entries[obj.Name]; ok && clients.fingerprint == fingerprint {
+		return clients, nil
 	}
-	reInit := currentCa != newCa
 
-	lock.Lock()
-	// Lazily initialize aviClient so we don't skip other reconciliations
-	if r.aviClient == nil || reInit {
+	aviClient := r.aviClients.override
+	if aviClient == nil {
 		var err error
-		r.aviClient, err = aviclient.NewAviClientFromSecrets(r.Client, ctx, log, obj.Spec.Controller,
-			obj.Spec.AdminCredentialRef.Name, obj.Spec.AdminCredentialRef.Namespace,
-			obj.Spec.CertificateAuthorityRef.Name, obj.Spec.CertificateAuthorityRef.Namespace,
-			obj.Spec.ControllerVersion)
-
-		if err != nil {
-			log.Error(err, "Cannot init AVI clients from secrets")
-			return res, err
+		if aviClient, err = newAviClient(ctx, r.Client, log, obj); err != nil {
+			return nil, err
 		}
+		log.Info("AVI Client initialized successfully")
+	}
+	clients := &aviClients{
+		fingerprint:    fingerprint,
+		aviClient:      aviClient,
+		userReconciler: user.NewProvider(r.Client, aviClient, r.Log, r.Scheme, r.PermissionMatrix),
+	}
+	if r.aviClients.entries == nil {
+		r.aviClients.entries = make(map[string]*aviClients)
+	}
+	r.aviClients.entries[obj.Name] = clients
+	log.Info("Ako User Reconciler initialized")
+	return clients, nil
+}
 
-		version, err := r.aviClient.GetControllerVersion()
-		if err != nil {
-			return ctrl.Result{}, err
-		}
+// resyncAviUsers makes the AKO cluster controller reconcile the avi users of
+// the clusters of the AKODeploymentConfig again, the secrets and ConfigMaps they
+// are built from may have changed
+func (r *AKODeploymentConfigReconciler) resyncAviUsers(name string) {
+	r.aviClients.lock.Lock()
+	defer r.aviClients.lock.Unlock()
+	if clients, ok := r.aviClients.entries[name]; ok {
+		clients.syncedUsers.Clear()
+	}
+}
 
-		if obj.Spec.ControllerVersion != version {
-			// re-init aviClient with real version
-			r.aviClient, err = aviclient.NewAviClientFromSecrets(r.Client, ctx, log, obj.Spec.Controller,
-				obj.Spec.AdminCredentialRef.Name, obj.Spec.AdminCredentialRef.Namespace,
-				obj.Spec.CertificateAuthorityRef.Name, obj.Spec.CertificateAuthorityRef.Namespace,
-				version)
-
-			if err != nil {
-				log.Error(err, "Cannot init AVI clients with actual avi controller version")
-				return res, err
-			}
-		}
+// forgetAVI drops the avi clients of a deleted AKODeploymentConfig
+func (r *AKODeploymentConfigReconciler) forgetAVI(name string) {
+	r.aviClients.lock.Lock()
+	defer r.aviClients.lock.Unlock()
+	delete(r.aviClients.entries, name)
+}
 
-		log.Info("AVI Client initialized successfully")
+// newAviClient builds the avi client of the AKODeploymentConfig for the actual
+// version of its avi controller
+func newAviClient(ctx context.Context, c client.Client, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+	aviClient, err := aviclient.NewAviClientFromSecrets(c, ctx, log, obj.Spec.Controller,
+		obj.Spec.AdminCredentialRef.Name, obj.Spec.AdminCredentialRef.Namespace,
+		obj.Spec.CertificateAuthorityRef.Name, obj.Spec.CertificateAuthorityRef.Namespace,
+		obj.Spec.ControllerVersion)
+	if err != nil {
+		log.Error(err, "Cannot init AVI clients from secrets")
+		return nil, err
 	}
-	lock.Unlock()
 
-	if r.userReconciler == nil || reInit {
-		r.userReconciler = user.NewProvider(r.Client, r.aviClient, r.Log, r.Scheme)
-		log.Info("Ako User Reconciler initialized")
+	version, err := aviClient.GetControllerVersion()
+	if err != nil {
+		return nil, err
+	}
+	if obj.Spec.ControllerVersion == version {
+		return aviClient, nil
 	}
 
-	return res, nil
+	// re-init aviClient with real version
+	aviClient, err = aviclient.NewAviClientFromSecrets(c, ctx, log, obj.Spec.Controller,
+		obj.Spec.AdminCredentialRef.Name, obj.Spec.AdminCredentialRef.Namespace,
+		obj.Spec.CertificateAuthorityRef.Name, obj.Spec.CertificateAuthorityRef.Namespace,
+		version)
+	if err != nil {
+		log.Error(err, "Cannot init AVI clients with actual avi controller version")
+		return nil, err
+	}
+	return aviClient, nil
 }
 
-// reconcileAVI reconciles every cluster that matches the
-// AKODeploymentConfig's selector by conducting AVI related operations
+// reconcileAVI reconciles the AVI resources shared by the clusters that match
+// the AKODeploymentConfig's selector, the AVI users of the clusters are
+// reconciled by the AKOClusterReconciler
 // It's a reconcilePhase function
 func (r *AKODeploymentConfigReconciler) reconcileAVI(
 	ctx context.Context,
 	log logr.Logger,
 	obj *akoov1alpha1.AKODeploymentConfig,
 ) (ctrl.Result, error) {
-	if res, err := r.initAVI(ctx, log, obj); err != nil {
+	clients, err := r.initAVI(ctx, log, obj)
+	if err != nil {
 		log.Error(err, "Failed to initialize avi related clients")
-		return res, err
+		return ctrl.Result{}, err
 	}
 
 	return phases.ReconcilePhases(ctx, log, obj, []phases.ReconcilePhase{
-		r.reconcileNetworkSubnets,
-		r.reconcileCloudUsableNetwork,
+		withAviClient(clients.aviClient, r.reconcileNetworkSubnets),
+		withAviClient(clients.aviClient, r.reconcileCloudUsableNetwork),
 		r.reconcileAviInfraSetting,
-		r.reconcileControllerVersion,
-		func(ctx context.Context, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
-			return phases.ReconcileClustersPhases(ctx, r.Client, log, obj,
-				[]phases.ReconcileClusterPhase{
-					r.userReconciler.ReconcileAviUser,
-				},
-				[]phases.ReconcileClusterPhase{
-					r.userReconciler.ReconcileAviUserDelete,
-				},
-			)
-		},
+		withAviClient(clients.aviClient, r.reconcileControlPlaneDNS),
+		withAviClient(clients.aviClient, r.reconcileControllerVersion),
 	})
 }
 
@@ -156,22 +236,24 @@ func (r *AKODeploymentConfigReconciler) reconcileAVIDelete(
 	log logr.Logger,
 	obj *akoov1alpha1.AKODeploymentConfig,
 ) (ctrl.Result, error) {
-	if res, err := r.initAVI(ctx, log, obj); err != nil {
+	clients, err := r.initAVI(ctx, log, obj)
+	if err != nil {
 		log.Error(err, "Failed to initialize avi related clients")
-		return res, err
+		return ctrl.Result{}, err
 	}
 
 	return phases.ReconcilePhases(ctx, log, obj, []phases.ReconcilePhase{
 		r.reconcileAviInfraSettingDelete,
+		withAviClient(clients.aviClient, r.reconcileControlPlaneDNSDelete),
 		func(ctx context.Context, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
 			return phases.ReconcileClustersPhases(ctx, r.Client, log, obj,
 				[]phases.ReconcileClusterPhase{
-					r.userReconciler.ReconcileAviUserDelete,
+					clients.userReconciler.ReconcileAviUserDelete,
 				},
 				[]phases.ReconcileClusterPhase{
 					// TODO(fangyuanl): handle the data network configuration
 					// deletion
-					r.userReconciler.ReconcileAviUserDelete,
+					clients.userReconciler.ReconcileAviUserDelete,
 				},
 			)
 		},
@@ -184,12 +266,13 @@ func (r *AKODeploymentConfigReconciler) reconcileAVIDelete(
 func (r *AKODeploymentConfigReconciler) reconcileControllerVersion(
 	ctx context.Context,
 	log logr.Logger,
+	aviClient aviclient.Client,
 	obj *akoov1alpha1.AKODeploymentConfig,
 ) (ctrl.Result, error) {
 	log = log.WithValues("controllerVersion", obj.Spec.ControllerVersion)
 	log.Info("Start reconciling AVI controller version")
 
-	version, err := r.aviClient.GetControllerVersion()
+	version, err := aviClient.GetControllerVersion()
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -207,18 +290,19 @@ func (r *AKODeploymentConfigReconciler) reconcileControllerVersion(
 func (r *AKODeploymentConfigReconciler) reconcileNetworkSubnets(
 	ctx context.Context,
 	log logr.Logger,
+	aviClient aviclient.Client,
 	obj *akoov1alpha1.AKODeploymentConfig,
 ) (ctrl.Result, error) {
 	res := ctrl.Result{}
 
 	log.Info("Start reconciling AVI Network Subnets")
 
-	if r.aviClient == nil {
+	if aviClient == nil {
 		log.Info("AVI client not initialized, requeue")
 		return res, errors.New("AVI client not initialized")
 	}
 
-	network, err := r.aviClient.NetworkGetByName(obj.Spec.DataNetwork.Name, obj.Spec.CloudName)
+	network, err := aviClient.NetworkGetByName(obj.Spec.DataNetwork.Name, obj.Spec.CloudName)
 	if err != nil {
 		log.Info("[WARN] Failed to get the Data Network from AVI Controller")
 		return res, nil
@@ -243,7 +327,7 @@ func (r *AKODeploymentConfigReconciler) reconcileNetworkSubnets(
 
 	if modified {
 		log.V(3).Info("Change detected, updating Network", "network", obj.Spec.DataNetwork.Name)
-		_, err := r.aviClient.NetworkUpdate(network)
+		_, err := aviClient.NetworkUpdate(network)
 		if err != nil {
 			log.Error(err, "Failed to update Network, requeue the request", "network", network)
 			return res, err
@@ -259,19 +343,20 @@ func (r *AKODeploymentConfigReconciler) reconcileNetworkSubnets(
 func (r *AKODeploymentConfigReconciler) reconcileCloudUsableNetwork(
 	ctx context.Context,
 	log logr.Logger,
+	aviClient aviclient.Client,
 	obj *akoov1alpha1.AKODeploymentConfig,
 ) (ctrl.Result, error) {
 	log = log.WithValues("cloud", obj.Spec.CloudName)
 	log.Info("Start reconciling AVI cloud usable network")
 
 	if obj.Spec.ControlPlaneNetwork.Name != "" && obj.Spec.ControlPlaneNetwork.CIDR != "" {
-		if err := r.AddUsableNetwork(r.aviClient, obj.Spec.CloudName, obj.Spec.ControlPlaneNetwork.Name, log); err != nil {
+		if err := r.AddUsableNetwork(aviClient, obj.Spec.CloudName, obj.Spec.ControlPlaneNetwork.Name, log); err != nil {
 			log.Error(err, "Failed to add usable network", "network", obj.Spec.ControlPlaneNetwork.Name)
 			return ctrl.Result{}, err
 		}
 	}
 
-	if err := r.AddUsableNetwork(r.aviClient, obj.Spec.CloudName, obj.Spec.DataNetwork.Name, log); err != nil {
+	if err := r.AddUsableNetwork(aviClient, obj.Spec.CloudName, obj.Spec.DataNetwork.Name, log); err != nil {
 		log.Error(err, "Failed to add usable network", "network", obj.Spec.DataNetwork.Name)
 		return ctrl.Result{}, err
 	}
@@ -279,6 +364,145 @@ func (r *AKODeploymentConfigReconciler) reconcileCloudUsableNetwork(
 	return ctrl.Result{}, nil
 }
 
+// reconcileControlPlaneDNS adds the control plane domain to the Avi DNS profile
+// of the cloud, so that the control plane virtual services are registered in it.
+// The domain is recorded on the AKODeploymentConfig once added, so that it's
+// removed again when the AKODeploymentConfig stops using it or is deleted.
+func (r *AKODeploymentConfigReconciler) reconcileControlPlaneDNS(
+	ctx context.Context,
+	log logr.Logger,
+	aviClient aviclient.Client,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	domain := ""
+	if dns := obj.Spec.ControlPlaneDNS; dns != nil && dns.Provider == akoov1alpha1.ControlPlaneDNSProviderAviDNS {
+		domain = dns.Domain
+	}
+	if recorded := obj.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation]; recorded != "" && recorded != domain {
+		if err := r.removeControlPlaneDNS(ctx, log, aviClient, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if domain == "" {
+		return ctrl.Result{}, nil
+	}
+	log = log.WithValues("cloud", obj.Spec.CloudName, "domain", domain)
+	log.Info("Start reconciling AVI DNS service domain")
+
+	updated, err := aviclient.EnsureDNSServiceDomain(aviClient, obj.Spec.CloudName, domain)
+	if err != nil {
+		log.Error(err, "Failed to add the control plane domain to the AVI DNS profile")
+		return ctrl.Result{}, err
+	}
+	if updated {
+		log.Info("Successfully added the control plane domain to the AVI DNS profile")
+	} else if obj.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] != domain {
+		// a domain that was in the profile before is only shared with the
+		// AKODeploymentConfigs which added it, never removed otherwise
+		users, err := r.controlPlaneDNSUsers(ctx, obj, domain)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		recorded := false
+		for _, adc := range users {
+			recorded = recorded || adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] == domain
+		}
+		if !recorded {
+			return ctrl.Result{}, nil
+		}
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] = domain
+	return ctrl.Result{}, nil
+}
+
+// reconcileControlPlaneDNSDelete removes the control plane domain added by the
+// AKODeploymentConfig from the Avi DNS profile of the cloud
+func (r *AKODeploymentConfigReconciler) reconcileControlPlaneDNSDelete(
+	ctx context.Context,
+	log logr.Logger,
+	aviClient aviclient.Client,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	if obj.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] == "" {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.removeControlPlaneDNS(ctx, log, aviClient, obj)
+}
+
+// removeControlPlaneDNS removes the domain recorded on the AKODeploymentConfig
+// from the Avi DNS profile of the cloud and forgets it. The domain stays while
+// other AKODeploymentConfigs of the cloud use it, they record it instead so
+// that the last of them removes it.
+func (r *AKODeploymentConfigReconciler) removeControlPlaneDNS(
+	ctx context.Context,
+	log logr.Logger,
+	aviClient aviclient.Client,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) error {
+	domain := obj.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation]
+	log = log.WithValues("cloud", obj.Spec.CloudName, "domain", domain)
+	users, err := r.controlPlaneDNSUsers(ctx, obj, domain)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		updated, err := aviclient.RemoveDNSServiceDomain(aviClient, obj.Spec.CloudName, domain)
+		if err != nil {
+			log.Error(err, "Failed to remove the control plane domain from the AVI DNS profile")
+			return err
+		}
+		if updated {
+			log.Info("Successfully removed the control plane domain from the AVI DNS profile")
+		}
+	}
+	for _, adc := range users {
+		if adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] == domain {
+			continue
+		}
+		log.Info("The control plane domain is still used, hand it over", "akodeploymentconfig", adc.Name)
+		base := adc.DeepCopy()
+		if adc.Annotations == nil {
+			adc.Annotations = make(map[string]string)
+		}
+		adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] = domain
+		if err := r.Patch(ctx, adc, client.MergeFrom(base)); err != nil {
+			log.Error(err, "Failed to hand over the control plane domain", "akodeploymentconfig", adc.Name)
+			return err
+		}
+	}
+	delete(obj.Annotations, akoov1alpha1.AviDNSServiceDomainAnnotation)
+	return nil
+}
+
+// controlPlaneDNSUsers returns the other AKODeploymentConfigs of the cloud, not
+// being deleted, which recorded the domain in the Avi DNS profile or use it
+func (r *AKODeploymentConfigReconciler) controlPlaneDNSUsers(
+	ctx context.Context,
+	obj *akoov1alpha1.AKODeploymentConfig,
+	domain string,
+) ([]*akoov1alpha1.AKODeploymentConfig, error) {
+	var adcs akoov1alpha1.AKODeploymentConfigList
+	if err := r.List(ctx, &adcs); err != nil {
+		return nil, err
+	}
+	var users []*akoov1alpha1.AKODeploymentConfig
+	for i := range adcs.Items {
+		adc := &adcs.Items[i]
+		if adc.Name == obj.Name || adc.Spec.CloudName != obj.Spec.CloudName || !adc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		dns := adc.Spec.ControlPlaneDNS
+		if adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation] == domain ||
+			(dns != nil && dns.Provider == akoov1alpha1.ControlPlaneDNSProviderAviDNS && dns.Domain == domain) {
+			users = append(users, adc)
+		}
+	}
+	return users, nil
+}
+
 func (r *AKODeploymentConfigReconciler) reconcileAviInfraSetting(
 	ctx context.Context,
 	log logr.Logger,
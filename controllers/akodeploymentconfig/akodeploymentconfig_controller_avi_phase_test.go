@@ -0,0 +1,304 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package akodeploymentconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+func TestReconcileControlPlaneDNS(t *testing.T) {
+	aviDNS := func(domain string) *akoov1alpha1.ControlPlaneDNS {
+		return &akoov1alpha1.ControlPlaneDNS{Domain: domain, Provider: akoov1alpha1.ControlPlaneDNSProviderAviDNS}
+	}
+	otherADC := func(dns *akoov1alpha1.ControlPlaneDNS, recorded string) *akoov1alpha1.AKODeploymentConfig {
+		adc := newTestADC("other-adc", nil)
+		adc.Spec.CloudName = "test-cloud"
+		adc.Spec.ControlPlaneDNS = dns
+		if recorded != "" {
+			adc.Annotations = map[string]string{akoov1alpha1.AviDNSServiceDomainAnnotation: recorded}
+		}
+		return adc
+	}
+	for _, tc := range []struct {
+		name             string
+		dns              *akoov1alpha1.ControlPlaneDNS
+		recorded         string
+		others           []client.Object
+		domains          []string
+		expected         []string
+		expectedRecorded string
+		updated          bool
+	}{
+		{
+			name:     "no control plane dns",
+			domains:  []string{"avi.example.com"},
+			expected: []string{"avi.example.com"},
+		},
+		{
+			name:     "published by external-dns",
+			dns:      &akoov1alpha1.ControlPlaneDNS{Domain: "k8s.example.com", Provider: akoov1alpha1.ControlPlaneDNSProviderExternalDNS},
+			domains:  []string{"avi.example.com"},
+			expected: []string{"avi.example.com"},
+		},
+		{
+			name:             "domain added to the avi dns profile",
+			dns:              aviDNS("k8s.example.com"),
+			domains:          []string{"avi.example.com"},
+			expected:         []string{"avi.example.com", "k8s.example.com"},
+			expectedRecorded: "k8s.example.com",
+			updated:          true,
+		},
+		{
+			name:     "domain already in the avi dns profile",
+			dns:      aviDNS("k8s.example.com"),
+			domains:  []string{"k8s.example.com"},
+			expected: []string{"k8s.example.com"},
+		},
+		{
+			name:             "domain already added by another adc",
+			dns:              aviDNS("k8s.example.com"),
+			others:           []client.Object{otherADC(aviDNS("k8s.example.com"), "k8s.example.com")},
+			domains:          []string{"k8s.example.com"},
+			expected:         []string{"k8s.example.com"},
+			expectedRecorded: "k8s.example.com",
+		},
+		{
+			name:     "domain removed when published by external-dns",
+			dns:      &akoov1alpha1.ControlPlaneDNS{Domain: "k8s.example.com", Provider: akoov1alpha1.ControlPlaneDNSProviderExternalDNS},
+			recorded: "k8s.example.com",
+			domains:  []string{"avi.example.com", "k8s.example.com"},
+			expected: []string{"avi.example.com"},
+			updated:  true,
+		},
+		{
+			name:             "domain replaced when changed",
+			dns:              aviDNS("new.example.com"),
+			recorded:         "k8s.example.com",
+			domains:          []string{"k8s.example.com"},
+			expected:         []string{"new.example.com"},
+			expectedRecorded: "new.example.com",
+			updated:          true,
+		},
+		{
+			name:     "domain handed over to another adc using it",
+			recorded: "k8s.example.com",
+			others:   []client.Object{otherADC(aviDNS("k8s.example.com"), "")},
+			domains:  []string{"k8s.example.com"},
+			expected: []string{"k8s.example.com"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			profile := &models.IPAMDNSProviderProfile{InternalProfile: &models.IPAMDNSInternalProfile{}}
+			for _, domain := range tc.domains {
+				profile.InternalProfile.DNSServiceDomain = append(profile.InternalProfile.DNSServiceDomain,
+					&models.DNSServiceDomain{DomainName: ptr.To(domain)})
+			}
+			fakeAviClient, updated := newTestDNSAviClient(t, profile)
+			r := newTestDNSReconciler(t, tc.others...)
+			adc := newTestADC("test-adc", nil)
+			adc.Spec.CloudName = "test-cloud"
+			adc.Spec.ControlPlaneDNS = tc.dns
+			if tc.recorded != "" {
+				adc.Annotations = map[string]string{akoov1alpha1.AviDNSServiceDomainAnnotation: tc.recorded}
+			}
+
+			if _, err := r.reconcileControlPlaneDNS(context.Background(), logr.Discard(), fakeAviClient, adc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *updated != tc.updated {
+				t.Errorf("expected the DNS profile update to be %v, got %v", tc.updated, *updated)
+			}
+			if recorded := adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation]; recorded != tc.expectedRecorded {
+				t.Errorf("expected the recorded domain to be %q, got %q", tc.expectedRecorded, recorded)
+			}
+			for _, obj := range tc.others {
+				other := &akoov1alpha1.AKODeploymentConfig{}
+				if err := r.Get(context.Background(), client.ObjectKeyFromObject(obj), other); err != nil {
+					t.Fatal(err)
+				}
+				if recorded := other.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation]; recorded != "k8s.example.com" {
+					t.Errorf("expected the other adc to record the domain, got %q", recorded)
+				}
+			}
+			expectDNSServiceDomains(t, profile, tc.expected)
+		})
+	}
+}
+
+func TestReconcileControlPlaneDNSDelete(t *testing.T) {
+	profile := &models.IPAMDNSProviderProfile{InternalProfile: &models.IPAMDNSInternalProfile{
+		DNSServiceDomain: []*models.DNSServiceDomain{
+			{DomainName: ptr.To("avi.example.com")},
+			{DomainName: ptr.To("k8s.example.com")},
+		},
+	}}
+	fakeAviClient, _ := newTestDNSAviClient(t, profile)
+	r := newTestDNSReconciler(t)
+	adc := newTestADC("test-adc", nil)
+	adc.Spec.CloudName = "test-cloud"
+	adc.Spec.ControlPlaneDNS = &akoov1alpha1.ControlPlaneDNS{Domain: "k8s.example.com", Provider: akoov1alpha1.ControlPlaneDNSProviderAviDNS}
+	adc.Annotations = map[string]string{akoov1alpha1.AviDNSServiceDomainAnnotation: "k8s.example.com"}
+
+	if _, err := r.reconcileControlPlaneDNSDelete(context.Background(), logr.Discard(), fakeAviClient, adc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := adc.Annotations[akoov1alpha1.AviDNSServiceDomainAnnotation]; ok {
+		t.Error("expected the recorded domain to be forgotten")
+	}
+	expectDNSServiceDomains(t, profile, []string{"avi.example.com"})
+}
+
+func newTestDNSAviClient(t *testing.T, profile *models.IPAMDNSProviderProfile) (*aviclient.FakeAviClient, *bool) {
+	fakeAviClient := aviclient.NewFakeAviClient()
+	fakeAviClient.Cloud.SetGetByNameCloudFunc(func(name string, options ...session.ApiOptionsParams) (*models.Cloud, error) {
+		return &models.Cloud{Name: ptr.To(name), DNSProviderRef: ptr.To("https://avi/api/ipamdnsproviderprofile/dns-uuid")}, nil
+	})
+	fakeAviClient.IPAMDNSProviderProfile.SetGetIPAMFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error) {
+		if uuid != "dns-uuid" {
+			t.Fatalf("unexpected DNS profile %s", uuid)
+		}
+		return profile, nil
+	})
+	updated := false
+	fakeAviClient.IPAMDNSProviderProfile.SetUpdateIPAMFn(func(obj *models.IPAMDNSProviderProfile, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error) {
+		updated = true
+		return obj, nil
+	})
+	return fakeAviClient, &updated
+}
+
+func newTestDNSReconciler(t *testing.T, objs ...client.Object) *AKODeploymentConfigReconciler {
+	scheme := runtime.NewScheme()
+	if err := akoov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &AKODeploymentConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+}
+
+func expectDNSServiceDomains(t *testing.T, profile *models.IPAMDNSProviderProfile, expected []string) {
+	var domains []string
+	for _, domain := range profile.InternalProfile.DNSServiceDomain {
+		domains = append(domains, *domain.DomainName)
+	}
+	if len(domains) != len(expected) {
+		t.Fatalf("expected domains %v, got %v", expected, domains)
+	}
+	for i := range domains {
+		if domains[i] != expected[i] {
+			t.Errorf("expected domains %v, got %v", expected, domains)
+		}
+	}
+}
+
+func TestReconcileControlPlaneDNSWithoutAviDNSProfile(t *testing.T) {
+	fakeAviClient := aviclient.NewFakeAviClient()
+	fakeAviClient.Cloud.SetGetByNameCloudFunc(func(name string, options ...session.ApiOptionsParams) (*models.Cloud, error) {
+		return &models.Cloud{Name: ptr.To(name)}, nil
+	})
+	r := &AKODeploymentConfigReconciler{}
+	adc := newTestADC("test-adc", nil)
+	adc.Spec.CloudName = "test-cloud"
+	adc.Spec.ControlPlaneDNS = &akoov1alpha1.ControlPlaneDNS{Domain: "k8s.example.com", Provider: akoov1alpha1.ControlPlaneDNSProviderAviDNS}
+
+	if _, err := r.reconcileControlPlaneDNS(context.Background(), logr.Discard(), fakeAviClient, adc); err == nil {
+		t.Fatal("expected an error when the cloud has no DNS profile")
+	}
+}
+
+func TestInitAVIPerAKODeploymentConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	r := &AKODeploymentConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+	r.SetAviClient(aviclient.NewFakeAviClient())
+	ctx := context.Background()
+
+	teamA, err := r.initAVI(ctx, logr.Discard(), newTestADC("team-a", nil))
+	if err != nil {
+		t.Fatalf("initAVI() error = %v", err)
+	}
+	teamB, err := r.initAVI(ctx, logr.Discard(), newTestADC("team-b", nil))
+	if err != nil {
+		t.Fatalf("initAVI() error = %v", err)
+	}
+	if teamA == teamB || teamA.userReconciler == teamB.userReconciler {
+		t.Error("each AKODeploymentConfig should get its own clients")
+	}
+	if again, _ := r.initAVI(ctx, logr.Discard(), newTestADC("team-a", nil)); again != teamA {
+		t.Error("the clients of an AKODeploymentConfig should be reused")
+	}
+
+	r.forgetAVI("team-a")
+	if again, _ := r.initAVI(ctx, logr.Discard(), newTestADC("team-a", nil)); again == teamA {
+		t.Error("the clients of a deleted AKODeploymentConfig should be dropped")
+	}
+}
+
+func TestSkipUnchangedAviUser(t *testing.T) {
+	r := &AKODeploymentConfigReconciler{Log: logr.Discard()}
+	r.SetAviClient(aviclient.NewFakeAviClient())
+	ctx := context.Background()
+	adc := newTestADC("team-a", nil)
+	clients, err := r.initAVI(ctx, logr.Discard(), adc)
+	if err != nil {
+		t.Fatalf("initAVI() error = %v", err)
+	}
+
+	runs := 0
+	var phaseErr error
+	phase := clients.skipUnchangedAviUser(func(context.Context, logr.Logger, *clusterv1.Cluster, *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+		runs++
+		return ctrl.Result{}, phaseErr
+	})
+	cluster := newTestCluster("workload", map[string]string{"team": "a"})
+	reconcile := func(expected int, msg string) {
+		t.Helper()
+		_, _ = phase(ctx, logr.Discard(), cluster, adc)
+		if runs != expected {
+			t.Fatalf("%s: expected %d runs, got %d", msg, expected, runs)
+		}
+	}
+
+	reconcile(1, "first reconcile")
+	reconcile(1, "periodic requeue")
+
+	cluster.Labels["tenant"] = "team-b"
+	reconcile(2, "cluster labels changed")
+
+	adc.Generation++
+	reconcile(3, "akodeploymentconfig spec changed")
+
+	r.resyncAviUsers(adc.Name)
+	reconcile(4, "akodeploymentconfig reconciled")
+
+	clients.forgetAviUser(cluster)
+	phaseErr = errors.New("avi controller unavailable")
+	reconcile(5, "forgotten after a failure")
+	reconcile(6, "failed phase is retried")
+}
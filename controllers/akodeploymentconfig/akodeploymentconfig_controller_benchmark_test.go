@@ -0,0 +1,73 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package akodeploymentconfig
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+// countingClient counts the objects the handlers read, the fake client scans
+// every object on an indexed List while the cache only deep copies the
+// objects of the index, which is what the objects/op metric reports
+type countingClient struct {
+	client.Client
+	read int
+}
+
+func (c *countingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	c.read += meta.LenList(list)
+	return nil
+}
+
+// BenchmarkSecretToAKODeploymentConfig maps a Secret event to the
+// AKODeploymentConfigs to reconcile among 1000 of them sharing 50 secrets
+func BenchmarkSecretToAKODeploymentConfig(b *testing.B) {
+	scheme := runtime.NewScheme()
+	if err := akoov1alpha1.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+	objs := make([]client.Object, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		objs = append(objs, &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("adc-%d", i)},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				AdminCredentialRef: &akoov1alpha1.SecretRef{
+					Name:      fmt.Sprintf("avi-credentials-%d", i%50),
+					Namespace: "tkg-system-networking",
+				},
+			},
+		})
+	}
+	c := &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSecretField, index.AKODeploymentConfigBySecret).
+		WithObjects(objs...).Build()}
+	r := &AKODeploymentConfigReconciler{}
+	mapFunc := r.secretToAKODeploymentConfig(c, logr.Discard())
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "avi-credentials-0", Namespace: "tkg-system-networking"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if requests := mapFunc(context.Background(), secret); len(requests) != 1000/50 {
+			b.Fatalf("got %d requests, expected %d", len(requests), 1000/50)
+		}
+	}
+	b.ReportMetric(float64(c.read)/float64(b.N), "objects/op")
+}
@@ -18,6 +18,8 @@ import (
 )
 
 func (r *AKODeploymentConfigReconciler) initCluster(log logr.Logger) {
+	lock.Lock()
+	defer lock.Unlock()
 	// Lazily initialize clusterReconciler
 	if r.ClusterReconciler == nil {
 		r.ClusterReconciler = cluster.NewReconciler(r.Client, r.Log, r.Scheme)
@@ -25,28 +27,6 @@ func (r *AKODeploymentConfigReconciler) initCluster(log logr.Logger) {
 	}
 }
 
-// reconcileClusters reconciles every cluster that matches the
-// AKODeploymentConfig's selector
-// It's a reconcilePhase function
-func (r *AKODeploymentConfigReconciler) reconcileClusters(
-	ctx context.Context,
-	log logr.Logger,
-	obj *akoov1alpha1.AKODeploymentConfig,
-) (ctrl.Result, error) {
-	r.initCluster(log)
-
-	return phases.ReconcileClustersPhases(ctx, r.Client, log, obj,
-		[]phases.ReconcileClusterPhase{
-			r.addClusterFinalizer,
-			r.ClusterReconciler.ReconcileAddonSecret,
-		},
-		[]phases.ReconcileClusterPhase{
-			r.ClusterReconciler.ReconcileAddonSecretDelete,
-			r.ClusterReconciler.ReconcileDelete,
-		},
-	)
-}
-
 // reconcileClustersDelete reconciles every cluster that matches the
 // AKODeploymentConfig's selector when a AKODeploymentConfig is being deleted
 // It's a reconcilePhase function
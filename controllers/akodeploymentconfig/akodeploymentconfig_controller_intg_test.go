@@ -431,14 +431,14 @@ func intgTestAkoDeploymentConfigController() {
 					Namespace: cluster.Namespace,
 				}, akoov1alpha1.AviClusterLabel, true)
 
-				//Reconcile -> reconcileNormal -> reconcileClusters(normal phase) -> addClusterFinalizer
+				//AKOClusterReconciler Reconcile -> addClusterFinalizer
 				By("should add Cluster Finalizer")
 				ensureClusterFinalizerMatchExpectation(client.ObjectKey{
 					Name:      cluster.Name,
 					Namespace: cluster.Namespace,
 				}, true)
 
-				//Reconcile -> reconcileNormal -> reconcileClusters(normal phase) -> r.clusterReconciler.ReconcileAddonSecret
+				//AKOClusterReconciler Reconcile -> r.ClusterReconciler.ReconcileAddonSecret
 				By("should Reconcile Cluster add-on secret")
 				ensureRuntimeObjectMatchExpectation(client.ObjectKey{
 					Name:      cluster.Name + "-load-balancer-and-ingress-service-addon",
@@ -647,7 +647,7 @@ func intgTestAkoDeploymentConfigController() {
 								deleteObjects(cluster)
 							})
 
-							//Reconcile -> reconcileNormal -> r.userReconciler.ReconcileAviUserDelete
+							//AKOClusterReconciler Reconcile -> r.userReconciler.ReconcileAviUserDelete
 							It("should delete Avi user", func() {
 								secret := &corev1.Secret{
 									ObjectMeta: metav1.ObjectMeta{
@@ -677,7 +677,7 @@ func intgTestAkoDeploymentConfigController() {
 								deleteObjects(obj)
 							})
 
-							//Reconcile -> reconcileNormal -> r.userReconciler.ReconcileAviUserDelete
+							//AKOClusterReconciler Reconcile -> r.userReconciler.ReconcileAviUserDelete
 							It("should delete Avi user", func() {
 								secret := &corev1.Secret{
 									ObjectMeta: metav1.ObjectMeta{
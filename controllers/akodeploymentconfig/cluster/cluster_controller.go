@@ -25,13 +25,11 @@ import (
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako"
 	akoo "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/metrics"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
 )
 
-const (
-	requeueAfterForAKODeletion = time.Second * 1
-)
-
 // NewReconciler initializes a ClusterReconciler
 func NewReconciler(c client.Client, log logr.Logger, scheme *runtime.Scheme) *ClusterReconciler {
 	return &ClusterReconciler{
@@ -52,6 +50,9 @@ type ClusterReconciler struct {
 	Log             logr.Logger
 	Scheme          *runtime.Scheme
 	GetRemoteClient remote.ClusterClientGetter
+
+	driftChecks  checkSchedule
+	healthChecks checkSchedule
 }
 
 // ReconcileDelete removes the finalizer on Cluster once AKO finishes its
@@ -79,10 +80,14 @@ func (r *ClusterReconciler) ReconcileDelete(
 		if finished {
 			log.Info("Removing finalizer", "finalizer", akoov1alpha1.ClusterFinalizer)
 			ctrlutil.RemoveFinalizer(cluster, akoov1alpha1.ClusterFinalizer)
+			metrics.ForgetCluster(cluster.Namespace, cluster.Name)
+			r.driftChecks.forget(cluster)
+			r.healthChecks.forget(cluster)
 		} else {
-			log.Info("AKO deletion is in progress, requeue", "after", requeueAfterForAKODeletion.String())
+			requeueAfter := config.Get().Requeue.AKODeletion.Duration
+			log.Info("AKO deletion is in progress, requeue", "after", requeueAfter.String())
 			log.Info("Cluster can not be deleted until finalizer is removed", "finalizer", akoov1alpha1.ClusterFinalizer)
-			return ctrl.Result{Requeue: true, RequeueAfter: requeueAfterForAKODeletion}, nil
+			return ctrl.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
 		}
 	}
 
@@ -105,6 +110,13 @@ func (r *ClusterReconciler) cleanup(
 		return true, nil
 	}
 
+	if akoDeletionTimedOut(obj) {
+		log.Info("AKO cleanup timed out, the remaining avi resources are left in avi controller",
+			"timeout", config.Get().Cleanup.AKODeletionTimeout.Duration.String())
+		conditions.MarkTrue(obj, akoov1alpha1.AviResourceCleanupSucceededCondition)
+		return true, nil
+	}
+
 	akoAddonSecret := &corev1.Secret{}
 	remoteClient, err := r.GetRemoteClient(ctx, akoov1alpha1.AKODeploymentConfigControllerName, r.Client, client.ObjectKey{
 		Name:      obj.Name,
@@ -116,14 +128,7 @@ func (r *ClusterReconciler) cleanup(
 
 	}
 
-	// in legacy cluster
-	//   - secret is load-balancer-and-ingress-service-data-values
-	// in clusterclass cluster
-	//   - secret is <cluster-name>-load-balancer-and-ingress-service-data-values
-	secretName := r.akoAddonDataValueName()
-	if akoo.IsClusterClassBasedCluster(obj) {
-		secretName = utils.AKOAddonSecretNameForClusterClass(obj)
-	}
+	secretName := AKODataValuesSecretName(obj)
 	if err := remoteClient.Get(ctx, client.ObjectKey{
 		Name:      secretName,
 		Namespace: akoov1alpha1.TKGSystemNamespace,
@@ -174,11 +179,28 @@ func (r *ClusterReconciler) cleanup(
 	return false, nil
 }
 
+// akoDeletionTimedOut checks if the cluster has been waiting for AKO to clean up
+// longer than the configured cleanup timeout
+func akoDeletionTimedOut(obj *clusterv1.Cluster) bool {
+	timeout := config.Get().Cleanup.AKODeletionTimeout.Duration
+	if timeout == 0 || obj.GetDeletionTimestamp() == nil {
+		return false
+	}
+	return time.Since(obj.GetDeletionTimestamp().Time) > timeout
+}
+
 func GetFakeRemoteClient(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
 	// return fake client
 	return fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), nil
 }
 
-func (r *ClusterReconciler) akoAddonDataValueName() string {
+// AKODataValuesSecretName returns the name of the AKO data values secret in the
+// tkg-system namespace of the workload cluster
+//   - in legacy cluster it's load-balancer-and-ingress-service-data-values
+//   - in clusterclass cluster it's <cluster-name>-load-balancer-and-ingress-service-data-values
+func AKODataValuesSecretName(cluster *clusterv1.Cluster) string {
+	if akoo.IsClusterClassBasedCluster(cluster) {
+		return utils.AKOAddonSecretNameForClusterClass(cluster)
+	}
 	return "load-balancer-and-ingress-service-data-values"
 }
@@ -14,6 +14,7 @@ import (
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako"
 	akoo "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -58,7 +59,7 @@ func (r *ClusterReconciler) ReconcileAddonSecret(
 	if isVIPProvider && cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
 		svc := &corev1.Service{}
 		if err = r.Get(ctx, client.ObjectKey{
-			Name:      cluster.Namespace + "-" + cluster.Name + "-" + akoov1alpha1.HAServiceName,
+			Name:      haprovider.HAServiceName(cluster),
 			Namespace: akoov1alpha1.TKGSystemNamespace,
 		}, svc); err != nil {
 			log.Info("Failed to get cluster control plane load balancer type of service, requeue")
@@ -189,6 +190,13 @@ func AkoAddonSecretDataYaml(cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODep
 		return "", err
 	}
 
+	// AKO runs in the same tenant as its avi user
+	tenantName, err := akoo.GetClusterAviTenant(cluster, obj)
+	if err != nil {
+		return "", err
+	}
+	secret.LoadBalancerAndIngressService.Config.ControllerSettings.TenantName = tenantName
+
 	//Pass cluster role information to ako
 	//Avoid setting DeleteConfig for management cluster
 	if cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
@@ -0,0 +1,294 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/metrics"
+)
+
+// akoDrift lists what differs between the AKO configuration running in a
+// workload cluster and the expected one
+type akoDrift struct {
+	// fields are the names of the drifted settings, never their values since
+	// they include the avi credentials
+	fields []string
+	// dataValues is the data values secret to re-apply, nil when it's missing
+	// or up to date
+	dataValues *corev1.Secret
+	// configMap is the avi-k8s-config ConfigMap to re-apply, nil when it's
+	// missing or up to date
+	configMap *corev1.ConfigMap
+}
+
+// ReconcileAKODrift compares the AKO configuration running in the workload
+// cluster, that is the data values secret in tkg-system, the AKO StatefulSet
+// and the avi-k8s-config ConfigMap in avi-system, with the one rendered from
+// the AKODeploymentConfig. The result is reported in the
+// AKOConfigurationInSync condition of the cluster, and the drifted data values
+// and ConfigMap are re-applied when the operator is configured to.
+func (r *ClusterReconciler) ReconcileAKODrift(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	res := ctrl.Result{}
+	if !config.Enabled(config.AKODriftDetection) {
+		return res, nil
+	}
+	// AKO can't be running before the control plane is up, nor when
+	// ReconcileAddonSecret stopped deploying it
+	if !conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) ||
+		conditions.IsFalse(cluster, akoov1alpha1.ClusterIpFamilyValidationSucceededCondition) {
+		log.V(3).Info("AKO is not deployed in the cluster yet, skip drift detection")
+		return res, nil
+	}
+	driftDetection := config.Get().DriftDetection
+	due, wait := r.driftChecks.due(cluster, driftDetection.Interval.Duration)
+	res.RequeueAfter = wait
+	if !due {
+		return res, nil
+	}
+
+	aviSecret, err := r.getClusterAviUserSecret(cluster, ctx)
+	if err != nil {
+		log.Info("Failed to get cluster avi user secret, requeue")
+		return res, err
+	}
+	expectedYaml, err := AkoAddonSecretDataYaml(cluster, obj, aviSecret)
+	if err != nil {
+		log.Error(err, "Failed to render the expected AKO data values")
+		return res, err
+	}
+	expected, err := ako.NewValuesFromBytes([]byte(expectedYaml))
+	if err != nil {
+		return res, err
+	}
+
+	remoteClient, err := r.GetRemoteClient(ctx, akoov1alpha1.AKODeploymentConfigControllerName, r.Client, client.ObjectKey{
+		Name:      cluster.Name,
+		Namespace: cluster.Namespace,
+	})
+	if err != nil {
+		log.Info("Failed to create remote client for cluster, skip drift detection", "error", err.Error())
+		conditions.MarkUnknown(cluster, akoov1alpha1.AKOConfigurationInSyncCondition, akoov1alpha1.AKOConfigurationCheckFailedReason,
+			"failed to connect to the cluster: %v", err)
+		return res, nil
+	}
+
+	drift, err := detectAKODrift(ctx, remoteClient, cluster, expected)
+	if err != nil {
+		log.Info("Failed to read the AKO configuration of the cluster, skip drift detection", "error", err.Error())
+		conditions.MarkUnknown(cluster, akoov1alpha1.AKOConfigurationInSyncCondition, akoov1alpha1.AKOConfigurationCheckFailedReason,
+			"failed to read the AKO configuration: %v", err)
+		return res, nil
+	}
+	metrics.AKOConfigurationDrift.WithLabelValues(cluster.Namespace, cluster.Name).Set(float64(len(drift.fields)))
+	if len(drift.fields) == 0 {
+		conditions.MarkTrue(cluster, akoov1alpha1.AKOConfigurationInSyncCondition)
+		return res, nil
+	}
+
+	log.Info("AKO configuration drifted in the cluster", "fields", drift.fields)
+	conditions.MarkFalse(cluster, akoov1alpha1.AKOConfigurationInSyncCondition, akoov1alpha1.AKOConfigurationDriftedReason,
+		clusterv1.ConditionSeverityWarning, "%s drifted", strings.Join(drift.fields, ", "))
+	if !driftDetection.Reapply {
+		return res, nil
+	}
+	return res, r.reapplyAKOConfiguration(ctx, log, remoteClient, cluster, expectedYaml, expected, drift)
+}
+
+// reapplyAKOConfiguration writes the expected data values and avi-k8s-config
+// settings back to the workload cluster. The missing objects are left to the
+// add-on and package controllers which create them.
+func (r *ClusterReconciler) reapplyAKOConfiguration(
+	ctx context.Context,
+	log logr.Logger,
+	remoteClient client.Client,
+	cluster *clusterv1.Cluster,
+	expectedYaml string,
+	expected *ako.Values,
+	drift *akoDrift,
+) error {
+	reapplied := false
+	if drift.dataValues != nil {
+		if drift.dataValues.Data == nil {
+			drift.dataValues.Data = map[string][]byte{}
+		}
+		drift.dataValues.Data[akoov1alpha1.TKGAddOnSecretDataKey] = []byte(expectedYaml)
+		if err := remoteClient.Update(ctx, drift.dataValues); err != nil {
+			log.Error(err, "Failed to re-apply AKO data values")
+			return err
+		}
+		log.Info("Re-applied AKO data values", "secret", client.ObjectKeyFromObject(drift.dataValues))
+		reapplied = true
+	}
+	if drift.configMap != nil {
+		if drift.configMap.Data == nil {
+			drift.configMap.Data = map[string]string{}
+		}
+		for key, value := range akoConfigMapSettings(expected) {
+			if value != "" {
+				drift.configMap.Data[key] = value
+			}
+		}
+		if err := remoteClient.Update(ctx, drift.configMap); err != nil {
+			log.Error(err, "Failed to re-apply AKO ConfigMap")
+			return err
+		}
+		log.Info("Re-applied AKO ConfigMap", "configmap", client.ObjectKeyFromObject(drift.configMap))
+		reapplied = true
+	}
+	if reapplied {
+		metrics.AKOConfigurationReapplied.WithLabelValues(cluster.Namespace, cluster.Name).Inc()
+	}
+	return nil
+}
+
+// detectAKODrift reads the AKO configuration of the workload cluster and
+// compares it with the expected values
+func detectAKODrift(ctx context.Context, remoteClient client.Client, cluster *clusterv1.Cluster, expected *ako.Values) (*akoDrift, error) {
+	drift := &akoDrift{}
+
+	secret := &corev1.Secret{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{
+		Name:      AKODataValuesSecretName(cluster),
+		Namespace: akoov1alpha1.TKGSystemNamespace,
+	}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		drift.fields = append(drift.fields, "secret "+AKODataValuesSecretName(cluster))
+	} else {
+		var fields []string
+		actual, err := ako.NewValuesFromBytes(secret.Data[akoov1alpha1.TKGAddOnSecretDataKey])
+		if err != nil {
+			fields = []string{akoov1alpha1.TKGAddOnSecretDataKey}
+		} else {
+			fields = diffValues("", reflect.ValueOf(expected).Elem(), reflect.ValueOf(actual).Elem())
+		}
+		for _, f := range fields {
+			drift.fields = append(drift.fields, "data values "+f)
+		}
+		if len(fields) != 0 {
+			drift.dataValues = secret
+		}
+	}
+
+	ss := &appv1.StatefulSet{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{
+		Name:      akoov1alpha1.AkoStatefulSetName,
+		Namespace: akoov1alpha1.AviNamespace,
+	}, ss); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		drift.fields = append(drift.fields, "statefulset "+akoov1alpha1.AkoStatefulSetName)
+	} else if replicas := expected.LoadBalancerAndIngressService.Config.ReplicaCount; ss.Spec.Replicas != nil && int(*ss.Spec.Replicas) != replicas {
+		drift.fields = append(drift.fields, "statefulset replicas")
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{
+		Name:      akoov1alpha1.AKOConfigMapName,
+		Namespace: akoov1alpha1.AviNamespace,
+	}, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		drift.fields = append(drift.fields, "configmap "+akoov1alpha1.AKOConfigMapName)
+	} else {
+		settings := akoConfigMapSettings(expected)
+		keys := make([]string, 0, len(settings))
+		for key := range settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if settings[key] != "" && cm.Data[key] != settings[key] {
+				drift.fields = append(drift.fields, "configmap "+key)
+				drift.configMap = cm
+			}
+		}
+	}
+	return drift, nil
+}
+
+// akoConfigMapSettings returns the avi-k8s-config entries AKO renders from the
+// data values. The empty ones are left to the AKO defaults.
+func akoConfigMapSettings(v *ako.Values) map[string]string {
+	c := v.LoadBalancerAndIngressService.Config
+	settings := map[string]string{}
+	if c.ControllerSettings != nil {
+		settings["controllerIP"] = c.ControllerSettings.ControllerIP
+		settings["controllerVersion"] = c.ControllerSettings.ControllerVersion
+		settings["cloudName"] = c.ControllerSettings.CloudName
+		settings["serviceEngineGroupName"] = c.ControllerSettings.ServiceEngineGroupName
+		settings["tenantName"] = c.ControllerSettings.TenantName
+	}
+	if c.AKOSettings != nil {
+		settings["clusterName"] = c.AKOSettings.ClusterName
+		settings["deleteConfig"] = c.AKOSettings.DeleteConfig
+		settings["logLevel"] = c.AKOSettings.LogLevel
+		settings["cniPlugin"] = c.AKOSettings.CniPlugin
+	}
+	if c.L7Settings != nil {
+		settings["serviceType"] = c.L7Settings.ServiceType
+	}
+	return settings
+}
+
+// diffValues returns the yaml paths of the fields which differ between two
+// ako.Values structs
+func diffValues(path string, expected, actual reflect.Value) []string {
+	if expected.Kind() == reflect.Ptr {
+		if expected.IsNil() && actual.IsNil() {
+			return nil
+		}
+		if expected.IsNil() {
+			expected = reflect.New(expected.Type().Elem())
+		}
+		if actual.IsNil() {
+			actual = reflect.New(actual.Type().Elem())
+		}
+		return diffValues(path, expected.Elem(), actual.Elem())
+	}
+	if expected.Kind() != reflect.Struct {
+		if reflect.DeepEqual(expected.Interface(), actual.Interface()) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	var fields []string
+	for i := 0; i < expected.NumField(); i++ {
+		name, _, _ := strings.Cut(expected.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		if path != "" {
+			name = fmt.Sprintf("%s.%s", path, name)
+		}
+		fields = append(fields, diffValues(name, expected.Field(i), actual.Field(i))...)
+	}
+	return fields
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/cluster"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+)
+
+func unitTestAKODrift() {
+	var (
+		ctx                 context.Context
+		reconciler          *cluster.ClusterReconciler
+		remoteClient        client.Client
+		remoteErr           error
+		capiCluster         *clusterv1.Cluster
+		akoDeploymentConfig *akoov1alpha1.AKODeploymentConfig
+		valuesSecret        *corev1.Secret
+		statefulSet         *appv1.StatefulSet
+		configMap           *corev1.ConfigMap
+		expectedYaml        string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		remoteErr = nil
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "default",
+			},
+		}
+		conditions.MarkTrue(capiCluster, clusterv1.ControlPlaneInitializedCondition)
+		akoDeploymentConfig = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-adc"},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				CloudName:          "test-cloud",
+				Controller:         "10.23.122.1",
+				ControllerVersion:  "22.1.3",
+				ServiceEngineGroup: "Default-SEG",
+				DataNetwork: akoov1alpha1.DataNetwork{
+					Name: "test-akdc",
+					CIDR: "10.0.0.0/24",
+				},
+			},
+		}
+		aviUserSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster-avi-credentials",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"username": []byte("test-cluster"),
+				"password": []byte("Admin!23"),
+			},
+		}
+
+		var err error
+		expectedYaml, err = cluster.AkoAddonSecretDataYaml(capiCluster, akoDeploymentConfig, aviUserSecret)
+		Expect(err).ShouldNot(HaveOccurred())
+		valuesSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "load-balancer-and-ingress-service-data-values",
+				Namespace: akoov1alpha1.TKGSystemNamespace,
+			},
+			Data: map[string][]byte{"values.yaml": []byte(expectedYaml)},
+		}
+		statefulSet = &appv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      akoov1alpha1.AkoStatefulSetName,
+				Namespace: akoov1alpha1.AviNamespace,
+			},
+			Spec: appv1.StatefulSetSpec{Replicas: ptr.To(int32(1))},
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      akoov1alpha1.AKOConfigMapName,
+				Namespace: akoov1alpha1.AviNamespace,
+			},
+			Data: map[string]string{
+				"controllerIP":           "10.23.122.1",
+				"controllerVersion":      "22.1.3",
+				"cloudName":              "test-cloud",
+				"serviceEngineGroupName": "Default-SEG",
+				"tenantName":             "admin",
+				"clusterName":            "default-test-cluster",
+				"deleteConfig":           "false",
+				"logLevel":               "INFO",
+				"serviceType":            "NodePort",
+			},
+		}
+
+		mgmtScheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(mgmtScheme)).Should(Succeed())
+		Expect(clusterv1.AddToScheme(mgmtScheme)).Should(Succeed())
+		mgmtClient := fake.NewClientBuilder().WithScheme(mgmtScheme).WithObjects(aviUserSecret).Build()
+		reconciler = cluster.NewReconciler(mgmtClient, logr.Discard(), mgmtScheme)
+		reconciler.GetRemoteClient = func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+			return remoteClient, remoteErr
+		}
+	})
+
+	JustBeforeEach(func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(valuesSecret, statefulSet, configMap).Build()
+	})
+
+	AfterEach(func() {
+		config.Set(nil)
+	})
+
+	When("AKO runs with the expected configuration", func() {
+		It("should mark the configuration in sync and check it again later", func() {
+			res, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.RequeueAfter).Should(Equal(config.DefaultDriftDetectionInterval))
+			Expect(conditions.IsTrue(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(BeTrue())
+		})
+	})
+
+	When("the cluster control plane is not initialized", func() {
+		BeforeEach(func() {
+			conditions.Delete(capiCluster, clusterv1.ControlPlaneInitializedCondition)
+		})
+
+		It("should skip the check", func() {
+			res, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.IsZero()).Should(BeTrue())
+			Expect(conditions.Has(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(BeFalse())
+		})
+	})
+
+	When("the AKO configuration drifted", func() {
+		BeforeEach(func() {
+			valuesSecret.Data["values.yaml"] = []byte(
+				`loadBalancerAndIngressService: {config: {controller_settings: {cloud_name: other-cloud}}}`)
+			statefulSet.Spec.Replicas = ptr.To(int32(2))
+			configMap.Data["controllerIP"] = "10.23.122.2"
+		})
+
+		It("should report the drifted settings without their values", func() {
+			_, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(conditions.IsFalse(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(BeTrue())
+			Expect(conditions.GetReason(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(Equal(akoov1alpha1.AKOConfigurationDriftedReason))
+			message := conditions.GetMessage(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)
+			Expect(message).Should(ContainSubstring("data values loadBalancerAndIngressService.config.controller_settings.cloud_name"))
+			Expect(message).Should(ContainSubstring("data values loadBalancerAndIngressService.config.avi_credentials.password"))
+			Expect(message).Should(ContainSubstring("statefulset replicas"))
+			Expect(message).Should(ContainSubstring("configmap controllerIP"))
+			Expect(message).ShouldNot(ContainSubstring("Admin!23"))
+		})
+
+		It("should leave the cluster untouched by default", func() {
+			_, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			cm := &corev1.ConfigMap{}
+			Expect(remoteClient.Get(ctx, client.ObjectKeyFromObject(configMap), cm)).Should(Succeed())
+			Expect(cm.Data["controllerIP"]).Should(Equal("10.23.122.2"))
+		})
+
+		When("re-apply is enabled", func() {
+			BeforeEach(func() {
+				c := &config.OperatorConfiguration{DriftDetection: config.DriftDetectionConfiguration{Reapply: true}}
+				c.Default()
+				config.Set(c)
+			})
+
+			It("should write the expected data values and ConfigMap back", func() {
+				_, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				secret := &corev1.Secret{}
+				Expect(remoteClient.Get(ctx, client.ObjectKeyFromObject(valuesSecret), secret)).Should(Succeed())
+				Expect(string(secret.Data["values.yaml"])).Should(Equal(expectedYaml))
+				cm := &corev1.ConfigMap{}
+				Expect(remoteClient.Get(ctx, client.ObjectKeyFromObject(configMap), cm)).Should(Succeed())
+				Expect(cm.Data["controllerIP"]).Should(Equal("10.23.122.1"))
+			})
+		})
+	})
+
+	When("AKO is not deployed in the cluster", func() {
+		JustBeforeEach(func() {
+			remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		})
+
+		It("should report the missing objects", func() {
+			_, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			message := conditions.GetMessage(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)
+			Expect(message).Should(ContainSubstring("secret load-balancer-and-ingress-service-data-values"))
+			Expect(message).Should(ContainSubstring("statefulset ako"))
+			Expect(message).Should(ContainSubstring("configmap avi-k8s-config"))
+		})
+	})
+
+	When("the cluster can't be reached", func() {
+		BeforeEach(func() {
+			remoteErr = errors.New("connection refused")
+		})
+
+		It("should mark the configuration unknown", func() {
+			res, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.RequeueAfter).Should(Equal(config.DefaultDriftDetectionInterval))
+			Expect(conditions.IsUnknown(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(BeTrue())
+		})
+	})
+
+	When("drift detection is disabled", func() {
+		BeforeEach(func() {
+			c := &config.OperatorConfiguration{FeatureGates: map[string]bool{string(config.AKODriftDetection): false}}
+			c.Default()
+			config.Set(c)
+		})
+
+		It("should skip the check", func() {
+			res, err := reconciler.ReconcileAKODrift(ctx, logr.Discard(), capiCluster, akoDeploymentConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.IsZero()).Should(BeTrue())
+			Expect(conditions.Has(capiCluster, akoov1alpha1.AKOConfigurationInSyncCondition)).Should(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+)
+
+// checkSchedule remembers when a periodic check last ran in each cluster, so the
+// checks sharing the cluster reconciles each run at their own interval
+type checkSchedule struct {
+	lastRun sync.Map
+}
+
+// due tells if the check is due in the cluster, or how long until it is
+func (s *checkSchedule) due(cluster *clusterv1.Cluster, interval time.Duration) (bool, time.Duration) {
+	key := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}
+	if last, ok := s.lastRun.Load(key); ok {
+		if wait := interval - time.Since(last.(time.Time)); wait > 0 {
+			return false, wait
+		}
+	}
+	s.lastRun.Store(key, time.Now())
+	return true, interval
+}
+
+// forget drops the cluster from the schedule
+func (s *checkSchedule) forget(cluster *clusterv1.Cluster) {
+	s.lastRun.Delete(types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name})
+}
+
+// ReconcileAKOHealth probes AKO in the workload cluster and reports its health
+// in the AKOReady condition of the cluster
+func (r *ClusterReconciler) ReconcileAKOHealth(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	_ *akoov1alpha1.AKODeploymentConfig,
+) (ctrl.Result, error) {
+	res := ctrl.Result{}
+	if !config.Enabled(config.AKOHealthCheck) {
+		return res, nil
+	}
+	if !conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+		log.V(3).Info("cluster control plane is not initialized yet, skip AKO health check")
+		return res, nil
+	}
+	if conditions.IsFalse(cluster, akoov1alpha1.ClusterIpFamilyValidationSucceededCondition) {
+		conditions.MarkFalse(cluster, akoov1alpha1.AKOReadyCondition, akoov1alpha1.AKONotDeployedReason,
+			clusterv1.ConditionSeverityWarning, "AKO is not deployed in a cluster with this ip family")
+		return res, nil
+	}
+	due, wait := r.healthChecks.due(cluster, config.Get().HealthCheck.Interval.Duration)
+	res.RequeueAfter = wait
+	if !due {
+		return res, nil
+	}
+
+	remoteClient, err := r.GetRemoteClient(ctx, akoov1alpha1.AKODeploymentConfigControllerName, r.Client, client.ObjectKey{
+		Name:      cluster.Name,
+		Namespace: cluster.Namespace,
+	})
+	if err != nil {
+		log.Info("Failed to create remote client for cluster, skip AKO health check", "error", err.Error())
+		conditions.MarkUnknown(cluster, akoov1alpha1.AKOReadyCondition, akoov1alpha1.AKOHealthCheckFailedReason,
+			"failed to connect to the cluster: %v", err)
+		return res, nil
+	}
+	health, err := ako.GetHealth(ctx, remoteClient)
+	if err != nil {
+		log.Info("Failed to probe AKO in the cluster", "error", err.Error())
+		conditions.MarkUnknown(cluster, akoov1alpha1.AKOReadyCondition, akoov1alpha1.AKOHealthCheckFailedReason,
+			"failed to probe AKO: %v", err)
+		return res, nil
+	}
+
+	if health.Ready {
+		conditions.Set(cluster, &clusterv1.Condition{
+			Type:    akoov1alpha1.AKOReadyCondition,
+			Status:  corev1.ConditionTrue,
+			Message: health.Message,
+		})
+		return res, nil
+	}
+	log.Info("AKO is not ready in the cluster", "reason", health.Reason, "message", health.Message)
+	severity := clusterv1.ConditionSeverityWarning
+	if health.Reason == akoov1alpha1.AKOObjectDeletionReason || health.Reason == akoov1alpha1.AKODisabledReason {
+		severity = clusterv1.ConditionSeverityInfo
+	}
+	conditions.MarkFalse(cluster, akoov1alpha1.AKOReadyCondition, health.Reason, severity, "%s", health.Message)
+	return res, nil
+}
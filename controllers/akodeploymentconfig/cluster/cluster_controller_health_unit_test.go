@@ -0,0 +1,103 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/cluster"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+)
+
+func unitTestAKOHealth() {
+	var (
+		ctx          context.Context
+		reconciler   *cluster.ClusterReconciler
+		remoteClient client.Client
+		remoteErr    error
+		capiCluster  *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		remoteErr = nil
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "default",
+			},
+		}
+		conditions.MarkTrue(capiCluster, clusterv1.ControlPlaneInitializedCondition)
+		reconciler = cluster.NewReconciler(fake.NewClientBuilder().Build(), logr.Discard(), runtime.NewScheme())
+		reconciler.GetRemoteClient = func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+			return remoteClient, remoteErr
+		}
+	})
+
+	When("AKO is not deployed", func() {
+		It("should mark AKO not ready and probe it again later", func() {
+			res, err := reconciler.ReconcileAKOHealth(ctx, logr.Discard(), capiCluster, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.RequeueAfter).Should(Equal(config.DefaultHealthCheckInterval))
+			Expect(conditions.IsFalse(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(BeTrue())
+			Expect(conditions.GetReason(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(Equal(akoov1alpha1.AKONotDeployedReason))
+		})
+
+		It("should not probe it again before the interval", func() {
+			_, err := reconciler.ReconcileAKOHealth(ctx, logr.Discard(), capiCluster, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(remoteClient.Create(ctx, &appv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+				Name:      akoov1alpha1.AkoStatefulSetName,
+				Namespace: akoov1alpha1.AviNamespace,
+			}})).Should(Succeed())
+
+			res, err := reconciler.ReconcileAKOHealth(ctx, logr.Discard(), capiCluster, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.RequeueAfter).Should(BeNumerically(">", 0))
+			Expect(res.RequeueAfter).Should(BeNumerically("<=", config.DefaultHealthCheckInterval))
+			Expect(conditions.GetReason(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(Equal(akoov1alpha1.AKONotDeployedReason))
+		})
+	})
+
+	When("the cluster can't be reached", func() {
+		BeforeEach(func() {
+			remoteErr = errors.New("connection refused")
+		})
+
+		It("should mark AKO health unknown", func() {
+			_, err := reconciler.ReconcileAKOHealth(ctx, logr.Discard(), capiCluster, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(conditions.IsUnknown(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(BeTrue())
+			Expect(conditions.GetReason(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(Equal(akoov1alpha1.AKOHealthCheckFailedReason))
+		})
+	})
+
+	When("the cluster control plane is not initialized", func() {
+		BeforeEach(func() {
+			conditions.Delete(capiCluster, clusterv1.ControlPlaneInitializedCondition)
+		})
+
+		It("should skip the probe", func() {
+			res, err := reconciler.ReconcileAKOHealth(ctx, logr.Discard(), capiCluster, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.IsZero()).Should(BeTrue())
+			Expect(conditions.Has(capiCluster, akoov1alpha1.AKOReadyCondition)).Should(BeFalse())
+		})
+	})
+}
@@ -206,6 +206,18 @@ func unitTestAKODeploymentYaml() {
 				Expect(secretData).Should(ContainSubstring("delete_config: \"true\""))
 			})
 
+			When("tenant is derived from cluster namespace", func() {
+				BeforeEach(func() {
+					akoDeploymentConfig.Spec.Tenant.Mode = akoov1alpha1.AVITenantModeNamespace
+				})
+
+				It("should set tenant_name to the cluster namespace", func() {
+					secretData, err := cluster.AkoAddonSecretDataYaml(capicluster, akoDeploymentConfig, aviUserSecret)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(secretData).Should(ContainSubstring("tenant_name: " + capicluster.Namespace))
+				})
+			})
+
 			When("cluster has avi_delete_config label", func() {
 				BeforeEach(func() {
 					capicluster.Labels[akoov1alpha1.AviClusterDeleteConfigLabel] = "true"
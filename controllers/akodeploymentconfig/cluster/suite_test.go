@@ -37,4 +37,6 @@ func intgTests() {
 func unitTests() {
 	Describe("AKO Deployment Spec generation", unitTestAKODeploymentYaml)
 	Describe("Cluster ip family Validation", unitTestValidateClusterIpFamily)
+	Describe("AKO configuration drift detection", unitTestAKODrift)
+	Describe("AKO health check", unitTestAKOHealth)
 }
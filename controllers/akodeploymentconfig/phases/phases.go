@@ -6,6 +6,7 @@ package phases
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 
@@ -19,6 +20,7 @@ import (
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 )
 
 // ReconcilePhase defines a function that reconciles one aspect of
@@ -55,7 +57,9 @@ func ReconcilePhases(
 type ReconcileClusterPhase func(context.Context, logr.Logger, *clusterv1.Cluster, *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error)
 
 // reconcileClusters reconcile every cluster that matches the
-// AKODeploymentConfig's selector by running through an array of phases
+// AKODeploymentConfig's selector by running through an array of phases. It's
+// used to clean up the clusters of a deleted AKODeploymentConfig, up to
+// concurrency.clusterCleanup clusters at a time.
 func ReconcileClustersPhases(
 	ctx context.Context,
 	client client.Client,
@@ -78,66 +82,107 @@ func ReconcileClustersPhases(
 		return res, nil
 	}
 
-	var allErrs []error
-	// For each cluster managed by the AKODeploymentConfig, run each phase
-	// function
-	for _, cluster := range clusters.Items {
-		var errs []error
+	workers := config.Get().Concurrency.ClusterCleanup
+	if workers > len(clusters.Items) {
+		workers = len(clusters.Items)
+	}
 
-		clog := log.WithValues("cluster", cluster.Namespace+"/"+cluster.Name)
+	// For each cluster managed by the AKODeploymentConfig, run each phase
+	// function. Up to workers clusters are reconciled in parallel, every
+	// cluster has its own patch helper and result.
+	results := make([]ctrl.Result, len(clusters.Items))
+	clusterErrs := make([]error, len(clusters.Items))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i], clusterErrs[i] = ReconcileClusterPhases(ctx, client, log, &clusters.Items[i], obj, normalPhases, deletePhases)
+			}
+		}()
+	}
+	for i := range clusters.Items {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
 
-		// skip reconcile if cluster is using kube-vip to provide load balancer service
-		if isLBProvider, err := ako_operator.IsLoadBalancerProvider(&cluster); err != nil {
-			log.Error(err, "can't unmarshal cluster variables")
-			allErrs = append(allErrs, err)
-			continue
-		} else if !isLBProvider {
-			log.Info(fmt.Sprintf("cluster uses kube-vip to provide load balancer type of service, skip reconciling for cluster %s/%s", cluster.Namespace, cluster.Name))
-			continue
+	var allErrs []error
+	for i := range clusters.Items {
+		if clusterErrs[i] != nil {
+			allErrs = append(allErrs, clusterErrs[i])
 		}
+		res = util.LowestNonZeroResult(res, results[i])
+	}
+	return res, kerrors.NewAggregate(allErrs)
+}
 
-		// Always Patch for each cluster when exiting this function so changes to the resource are updated on the API server.
-		patchHelper, err := patch.NewHelper(&cluster, client)
-		if err != nil {
-			return res, errors.Wrapf(err, "failed to init patch helper for %s %s",
-				cluster.GroupVersionKind(), cluster.Namespace+"/"+cluster.Name)
-		}
+// ReconcileClusterPhases runs the phases of a single cluster regardless of their
+// error status and patches the cluster. The error of a cluster is prefixed with its
+// name, the result only accounts for the phases which ran before the first error.
+func ReconcileClusterPhases(
+	ctx context.Context,
+	client client.Client,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	obj *akoov1alpha1.AKODeploymentConfig,
+	normalPhases []ReconcileClusterPhase,
+	deletePhases []ReconcileClusterPhase,
+) (ctrl.Result, error) {
+	res := ctrl.Result{}
+	clusterName := cluster.Namespace + "/" + cluster.Name
+	clog := log.WithValues("cluster", clusterName)
+
+	// skip reconcile if cluster is using kube-vip to provide load balancer service
+	if isLBProvider, err := ako_operator.IsLoadBalancerProvider(cluster); err != nil {
+		clog.Error(err, "can't unmarshal cluster variables")
+		return res, errors.Wrapf(err, "cluster %s", clusterName)
+	} else if !isLBProvider {
+		clog.Info(fmt.Sprintf("cluster uses kube-vip to provide load balancer type of service, skip reconciling for cluster %s", clusterName))
+		return res, nil
+	}
 
-		// update cluster avi label before run any phase functions
-		ako_operator.ApplyClusterLabel(log, &cluster, obj)
+	// Always Patch the cluster when exiting this function so changes to the resource are updated on the API server.
+	patchHelper, err := patch.NewHelper(cluster, client)
+	if err != nil {
+		return res, errors.Wrapf(err, "failed to init patch helper for %s %s",
+			cluster.GroupVersionKind(), clusterName)
+	}
 
-		phases := normalPhases
-		if !cluster.GetDeletionTimestamp().IsZero() {
-			phases = deletePhases
-		}
-		for _, phase := range phases {
-			// Call the inner reconciliation methods regardless of
-			// the error status
-			phaseResult, err := phase(ctx, clog, &cluster, obj)
-			if err != nil {
-				errs = append(errs, err)
-			}
-			if len(errs) > 0 {
-				continue
-			}
-			res = util.LowestNonZeroResult(res, phaseResult)
-		}
+	// update cluster avi label before run any phase functions
+	ako_operator.ApplyClusterLabel(clog, cluster, obj)
 
-		clusterErr := kerrors.NewAggregate(errs)
-		patchOpts := []patch.Option{}
-		if clusterErr == nil {
-			patchOpts = append(patchOpts, patch.WithStatusObservedGeneration{})
-		} else {
-			allErrs = append(allErrs, clusterErr)
+	phases := normalPhases
+	if !cluster.GetDeletionTimestamp().IsZero() {
+		phases = deletePhases
+	}
+	var errs []error
+	for _, phase := range phases {
+		// Call the inner reconciliation methods regardless of
+		// the error status
+		phaseResult, err := phase(ctx, clog, cluster, obj)
+		if err != nil {
+			errs = append(errs, err)
 		}
-
-		if err := patchHelper.Patch(ctx, &cluster, patchOpts...); err != nil {
-			clusterErr = kerrors.NewAggregate([]error{clusterErr, err})
-			if clusterErr != nil {
-				log.Error(clusterErr, "patch failed")
-			}
+		if len(errs) > 0 {
+			continue
 		}
+		res = util.LowestNonZeroResult(res, phaseResult)
 	}
 
-	return res, kerrors.NewAggregate(allErrs)
+	patchOpts := []patch.Option{}
+	if len(errs) == 0 {
+		patchOpts = append(patchOpts, patch.WithStatusObservedGeneration{})
+	}
+	if err := patchHelper.Patch(ctx, cluster, patchOpts...); err != nil {
+		clog.Error(err, "patch failed")
+		errs = append(errs, err)
+	}
+
+	if clusterErr := kerrors.NewAggregate(errs); clusterErr != nil {
+		return res, errors.Wrapf(clusterErr, "cluster %s", clusterName)
+	}
+	return res, nil
 }
@@ -0,0 +1,159 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package phases
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+)
+
+func ReconcileClustersPhasesUnitTest() {
+	const clusterCount = 20
+
+	var (
+		kclient             client.Client
+		akoDeploymentConfig *akoov1alpha1.AKODeploymentConfig
+		running, maxRunning int32
+	)
+
+	// track counts the phases running at the same time
+	track := func() func() {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return func() { atomic.AddInt32(&running, -1) }
+	}
+
+	setWorkers := func(n int) {
+		c := &config.OperatorConfiguration{}
+		c.Default()
+		c.Concurrency.ClusterCleanup = n
+		config.Set(c)
+	}
+
+	BeforeEach(func() {
+		running, maxRunning = 0, 0
+		akoDeploymentConfig = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-ako-deployment-config"},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ClusterSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"test": "test"},
+				},
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).Should(Succeed())
+		Expect(akoov1alpha1.AddToScheme(scheme)).Should(Succeed())
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(akoDeploymentConfig)
+		for i := 0; i < clusterCount; i++ {
+			builder = builder.WithObjects(&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("cluster-%02d", i),
+					Namespace: "default",
+					Labels:    map[string]string{"test": "test"},
+				},
+			})
+		}
+		kclient = builder.Build()
+	})
+
+	AfterEach(func() {
+		config.Set(nil)
+	})
+
+	It("should reconcile the clusters one at a time by default", func() {
+		setWorkers(1)
+		_, err := ReconcileClustersPhases(context.Background(), kclient, logr.Discard(), akoDeploymentConfig,
+			[]ReconcileClusterPhase{
+				func(context.Context, logr.Logger, *clusterv1.Cluster, *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+					defer track()()
+					return ctrl.Result{}, nil
+				},
+			}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(maxRunning).Should(Equal(int32(1)))
+	})
+
+	It("should reconcile up to the configured number of clusters in parallel", func() {
+		setWorkers(4)
+		var mu sync.Mutex
+		reconciled := map[string]bool{}
+		_, err := ReconcileClustersPhases(context.Background(), kclient, logr.Discard(), akoDeploymentConfig,
+			[]ReconcileClusterPhase{
+				func(_ context.Context, _ logr.Logger, cluster *clusterv1.Cluster, _ *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+					defer track()()
+					mu.Lock()
+					defer mu.Unlock()
+					reconciled[cluster.Name] = true
+					return ctrl.Result{}, nil
+				},
+			}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(reconciled).Should(HaveLen(clusterCount))
+		Expect(maxRunning).Should(BeNumerically(">", 1))
+		Expect(maxRunning).Should(BeNumerically("<=", 4))
+	})
+
+	It("should patch every cluster with its own changes", func() {
+		setWorkers(4)
+		_, err := ReconcileClustersPhases(context.Background(), kclient, logr.Discard(), akoDeploymentConfig,
+			[]ReconcileClusterPhase{
+				func(_ context.Context, _ logr.Logger, cluster *clusterv1.Cluster, _ *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+					cluster.Annotations = map[string]string{"reconciled": cluster.Name}
+					return ctrl.Result{}, nil
+				},
+			}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		clusters := &clusterv1.ClusterList{}
+		Expect(kclient.List(context.Background(), clusters)).Should(Succeed())
+		Expect(clusters.Items).Should(HaveLen(clusterCount))
+		for _, cluster := range clusters.Items {
+			Expect(cluster.Annotations).Should(HaveKeyWithValue("reconciled", cluster.Name))
+			Expect(cluster.Labels).Should(HaveKeyWithValue(akoov1alpha1.AviClusterLabel, akoDeploymentConfig.Name))
+		}
+	})
+
+	It("should aggregate the cluster errors and keep the lowest non zero result", func() {
+		setWorkers(4)
+		res, err := ReconcileClustersPhases(context.Background(), kclient, logr.Discard(), akoDeploymentConfig,
+			[]ReconcileClusterPhase{
+				func(_ context.Context, _ logr.Logger, cluster *clusterv1.Cluster, _ *akoov1alpha1.AKODeploymentConfig) (ctrl.Result, error) {
+					switch cluster.Name {
+					case "cluster-03", "cluster-11":
+						return ctrl.Result{RequeueAfter: time.Second}, errors.New("phase failed")
+					case "cluster-07":
+						return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+					}
+					return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+				},
+			}, nil)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("cluster default/cluster-03: phase failed"))
+		Expect(err.Error()).Should(ContainSubstring("cluster default/cluster-11: phase failed"))
+		Expect(res.RequeueAfter).Should(Equal(5 * time.Second))
+	})
+}
@@ -4,335 +4,89 @@
 package user
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 	"github.com/vmware/alb-sdk/go/models"
-	"golang.org/x/mod/semver"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 )
 
-var AkoRolePermissionMap = map[string]string{
-	"PERMISSION_VIRTUALSERVICE":                "WRITE_ACCESS",
-	"PERMISSION_POOL":                          "WRITE_ACCESS",
-	"PERMISSION_POOLGROUP":                     "WRITE_ACCESS",
-	"PERMISSION_HTTPPOLICYSET":                 "WRITE_ACCESS",
-	"PERMISSION_NETWORKSECURITYPOLICY":         "WRITE_ACCESS",
-	"PERMISSION_AUTOSCALE":                     "WRITE_ACCESS",
-	"PERMISSION_DNSPOLICY":                     "WRITE_ACCESS",
-	"PERMISSION_NETWORKPROFILE":                "WRITE_ACCESS",
-	"PERMISSION_APPLICATIONPROFILE":            "WRITE_ACCESS",
-	"PERMISSION_APPLICATIONPERSISTENCEPROFILE": "WRITE_ACCESS",
-	"PERMISSION_HEALTHMONITOR":                 "WRITE_ACCESS",
-	"PERMISSION_ANALYTICSPROFILE":              "WRITE_ACCESS",
-	"PERMISSION_IPAMDNSPROVIDERPROFILE":        "WRITE_ACCESS",
-	"PERMISSION_CUSTOMIPAMDNSPROFILE":          "WRITE_ACCESS",
-	"PERMISSION_TRAFFICCLONEPROFILE":           "WRITE_ACCESS",
-	"PERMISSION_VSDATASCRIPTSET":               "WRITE_ACCESS",
-	"PERMISSION_PKIPROFILE":                    "WRITE_ACCESS",
-	"PERMISSION_SSLKEYANDCERTIFICATE":          "WRITE_ACCESS",
-	"PERMISSION_SERVICEENGINEGROUP":            "WRITE_ACCESS",
-	"PERMISSION_NETWORK":                       "WRITE_ACCESS",
-	"PERMISSION_VRFCONTEXT":                    "WRITE_ACCESS",
-	"PERMISSION_L4POLICYSET":                   "WRITE_ACCESS",
+// akoRolePermissionTypes are the access types a permission of the ako user role can have
+var akoRolePermissionTypes = sets.New("NO_ACCESS", "READ_ACCESS", "WRITE_ACCESS")
 
-	"PERMISSION_IPADDRGROUP":                  "READ_ACCESS",
-	"PERMISSION_STRINGGROUP":                  "READ_ACCESS",
-	"PERMISSION_PROTOCOLPARSER":               "READ_ACCESS",
-	"PERMISSION_SSLPROFILE":                   "READ_ACCESS",
-	"PERMISSION_AUTHPROFILE":                  "READ_ACCESS",
-	"PERMISSION_PINGACCESSAGENT":              "READ_ACCESS",
-	"PERMISSION_CERTIFICATEMANAGEMENTPROFILE": "READ_ACCESS",
-	"PERMISSION_HARDWARESECURITYMODULEGROUP":  "READ_ACCESS",
-	"PERMISSION_SSOPOLICY":                    "READ_ACCESS",
-	"PERMISSION_WAFPROFILE":                   "READ_ACCESS",
-	"PERMISSION_WAFPOLICY":                    "READ_ACCESS",
-	"PERMISSION_CLOUD":                        "READ_ACCESS",
-	"PERMISSION_SYSTEMCONFIGURATION":          "READ_ACCESS",
-	"PERMISSION_CONTROLLER":                   "READ_ACCESS",
-	"PERMISSION_TENANT":                       "READ_ACCESS",
+// akoUserRole describes the avi role the ako users of an AKODeploymentConfig get
+type akoUserRole struct {
+	// name of the role in avi controller
+	name string
+	// permissions maps each permission resource to its access type
+	permissions map[string]string
+	// exact removes the role permissions which are not in permissions, otherwise
+	// they are left as-is
+	exact bool
+	// matrix tells which permissions the avi controller version supports
+	matrix *permissionMatrix
+}
 
-	"PERMISSION_NATPOLICY":         "NO_ACCESS",
-	"PERMISSION_WAFPOLICYPSMGROUP": "NO_ACCESS",
-	"PERMISSION_ERRORPAGEPROFILE":  "NO_ACCESS",
-	"PERMISSION_ERRORPAGEBODY":     "NO_ACCESS",
-	"PERMISSION_ALERTCONFIG":       "NO_ACCESS",
-	"PERMISSION_ALERT":             "NO_ACCESS",
-	"PERMISSION_ACTIONGROUPCONFIG": "NO_ACCESS",
-	"PERMISSION_ALERTSYSLOGCONFIG": "NO_ACCESS",
-	"PERMISSION_ALERTEMAILCONFIG":  "NO_ACCESS",
-	"PERMISSION_SNMPTRAPPROFILE":   "NO_ACCESS",
-	"PERMISSION_TRAFFIC_CAPTURE":   "NO_ACCESS",
-	"PERMISSION_SERVICEENGINE":     "NO_ACCESS",
-	"PERMISSION_USER_CREDENTIAL":   "NO_ACCESS",
-	"PERMISSION_REBOOT":            "NO_ACCESS",
-	"PERMISSION_UPGRADE":           "NO_ACCESS",
-	"PERMISSION_TECHSUPPORT":       "NO_ACCESS",
-	"PERMISSION_INTERNAL":          "NO_ACCESS",
-	"PERMISSION_CONTROLLERSITE":    "NO_ACCESS",
-	"PERMISSION_IMAGE":             "NO_ACCESS",
-	"PERMISSION_USER":              "NO_ACCESS",
-	"PERMISSION_ROLE":              "NO_ACCESS",
-	"PERMISSION_GSLB":              "NO_ACCESS",
-	"PERMISSION_GSLBSERVICE":       "NO_ACCESS",
-	"PERMISSION_GSLBGEODBPROFILE":  "NO_ACCESS",
+// newAkoUserRole returns the ako-essential-role shared by all the
+// AKODeploymentConfigs without a role permission profile
+func newAkoUserRole(matrix *permissionMatrix) *akoUserRole {
+	return &akoUserRole{
+		name:        akoov1alpha1.AkoUserRoleName,
+		permissions: matrix.permissionMap(),
+		matrix:      matrix,
+	}
 }
 
-var AkoRolePermission = []*models.Permission{
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_VIRTUALSERVICE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_POOL"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_POOLGROUP"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_HTTPPOLICYSET"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_NETWORKSECURITYPOLICY"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_AUTOSCALE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_DNSPOLICY"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_NETWORKPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_APPLICATIONPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_APPLICATIONPERSISTENCEPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_HEALTHMONITOR"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_ANALYTICSPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_IPAMDNSPROVIDERPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_CUSTOMIPAMDNSPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_TRAFFICCLONEPROFILE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_IPADDRGROUP"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_STRINGGROUP"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_VSDATASCRIPTSET"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_PROTOCOLPARSER"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_SSLPROFILE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_AUTHPROFILE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_PINGACCESSAGENT"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_PKIPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_SSLKEYANDCERTIFICATE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_CERTIFICATEMANAGEMENTPROFILE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_HARDWARESECURITYMODULEGROUP"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_SSOPOLICY"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_NATPOLICY"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_WAFPROFILE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_WAFPOLICY"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_WAFPOLICYPSMGROUP"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ERRORPAGEPROFILE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ERRORPAGEBODY"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ALERTCONFIG"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ALERT"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ACTIONGROUPCONFIG"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ALERTSYSLOGCONFIG"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ALERTEMAILCONFIG"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_SNMPTRAPPROFILE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_TRAFFIC_CAPTURE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_CLOUD"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_SERVICEENGINE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_SERVICEENGINEGROUP"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_NETWORK"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_VRFCONTEXT"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_USER_CREDENTIAL"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_SYSTEMCONFIGURATION"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_CONTROLLER"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_REBOOT"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_UPGRADE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_TECHSUPPORT"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_INTERNAL"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_CONTROLLERSITE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_IMAGE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_USER"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_ROLE"),
-	},
-	{
-		Type:     ptr.To("READ_ACCESS"),
-		Resource: ptr.To("PERMISSION_TENANT"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_GSLB"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_GSLBSERVICE"),
-	},
-	{
-		Type:     ptr.To("NO_ACCESS"),
-		Resource: ptr.To("PERMISSION_GSLBGEODBPROFILE"),
-	},
-	{
-		Type:     ptr.To("WRITE_ACCESS"),
-		Resource: ptr.To("PERMISSION_L4POLICYSET"),
-	},
+// newProfileAkoUserRole returns the role of an AKODeploymentConfig with a role
+// permission profile, the profile is merged over the permissions of the matrix
+func newProfileAkoUserRole(obj *akoov1alpha1.AKODeploymentConfig, matrix *permissionMatrix, profile map[string]string) (*akoUserRole, error) {
+	permissions := matrix.permissionMap()
+	for resource, permissionType := range profile {
+		if !strings.HasPrefix(resource, "PERMISSION_") {
+			return nil, errors.Errorf("invalid permission %s in role permission profile", resource)
+		}
+		if !akoRolePermissionTypes.Has(permissionType) {
+			return nil, errors.Errorf("invalid access type %s of permission %s in role permission profile", permissionType, resource)
+		}
+		permissions[resource] = permissionType
+	}
+	return &akoUserRole{
+		name:        akoov1alpha1.AkoUserRoleName + "-" + obj.Name,
+		permissions: permissions,
+		exact:       true,
+		matrix:      matrix,
+	}, nil
 }
 
-var deprecatePermissionMap = map[string]string{
-	"PERMISSION_PINGACCESSAGENT": "v30.2.1",
+// privileges returns the role permissions sorted by resource
+func (r *akoUserRole) privileges() []*models.Permission {
+	resources := make([]string, 0, len(r.permissions))
+	for resource := range r.permissions {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	privileges := make([]*models.Permission, 0, len(resources))
+	for _, resource := range resources {
+		privileges = append(privileges, &models.Permission{
+			Resource: ptr.To(resource),
+			Type:     ptr.To(r.permissions[resource]),
+		})
+	}
+	return privileges
 }
 
-func filterAkoRolePermissionByVersion(log logr.Logger, permissions []*models.Permission, version string) []*models.Permission {
+// filterAkoRolePermissionByVersion drops the permissions the avi version doesn't
+// support according to the permission matrix
+func filterAkoRolePermissionByVersion(log logr.Logger, matrix *permissionMatrix, permissions []*models.Permission, version string) []*models.Permission {
 	filtered := []*models.Permission{}
 	for _, permission := range permissions {
-		if v, ok := deprecatePermissionMap[*permission.Resource]; ok && semver.Compare(version, v) >= 0 {
-			log.Info("Skip deprecated permission", "permission", *permission.Resource)
-			// Skip deprecated permission
+		if !matrix.isAvailable(*permission.Resource, version) {
+			log.Info("Skip permission unavailable in current avi version", "permission", *permission.Resource)
 			continue
 		}
 
@@ -0,0 +1,216 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PermissionMatrixDataKey is the ConfigMap key holding a permission matrix override
+const PermissionMatrixDataKey = "permissions.yaml"
+
+//go:embed ako_role_permissions.yaml
+var defaultPermissionMatrixData []byte
+
+// defaultPermissionMatrix is the permission matrix shipped with the operator
+var defaultPermissionMatrix = mustParsePermissionMatrix(defaultPermissionMatrixData)
+
+// PermissionMatrixSource reads the ako user role permission matrix, the default one
+// merged with the ConfigMap overriding it when there is one. A nil source reads the
+// default permission matrix only.
+type PermissionMatrixSource struct {
+	// configMap is the ConfigMap overriding the default permission matrix, no
+	// override is read when its name is empty
+	configMap types.NamespacedName
+}
+
+// NewPermissionMatrixSource returns the source of the permission matrix overridden
+// by the namespace/name ConfigMap ref, the default permission matrix is used as-is
+// when ref is empty
+func NewPermissionMatrixSource(ref string) (*PermissionMatrixSource, error) {
+	if ref == "" {
+		return &PermissionMatrixSource{}, nil
+	}
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, errors.Errorf("invalid permission matrix configmap %q, expected namespace/name", ref)
+	}
+	return &PermissionMatrixSource{configMap: types.NamespacedName{Namespace: namespace, Name: name}}, nil
+}
+
+// ConfigMap returns the ConfigMap overriding the default ako user role permission
+// matrix, its name is empty when there is none
+func (s *PermissionMatrixSource) ConfigMap() types.NamespacedName {
+	if s == nil {
+		return types.NamespacedName{}
+	}
+	return s.configMap
+}
+
+// permissionCompatibility describes an ako user role permission and the avi
+// controller versions it is available in
+type permissionCompatibility struct {
+	Resource     string `yaml:"resource"`
+	Type         string `yaml:"type"`
+	IntroducedIn string `yaml:"introducedIn,omitempty"`
+	DeprecatedIn string `yaml:"deprecatedIn,omitempty"`
+	RemovedIn    string `yaml:"removedIn,omitempty"`
+}
+
+// permissionMatrix lists the ako user role permissions with their version
+// compatibility
+type permissionMatrix struct {
+	Permissions []permissionCompatibility `yaml:"permissions"`
+
+	index map[string]*permissionCompatibility
+}
+
+// parsePermissionMatrix parses and validates a permission matrix document
+func parsePermissionMatrix(data []byte) (*permissionMatrix, error) {
+	matrix := &permissionMatrix{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(matrix); err != nil {
+		return nil, errors.Wrap(err, "failed to parse permission matrix")
+	}
+	matrix.index = make(map[string]*permissionCompatibility, len(matrix.Permissions))
+	for i := range matrix.Permissions {
+		p := &matrix.Permissions[i]
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := matrix.index[p.Resource]; ok {
+			return nil, errors.Errorf("duplicated permission %s in permission matrix", p.Resource)
+		}
+		matrix.index[p.Resource] = p
+	}
+	return matrix, nil
+}
+
+func mustParsePermissionMatrix(data []byte) *permissionMatrix {
+	matrix, err := parsePermissionMatrix(data)
+	if err != nil {
+		panic(err)
+	}
+	return matrix
+}
+
+// validate checks the permission is well formed and normalizes its versions so
+// semver can compare them
+func (p *permissionCompatibility) validate() error {
+	if !strings.HasPrefix(p.Resource, "PERMISSION_") {
+		return errors.Errorf("invalid permission %s in permission matrix", p.Resource)
+	}
+	if !akoRolePermissionTypes.Has(p.Type) {
+		return errors.Errorf("invalid access type %s of permission %s in permission matrix", p.Type, p.Resource)
+	}
+	for _, v := range []*string{&p.IntroducedIn, &p.DeprecatedIn, &p.RemovedIn} {
+		if *v == "" {
+			continue
+		}
+		*v = canonicalVersion(*v)
+		if !semver.IsValid(*v) {
+			return errors.Errorf("invalid version %s of permission %s in permission matrix", *v, p.Resource)
+		}
+	}
+	return nil
+}
+
+// merge returns a copy of the matrix with the override permissions replacing
+// the ones with the same resource
+func (m *permissionMatrix) merge(override *permissionMatrix) *permissionMatrix {
+	merged := &permissionMatrix{index: map[string]*permissionCompatibility{}}
+	for _, p := range m.Permissions {
+		if o, ok := override.index[p.Resource]; ok {
+			p = *o
+		}
+		merged.Permissions = append(merged.Permissions, p)
+	}
+	for _, p := range override.Permissions {
+		if _, ok := m.index[p.Resource]; !ok {
+			merged.Permissions = append(merged.Permissions, p)
+		}
+	}
+	for i := range merged.Permissions {
+		merged.index[merged.Permissions[i].Resource] = &merged.Permissions[i]
+	}
+	return merged
+}
+
+// permissionMap maps each permission resource of the matrix to its access type
+func (m *permissionMatrix) permissionMap() map[string]string {
+	permissions := make(map[string]string, len(m.Permissions))
+	for _, p := range m.Permissions {
+		permissions[p.Resource] = p.Type
+	}
+	return permissions
+}
+
+// isAvailable checks if the permission can be added to a role in the avi version.
+// Permissions which are not part of the matrix are always available.
+func (m *permissionMatrix) isAvailable(resource, version string) bool {
+	p, ok := m.index[resource]
+	if !ok {
+		return true
+	}
+	if p.IntroducedIn != "" && semver.Compare(version, p.IntroducedIn) < 0 {
+		return false
+	}
+	return !isAtLeast(version, p.DeprecatedIn) && !isAtLeast(version, p.RemovedIn)
+}
+
+// isRemoved checks if the avi version no longer knows the permission at all
+func (m *permissionMatrix) isRemoved(resource, version string) bool {
+	p, ok := m.index[resource]
+	return ok && isAtLeast(version, p.RemovedIn)
+}
+
+// isAtLeast checks version is the same as or newer than v, an empty v is never reached
+func isAtLeast(version, v string) bool {
+	return v != "" && semver.Compare(version, v) >= 0
+}
+
+// canonicalVersion adds the v prefix semver expects to an avi version
+func canonicalVersion(version string) string {
+	if len(version) > 0 && version[0] != 'v' {
+		return "v" + version
+	}
+	return version
+}
+
+// get returns the default permission matrix merged with the override ConfigMap, if
+// there is one
+func (s *PermissionMatrixSource) get(ctx context.Context, c client.Reader) (*permissionMatrix, error) {
+	ref := s.ConfigMap()
+	if ref.Name == "" {
+		return defaultPermissionMatrix, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, ref, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return defaultPermissionMatrix, nil
+		}
+		return nil, err
+	}
+	data, ok := configMap.Data[PermissionMatrixDataKey]
+	if !ok {
+		return nil, errors.Errorf("configmap %s has no %s key", ref, PermissionMatrixDataKey)
+	}
+	override, err := parsePermissionMatrix([]byte(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid permission matrix in configmap %s", ref)
+	}
+	return defaultPermissionMatrix.merge(override), nil
+}
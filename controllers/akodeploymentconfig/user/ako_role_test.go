@@ -5,22 +5,161 @@ package user
 
 import (
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 )
 
 func TestRolePermissionAndMapMatch(t *testing.T) {
-	if len(AkoRolePermission) != len(AkoRolePermissionMap) {
-		t.Errorf("len(AkoRolePermission) == %d, len(AkoRolePermissionMap) == %d", len(AkoRolePermission), len(AkoRolePermissionMap))
+	privileges := newAkoUserRole(defaultPermissionMatrix).privileges()
+	permissionMap := defaultPermissionMatrix.permissionMap()
+	if len(privileges) != len(defaultPermissionMatrix.Permissions) {
+		t.Errorf("len(privileges) == %d, len(permissions) == %d", len(privileges), len(defaultPermissionMatrix.Permissions))
 	}
 
 	allMatch := true
-	for _, permission := range AkoRolePermission {
-		if *permission.Type != AkoRolePermissionMap[*permission.Resource] {
+	for _, permission := range privileges {
+		if *permission.Type != permissionMap[*permission.Resource] {
 			allMatch = false
-			t.Logf("AkoRolePermission[%s] == %s, AkoRolePermissionMap[%s] == %s", *permission.Resource, *permission.Type, *permission.Resource, AkoRolePermissionMap[*permission.Resource])
+			t.Logf("privileges[%s] == %s, permissionMap[%s] == %s", *permission.Resource, *permission.Type, *permission.Resource, permissionMap[*permission.Resource])
 		}
 	}
 
 	if !allMatch {
-		t.Error("Not all entries in AkoRolePermission and AkoRolePermissionMap match")
+		t.Error("Not all entries in privileges and permissionMap match")
+	}
+}
+
+func TestNewPermissionMatrixSource(t *testing.T) {
+	source, err := NewPermissionMatrixSource("tkg-system/ako-permissions")
+	if err != nil {
+		t.Fatalf("NewPermissionMatrixSource() error = %v", err)
+	}
+	if source.ConfigMap().String() != "tkg-system/ako-permissions" {
+		t.Errorf("source.ConfigMap() == %s", source.ConfigMap())
+	}
+	if source, err := NewPermissionMatrixSource(""); err != nil || source.ConfigMap().Name != "" {
+		t.Errorf("NewPermissionMatrixSource(\"\") == %v, %v", source, err)
+	}
+	if _, err := NewPermissionMatrixSource("ako-permissions"); err == nil {
+		t.Error("NewPermissionMatrixSource(\"ako-permissions\") should fail")
+	}
+	var nilSource *PermissionMatrixSource
+	if nilSource.ConfigMap().Name != "" {
+		t.Error("a nil source should have no override")
+	}
+}
+
+func TestNewProfileAkoUserRole(t *testing.T) {
+	adc := &akoov1alpha1.AKODeploymentConfig{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+
+	role, err := newProfileAkoUserRole(adc, defaultPermissionMatrix, map[string]string{
+		"PERMISSION_VIRTUALSERVICE": "READ_ACCESS",
+		"PERMISSION_GSLB":           "WRITE_ACCESS",
+	})
+	if err != nil {
+		t.Fatalf("newProfileAkoUserRole() error = %v", err)
+	}
+	if role.name != "ako-essential-role-team-a" {
+		t.Errorf("role.name == %s", role.name)
+	}
+	if !role.exact {
+		t.Error("role from profile should be exact")
+	}
+	if role.permissions["PERMISSION_VIRTUALSERVICE"] != "READ_ACCESS" || role.permissions["PERMISSION_GSLB"] != "WRITE_ACCESS" {
+		t.Errorf("profile permissions not merged: %v", role.permissions)
+	}
+	if len(role.permissions) != len(defaultPermissionMatrix.Permissions) || defaultPermissionMatrix.permissionMap()["PERMISSION_VIRTUALSERVICE"] != "WRITE_ACCESS" {
+		t.Error("profile should be merged over a copy of the default permission matrix")
+	}
+
+	for _, profile := range []map[string]string{
+		{"PERMISSION_VIRTUALSERVICE": "FULL_ACCESS"},
+		{"VIRTUALSERVICE": "READ_ACCESS"},
+	} {
+		if _, err := newProfileAkoUserRole(adc, defaultPermissionMatrix, profile); err == nil {
+			t.Errorf("newProfileAkoUserRole(%v) should fail", profile)
+		}
+	}
+}
+
+func TestParsePermissionMatrix(t *testing.T) {
+	if len(defaultPermissionMatrix.Permissions) == 0 {
+		t.Fatal("embedded permission matrix is empty")
+	}
+
+	for name, data := range map[string]string{
+		"unknown field":   "permissions:\n- resource: PERMISSION_POOL\n  type: READ_ACCESS\n  addedIn: v22.1.1\n",
+		"invalid type":    "permissions:\n- resource: PERMISSION_POOL\n  type: FULL_ACCESS\n",
+		"invalid version": "permissions:\n- resource: PERMISSION_POOL\n  type: READ_ACCESS\n  removedIn: latest\n",
+		"duplicated":      "permissions:\n- resource: PERMISSION_POOL\n  type: READ_ACCESS\n- resource: PERMISSION_POOL\n  type: WRITE_ACCESS\n",
+	} {
+		if _, err := parsePermissionMatrix([]byte(data)); err == nil {
+			t.Errorf("parsePermissionMatrix() with %s should fail", name)
+		}
+	}
+}
+
+func TestPermissionMatrixVersions(t *testing.T) {
+	matrix, err := parsePermissionMatrix([]byte(`permissions:
+- resource: PERMISSION_NEW
+  type: READ_ACCESS
+  introducedIn: 22.1.3
+- resource: PERMISSION_OLD
+  type: READ_ACCESS
+  deprecatedIn: v30.1.1
+  removedIn: v30.2.1
+`))
+	if err != nil {
+		t.Fatalf("parsePermissionMatrix() error = %v", err)
+	}
+
+	testcases := []struct {
+		resource, version  string
+		available, removed bool
+	}{
+		{"PERMISSION_NEW", "v22.1.2", false, false},
+		{"PERMISSION_NEW", "v22.1.3", true, false},
+		{"PERMISSION_OLD", "v30.1.0", true, false},
+		{"PERMISSION_OLD", "v30.1.1", false, false},
+		{"PERMISSION_OLD", "v30.2.1", false, true},
+		{"PERMISSION_UNKNOWN", "v30.2.1", true, false},
+	}
+	for _, tc := range testcases {
+		if got := matrix.isAvailable(tc.resource, tc.version); got != tc.available {
+			t.Errorf("isAvailable(%s, %s) == %v", tc.resource, tc.version, got)
+		}
+		if got := matrix.isRemoved(tc.resource, tc.version); got != tc.removed {
+			t.Errorf("isRemoved(%s, %s) == %v", tc.resource, tc.version, got)
+		}
+	}
+}
+
+func TestMergePermissionMatrix(t *testing.T) {
+	override, err := parsePermissionMatrix([]byte(`permissions:
+- resource: PERMISSION_POOL
+  type: READ_ACCESS
+- resource: PERMISSION_NEW
+  type: WRITE_ACCESS
+  introducedIn: v31.1.1
+`))
+	if err != nil {
+		t.Fatalf("parsePermissionMatrix() error = %v", err)
+	}
+
+	merged := defaultPermissionMatrix.merge(override)
+	if len(merged.Permissions) != len(defaultPermissionMatrix.Permissions)+1 {
+		t.Errorf("len(merged.Permissions) == %d", len(merged.Permissions))
+	}
+	permissions := merged.permissionMap()
+	if permissions["PERMISSION_POOL"] != "READ_ACCESS" || permissions["PERMISSION_NEW"] != "WRITE_ACCESS" {
+		t.Errorf("override not merged: %v", permissions)
+	}
+	if merged.isAvailable("PERMISSION_NEW", "v30.2.1") {
+		t.Error("PERMISSION_NEW should not be available before v31.1.1")
+	}
+	if defaultPermissionMatrix.permissionMap()["PERMISSION_POOL"] != "WRITE_ACCESS" {
+		t.Error("merge should not change the default permission matrix")
 	}
 }
@@ -55,4 +55,6 @@ func intgTests() {
 
 func unitTests() {
 	Describe("AKO user reconciler unit tests", SyncAkoUserRoleTest)
+	Describe("AKO user tenant unit tests", AviTenantTest)
+	Describe("AKO user role permission profile unit tests", RolePermissionProfileTest)
 }
@@ -5,10 +5,14 @@ package user
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
 	"golang.org/x/mod/semver"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -22,16 +26,28 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	akoo "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
 )
 
+// previousTenantRequeueAfter is how often the tenant a cluster moved away from
+// is checked for the virtual services AKO has left in it
+const previousTenantRequeueAfter = 30 * time.Second
+
 // AkoUserReconciler reconcile avi user related resources
 type AkoUserReconciler struct {
 	client.Client
 	aviClient aviclient.Client
 	Log       logr.Logger
 	Scheme    *runtime.Scheme
+
+	// permissionMatrix reads the permission matrix the ako user roles are built from
+	permissionMatrix *PermissionMatrixSource
+
+	// roleLock serializes the lookup and creation of the avi tenants and roles
+	// shared by the clusters reconciled in parallel
+	roleLock sync.Mutex
 }
 
 // NewProvider returns AKOUserReconciler object.
@@ -39,12 +55,14 @@ func NewProvider(client client.Client,
 	aviClient aviclient.Client,
 	logger logr.Logger,
 	scheme *runtime.Scheme,
+	permissionMatrix *PermissionMatrixSource,
 ) *AkoUserReconciler {
 	return &AkoUserReconciler{
-		Client:    client,
-		aviClient: aviClient,
-		Log:       logger,
-		Scheme:    scheme,
+		Client:           client,
+		aviClient:        aviClient,
+		Log:              logger,
+		Scheme:           scheme,
+		permissionMatrix: permissionMatrix,
 	}
 }
 
@@ -148,11 +166,72 @@ func (r *AkoUserReconciler) reconcileAviUserDelete(
 		}
 	}
 
+	// the avi resources of the cluster are gone, the tenant it moved away from
+	// doesn't need to wait for AKO any more
+	for _, annotation := range []string{akoov1alpha1.AviTenantAnnotation, akoov1alpha1.AviPreviousTenantAnnotation} {
+		if tenantName, ok := cluster.Annotations[annotation]; ok {
+			r.deleteUnusedAviTenant(ctx, log, cluster, obj, tenantName)
+		}
+	}
+
 	log.Info("AVI User credentials finished cleanup, updating Cluster condition")
 	conditions.MarkTrue(cluster, akoov1alpha1.AviUserCleanupSucceededCondition)
 	return res, nil
 }
 
+// deleteUnusedAviTenant deletes a tenant of the cluster when the operator created it
+// and no other cluster uses it any more. Failures are only logged so they don't block
+// the cluster deletion, the tenant is left in the avi controller in that case.
+func (r *AkoUserReconciler) deleteUnusedAviTenant(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	obj *akoov1alpha1.AKODeploymentConfig,
+	tenantName string,
+) {
+	if !akoo.IsPerClusterAviTenant(obj) || !obj.Spec.Tenant.DeleteWhenUnused {
+		return
+	}
+	log = log.WithValues("tenant", tenantName)
+
+	clusters := &clusterv1.ClusterList{}
+	if err := r.Client.List(ctx, clusters); err != nil {
+		log.Error(err, "Failed to list clusters, skip deleting AVI tenant")
+		return
+	}
+	for _, c := range clusters.Items {
+		if c.Namespace == cluster.Namespace && c.Name == cluster.Name {
+			continue
+		}
+		// a cluster being deleted doesn't need the tenant once its avi resources are gone
+		if !c.GetDeletionTimestamp().IsZero() && conditions.IsTrue(&c, akoov1alpha1.AviResourceCleanupSucceededCondition) {
+			continue
+		}
+		if c.Annotations[akoov1alpha1.AviTenantAnnotation] == tenantName || c.Annotations[akoov1alpha1.AviPreviousTenantAnnotation] == tenantName {
+			log.Info("AVI tenant is still used by another cluster, skip deleting it", "cluster", c.Namespace+"/"+c.Name)
+			return
+		}
+	}
+
+	tenant, err := r.aviClient.TenantGetByName(tenantName)
+	if aviclient.IsAviTenantNonExistentError(err) {
+		log.Info("AVI tenant is already gone")
+		return
+	} else if err != nil {
+		log.Error(err, "Failed to get AVI tenant, skip deleting it")
+		return
+	}
+	if tenant.Description == nil || *tenant.Description != akoov1alpha1.AviTenantDescription {
+		log.Info("AVI tenant is not created by ako operator, skip deleting it")
+		return
+	}
+	if err := r.aviClient.TenantDeleteByName(tenantName); err != nil {
+		log.Error(err, "Failed to delete AVI tenant")
+		return
+	}
+	log.Info("Deleted unused AVI tenant")
+}
+
 // reconcileAviUserNormal ensure each workload cluster has an independent avi user
 func (r *AkoUserReconciler) reconcileAviUserNormal(
 	ctx context.Context,
@@ -194,6 +273,12 @@ func (r *AkoUserReconciler) reconcileAviUserNormal(
 			log.Error(err, "Failed to get cluster avi user secret, requeue")
 			return res, err
 		}
+		// AKO is pointed at the cluster tenant even though the customers
+		// manage the user, it has to exist
+		if err := r.reconcileCustomerUserAviTenant(log, cluster, obj); err != nil {
+			log.Error(err, "Failed to get cluster avi tenant")
+			return res, err
+		}
 	} else {
 		log.Info("AVI user credentials managed by tkg system")
 		mcSecretName, mcSecretNamespace := r.mcAVISecretNameNameSpace(cluster.Name, cluster.Namespace)
@@ -241,18 +326,113 @@ func (r *AkoUserReconciler) reconcileAviUserNormal(
 		aviUsername := string(mcSecret.Data["username"][:])
 		aviPassword := string(mcSecret.Data["password"][:])
 
+		tenantName, err := akoo.GetClusterAviTenant(cluster, obj)
+		if err != nil {
+			log.Error(err, "Failed to get cluster avi tenant")
+			return res, err
+		}
+
+		role, err := r.getAkoUserRole(ctx, obj)
+		if err != nil {
+			log.Error(err, "Failed to get ako user role permissions")
+			return res, err
+		}
+
 		// ensures the AVI User exists and matches the mc secret
-		if err = r.createOrUpdateAviUser(log, aviUsername, aviPassword, obj.Spec.Tenant.Name); err != nil {
+		if err = r.createOrUpdateAviUser(log, aviUsername, aviPassword, tenantName, role, obj); err != nil {
 			log.Error(err, "Failed to create/update cluster avi user")
 			return res, err
 		} else {
 			log.Info("Successfully created/updated AVI User in AVI Controller")
 		}
+
+		// the user moved to another tenant, the previous one may not be used
+		// anymore once AKO moved its virtual services out of it
+		if previous, ok := cluster.Annotations[akoov1alpha1.AviTenantAnnotation]; ok && previous != tenantName && akoo.IsPerClusterAviTenant(obj) {
+			cluster.Annotations[akoov1alpha1.AviPreviousTenantAnnotation] = previous
+		}
+		recordAviTenant(cluster, obj, tenantName)
+		if r.releasePreviousAviTenant(ctx, log, cluster, obj) {
+			res.RequeueAfter = previousTenantRequeueAfter
+		}
 	}
 
 	return res, nil
 }
 
+// releasePreviousAviTenant deletes the tenant the cluster moved away from once AKO
+// has no virtual service left in it, AKO deletes their pools together with them.
+// It returns true while AKO still has to move out of the tenant.
+func (r *AkoUserReconciler) releasePreviousAviTenant(
+	ctx context.Context,
+	log logr.Logger,
+	cluster *clusterv1.Cluster,
+	obj *akoov1alpha1.AKODeploymentConfig,
+) bool {
+	previous, ok := cluster.Annotations[akoov1alpha1.AviPreviousTenantAnnotation]
+	if !ok {
+		return false
+	}
+	if previous != cluster.Annotations[akoov1alpha1.AviTenantAnnotation] {
+		if _, err := r.aviClient.TenantGetByName(previous); err != nil && !aviclient.IsAviTenantNonExistentError(err) {
+			log.Error(err, "Failed to get the previous AVI tenant, check it later", "tenant", previous)
+			return true
+		} else if err == nil {
+			vss, err := r.aviClient.VirtualServiceGetAll(session.SetOptTenant(previous),
+				session.SetParams(map[string]string{"created_by": akoCreatedBy(cluster)}))
+			if err != nil {
+				log.Error(err, "Failed to list the AKO virtual services in the previous AVI tenant, check it later", "tenant", previous)
+				return true
+			}
+			if len(vss) > 0 {
+				log.Info("AKO still has virtual services in the previous AVI tenant, wait for it to move them", "tenant", previous, "count", len(vss))
+				return true
+			}
+			r.deleteUnusedAviTenant(ctx, log, cluster, obj, previous)
+		}
+	}
+	delete(cluster.Annotations, akoov1alpha1.AviPreviousTenantAnnotation)
+	return false
+}
+
+// akoCreatedBy is the created_by field AKO sets on the avi objects of the cluster
+func akoCreatedBy(cluster *clusterv1.Cluster) string {
+	return "ako-" + cluster.Namespace + "-" + cluster.Name
+}
+
+// reconcileCustomerUserAviTenant ensures the tenant derived from the cluster exists
+// when the avi user is managed by the customers. The tenant is created when the
+// AKODeploymentConfig allows it, the customers have to give their user access to it.
+func (r *AkoUserReconciler) reconcileCustomerUserAviTenant(log logr.Logger, cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig) error {
+	if !akoo.IsPerClusterAviTenant(obj) {
+		return nil
+	}
+	tenantName, err := akoo.GetClusterAviTenant(cluster, obj)
+	if err != nil {
+		return err
+	}
+	r.roleLock.Lock()
+	_, err = r.getOrCreateAviTenant(log, tenantName, obj)
+	r.roleLock.Unlock()
+	if err != nil {
+		return err
+	}
+	recordAviTenant(cluster, obj, tenantName)
+	return nil
+}
+
+// recordAviTenant records the tenant of the cluster so it can be cleaned up together
+// with the last cluster using it
+func recordAviTenant(cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig, tenantName string) {
+	if !akoo.IsPerClusterAviTenant(obj) {
+		return
+	}
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[akoov1alpha1.AviTenantAnnotation] = tenantName
+}
+
 // getAVIControllerCA get avi certificateAuthority secret
 func (r *AkoUserReconciler) getAVIControllerCA(ctx context.Context, obj *akoov1alpha1.AKODeploymentConfig) (*corev1.Secret, error) {
 	aviControllerCA := &corev1.Secret{}
@@ -263,30 +443,40 @@ func (r *AkoUserReconciler) getAVIControllerCA(ctx context.Context, obj *akoov1a
 	return aviControllerCA, err
 }
 
+// getAkoUserRole returns the role the ako users of the AKODeploymentConfig get, built
+// from the permission matrix and its role permission profile when one is referenced
+func (r *AkoUserReconciler) getAkoUserRole(ctx context.Context, obj *akoov1alpha1.AKODeploymentConfig) (*akoUserRole, error) {
+	matrix, err := r.permissionMatrix.get(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Spec.RolePermissionProfileRef == nil {
+		return newAkoUserRole(matrix), nil
+	}
+	profile := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      obj.Spec.RolePermissionProfileRef.Name,
+		Namespace: obj.Spec.RolePermissionProfileRef.Namespace,
+	}, profile); err != nil {
+		return nil, err
+	}
+	return newProfileAkoUserRole(obj, matrix, profile.Data)
+}
+
 // createOrUpdateAviUser create an avi user in avi controller
-func (r *AkoUserReconciler) createOrUpdateAviUser(log logr.Logger, aviUsername, aviPassword, tenantName string) error {
+func (r *AkoUserReconciler) createOrUpdateAviUser(log logr.Logger, aviUsername, aviPassword, tenantName string, role *akoUserRole, obj *akoov1alpha1.AKODeploymentConfig) error {
 	version, err := r.aviClient.GetControllerVersion()
 	if err != nil {
 		return err
 	}
 	// Add v prefix if not present so semver can parse it
-	if len(version) > 0 && version[0] != 'v' {
-		version = "v" + version
-	}
+	version = canonicalVersion(version)
 
 	aviUser, err := r.aviClient.UserGetByName(aviUsername)
 	// user not found, create one
 	if aviclient.IsAviUserNonExistentError(err) {
 		log.Info("AVI User not found, creating a new user", "user", aviUsername)
-		// for avi essential version the default tenant is admin
-		if tenantName == "" {
-			tenantName = "admin"
-		}
-		tenant, err := r.aviClient.TenantGet(tenantName)
-		if err != nil {
-			return err
-		}
-		role, err := r.getOrCreateAkoUserRole(log, tenant.URL, version)
+		tenant, aviRole, err := r.getOrCreateAviTenantAndRole(log, tenantName, version, role, obj)
 		if err != nil {
 			return err
 		}
@@ -297,7 +487,7 @@ func (r *AkoUserReconciler) createOrUpdateAviUser(log logr.Logger, aviUsername,
 			Access: []*models.UserRole{
 				{
 					AllTenants: ptr.To(false),
-					RoleRef:    role.URL,
+					RoleRef:    aviRole.URL,
 					TenantRef:  tenant.URL,
 				},
 			},
@@ -315,16 +505,49 @@ func (r *AkoUserReconciler) createOrUpdateAviUser(log logr.Logger, aviUsername,
 		return err
 	}
 
+	// the tenant of the cluster changes with its namespace or labels, make sure
+	// it exists before looking up the role in it
+	var tenant *models.Tenant
+	if akoo.IsPerClusterAviTenant(obj) {
+		r.roleLock.Lock()
+		tenant, err = r.getOrCreateAviTenant(log, tenantName, obj)
+		r.roleLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
 	// ensure user's role align with latest essential permission when user found
-	if _, err := r.ensureAkoUserRole(log, version); err != nil {
+	r.roleLock.Lock()
+	aviRole, err := r.ensureAkoUserRole(log, version, role, tenantScopedOptions(tenantName, obj)...)
+	r.roleLock.Unlock()
+	if aviclient.IsAviRoleNonExistentError(err) {
+		// the role is switched when a role permission profile is referenced or removed
+		if _, aviRole, err = r.getOrCreateAviTenantAndRole(log, tenantName, version, role, obj); err != nil {
+			return err
+		}
+	} else if err != nil {
 		return err
 	}
+
+	updated := false
+	if tenant != nil && syncAviUserTenant(aviUser, tenant, aviRole) {
+		log.Info("AVI User found, moving it to the cluster tenant", "tenant", tenantName)
+		updated = true
+	}
+	if syncAviUserRoleRef(aviUser, aviRole) {
+		log.Info("AVI User found, updating the role", "role", role.name)
+		updated = true
+	}
 	// Update the password when user found, this is needed when the AVI user was
 	// created before the mc Secret. And this operation will sync
 	// the User's password to be the same as mc Secret's
 	if aviUser.Password == nil || *aviUser.Password != aviPassword {
 		log.Info("AVI User found, updating the password")
 		aviUser.Password = &aviPassword
+		updated = true
+	}
+	if updated {
 		if _, err := r.aviClient.UserUpdate(aviUser); err != nil {
 			return err
 		}
@@ -332,60 +555,165 @@ func (r *AkoUserReconciler) createOrUpdateAviUser(log logr.Logger, aviUsername,
 	return nil
 }
 
+// syncAviUserRoleRef binds the user to role in every tenant it has access to. It
+// returns a bool indicating whether the user was changed.
+func syncAviUserRoleRef(user *models.User, role *models.Role) bool {
+	if role == nil || role.URL == nil {
+		return false
+	}
+	updated := false
+	for _, access := range user.Access {
+		if access.RoleRef == nil || aviRefUUID(*access.RoleRef) != aviRefUUID(*role.URL) {
+			access.RoleRef = role.URL
+			updated = true
+		}
+	}
+	return updated
+}
+
+// syncAviUserTenant gives the user access to tenant only, with role. It returns a
+// bool indicating whether the user was changed.
+func syncAviUserTenant(user *models.User, tenant *models.Tenant, role *models.Role) bool {
+	if tenant == nil || tenant.URL == nil || role == nil || role.URL == nil {
+		return false
+	}
+	inTenant := user.DefaultTenantRef != nil && aviRefUUID(*user.DefaultTenantRef) == aviRefUUID(*tenant.URL) &&
+		len(user.Access) == 1 && user.Access[0].TenantRef != nil && aviRefUUID(*user.Access[0].TenantRef) == aviRefUUID(*tenant.URL)
+	if inTenant {
+		return false
+	}
+	user.DefaultTenantRef = tenant.URL
+	user.Access = []*models.UserRole{
+		{
+			AllTenants: ptr.To(false),
+			RoleRef:    role.URL,
+			TenantRef:  tenant.URL,
+		},
+	}
+	return true
+}
+
+// aviRefUUID returns the uuid of an avi object reference, dropping the object name
+// avi appends to the reference as url fragment
+func aviRefUUID(ref string) string {
+	return aviclient.GetUUIDFromRef(strings.SplitN(ref, "#", 2)[0])
+}
+
+// getOrCreateAviTenantAndRole gets the tenant the ako user lives in and the ako user
+// role in it, creating them when needed
+func (r *AkoUserReconciler) getOrCreateAviTenantAndRole(log logr.Logger, tenantName, version string, role *akoUserRole, obj *akoov1alpha1.AKODeploymentConfig) (*models.Tenant, *models.Role, error) {
+	r.roleLock.Lock()
+	defer r.roleLock.Unlock()
+
+	tenant, err := r.getOrCreateAviTenant(log, tenantName, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	aviRole, err := r.getOrCreateAkoUserRole(log, tenant.URL, version, role, tenantScopedOptions(tenantName, obj)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tenant, aviRole, nil
+}
+
+// getOrCreateAviTenant gets the tenant the ako user lives in. A tenant derived from the
+// cluster is created when it doesn't exist and the AKODeploymentConfig allows it.
+func (r *AkoUserReconciler) getOrCreateAviTenant(log logr.Logger, tenantName string, obj *akoov1alpha1.AKODeploymentConfig) (*models.Tenant, error) {
+	// for avi essential version the default tenant is admin
+	if tenantName == "" {
+		tenantName = akoov1alpha1.AviDefaultTenant
+	}
+	if !akoo.IsPerClusterAviTenant(obj) {
+		return r.aviClient.TenantGet(tenantName)
+	}
+
+	tenant, err := r.aviClient.TenantGetByName(tenantName)
+	if aviclient.IsAviTenantNonExistentError(err) && obj.Spec.Tenant.CreateIfMissing {
+		log.Info("AVI Tenant not found, creating a new tenant", "tenant", tenantName)
+		return r.aviClient.TenantCreate(&models.Tenant{
+			Name:        ptr.To(tenantName),
+			Description: ptr.To(akoov1alpha1.AviTenantDescription),
+		})
+	}
+	return tenant, err
+}
+
+// tenantScopedOptions returns the avi api options which scope the ako user role to
+// the cluster's tenant. In static tenant mode the role is shared by all the clusters.
+func tenantScopedOptions(tenantName string, obj *akoov1alpha1.AKODeploymentConfig) []session.ApiOptionsParams {
+	if !akoo.IsPerClusterAviTenant(obj) {
+		return nil
+	}
+	return []session.ApiOptionsParams{session.SetOptTenant(tenantName)}
+}
+
 // getOrCreateAkoUserRole get ako user's role, create one if not exist
-func (r *AkoUserReconciler) getOrCreateAkoUserRole(log logr.Logger, roleTenantRef *string, version string) (*models.Role, error) {
-	log.Info("Ensure AKO User Role")
-	role, err := r.aviClient.RoleGetByName(akoov1alpha1.AkoUserRoleName)
+func (r *AkoUserReconciler) getOrCreateAkoUserRole(log logr.Logger, roleTenantRef *string, version string, desired *akoUserRole, options ...session.ApiOptionsParams) (*models.Role, error) {
+	log.Info("Ensure AKO User Role", "role", desired.name)
+	role, err := r.aviClient.RoleGetByName(desired.name, options...)
 	// not found ako user role, create one
 	if aviclient.IsAviRoleNonExistentError(err) {
-		log.V(3).Info("Creating AKO User Role since it's not found", "role", akoov1alpha1.AkoUserRoleName)
+		log.V(3).Info("Creating AKO User Role since it's not found", "role", desired.name)
 		log.Info("current avi version", "version", version)
 		role = &models.Role{
-			Name:       ptr.To(akoov1alpha1.AkoUserRoleName),
-			Privileges: filterAkoRolePermissionByVersion(log, AkoRolePermission, version),
+			Name:       ptr.To(desired.name),
+			Privileges: filterAkoRolePermissionByVersion(log, desired.matrix, desired.privileges(), version),
 			TenantRef:  roleTenantRef,
 		}
-		return r.aviClient.RoleCreate(role)
+		return r.aviClient.RoleCreate(role, options...)
 	}
 	if err == nil {
-		return r.ensureAkoUserRole(log, version)
+		return r.ensureAkoUserRole(log, version, desired, options...)
 	}
 	return role, err
 }
 
-// ensureAkoUserRole ensure ako-essential-role has the latest permission
-func (r *AkoUserReconciler) ensureAkoUserRole(log logr.Logger, version string) (*models.Role, error) {
-	role, err := r.aviClient.RoleGetByName(akoov1alpha1.AkoUserRoleName)
+// ensureAkoUserRole ensure the ako user role has the latest permission
+func (r *AkoUserReconciler) ensureAkoUserRole(log logr.Logger, version string, desired *akoUserRole, options ...session.ApiOptionsParams) (*models.Role, error) {
+	role, err := r.aviClient.RoleGetByName(desired.name, options...)
 	if err != nil {
 		return role, err
 	}
 
 	// check if role needs to be synced
-	if syncAkoUserRole(role, version) {
-		log.Info("Syncing AKO User Role with expected permissions")
-		return r.aviClient.RoleUpdate(role)
+	if syncAkoUserRolePermissions(role, desired, version) {
+		log.Info("Syncing AKO User Role with expected permissions", "role", desired.name)
+		return r.aviClient.RoleUpdate(role, options...)
 	}
 
 	return role, nil
 }
 
-// syncAkoUserRole checks the Role for the complete/correct
-// set of permissions and makes any additions/updates necessary.
-// Any additional permissions on the Role that are not part of
-// the desired AKO role are left as-is. It returns a bool
-// indicating whether the Role was changed.
-// It also filters out permissions that are deprecated in the current AVI version.
-func syncAkoUserRole(role *models.Role, version string) bool {
+// syncAkoUserRolePermissions makes the Role permissions match the desired
+// ako user role. Permissions which are not part of the desired role are only
+// removed when the desired role is exact, i.e. it comes from a role permission
+// profile which is the complete source of truth for its own role. It returns a
+// bool indicating whether the Role was changed.
+// Permissions removed from the AVI version are dropped from every role, while
+// permissions the AVI version doesn't support are only kept on non exact roles.
+func syncAkoUserRolePermissions(role *models.Role, desired *akoUserRole, version string) bool {
 	existingResources := sets.New[string]()
 	updated := false
 
-	for i, permission := range role.Privileges {
-		desiredType, ok := AkoRolePermissionMap[*permission.Resource]
-		if !ok {
+	privileges := make([]*models.Permission, 0, len(role.Privileges))
+	for _, permission := range role.Privileges {
+		if desired.matrix.isRemoved(*permission.Resource, version) {
+			// The AVI Controller rejects roles with permissions it no longer knows.
+			updated = true
+			continue
+		}
+		desiredType, ok := desired.permissions[*permission.Resource]
+		if !ok || (desired.exact && !desired.matrix.isAvailable(*permission.Resource, version)) {
+			if desired.exact {
+				// Existing AVI role has a permission the profile doesn't grant: drop it.
+				updated = true
+				continue
+			}
 			// Existing AVI role in AVI Controller has a permission that's not part of
-			// the desired AKO role defined in AkoRolePermissionMap: leave it as-is.
+			// the desired AKO role built from the permission matrix: leave it as-is.
 			// Since those could come from a new AVI Controller version that AKO-Operator
 			// Might not be aware of.
+			privileges = append(privileges, permission)
 			continue
 		}
 
@@ -394,21 +722,22 @@ func syncAkoUserRole(role *models.Role, version string) bool {
 		if *permission.Type != desiredType {
 			// Existing AVI role has a permission that's part of the
 			// desired AKO role, but has the wrong type: update it.
-			role.Privileges[i].Type = ptr.To(desiredType)
+			permission.Type = ptr.To(desiredType)
 			updated = true
 		}
+		privileges = append(privileges, permission)
 	}
 
-	for resource, desiredType := range AkoRolePermissionMap {
+	for resource, desiredType := range desired.permissions {
 		if !existingResources.Has(resource) {
-			// Filter out permissions that are deprecated in the current AVI version.
-			if deprecateVersion, ok := deprecatePermissionMap[resource]; ok && semver.Compare(version, deprecateVersion) >= 0 {
-				// Skip adding deprecated permissions to the role
+			// Filter out permissions that are unavailable in the current AVI version.
+			if !desired.matrix.isAvailable(resource, version) {
+				// Skip adding unavailable permissions to the role
 				continue
 			}
 			// Existing AVI role is missing a permission that's
 			// part of the desired AKO role: add it.
-			role.Privileges = append(role.Privileges, &models.Permission{
+			privileges = append(privileges, &models.Permission{
 				Resource: ptr.To(resource),
 				Type:     ptr.To(desiredType),
 			})
@@ -416,6 +745,7 @@ func syncAkoUserRole(role *models.Role, version string) bool {
 		}
 	}
 
+	role.Privileges = privileges
 	return updated
 }
 
@@ -5,18 +5,24 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
 	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func AkoUserReconcilerTest() {
@@ -40,7 +46,8 @@ func AkoUserReconcilerTest() {
 		userReconciler = NewProvider(testClient,
 			nil,
 			ctrl.Log.WithName("reconciler").WithName("AviUser"),
-			mgr.GetScheme())
+			mgr.GetScheme(),
+			nil)
 
 		akoDeploymentConfig = &akoov1alpha1.AKODeploymentConfig{
 			ObjectMeta: metav1.ObjectMeta{
@@ -135,20 +142,259 @@ func AkoUserReconcilerTest() {
 	})
 }
 
+func AviTenantTest() {
+	var (
+		ctx            context.Context
+		fakeAviClient  *aviclient.FakeAviClient
+		userReconciler *AkoUserReconciler
+		adc            *akoov1alpha1.AKODeploymentConfig
+		cluster        *clusterv1.Cluster
+		tenants        map[string]*models.Tenant
+		roleTenants    []string
+	)
+
+	// roleTenant returns the tenant the role operation is scoped to
+	roleTenant := func(options ...session.ApiOptionsParams) string {
+		opts := &session.ApiOptions{}
+		for _, opt := range options {
+			Expect(opt(opts)).Should(Succeed())
+		}
+		return fmt.Sprintf("%v", opts)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		tenants = map[string]*models.Tenant{}
+		roleTenants = nil
+
+		fakeAviClient = aviclient.NewFakeAviClient()
+		fakeAviClient.Tenant.SetGetTenantFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+			return &models.Tenant{Name: ptr.To(uuid), URL: ptr.To("https://10.0.0.x/api/tenant/" + uuid)}, nil
+		})
+		fakeAviClient.Tenant.SetGetByNameTenantFunc(func(name string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+			if tenant, ok := tenants[name]; ok {
+				return tenant, nil
+			}
+			return nil, errors.New("No object of type tenant with name " + name + " is found")
+		})
+		fakeAviClient.Tenant.SetCreateTenantFunc(func(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+			obj.URL = ptr.To("https://10.0.0.x/api/tenant/" + *obj.Name)
+			tenants[*obj.Name] = obj
+			return obj, nil
+		})
+		fakeAviClient.Tenant.SetDeleteByNameTenantFunc(func(name string, options ...session.ApiOptionsParams) error {
+			delete(tenants, name)
+			return nil
+		})
+		fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+			roleTenants = append(roleTenants, roleTenant(options...))
+			return nil, errors.New("No object of type role with name " + name + " is found")
+		})
+		fakeAviClient.Role.SetCreateRoleFunc(func(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
+			roleTenants = append(roleTenants, roleTenant(options...))
+			return obj, nil
+		})
+		fakeAviClient.User.SetGetByNameUserFunc(func(name string, options ...session.ApiOptionsParams) (*models.User, error) {
+			return nil, errors.New("No object of type user with name " + name + " is found")
+		})
+		fakeAviClient.User.SetCreateUserFunc(func(obj *models.User, options ...session.ApiOptionsParams) (*models.User, error) {
+			return obj, nil
+		})
+
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				Tenant: akoov1alpha1.AVITenant{
+					Mode:             akoov1alpha1.AVITenantModeNamespace,
+					CreateIfMissing:  true,
+					DeleteWhenUnused: true,
+				},
+			},
+		}
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-cluster",
+				Namespace:   "team-a",
+				Annotations: map[string]string{akoov1alpha1.AviTenantAnnotation: "team-a"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).Should(Succeed())
+		userReconciler = NewProvider(fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build(),
+			fakeAviClient,
+			ctrl.Log.WithName("reconciler").WithName("AviUser"),
+			scheme,
+			nil)
+	})
+
+	When("tenant mode is Static", func() {
+		BeforeEach(func() {
+			adc.Spec.Tenant = akoov1alpha1.AVITenant{}
+		})
+		It("should use the admin tenant and share the role", func() {
+			tenant, err := userReconciler.getOrCreateAviTenant(ctrl.Log, "", adc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(*tenant.Name).Should(Equal(akoov1alpha1.AviDefaultTenant))
+			Expect(tenantScopedOptions("admin", adc)).Should(BeEmpty())
+		})
+	})
+
+	When("tenant mode is Namespace", func() {
+		It("should create the missing tenant and scope the role to it", func() {
+			Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "team-a", newAkoUserRole(defaultPermissionMatrix), adc)).Should(Succeed())
+			Expect(tenants).Should(HaveKey("team-a"))
+			Expect(*tenants["team-a"].Description).Should(Equal(akoov1alpha1.AviTenantDescription))
+			Expect(roleTenants).Should(HaveLen(2))
+			for _, t := range roleTenants {
+				Expect(t).Should(ContainSubstring("team-a"))
+			}
+		})
+
+		It("should not create the missing tenant if not allowed", func() {
+			adc.Spec.Tenant.CreateIfMissing = false
+			Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "team-a", newAkoUserRole(defaultPermissionMatrix), adc)).ShouldNot(Succeed())
+			Expect(tenants).ShouldNot(HaveKey("team-a"))
+		})
+
+		It("should move the existing user to the tenant of the cluster", func() {
+			var updated *models.User
+			fakeAviClient.Role.SetCreateRoleFunc(func(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
+				obj.URL = ptr.To("https://10.0.0.x/api/role/" + roleTenant(options...))
+				return obj, nil
+			})
+			fakeAviClient.User.SetGetByNameUserFunc(func(name string, options ...session.ApiOptionsParams) (*models.User, error) {
+				return &models.User{
+					Name:             ptr.To(name),
+					Password:         ptr.To("pwd"),
+					DefaultTenantRef: ptr.To("https://10.0.0.x/api/tenant/team-old"),
+					Access: []*models.UserRole{{
+						AllTenants: ptr.To(false),
+						RoleRef:    ptr.To("https://10.0.0.x/api/role/team-old"),
+						TenantRef:  ptr.To("https://10.0.0.x/api/tenant/team-old"),
+					}},
+				}, nil
+			})
+			fakeAviClient.User.SetUpdateUserFunc(func(obj *models.User, options ...session.ApiOptionsParams) (*models.User, error) {
+				updated = obj
+				return obj, nil
+			})
+			Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "team-a", newAkoUserRole(defaultPermissionMatrix), adc)).Should(Succeed())
+			Expect(tenants).Should(HaveKey("team-a"))
+			Expect(updated).ShouldNot(BeNil())
+			Expect(*updated.DefaultTenantRef).Should(Equal(*tenants["team-a"].URL))
+			Expect(updated.Access).Should(HaveLen(1))
+			Expect(*updated.Access[0].TenantRef).Should(Equal(*tenants["team-a"].URL))
+			Expect(*updated.Access[0].RoleRef).Should(ContainSubstring("team-a"))
+		})
+
+		It("should ensure the tenant of a customer managed user", func() {
+			delete(cluster.Annotations, akoov1alpha1.AviTenantAnnotation)
+			Expect(userReconciler.reconcileCustomerUserAviTenant(ctrl.Log, cluster, adc)).Should(Succeed())
+			Expect(tenants).Should(HaveKey("team-a"))
+			Expect(cluster.Annotations).Should(HaveKeyWithValue(akoov1alpha1.AviTenantAnnotation, "team-a"))
+		})
+
+		It("should fail when the tenant of a customer managed user is missing", func() {
+			adc.Spec.Tenant.CreateIfMissing = false
+			delete(cluster.Annotations, akoov1alpha1.AviTenantAnnotation)
+			Expect(userReconciler.reconcileCustomerUserAviTenant(ctrl.Log, cluster, adc)).ShouldNot(Succeed())
+			Expect(cluster.Annotations).ShouldNot(HaveKey(akoov1alpha1.AviTenantAnnotation))
+		})
+
+		It("should delete the tenant created by ako operator with the last cluster", func() {
+			tenants["team-a"] = &models.Tenant{Name: ptr.To("team-a"), Description: ptr.To(akoov1alpha1.AviTenantDescription)}
+			userReconciler.deleteUnusedAviTenant(ctx, ctrl.Log, cluster, adc, "team-a")
+			Expect(tenants).ShouldNot(HaveKey("team-a"))
+		})
+
+		It("should not delete the tenant which is not created by ako operator", func() {
+			tenants["team-a"] = &models.Tenant{Name: ptr.To("team-a")}
+			userReconciler.deleteUnusedAviTenant(ctx, ctrl.Log, cluster, adc, "team-a")
+			Expect(tenants).Should(HaveKey("team-a"))
+		})
+
+		It("should not delete the tenant if not enabled", func() {
+			adc.Spec.Tenant.DeleteWhenUnused = false
+			tenants["team-a"] = &models.Tenant{Name: ptr.To("team-a"), Description: ptr.To(akoov1alpha1.AviTenantDescription)}
+			userReconciler.deleteUnusedAviTenant(ctx, ctrl.Log, cluster, adc, "team-a")
+			Expect(tenants).Should(HaveKey("team-a"))
+		})
+
+		When("the cluster moved to another tenant", func() {
+			var vss []*models.VirtualService
+
+			BeforeEach(func() {
+				vss = []*models.VirtualService{{Name: ptr.To("team-a-vs")}}
+				cluster.Annotations = map[string]string{
+					akoov1alpha1.AviTenantAnnotation:         "team-b",
+					akoov1alpha1.AviPreviousTenantAnnotation: "team-a",
+				}
+				tenants["team-a"] = &models.Tenant{Name: ptr.To("team-a"), Description: ptr.To(akoov1alpha1.AviTenantDescription)}
+				fakeAviClient.VirtualService.SetGetAllFn(func(options ...session.ApiOptionsParams) ([]*models.VirtualService, error) {
+					Expect(roleTenant(options...)).Should(ContainSubstring("team-a"))
+					Expect(roleTenant(options...)).Should(ContainSubstring("ako-team-a-test-cluster"))
+					return vss, nil
+				})
+			})
+
+			It("should keep the previous tenant while AKO has virtual services in it", func() {
+				Expect(userReconciler.releasePreviousAviTenant(ctx, ctrl.Log, cluster, adc)).Should(BeTrue())
+				Expect(tenants).Should(HaveKey("team-a"))
+				Expect(cluster.Annotations).Should(HaveKeyWithValue(akoov1alpha1.AviPreviousTenantAnnotation, "team-a"))
+			})
+
+			It("should delete the previous tenant once AKO moved out of it", func() {
+				vss = nil
+				Expect(userReconciler.releasePreviousAviTenant(ctx, ctrl.Log, cluster, adc)).Should(BeFalse())
+				Expect(tenants).ShouldNot(HaveKey("team-a"))
+				Expect(cluster.Annotations).ShouldNot(HaveKey(akoov1alpha1.AviPreviousTenantAnnotation))
+			})
+
+			It("should forget the previous tenant once it is gone", func() {
+				delete(tenants, "team-a")
+				Expect(userReconciler.releasePreviousAviTenant(ctx, ctrl.Log, cluster, adc)).Should(BeFalse())
+				Expect(cluster.Annotations).ShouldNot(HaveKey(akoov1alpha1.AviPreviousTenantAnnotation))
+			})
+		})
+
+		When("another cluster still uses the tenant", func() {
+			JustBeforeEach(func() {
+				other := cluster.DeepCopy()
+				other.Name = "other-cluster"
+				other.ResourceVersion = ""
+				Expect(userReconciler.Client.Create(ctx, other)).Should(Succeed())
+			})
+			It("should not delete the tenant", func() {
+				tenants["team-a"] = &models.Tenant{Name: ptr.To("team-a"), Description: ptr.To(akoov1alpha1.AviTenantDescription)}
+				userReconciler.deleteUnusedAviTenant(ctx, ctrl.Log, cluster, adc, "team-a")
+				Expect(tenants).Should(HaveKey("team-a"))
+			})
+		})
+	})
+}
+
 func SyncAkoUserRoleTest() {
+	var defaultRole *akoUserRole
+
+	BeforeEach(func() {
+		defaultRole = newAkoUserRole(defaultPermissionMatrix)
+	})
+
 	Specify("role has no permissions", func() {
 		role := &models.Role{}
 
-		updated := syncAkoUserRole(role, "v20.0.0")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v20.0.0")
 		Expect(updated).To(BeTrue())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission)))
-		Expect(role.Privileges).To(ContainElements(AkoRolePermission))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions)))
+		Expect(role.Privileges).To(ContainElements(defaultRole.privileges()))
 	})
 
 	Specify("role has some permissions with wrong type", func() {
 		role := &models.Role{}
 
-		for i, permission := range AkoRolePermission {
+		for i, permission := range defaultRole.privileges() {
 			role.Privileges = append(role.Privileges, &models.Permission{
 				Resource: ptr.To(*permission.Resource),
 				Type:     ptr.To(*permission.Type),
@@ -159,16 +405,16 @@ func SyncAkoUserRoleTest() {
 			}
 		}
 
-		updated := syncAkoUserRole(role, "v20.0.0")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v20.0.0")
 		Expect(updated).To(BeTrue())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission)))
-		Expect(role.Privileges).To(ContainElements(AkoRolePermission))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions)))
+		Expect(role.Privileges).To(ContainElements(defaultRole.privileges()))
 	})
 
 	Specify("role has some permissions missing", func() {
 		role := &models.Role{}
 
-		for i, permission := range AkoRolePermission {
+		for i, permission := range defaultRole.privileges() {
 			if i%7 == 0 {
 				continue
 			}
@@ -179,16 +425,16 @@ func SyncAkoUserRoleTest() {
 			})
 		}
 
-		updated := syncAkoUserRole(role, "v20.0.0")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v20.0.0")
 		Expect(updated).To(BeTrue())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission)))
-		Expect(role.Privileges).To(ContainElements(AkoRolePermission))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions)))
+		Expect(role.Privileges).To(ContainElements(defaultRole.privileges()))
 	})
 
 	Specify("role has some extra permissions", func() {
 		role := &models.Role{}
 
-		for _, permission := range AkoRolePermission {
+		for _, permission := range defaultRole.privileges() {
 			role.Privileges = append(role.Privileges, &models.Permission{
 				Resource: ptr.To(*permission.Resource),
 				Type:     ptr.To(*permission.Type),
@@ -208,17 +454,17 @@ func SyncAkoUserRoleTest() {
 			role.Privileges[i], role.Privileges[j] = role.Privileges[j], role.Privileges[i]
 		})
 
-		updated := syncAkoUserRole(role, "v20.0.0")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v20.0.0")
 		Expect(updated).To(BeFalse())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission) + len(additionalPrivileges)))
-		Expect(role.Privileges).To(ContainElements(AkoRolePermission))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions) + len(additionalPrivileges)))
+		Expect(role.Privileges).To(ContainElements(defaultRole.privileges()))
 		Expect(role.Privileges).To(ContainElements(additionalPrivileges))
 	})
 
 	Specify("role has a combination of missing, incorrect and extra permissions", func() {
 		role := &models.Role{}
 
-		for i, permission := range AkoRolePermission {
+		for i, permission := range defaultRole.privileges() {
 			if i%7 == 0 {
 				continue
 			}
@@ -246,19 +492,19 @@ func SyncAkoUserRoleTest() {
 			role.Privileges[i], role.Privileges[j] = role.Privileges[j], role.Privileges[i]
 		})
 
-		updated := syncAkoUserRole(role, "v20.0.0")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v20.0.0")
 		Expect(updated).To(BeTrue())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission) + len(additionalPrivileges)))
-		Expect(role.Privileges).To(ContainElements(AkoRolePermission))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions) + len(additionalPrivileges)))
+		Expect(role.Privileges).To(ContainElements(defaultRole.privileges()))
 		Expect(role.Privileges).To(ContainElements(additionalPrivileges))
 	})
 
 	Specify("AVI Controller is higher than 30.2.1", func() {
 		role := &models.Role{}
 
-		updated := syncAkoUserRole(role, "v30.2.1")
+		updated := syncAkoUserRolePermissions(role, defaultRole, "v30.2.1")
 		newPermissions := []*models.Permission{}
-		for _, permission := range AkoRolePermission {
+		for _, permission := range defaultRole.privileges() {
 			if *permission.Resource == "PERMISSION_PINGACCESSAGENT" {
 				continue
 			}
@@ -266,7 +512,211 @@ func SyncAkoUserRoleTest() {
 
 		}
 		Expect(updated).To(BeTrue())
-		Expect(role.Privileges).To(HaveLen(len(AkoRolePermission) - 1))
+		Expect(role.Privileges).To(HaveLen(len(defaultPermissionMatrix.Permissions) - 1))
 		Expect(role.Privileges).To(ContainElements(newPermissions))
 	})
+
+	When("the role comes from a role permission profile", func() {
+		var desired *akoUserRole
+
+		BeforeEach(func() {
+			var err error
+			desired, err = newProfileAkoUserRole(&akoov1alpha1.AKODeploymentConfig{}, defaultPermissionMatrix, map[string]string{
+				"PERMISSION_GSLB": "READ_ACCESS",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		Specify("role has extra and incorrect permissions", func() {
+			role := &models.Role{Privileges: desired.privileges()}
+			role.Privileges[0].Type = ptr.To("INCORRECT_TYPE")
+			role.Privileges = append(role.Privileges, &models.Permission{
+				Resource: ptr.To("ADDITIONAL_PERMISSION"),
+				Type:     ptr.To("WRITE_ACCESS"),
+			})
+
+			updated := syncAkoUserRolePermissions(role, desired, "v20.0.0")
+			Expect(updated).To(BeTrue())
+			Expect(role.Privileges).To(ConsistOf(desired.privileges()))
+		})
+
+		Specify("role is in sync", func() {
+			role := &models.Role{Privileges: desired.privileges()}
+
+			updated := syncAkoUserRolePermissions(role, desired, "v20.0.0")
+			Expect(updated).To(BeFalse())
+			Expect(role.Privileges).To(ContainElement(&models.Permission{
+				Resource: ptr.To("PERMISSION_GSLB"),
+				Type:     ptr.To("READ_ACCESS"),
+			}))
+		})
+
+		Specify("role has deprecated permissions", func() {
+			role := &models.Role{Privileges: desired.privileges()}
+
+			updated := syncAkoUserRolePermissions(role, desired, "v30.2.1")
+			Expect(updated).To(BeTrue())
+			Expect(role.Privileges).To(HaveLen(len(desired.permissions) - 1))
+		})
+	})
+}
+
+func RolePermissionProfileTest() {
+	var (
+		ctx            context.Context
+		fakeAviClient  *aviclient.FakeAviClient
+		userReconciler *AkoUserReconciler
+		adc            *akoov1alpha1.AKODeploymentConfig
+		profile        *corev1.ConfigMap
+		matrix         *corev1.ConfigMap
+		matrixSource   *PermissionMatrixSource
+		roles          map[string]*models.Role
+		aviUser        *models.User
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		roles = map[string]*models.Role{}
+		matrixSource = nil
+
+		fakeAviClient = aviclient.NewFakeAviClient()
+		fakeAviClient.Tenant.SetGetTenantFunc(func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+			return &models.Tenant{Name: ptr.To(uuid), URL: ptr.To("https://10.0.0.x/api/tenant/" + uuid)}, nil
+		})
+		fakeAviClient.Role.SetGetByNameRoleFunc(func(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
+			if role, ok := roles[name]; ok {
+				return role, nil
+			}
+			return nil, errors.New("No object of type role with name " + name + " is found")
+		})
+		fakeAviClient.Role.SetCreateRoleFunc(func(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
+			obj.URL = ptr.To("https://10.0.0.x/api/role/role-" + *obj.Name + "#" + *obj.Name)
+			roles[*obj.Name] = obj
+			return obj, nil
+		})
+		fakeAviClient.Role.SetUpdateRoleFunc(func(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
+			roles[*obj.Name] = obj
+			return obj, nil
+		})
+		fakeAviClient.User.SetGetByNameUserFunc(func(name string, options ...session.ApiOptionsParams) (*models.User, error) {
+			if aviUser == nil {
+				return nil, errors.New("No object of type user with name " + name + " is found")
+			}
+			return aviUser, nil
+		})
+		fakeAviClient.User.SetCreateUserFunc(func(obj *models.User, options ...session.ApiOptionsParams) (*models.User, error) {
+			aviUser = obj
+			return obj, nil
+		})
+		fakeAviClient.User.SetUpdateUserFunc(func(obj *models.User, options ...session.ApiOptionsParams) (*models.User, error) {
+			aviUser = obj
+			return obj, nil
+		})
+		aviUser = nil
+
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				RolePermissionProfileRef: &akoov1alpha1.ConfigMapRef{
+					Name:      "ako-role-profile",
+					Namespace: "default",
+				},
+			},
+		}
+		profile = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ako-role-profile",
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				"PERMISSION_GSLB": "READ_ACCESS",
+			},
+		}
+		matrix = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ako-role-permission-matrix",
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				PermissionMatrixDataKey: "permissions:\n- resource: PERMISSION_GSLB\n  type: NO_ACCESS\n  removedIn: v31.1.1\n",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).Should(Succeed())
+		userReconciler = NewProvider(fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile, matrix).Build(),
+			fakeAviClient,
+			ctrl.Log.WithName("reconciler").WithName("AviUser"),
+			scheme,
+			matrixSource)
+	})
+
+	It("should use the shared role without a profile", func() {
+		adc.Spec.RolePermissionProfileRef = nil
+		role, err := userReconciler.getAkoUserRole(ctx, adc)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(role.name).Should(Equal(akoov1alpha1.AkoUserRoleName))
+		Expect(role.exact).Should(BeFalse())
+	})
+
+	When("the permission matrix is overridden", func() {
+		BeforeEach(func() {
+			var err error
+			matrixSource, err = NewPermissionMatrixSource("default/ako-role-permission-matrix")
+			Expect(err).ShouldNot(HaveOccurred())
+			adc.Spec.RolePermissionProfileRef = nil
+		})
+
+		It("should use the permissions of the override", func() {
+			role, err := userReconciler.getAkoUserRole(ctx, adc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(role.permissions).Should(HaveKeyWithValue("PERMISSION_GSLB", "NO_ACCESS"))
+			Expect(role.matrix.isRemoved("PERMISSION_GSLB", "v31.1.1")).Should(BeTrue())
+		})
+
+		It("should drop permissions removed from the avi version", func() {
+			role, err := userReconciler.getAkoUserRole(ctx, adc)
+			Expect(err).ShouldNot(HaveOccurred())
+			aviRole := &models.Role{Privileges: role.privileges()}
+			Expect(syncAkoUserRolePermissions(aviRole, role, "v31.1.1")).Should(BeTrue())
+			Expect(aviRole.Privileges).Should(HaveLen(len(role.permissions) - 1))
+		})
+
+		It("should fail when the override is invalid", func() {
+			matrix.Data[PermissionMatrixDataKey] = "permissions:\n- resource: PERMISSION_GSLB\n  type: ALL\n"
+			Expect(userReconciler.Client.Update(ctx, matrix)).Should(Succeed())
+			_, err := userReconciler.getAkoUserRole(ctx, adc)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	It("should fail when the profile is missing", func() {
+		adc.Spec.RolePermissionProfileRef.Name = "missing"
+		_, err := userReconciler.getAkoUserRole(ctx, adc)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should create the role of the AKODeploymentConfig from the profile", func() {
+		role, err := userReconciler.getAkoUserRole(ctx, adc)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "", role, adc)).Should(Succeed())
+		Expect(roles).Should(HaveKey("ako-essential-role-team-a"))
+		Expect(roles["ako-essential-role-team-a"].Privileges).Should(ContainElement(&models.Permission{
+			Resource: ptr.To("PERMISSION_GSLB"),
+			Type:     ptr.To("READ_ACCESS"),
+		}))
+		Expect(*aviUser.Access[0].RoleRef).Should(Equal(*roles["ako-essential-role-team-a"].URL))
+	})
+
+	It("should move the existing user to the role of the profile", func() {
+		Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "", newAkoUserRole(defaultPermissionMatrix), adc)).Should(Succeed())
+		Expect(*aviUser.Access[0].RoleRef).Should(Equal(*roles[akoov1alpha1.AkoUserRoleName].URL))
+
+		role, err := userReconciler.getAkoUserRole(ctx, adc)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(userReconciler.createOrUpdateAviUser(ctrl.Log, "user", "pwd", "", role, adc)).Should(Succeed())
+		Expect(*aviUser.Access[0].RoleRef).Should(Equal(*roles["ako-essential-role-team-a"].URL))
+	})
 }
@@ -13,40 +13,60 @@ import (
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/predicates"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // SetupWithManager adds this reconciler to a new controller then to the
 // provided manager.
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Haprovider == nil {
+		return errors.New("cluster reconciler requires an HA provider")
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		// Watch Cluster resources.
 		For(&clusterv1.Cluster{}).
 		Watches(
 			&corev1.Service{},
 			handler.EnqueueRequestsFromMapFunc(r.serviceToCluster(r.Client, r.Log)),
+			builder.WithPredicates(predicates.HAService()),
 		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: config.Get().Concurrency.Cluster}).
 		Complete(r)
 }
 
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=ako.vmware.com,resources=l4rules,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=kubeadmcontrolplanes,verbs=get;list;watch
+
 type ClusterReconciler struct {
 	client.Client
 	Log        logr.Logger
 	Scheme     *runtime.Scheme
+	Recorder   record.EventRecorder
 	Haprovider *haprovider.HAProvider
+
+	// aviClients caches the Avi client of every AKODeploymentConfig, the
+	// migrations from kube-vip check the Avi virtual services through them
+	aviClients ako_operator.AviClients
 }
 
 func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
@@ -80,19 +100,39 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 
 	log = log.WithValues("Cluster", cluster.Namespace+"/"+cluster.Name)
 
+	// move the cluster from kube-vip to Avi when it's requested
+	if res, err := r.reconcileHAMigration(ctx, log, cluster); err != nil || !res.IsZero() {
+		return res, err
+	}
+
 	isVIPProvider, err := ako_operator.IsControlPlaneVIPProvider(cluster)
 	if err != nil {
 		log.Error(err, "can't unmarshal cluster variables")
 		return res, err
 	}
 
+	if !cluster.DeletionTimestamp.IsZero() && ctrlutil.ContainsFinalizer(cluster, akoov1alpha1.HAServiceFinalizer) &&
+		len(cluster.Finalizers) == 1 {
+		// the HA service outside of the cluster namespace isn't owned by the
+		// cluster, it's deleted once the cluster api is done with the cluster
+		log.Info("Deleting HA service of the cluster")
+		if err = r.Haprovider.DeleteHAService(ctx, cluster); err != nil {
+			log.Error(err, "Fail to delete HA service")
+			return res, err
+		}
+		return res, nil
+	}
+
 	if isVIPProvider {
 		log.Info("AVI is control plane HA provider")
-		r.Haprovider = haprovider.NewProvider(r.Client, r.Log)
 		if err = r.Haprovider.CreateOrUpdateHAService(ctx, cluster); err != nil {
 			log.Error(err, "Fail to reconcile HA service")
 			return res, err
 		}
+		// resolve the FQDN control plane endpoint again to follow the DNS changes
+		if haprovider.ResolvesFQDN(cluster) {
+			res.RequeueAfter = config.Get().DNS.ResolveInterval.Duration
+		}
 	}
 
 	// skip reconcile if cluster is using kube-vip to provide load balancer service
@@ -136,9 +176,6 @@ func (r *ClusterReconciler) serviceToCluster(c client.Client, log logr.Logger) h
 			return nil
 		}
 		logger := log.WithValues("service", service.Namespace+"/"+service.Name)
-		if r.skipService(service) {
-			return []reconcile.Request{}
-		}
 		// in bootstrap kind cluster, ensure ako deletion before delete service
 		if ako_operator.IsBootStrapCluster() && !service.DeletionTimestamp.IsZero() {
 			if err := r.deleteAKOStatefulSet(ctx, c, v1alpha1.AkoStatefulSetName, v1alpha1.TKGSystemNamespace); err != nil {
@@ -146,10 +183,17 @@ func (r *ClusterReconciler) serviceToCluster(c client.Client, log logr.Logger) h
 			}
 			return []reconcile.Request{}
 		}
+		// the HA services may live in another namespace than their cluster,
+		// they are mapped back with the cluster annotations
+		keys := index.ServiceByCluster(service)
+		if len(keys) == 0 {
+			return []reconcile.Request{}
+		}
+		namespace, name, _ := strings.Cut(keys[0], "/")
 		var cluster clusterv1.Cluster
 		if err := c.Get(ctx, client.ObjectKey{
-			Name:      service.Annotations[v1alpha1.TKGClusterNameLabel],
-			Namespace: service.Annotations[v1alpha1.TKGClusterNameSpaceLabel],
+			Name:      name,
+			Namespace: namespace,
 		}, &cluster); err != nil {
 			return []reconcile.Request{}
 		}
@@ -165,10 +209,6 @@ func (r *ClusterReconciler) serviceToCluster(c client.Client, log logr.Logger) h
 	}
 }
 
-func (r *ClusterReconciler) skipService(service *corev1.Service) bool {
-	return service.Spec.Type != corev1.ServiceTypeLoadBalancer || !strings.Contains(service.Name, v1alpha1.HAServiceName)
-}
-
 // deleteAKOStatefulSet deletes the stateful set with specified name and namespace
 func (r *ClusterReconciler) deleteAKOStatefulSet(ctx context.Context, c client.Client, name string, namespace string) error {
 	akoStatefulSet := &v1.StatefulSet{}
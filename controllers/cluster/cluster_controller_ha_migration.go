@@ -0,0 +1,136 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+)
+
+const (
+	// haMigrationTimeout is how long Avi has to serve the control plane
+	// endpoint of a migrated cluster before the migration is rolled back
+	haMigrationTimeout = 10 * time.Minute
+	// haMigrationRequeueAfter is how often the migration is checked
+	haMigrationRequeueAfter = 10 * time.Second
+)
+
+// reconcileHAMigration moves a running cluster from kube-vip to an Avi control
+// plane VIP once it's annotated with HAMigrationRequested. The HA service is
+// created with the control plane endpoint as preferred VIP, once its Avi
+// virtual service is up with API servers up in its pool, the migration
+// completes and kube-vip can be removed from the control plane. The HA service
+// is deleted again if the VIP can't be requested, Avi allocates another VIP or
+// doesn't serve it in time. A non zero result requeues the cluster while the
+// migration is in progress, the regular HA service reconciliation only runs
+// once it's completed.
+func (r *ClusterReconciler) reconcileHAMigration(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster) (ctrl.Result, error) {
+	switch cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] {
+	case akoov1alpha1.HAMigrationRequested:
+		return r.startHAMigration(ctx, log, cluster)
+	case akoov1alpha1.HAMigrationInProgress:
+	default:
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("migration", akoov1alpha1.HAMigrationInProgress)
+	if !cluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.rollbackHAMigration(ctx, log, cluster, "the cluster is being deleted")
+	}
+	adc, err := ako_operator.GetAKODeploymentConfigForCluster(ctx, r.Client, log, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if adc == nil {
+		return ctrl.Result{}, r.rollbackHAMigration(ctx, log, cluster, "the cluster is not selected by any AKODeploymentConfig")
+	}
+	aviClient, err := r.aviClients.Get(ctx, r.Client, log, adc)
+	if err != nil {
+		log.Error(err, "Cannot init AVI clients from secrets")
+		return ctrl.Result{}, err
+	}
+	migrated, err := r.Haprovider.MigrateHAService(ctx, aviClient, cluster)
+	if errors.Is(err, haprovider.ErrHAMigrationVIPMismatch) || errors.Is(err, haprovider.ErrControlPlaneVIPInvalid) {
+		return ctrl.Result{}, r.rollbackHAMigration(ctx, log, cluster, err.Error())
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !migrated {
+		if r.Haprovider.HAMigrationExpired(cluster, haMigrationTimeout) {
+			return ctrl.Result{}, r.rollbackHAMigration(ctx, log, cluster,
+				"Avi doesn't serve the control plane endpoint after "+haMigrationTimeout.String())
+		}
+		log.Info("Waiting for Avi to serve the control plane endpoint")
+		return ctrl.Result{RequeueAfter: haMigrationRequeueAfter}, nil
+	}
+
+	cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] = akoov1alpha1.HAMigrationCompleted
+	delete(cluster.Annotations, akoov1alpha1.HAMigrationStartedAnnotation)
+	conditions.MarkTrue(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition)
+	r.Recorder.Eventf(cluster, corev1.EventTypeNormal, haprovider.KubeVipRemovableReason,
+		"Control plane endpoint %s is served by Avi, kube-vip can be removed", cluster.Spec.ControlPlaneEndpoint.Host)
+	log.Info("Control plane endpoint is served by Avi, kube-vip can be removed")
+	return ctrl.Result{}, nil
+}
+
+// startHAMigration checks the cluster can move to Avi and starts the migration
+func (r *ClusterReconciler) startHAMigration(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster) (ctrl.Result, error) {
+	isVIPProvider, err := ako_operator.IsControlPlaneVIPProvider(cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if isVIPProvider {
+		log.Info("Avi already provides the control plane VIP, nothing to migrate")
+		cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] = akoov1alpha1.HAMigrationCompleted
+		conditions.MarkTrue(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition)
+		return ctrl.Result{}, nil
+	}
+	reason := ""
+	if cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
+		reason = "the management cluster can't be migrated"
+	} else if cluster.Spec.ControlPlaneEndpoint.Host == "" {
+		reason = "the cluster has no control plane endpoint"
+	} else if adc, err := ako_operator.GetAKODeploymentConfigForCluster(ctx, r.Client, log, cluster); err != nil {
+		return ctrl.Result{}, err
+	} else if adc == nil {
+		reason = "the cluster is not selected by any AKODeploymentConfig"
+	}
+	if reason != "" {
+		return ctrl.Result{}, r.rollbackHAMigration(ctx, log, cluster, reason)
+	}
+
+	log.Info("Migrating the control plane endpoint from kube-vip to Avi", "endpoint", cluster.Spec.ControlPlaneEndpoint.Host)
+	cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] = akoov1alpha1.HAMigrationInProgress
+	r.Haprovider.StartHAMigration(cluster)
+	conditions.MarkFalse(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition, akoov1alpha1.HAMigrationInProgressReason,
+		clusterv1.ConditionSeverityInfo, "Waiting for Avi to serve the control plane endpoint %s", cluster.Spec.ControlPlaneEndpoint.Host)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// rollbackHAMigration deletes the HA service created for the migration, the
+// cluster keeps using kube-vip
+func (r *ClusterReconciler) rollbackHAMigration(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster, reason string) error {
+	if err := r.Haprovider.DeleteHAService(ctx, cluster); err != nil {
+		return err
+	}
+	cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] = akoov1alpha1.HAMigrationRolledBack
+	delete(cluster.Annotations, akoov1alpha1.HAMigrationStartedAnnotation)
+	conditions.MarkFalse(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition, akoov1alpha1.HAMigrationRolledBackReason,
+		clusterv1.ConditionSeverityWarning, "Migration to Avi rolled back: %s", reason)
+	r.Recorder.Eventf(cluster, corev1.EventTypeWarning, haprovider.HAMigrationRolledBackReason,
+		"Migration of the control plane endpoint to Avi rolled back: %s", reason)
+	log.Info("Migration to Avi rolled back, the cluster keeps using kube-vip", "reason", reason)
+	return nil
+}
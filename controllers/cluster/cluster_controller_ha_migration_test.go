@@ -0,0 +1,253 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
+)
+
+func TestReconcileHAMigration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, clusterv1.AddToScheme, akoov1alpha1.AddToScheme, akov1beta1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name          string
+		phase         string
+		started       time.Duration
+		ingress       string
+		noEndpoint    bool
+		vsDown        bool
+		dataNetwork   string
+		noService     bool
+		expectedPhase string
+		expectedWait  bool
+		serviceExists bool
+	}{
+		{
+			name:          "migration started",
+			phase:         akoov1alpha1.HAMigrationRequested,
+			expectedPhase: akoov1alpha1.HAMigrationInProgress,
+			expectedWait:  true,
+		},
+		{
+			name:          "migration refused without control plane endpoint",
+			phase:         akoov1alpha1.HAMigrationRequested,
+			noEndpoint:    true,
+			expectedPhase: akoov1alpha1.HAMigrationRolledBack,
+		},
+		{
+			name:          "waiting for the VIP",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Minute,
+			expectedPhase: akoov1alpha1.HAMigrationInProgress,
+			expectedWait:  true,
+			serviceExists: true,
+		},
+		{
+			name:          "waiting for the virtual service",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Minute,
+			ingress:       "10.0.0.10",
+			vsDown:        true,
+			expectedPhase: akoov1alpha1.HAMigrationInProgress,
+			expectedWait:  true,
+			serviceExists: true,
+		},
+		{
+			name:          "rolled back when the virtual service is not up in time",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Hour,
+			ingress:       "10.0.0.10",
+			vsDown:        true,
+			expectedPhase: akoov1alpha1.HAMigrationRolledBack,
+		},
+		{
+			name:          "migration completed",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Minute,
+			ingress:       "10.0.0.10",
+			expectedPhase: akoov1alpha1.HAMigrationCompleted,
+			serviceExists: true,
+		},
+		{
+			name:          "rolled back when Avi allocates another VIP",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Minute,
+			ingress:       "10.0.0.11",
+			expectedPhase: akoov1alpha1.HAMigrationRolledBack,
+		},
+		{
+			name:          "rolled back when the control plane endpoint can't be requested",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Minute,
+			dataNetwork:   "10.20.0.0/24",
+			noService:     true,
+			expectedPhase: akoov1alpha1.HAMigrationRolledBack,
+		},
+		{
+			name:          "rolled back after the timeout",
+			phase:         akoov1alpha1.HAMigrationInProgress,
+			started:       time.Hour,
+			expectedPhase: akoov1alpha1.HAMigrationRolledBack,
+		},
+		{
+			name:          "completed migration left alone",
+			phase:         akoov1alpha1.HAMigrationCompleted,
+			expectedPhase: akoov1alpha1.HAMigrationCompleted,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fake.NewClientBuilder().WithScheme(scheme).
+				WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+				WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).
+				WithObjects(
+					&akoov1alpha1.AKODeploymentConfig{
+						ObjectMeta: metav1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+						Spec: akoov1alpha1.AKODeploymentConfigSpec{
+							AdminCredentialRef:      &akoov1alpha1.SecretRef{Name: "controller-credentials", Namespace: "default"},
+							CertificateAuthorityRef: &akoov1alpha1.SecretRef{Name: "controller-ca", Namespace: "default"},
+							DataNetwork:             akoov1alpha1.DataNetwork{CIDR: tc.dataNetwork},
+						},
+					},
+					&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "controller-credentials", Namespace: "default"}},
+					&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "controller-ca", Namespace: "default"}},
+				).Build()
+			recorder := record.NewFakeRecorder(100)
+			fakeAviClient := newTestMigrationAviClient(!tc.vsDown)
+			r := &ClusterReconciler{
+				Client:   fc,
+				Recorder: recorder,
+				Haprovider: haprovider.NewProvider(fc, recorder, logr.Discard()).
+					WithClock(clocktesting.NewFakePassiveClock(now)),
+				aviClients: ako_operator.AviClients{
+					NewClient: func(context.Context, client.Client, logr.Logger, *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+						return fakeAviClient, nil
+					},
+				},
+			}
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-cluster",
+					Namespace:   "default",
+					Annotations: map[string]string{akoov1alpha1.HAMigrationAnnotation: tc.phase},
+				},
+			}
+			if !tc.noEndpoint {
+				cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443}
+			}
+			if tc.started != 0 {
+				cluster.Annotations[akoov1alpha1.HAMigrationStartedAnnotation] = now.Add(-tc.started).UTC().Format(time.RFC3339)
+			}
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      haprovider.HAServiceName(cluster),
+					Namespace: cluster.Namespace,
+					Annotations: map[string]string{
+						akoov1alpha1.AkoPreferredIPAnnotation:    "10.0.0.10",
+						akoov1alpha1.AkoVirtualServiceAnnotation: `{"test-cluster.default.k8s.example.com":"vs-uuid"}`,
+						akoov1alpha1.HAServiceAnnotationsKey:     "true",
+						akoov1alpha1.TKGClusterNameLabel:         cluster.Name,
+						akoov1alpha1.TKGClusterNameSpaceLabel:    cluster.Namespace,
+					},
+				},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			}
+			if tc.ingress != "" {
+				service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: tc.ingress}}
+			}
+			if !tc.noService {
+				if err := fc.Create(context.Background(), service); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			res, err := r.reconcileHAMigration(context.Background(), logr.Discard(), cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if phase := cluster.Annotations[akoov1alpha1.HAMigrationAnnotation]; phase != tc.expectedPhase {
+				t.Errorf("expected the migration to be %s, got %s", tc.expectedPhase, phase)
+			}
+			if tc.phase == akoov1alpha1.HAMigrationRequested && tc.expectedPhase == akoov1alpha1.HAMigrationInProgress {
+				if started := cluster.Annotations[akoov1alpha1.HAMigrationStartedAnnotation]; started != now.Format(time.RFC3339) {
+					t.Errorf("expected the migration to start at %s, got %s", now.Format(time.RFC3339), started)
+				}
+			}
+			if wait := !res.IsZero(); wait != tc.expectedWait {
+				t.Errorf("expected waiting for the migration to be %v, got %v", tc.expectedWait, wait)
+			}
+			err = fc.Get(context.Background(), client.ObjectKeyFromObject(service), &corev1.Service{})
+			if tc.expectedPhase == akoov1alpha1.HAMigrationRolledBack {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected the HA service to be deleted, got %v", err)
+				}
+				if !conditions.IsFalse(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition) {
+					t.Errorf("expected the %s condition to be false", akoov1alpha1.AviControlPlaneHAMigratedCondition)
+				}
+			} else if tc.serviceExists && err != nil {
+				t.Errorf("expected the HA service to be kept, got %v", err)
+			}
+			if tc.expectedPhase == akoov1alpha1.HAMigrationCompleted && tc.phase != tc.expectedPhase &&
+				!conditions.IsTrue(cluster, akoov1alpha1.AviControlPlaneHAMigratedCondition) {
+				t.Errorf("expected the %s condition to be true", akoov1alpha1.AviControlPlaneHAMigratedCondition)
+			}
+		})
+	}
+}
+
+// newTestMigrationAviClient returns an avi client whose virtual service of the
+// HA service is up or down, with an API server up in its pool
+func newTestMigrationAviClient(up bool) *aviclient.FakeAviClient {
+	state := "OPER_DOWN"
+	if up {
+		state = "OPER_UP"
+	}
+	fakeAviClient := aviclient.NewFakeAviClient()
+	fakeAviClient.VirtualService.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+		return &models.VirtualService{UUID: ptr.To(uuid), PoolRef: ptr.To("https://avi/api/pool/pool-uuid")}, nil
+	})
+	fakeAviClient.VsInventory.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error) {
+		return &models.VsInventory{
+			Runtime: &models.VsRuntimeSummary{OperStatus: &models.OperationalStatus{State: ptr.To(state)}},
+		}, nil
+	})
+	fakeAviClient.PoolInventory.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error) {
+		return &models.PoolInventory{
+			Runtime: &models.PoolRuntimeSummary{
+				NumServersUp: ptr.To(int64(1)),
+				OperStatus:   &models.OperationalStatus{State: ptr.To("OPER_UP")},
+			},
+		}, nil
+	})
+	return fakeAviClient
+}
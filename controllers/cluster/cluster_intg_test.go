@@ -4,10 +4,9 @@
 package cluster_test
 
 import (
+	"context"
 	"os"
 
-	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
-
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -126,8 +125,8 @@ func intgTestEnsureClusterHAProvider() {
 				})
 
 				BeforeEach(func() {
-					haprovider.QueryFQDN = func(fqdn string) (string, error) {
-						return "10.1.2.1", nil
+					fakeResolver = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+						return []string{"10.1.2.1"}, nil
 					}
 				})
 
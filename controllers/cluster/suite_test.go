@@ -4,6 +4,7 @@
 package cluster_test
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -12,8 +13,10 @@ import (
 	. "github.com/onsi/ginkgo"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/cluster"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/test/builder"
 	testutil "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/test/util"
+	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -23,16 +26,28 @@ import (
 	ctrlmgr "sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// fakeResolver resolves the control plane endpoint FQDNs of the test clusters,
+// the tests replace it to simulate the DNS
+var fakeResolver haprovider.Resolver = haprovider.ResolveFQDN
+
+func resolveFQDN(ctx context.Context, fqdn, ipFamily string) ([]string, error) {
+	return fakeResolver(ctx, fqdn, ipFamily)
+}
+
 // suite is used for unit and integration testing this controller.
 var suite = builder.NewTestSuiteForController(
 	func(mgr ctrlmgr.Manager) error {
 
 		builder.FakeAvi = aviclient.NewFakeAviClient()
 
+		log := ctrl.Log.WithName("controllers").WithName("Cluster")
+		recorder := mgr.GetEventRecorderFor("cluster-controller")
 		if err := (&cluster.ClusterReconciler{
-			Client: mgr.GetClient(),
-			Log:    ctrl.Log.WithName("controllers").WithName("Cluster"),
-			Scheme: mgr.GetScheme(),
+			Client:     mgr.GetClient(),
+			Log:        log,
+			Scheme:     mgr.GetScheme(),
+			Recorder:   recorder,
+			Haprovider: haprovider.NewProvider(mgr.GetClient(), recorder, log).WithResolver(resolveFQDN),
 		}).SetupWithManager(mgr); err != nil {
 			return err
 		}
@@ -51,6 +66,10 @@ var suite = builder.NewTestSuiteForController(
 		if err != nil {
 			return err
 		}
+		err = akov1beta1.AddToScheme(scheme)
+		if err != nil {
+			return err
+		}
 		return nil
 	},
 	filepath.Join(testutil.FindModuleDir("sigs.k8s.io/cluster-api"), "config", "crd", "bases"),
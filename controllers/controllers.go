@@ -4,32 +4,56 @@
 package controllers
 
 import (
+	"context"
+
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/user"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/cluster"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/machine"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
-func SetupReconcilers(mgr ctrl.Manager) error {
+func SetupReconcilers(mgr ctrl.Manager, permissionMatrix *user.PermissionMatrixSource) error {
+	if err := index.AddDefaultIndexes(context.Background(), mgr); err != nil {
+		return err
+	}
+	machineLog := ctrl.Log.WithName("controllers").WithName("Machine")
+	machineRecorder := mgr.GetEventRecorderFor("machine-controller")
 	if err := (&machine.MachineReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Machine"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        machineLog,
+		Scheme:     mgr.GetScheme(),
+		Recorder:   machineRecorder,
+		Haprovider: haprovider.NewProvider(mgr.GetClient(), machineRecorder, machineLog),
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}
 
-	if err := (&akodeploymentconfig.AKODeploymentConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("AKODeploymentConfig"),
-		Scheme: mgr.GetScheme(),
+	adcReconciler := &akodeploymentconfig.AKODeploymentConfigReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("AKODeploymentConfig"),
+		Scheme:           mgr.GetScheme(),
+		PermissionMatrix: permissionMatrix,
+	}
+	if err := adcReconciler.SetupWithManager(mgr); err != nil {
+		return err
+	}
+	// the AKO cluster reconciler shares the avi clients of the AKODeploymentConfig reconciler
+	if err := (&akodeploymentconfig.AKOClusterReconciler{
+		AKODeploymentConfigReconciler: adcReconciler,
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}
+	clusterLog := ctrl.Log.WithName("controllers").WithName("Cluster")
+	clusterRecorder := mgr.GetEventRecorderFor("cluster-controller")
 	if err := (&cluster.ClusterReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Cluster"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        clusterLog,
+		Scheme:     mgr.GetScheme(),
+		Recorder:   clusterRecorder,
+		Haprovider: haprovider.NewProvider(mgr.GetClient(), clusterRecorder, clusterLog),
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}
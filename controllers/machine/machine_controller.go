@@ -5,21 +5,26 @@ package machine
 
 import (
 	"context"
+	"time"
 
 	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/handlers"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -28,6 +33,9 @@ import (
 // SetupWithManager adds this reconciler to a new controller then to the
 // provided manager.
 func (r *MachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Haprovider == nil {
+		return errors.New("machine reconciler requires an HA provider")
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		// Watch Cluster API Machine resources.
 		For(&clusterv1.Machine{}).
@@ -35,6 +43,7 @@ func (r *MachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&clusterv1.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(handlers.MachinesForCluster(r.Client, r.Log)),
 		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: config.Get().Concurrency.Machine}).
 		Complete(r)
 }
 
@@ -42,9 +51,21 @@ type MachineReconciler struct {
 	client.Client
 	Log        logr.Logger
 	Scheme     *runtime.Scheme
+	Recorder   record.EventRecorder
 	Haprovider *haprovider.HAProvider
+
+	// aviClients caches the Avi client of every AKODeploymentConfig
+	aviClients ako_operator.AviClients
 }
 
+// drainRequeueAfter is how long to wait before checking again whether the Avi
+// pool stopped sending traffic to a control plane machine
+const drainRequeueAfter = 10 * time.Second
+
+// PreDrainTimeoutReason is the reason of the event emitted on a machine whose
+// pre-drain hook is released before the Avi pool stopped sending it traffic
+const PreDrainTimeoutReason = "PreDrainTimeout"
+
 func (r *MachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	log := r.Log.WithValues("Machine", req.NamespacedName)
 
@@ -99,11 +120,15 @@ func (r *MachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 	}
 
 	if isVIPProvider {
-		r.Haprovider = haprovider.NewProvider(r.Client, log)
 		if err = r.Haprovider.CreateOrUpdateHAEndpoints(ctx, obj); err != nil {
 			log.Error(err, "Fail to reconcile HA endpoint")
 			return res, err
 		}
+		if res, err := r.reconcilePreDrainHook(ctx, log, obj, cluster); err != nil || !res.IsZero() {
+			return res, err
+		}
+	} else {
+		delete(obj.Annotations, akoov1alpha1.PreDrainAnnotation)
 	}
 
 	// skip reconcile if cluster is using kube-vip to provide load balancer service
@@ -204,3 +229,83 @@ func (r *MachineReconciler) reconcileMachineDeletionHook(
 
 	return res, nil
 }
+
+// reconcilePreDrainHook holds the drain of the control plane machines until
+// the Avi pool of the HA service stops sending them the API server traffic.
+// The hook is added to the running machines; once a machine is deleted and its
+// address removed from the HA Endpoints, the hook is released when the Avi pool
+// member of the machine is removed or disabled, or when the pre-drain timeout
+// passes since the deletion. A non zero result requeues the machine while the
+// drain is held.
+func (r *MachineReconciler) reconcilePreDrainHook(
+	ctx context.Context,
+	log logr.Logger,
+	obj *clusterv1.Machine,
+	cluster *clusterv1.Cluster,
+) (ctrl.Result, error) {
+	if _, ok := obj.Labels[clusterv1.MachineControlPlaneLabel]; !ok {
+		return ctrl.Result{}, nil
+	}
+
+	// nothing to wait for when the whole cluster goes away
+	if !cluster.GetDeletionTimestamp().IsZero() {
+		delete(obj.Annotations, akoov1alpha1.PreDrainAnnotation)
+		return ctrl.Result{}, nil
+	}
+
+	if obj.GetDeletionTimestamp().IsZero() {
+		if cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
+			return ctrl.Result{}, nil
+		}
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string)
+		}
+		obj.Annotations[akoov1alpha1.PreDrainAnnotation] = "ako-operator"
+		return ctrl.Result{}, nil
+	}
+
+	if _, ok := obj.Annotations[akoov1alpha1.PreDrainAnnotation]; !ok {
+		return ctrl.Result{}, nil
+	}
+
+	adc, err := ako_operator.GetAKODeploymentConfigForCluster(ctx, r.Client, log, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if adc == nil {
+		delete(obj.Annotations, akoov1alpha1.PreDrainAnnotation)
+		log.Info("Cluster is not selected by any AKODeploymentConfig, removing pre-drain hook")
+		return ctrl.Result{}, nil
+	}
+
+	remaining := config.Get().Cleanup.PreDrainTimeout.Duration - time.Since(obj.GetDeletionTimestamp().Time)
+	if remaining <= 0 {
+		delete(obj.Annotations, akoov1alpha1.PreDrainAnnotation)
+		log.Info("Machine is not drained from the Avi pool in time, removing pre-drain hook")
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, corev1.EventTypeWarning, PreDrainTimeoutReason,
+				"Released the pre-drain hook after %s though the Avi pool may still send traffic to the machine",
+				config.Get().Cleanup.PreDrainTimeout.Duration)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	aviClient, err := r.aviClients.Get(ctx, r.Client, log, adc)
+	if err != nil {
+		log.Error(err, "Cannot init AVI clients from secrets")
+		return ctrl.Result{}, err
+	}
+
+	drained, err := r.Haprovider.MachineDrained(ctx, aviClient, obj, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !drained {
+		log.Info("Avi pool may still send traffic to the machine, holding the drain", "timeout", remaining)
+		return ctrl.Result{RequeueAfter: min(drainRequeueAfter, remaining)}, nil
+	}
+
+	delete(obj.Annotations, akoov1alpha1.PreDrainAnnotation)
+	log.Info("Machine is drained from the Avi pool, removing pre-drain hook")
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+func TestReconcilePreDrainHook(t *testing.T) {
+	deleted := metav1.Now()
+	expired := metav1.NewTime(deleted.Add(-time.Hour))
+	for _, tc := range []struct {
+		name           string
+		controlPlane   bool
+		machineDeleted bool
+		clusterDeleted bool
+		hook           bool
+		adc            bool
+		memberEnabled  bool
+		expired        bool
+		expectedHook   bool
+		expectedWait   bool
+		expectedEvent  bool
+	}{
+		{
+			name:         "hook added to a running control plane machine",
+			controlPlane: true,
+			adc:          true,
+			expectedHook: true,
+		},
+		{
+			name: "no hook on the worker machines",
+			adc:  true,
+		},
+		{
+			name:           "drain held while the pool member is enabled",
+			controlPlane:   true,
+			machineDeleted: true,
+			hook:           true,
+			adc:            true,
+			memberEnabled:  true,
+			expectedHook:   true,
+			expectedWait:   true,
+		},
+		{
+			name:           "hook released once the pool member is disabled",
+			controlPlane:   true,
+			machineDeleted: true,
+			hook:           true,
+			adc:            true,
+		},
+		{
+			name:           "hook released once the pre-drain timeout passed",
+			controlPlane:   true,
+			machineDeleted: true,
+			hook:           true,
+			adc:            true,
+			memberEnabled:  true,
+			expired:        true,
+			expectedEvent:  true,
+		},
+		{
+			name:           "hook released without AKODeploymentConfig",
+			controlPlane:   true,
+			machineDeleted: true,
+			hook:           true,
+			memberEnabled:  true,
+		},
+		{
+			name:           "hook released when the cluster is deleted",
+			controlPlane:   true,
+			machineDeleted: true,
+			clusterDeleted: true,
+			hook:           true,
+			adc:            true,
+			memberEnabled:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clusterv1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := akoov1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			objs := newTestDrainObjects()
+			if tc.adc {
+				objs = append(objs, newTestDrainADC())
+			}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"}}
+			if tc.clusterDeleted {
+				cluster.DeletionTimestamp = &deleted
+			}
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-machine",
+					Namespace:   "default",
+					Labels:      map[string]string{},
+					Annotations: map[string]string{},
+				},
+				Status: clusterv1.MachineStatus{
+					Addresses: clusterv1.MachineAddresses{{Type: clusterv1.MachineExternalIP, Address: "1.1.1.1"}},
+				},
+			}
+			if tc.controlPlane {
+				machine.Labels[clusterv1.MachineControlPlaneLabel] = ""
+			}
+			if tc.machineDeleted {
+				machine.DeletionTimestamp = &deleted
+			}
+			if tc.expired {
+				machine.DeletionTimestamp = &expired
+			}
+			if tc.hook {
+				machine.Annotations[akoov1alpha1.PreDrainAnnotation] = "ako-operator"
+			}
+
+			fakeAviClient := newTestDrainAviClient(tc.memberEnabled)
+			fc := fake.NewClientBuilder().WithScheme(scheme).
+				WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).WithObjects(objs...).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &MachineReconciler{
+				Client:     fc,
+				Recorder:   recorder,
+				Haprovider: haprovider.NewProvider(fc, recorder, logr.Discard()),
+				aviClients: ako_operator.AviClients{
+					NewClient: func(context.Context, client.Client, logr.Logger, *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+						return fakeAviClient, nil
+					},
+				},
+			}
+
+			res, err := r.reconcilePreDrainHook(context.Background(), logr.Discard(), machine, cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, hook := machine.Annotations[akoov1alpha1.PreDrainAnnotation]; hook != tc.expectedHook {
+				t.Errorf("expected the pre-drain hook to be %v, got %v", tc.expectedHook, hook)
+			}
+			if wait := res.RequeueAfter > 0; wait != tc.expectedWait {
+				t.Errorf("expected waiting for the drain to be %v, got %v", tc.expectedWait, wait)
+			}
+			if event := len(recorder.Events) != 0; event != tc.expectedEvent {
+				t.Errorf("expected the pre-drain timeout event to be %v, got %v", tc.expectedEvent, event)
+			}
+		})
+	}
+}
+
+// newTestDrainObjects returns the management cluster, the HA service of the
+// test cluster and the secrets of the avi controller
+func newTestDrainObjects() []client.Object {
+	return []client.Object{
+		&clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "mgmt",
+				Namespace: akoov1alpha1.TKGSystemNamespace,
+				Labels:    map[string]string{akoov1alpha1.TKGManagememtClusterRoleLabel: ""},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-test-cluster-control-plane", Namespace: "default"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "controller-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("password")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "controller-ca", Namespace: "default"},
+			Data:       map[string][]byte{"certificateAuthorityData": []byte("ca")},
+		},
+	}
+}
+
+func newTestDrainADC() *akoov1alpha1.AKODeploymentConfig {
+	return &akoov1alpha1.AKODeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+		Spec: akoov1alpha1.AKODeploymentConfigSpec{
+			Controller:              "10.0.0.1",
+			AdminCredentialRef:      &akoov1alpha1.SecretRef{Name: "controller-credentials", Namespace: "default"},
+			CertificateAuthorityRef: &akoov1alpha1.SecretRef{Name: "controller-ca", Namespace: "default"},
+		},
+	}
+}
+
+// newTestDrainAviClient serves the virtual service of the HA service of the
+// test cluster, whose pool has the test machine as member
+func newTestDrainAviClient(memberEnabled bool) *aviclient.FakeAviClient {
+	fakeAviClient := aviclient.NewFakeAviClient()
+	fakeAviClient.VirtualService.SetGetByNameFn(func(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+		return &models.VirtualService{Name: ptr.To(name), PoolRef: ptr.To("https://avi/api/pool/pool-uuid")}, nil
+	})
+	fakeAviClient.Pool.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+		return &models.Pool{Name: ptr.To(uuid), Servers: []*models.Server{
+			{IP: &models.IPAddr{Addr: ptr.To("1.1.1.1")}, Enabled: ptr.To(memberEnabled)},
+		}}, nil
+	})
+	return fakeAviClient
+}
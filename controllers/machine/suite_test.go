@@ -11,6 +11,7 @@ import (
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/machine"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/test/builder"
 	testutil "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/test/util"
 	corev1 "k8s.io/api/core/v1"
@@ -28,10 +29,14 @@ var suite = builder.NewTestSuiteForController(
 
 		builder.FakeAvi = aviclient.NewFakeAviClient()
 
+		log := ctrl.Log.WithName("controllers").WithName("Machine")
+		recorder := mgr.GetEventRecorderFor("machine-controller")
 		if err := (&machine.MachineReconciler{
-			Client: mgr.GetClient(),
-			Log:    ctrl.Log.WithName("controllers").WithName("Machine"),
-			Scheme: mgr.GetScheme(),
+			Client:     mgr.GetClient(),
+			Log:        log,
+			Scheme:     mgr.GetScheme(),
+			Recorder:   recorder,
+			Haprovider: haprovider.NewProvider(mgr.GetClient(), recorder, log),
 		}).SetupWithManager(mgr); err != nil {
 			return err
 		}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/pflag"
 	runv1alpha3 "github.com/vmware-tanzu/tanzu-framework/apis/run/v1alpha3"
+	akov1alpha2 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1alpha2"
 	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,6 +21,7 @@ import (
 	logsv1 "k8s.io/component-base/logs/api/v1"
 	"k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -28,7 +30,9 @@ import (
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/user"
 	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	operatorconfig "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 )
 
 var (
@@ -38,6 +42,9 @@ var (
 	metricsAddr          string
 	enableLeaderElection bool
 	profilerAddress      string
+	validationMode       string
+	permissionMatrix     string
+	configFile           string
 )
 
 func initLog() {
@@ -49,8 +56,10 @@ func init() {
 	// ignoring errors
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = clusterv1.AddToScheme(scheme)
+	_ = controlplanev1.AddToScheme(scheme)
 	_ = akoov1alpha1.AddToScheme(scheme)
 	_ = akov1beta1.AddToScheme(scheme)
+	_ = akov1alpha2.AddToScheme(scheme)
 	_ = runv1alpha3.AddToScheme(scheme)
 }
 
@@ -61,6 +70,9 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&metricsAddr, "metrics-addr", "localhost:8080", "The address the metric endpoint binds to.")
 	fs.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	fs.StringVar(&profilerAddress, "profiler-addr", "", "Bind address to expose the pprof profiler")
+	fs.StringVar(&validationMode, "webhook-validation-mode", akoov1alpha1.ValidationModeOnline, "AKODeploymentConfig webhook validation mode, online validates against the NSX Advanced Load Balancer controller, offline only validates the object structure. Objects can be created offline with the "+akoov1alpha1.AKODeploymentConfigValidationModeAnnotation+" annotation.")
+	fs.StringVar(&configFile, "config", "", "The operator configuration file. Without it the operator is configured with the legacy environment variables. The flags set on the command line take precedence over the file.")
+	fs.StringVar(&permissionMatrix, "permission-matrix-configmap", "", "The namespace/name of a ConfigMap overriding the embedded AKO user role permission matrix under its "+user.PermissionMatrixDataKey+" key.")
 }
 
 func main() {
@@ -80,6 +92,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := loadConfig(); err != nil {
+		setupLog.Error(err, "Invalid operator configuration")
+		os.Exit(1)
+	}
+
 	if profilerAddress != "" {
 		setupLog.Info(
 			"Profiler listening for requests",
@@ -108,15 +125,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = controllers.SetupReconcilers(mgr)
+	permissionMatrixSource, err := user.NewPermissionMatrixSource(permissionMatrix)
+	if err != nil {
+		setupLog.Error(err, "invalid permission matrix configmap")
+		os.Exit(1)
+	}
+
+	err = controllers.SetupReconcilers(mgr, permissionMatrixSource)
 	if err != nil {
 		setupLog.Error(err, "Unable to setup reconcilers")
 		os.Exit(1)
 	}
 
+	if configFile != "" && operatorconfig.Enabled(operatorconfig.ConfigHotReload) {
+		reloader, err := operatorconfig.NewReloader(configFile, ctrl.Log.WithName("config"))
+		if err == nil {
+			err = mgr.Add(reloader)
+		}
+		if err != nil {
+			setupLog.Error(err, "Unable to watch operator configuration")
+			os.Exit(1)
+		}
+	}
+
 	printRunningEnv()
 
 	//setup webhook here
+	if err = akoov1alpha1.SetValidationMode(validationMode); err != nil {
+		setupLog.Error(err, "invalid webhook validation mode")
+		os.Exit(1)
+	}
 	if err = (&akoov1alpha1.AKODeploymentConfig{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "AKODeploymentConfig")
 		os.Exit(1)
@@ -129,6 +167,30 @@ func main() {
 	}
 }
 
+// loadConfig validates the operator configuration, read from the configuration file
+// or the legacy environment variables, and applies it to the flags which were not
+// set on the command line
+func loadConfig() error {
+	if configFile != "" {
+		c, err := operatorconfig.Load(configFile)
+		if err != nil {
+			return err
+		}
+		operatorconfig.Set(c)
+	} else if _, err := operatorconfig.FromEnvironment(); err != nil {
+		return err
+	}
+
+	c := operatorconfig.Get()
+	if !pflag.CommandLine.Changed("webhook-validation-mode") {
+		validationMode = c.WebhookValidationMode
+	}
+	if !pflag.CommandLine.Changed("permission-matrix-configmap") {
+		permissionMatrix = c.PermissionMatrixConfigMap
+	}
+	return nil
+}
+
 func runProfiler(addr string) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -8,9 +8,9 @@ import (
 
 	"github.com/go-logr/logr"
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,69 +33,101 @@ func ListAkoDeploymentConfigSelectClusters(
 	}...); err != nil {
 		return nil, err
 	}
-	// remove clusters that:
-	// 1. not ready
-	// 2. management cluster
-	// 3. previously selected by other adc objects
+	var akoDeploymentConfigs akoov1alpha1.AKODeploymentConfigList
+	if err := kclient.List(ctx, &akoDeploymentConfigs); err != nil {
+		return nil, err
+	}
+	// remove clusters that current akodeploymentconfig doesn't manage
 	var newItems []clusterv1.Cluster
-	var allErrs []error
 	for _, cluster := range clusters.Items {
-		if !SkipCluster(&cluster) {
-			// management cluster can't be selected by other adc objects
-			// except the management cluster AKODeploymentConfig
-			if cluster.Namespace == akoov1alpha1.TKGSystemNamespace &&
-				obj.Name != akoov1alpha1.ManagementClusterAkoDeploymentConfig {
-				continue
-			}
-			adcName, exist := cluster.Labels[akoov1alpha1.AviClusterLabel]
-			// if cluster is already selected by other customized adc objects, skip
-			// only clusters selected by default adc with empty selector object can be overrided
-			if exist && adcName != obj.Name {
-				if !isDefaultWcADC(adcName) || !defaultADCHasEmptySelector(ctx, kclient) {
-					continue
-				}
-			}
+		if SelectAKODeploymentConfig(&cluster, akoDeploymentConfigs.Items).Manages(obj) {
 			newItems = append(newItems, cluster)
 		}
 	}
 	clusters.Items = newItems
-	return &clusters, kerrors.NewAggregate(allErrs)
+	return &clusters, nil
+}
+
+// AKODeploymentConfigSelectsCluster checks if the akodeploymentconfig manages the
+// cluster the same way ListAkoDeploymentConfigSelectClusters does
+func AKODeploymentConfigSelectsCluster(
+	ctx context.Context,
+	kclient client.Client,
+	obj *akoov1alpha1.AKODeploymentConfig,
+	cluster *clusterv1.Cluster) (bool, error) {
+	selection, err := GetAKODeploymentConfigSelection(ctx, kclient, cluster)
+	if err != nil {
+		return false, err
+	}
+	return selection.Manages(obj), nil
+}
+
+// GetAKODeploymentConfigSelection returns the decision of
+// SelectAKODeploymentConfig for the cluster among all the akodeploymentconfig
+// objects. Only the akodeploymentconfig objects which may select the cluster are
+// read, through the index.AKODeploymentConfigSelectorField index of the cache.
+func GetAKODeploymentConfigSelection(
+	ctx context.Context,
+	kclient client.Client,
+	cluster *clusterv1.Cluster) (*ClusterSelection, error) {
+	candidates := map[string]akoov1alpha1.AKODeploymentConfig{}
+	values := []string{index.AnyClusterLabel}
+	for key, value := range cluster.Labels {
+		values = append(values, index.ClusterLabel(key, value))
+	}
+	for _, value := range values {
+		var akoDeploymentConfigs akoov1alpha1.AKODeploymentConfigList
+		if err := kclient.List(ctx, &akoDeploymentConfigs, client.MatchingFields{
+			index.AKODeploymentConfigSelectorField: value,
+		}); err != nil {
+			return nil, err
+		}
+		for _, adc := range akoDeploymentConfigs.Items {
+			candidates[adc.Name] = adc
+		}
+	}
+
+	// the akodeploymentconfig in the avi label and the default one take part in
+	// the selection even when their selector doesn't match the cluster
+	for _, name := range []string{cluster.Labels[akoov1alpha1.AviClusterLabel], akoov1alpha1.WorkloadClusterAkoDeploymentConfig} {
+		if _, ok := candidates[name]; ok || name == "" {
+			continue
+		}
+		adc := &akoov1alpha1.AKODeploymentConfig{}
+		if err := kclient.Get(ctx, client.ObjectKey{Name: name}, adc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		candidates[name] = *adc
+	}
+
+	adcs := make([]akoov1alpha1.AKODeploymentConfig, 0, len(candidates))
+	for _, adc := range candidates {
+		adcs = append(adcs, adc)
+	}
+	return SelectAKODeploymentConfig(cluster, adcs), nil
 }
 
 // GetAKODeploymentConfigForCluster return the akodeloymentconfig object which selects
-// current cluster
+// current cluster, whether the cluster is ready or not
 func GetAKODeploymentConfigForCluster(
 	ctx context.Context,
 	kclient client.Client,
 	log logr.Logger,
 	cluster *clusterv1.Cluster) (*akoov1alpha1.AKODeploymentConfig, error) {
-	// list all the akodeploymentconfig objects
-	var akoDeploymentConfigs akoov1alpha1.AKODeploymentConfigList
-	if err := kclient.List(ctx, &akoDeploymentConfigs, []client.ListOption{}...); err != nil {
+	selection, err := GetAKODeploymentConfigSelection(ctx, kclient, cluster)
+	if err != nil {
 		log.Error(err, "Failed to list all AKODeploymentConfig objects")
 		return nil, err
 	}
-	// find which adc matches current cluster
-	var defaultAdc akoov1alpha1.AKODeploymentConfig
-	for _, akoDeploymentConfig := range akoDeploymentConfigs.Items {
-		if selector, err := metav1.LabelSelectorAsSelector(&akoDeploymentConfig.Spec.ClusterSelector); err != nil {
-			log.Error(err, "Failed to convert label sector to selector")
-		} else if selector.Empty() {
-			if isDefaultWcADC(akoDeploymentConfig.Name) {
-				defaultAdc = akoDeploymentConfig
-			}
-		} else if selector.Matches(labels.Set(cluster.GetLabels())) {
-			log.Info("cluster is selected by akodeploymentconfig", "adc", akoDeploymentConfig.Name)
-			return &akoDeploymentConfig, nil
-		}
-	}
-	// only default adc with empty selector can select all clusters and return
-	if defaultAdc.Name == akoov1alpha1.WorkloadClusterAkoDeploymentConfig {
-		log.Info("cluster is selected by akodeploymentconfig", "adc", defaultAdc.Name)
-		return &defaultAdc, nil
+	if selection.AKODeploymentConfig == nil {
+		log.Info("cluster is not selected by any akodeploymentconfig objects")
+		return nil, nil
 	}
-	log.Info("cluster is not selected by any akodeploymentconfig objects")
-	return nil, nil
+	log.Info("cluster is selected by akodeploymentconfig", "adc", selection.AKODeploymentConfig.Name, "rule", selection.Rule)
+	return selection.AKODeploymentConfig, nil
 }
 
 // SkipCluster checks if akodeploymentconfig controller should skip reconciling this cluster or not
@@ -111,26 +143,6 @@ func SkipCluster(cluster *clusterv1.Cluster) bool {
 	return false
 }
 
-// isDefaultWcADC check if akodeploymentconfig object is default one
-func isDefaultWcADC(adcName string) bool {
-	return adcName == akoov1alpha1.WorkloadClusterAkoDeploymentConfig
-}
-
-// defaultADCHasEmptySelector checks if default akodeploymentconfig has empty selector
-func defaultADCHasEmptySelector(ctx context.Context, kclient client.Client) bool {
-	var defaultAdc akoov1alpha1.AKODeploymentConfig
-	if err := kclient.Get(ctx, client.ObjectKey{
-		Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
-		&defaultAdc); err != nil {
-		return false
-	}
-	selector, err := metav1.LabelSelectorAsSelector(&defaultAdc.Spec.ClusterSelector)
-	if err != nil {
-		return false
-	}
-	return selector.Empty()
-}
-
 // applyClusterLabel applies the networking.tkg.tanzu.vmware.com/avi label to a Cluster
 func ApplyClusterLabel(log logr.Logger, cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig) {
 	if cluster.Labels == nil {
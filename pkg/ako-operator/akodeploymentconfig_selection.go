@@ -0,0 +1,236 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako_operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+// SelectionRule is a rule of the AKODeploymentConfig selection
+type SelectionRule string
+
+const (
+	// SelectionRuleManagementCluster restricts the management of the management
+	// cluster to the install-ako-for-management-cluster AKODeploymentConfig
+	SelectionRuleManagementCluster SelectionRule = "ManagementCluster"
+	// SelectionRuleClusterLabel keeps the AKODeploymentConfig in the avi label
+	// of the cluster as long as its non empty selector matches the cluster, and
+	// leaves the cluster to it until the cluster is relabeled unless it is the
+	// default AKODeploymentConfig with an empty selector
+	SelectionRuleClusterLabel SelectionRule = "ClusterLabel"
+	// SelectionRuleSelector picks the first AKODeploymentConfig, by name, whose
+	// non empty selector matches the cluster
+	SelectionRuleSelector SelectionRule = "Selector"
+	// SelectionRuleDefault falls back to install-ako-for-all when its selector
+	// is empty
+	SelectionRuleDefault SelectionRule = "Default"
+	// SelectionRuleClusterNotReady defers the management of a cluster which is
+	// not ready
+	SelectionRuleClusterNotReady SelectionRule = "ClusterNotReady"
+)
+
+// SelectionStep records how a rule applied to the cluster
+type SelectionStep struct {
+	Rule                SelectionRule `json:"rule"`
+	AKODeploymentConfig string        `json:"akoDeploymentConfig,omitempty"`
+	Matched             bool          `json:"matched"`
+	Message             string        `json:"message"`
+}
+
+// ClusterSelection is the AKODeploymentConfig selected for a cluster with the
+// trace of the rules which led to it
+type ClusterSelection struct {
+	// AKODeploymentConfig is the selected AKODeploymentConfig, nil when none
+	// selects the cluster
+	AKODeploymentConfig *akoov1alpha1.AKODeploymentConfig
+	// Rule is the rule which selected the AKODeploymentConfig
+	Rule SelectionRule
+	// Skipped is set when the cluster is not managed until it is ready
+	Skipped bool
+	// Trace lists the rules in the order they were evaluated
+	Trace []SelectionStep
+
+	cluster    *clusterv1.Cluster
+	candidates []*akoov1alpha1.AKODeploymentConfig
+}
+
+// Manages tells if the AKODeploymentConfig manages the cluster now. Its selector
+// must match the ready cluster, the management cluster is only managed by
+// install-ako-for-management-cluster and a cluster labeled with another
+// AKODeploymentConfig is left to it, unless that is the default one with an
+// empty selector.
+func (s *ClusterSelection) Manages(obj *akoov1alpha1.AKODeploymentConfig) bool {
+	managed, _, _ := s.manages(obj)
+	return managed
+}
+
+// manages returns the rule and the reason why the AKODeploymentConfig doesn't
+// manage the cluster
+func (s *ClusterSelection) manages(obj *akoov1alpha1.AKODeploymentConfig) (bool, SelectionRule, string) {
+	selector, err := metav1.LabelSelectorAsSelector(&obj.Spec.ClusterSelector)
+	if err != nil || !selector.Matches(labels.Set(s.cluster.Labels)) {
+		return false, SelectionRuleSelector, fmt.Sprintf("the selector of %s doesn't match the cluster labels", obj.Name)
+	}
+	if SkipCluster(s.cluster) {
+		return false, SelectionRuleClusterNotReady, fmt.Sprintf("the cluster is not ready, %s manages it once it is", obj.Name)
+	}
+	if s.cluster.Namespace == akoov1alpha1.TKGSystemNamespace && obj.Name != akoov1alpha1.ManagementClusterAkoDeploymentConfig {
+		return false, SelectionRuleManagementCluster, fmt.Sprintf("the management cluster can only be managed by %s",
+			akoov1alpha1.ManagementClusterAkoDeploymentConfig)
+	}
+	if name, ok := s.cluster.Labels[akoov1alpha1.AviClusterLabel]; ok && name != obj.Name &&
+		(name != akoov1alpha1.WorkloadClusterAkoDeploymentConfig || !s.defaultHasEmptySelector()) {
+		return false, SelectionRuleClusterLabel, fmt.Sprintf("the cluster is left to %s in its label until it is relabeled", name)
+	}
+	return true, "", ""
+}
+
+// defaultHasEmptySelector checks if the default AKODeploymentConfig exists with
+// an empty selector
+func (s *ClusterSelection) defaultHasEmptySelector() bool {
+	for _, adc := range s.candidates {
+		if adc.Name != akoov1alpha1.WorkloadClusterAkoDeploymentConfig {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&adc.Spec.ClusterSelector)
+		return err == nil && selector.Empty()
+	}
+	return false
+}
+
+// TraceJSON returns the trace in the compact form recorded in the cluster
+// annotation
+func (s *ClusterSelection) TraceJSON() string {
+	out, err := json.Marshal(s.Trace)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func (s *ClusterSelection) step(rule SelectionRule, adc string, matched bool, format string, args ...interface{}) {
+	s.Trace = append(s.Trace, SelectionStep{
+		Rule:                rule,
+		AKODeploymentConfig: adc,
+		Matched:             matched,
+		Message:             fmt.Sprintf(format, args...),
+	})
+}
+
+func (s *ClusterSelection) selectBy(rule SelectionRule, obj *akoov1alpha1.AKODeploymentConfig) {
+	s.AKODeploymentConfig = obj
+	s.Rule = rule
+}
+
+// SelectAKODeploymentConfig decides which of the AKODeploymentConfigs selects
+// the cluster. The rules apply in order:
+//  1. the AKODeploymentConfig in the avi label of the cluster keeps it as long as
+//     its non empty selector matches the cluster
+//  2. otherwise the first AKODeploymentConfig, by name, whose non empty selector
+//     matches the cluster selects it
+//  3. otherwise install-ako-for-all selects the cluster when its selector is
+//     empty
+//
+// The selected AKODeploymentConfig only manages the cluster as Manages allows,
+// the trace ends with the rule which prevents it. A selected cluster which is
+// not ready is skipped until it is, unless it is being deleted or runs in the
+// bootstrap cluster.
+func SelectAKODeploymentConfig(cluster *clusterv1.Cluster, adcs []akoov1alpha1.AKODeploymentConfig) *ClusterSelection {
+	s := &ClusterSelection{cluster: cluster}
+
+	s.candidates = make([]*akoov1alpha1.AKODeploymentConfig, 0, len(adcs))
+	for i := range adcs {
+		s.candidates = append(s.candidates, &adcs[i])
+	}
+	sort.Slice(s.candidates, func(i, j int) bool { return s.candidates[i].Name < s.candidates[j].Name })
+
+	if name, ok := cluster.Labels[akoov1alpha1.AviClusterLabel]; ok {
+		s.selectByLabel(cluster, name, s.candidates)
+	}
+	if s.AKODeploymentConfig == nil {
+		s.selectBySelector(cluster, s.candidates)
+	}
+	if s.AKODeploymentConfig == nil {
+		s.selectByDefault(s.candidates)
+	}
+
+	if s.AKODeploymentConfig != nil {
+		if managed, rule, reason := s.manages(s.AKODeploymentConfig); !managed {
+			s.Skipped = rule == SelectionRuleClusterNotReady
+			s.step(rule, s.AKODeploymentConfig.Name, true, "%s", reason)
+		}
+	}
+	return s
+}
+
+func (s *ClusterSelection) selectByLabel(cluster *clusterv1.Cluster, name string, candidates []*akoov1alpha1.AKODeploymentConfig) {
+	var labeled *akoov1alpha1.AKODeploymentConfig
+	for _, adc := range candidates {
+		if adc.Name == name {
+			labeled = adc
+		}
+	}
+	if labeled == nil {
+		s.step(SelectionRuleClusterLabel, name, false, "the cluster is labeled with %s which doesn't exist", name)
+		return
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labeled.Spec.ClusterSelector)
+	switch {
+	case err != nil:
+		s.step(SelectionRuleClusterLabel, name, false, "the cluster is labeled with %s whose selector is invalid: %v", name, err)
+	case selector.Empty():
+		s.step(SelectionRuleClusterLabel, name, false, "the cluster is labeled with %s whose empty selector can be overridden", name)
+	case !selector.Matches(labels.Set(cluster.Labels)):
+		s.step(SelectionRuleClusterLabel, name, false, "the cluster is labeled with %s whose selector %s no longer matches it", name, selector)
+	default:
+		s.step(SelectionRuleClusterLabel, name, true, "the cluster is labeled with %s whose selector %s still matches it", name, selector)
+		s.selectBy(SelectionRuleClusterLabel, labeled)
+	}
+}
+
+func (s *ClusterSelection) selectBySelector(cluster *clusterv1.Cluster, candidates []*akoov1alpha1.AKODeploymentConfig) {
+	for _, adc := range candidates {
+		selector, err := metav1.LabelSelectorAsSelector(&adc.Spec.ClusterSelector)
+		switch {
+		case err != nil:
+			s.step(SelectionRuleSelector, adc.Name, false, "the selector is invalid: %v", err)
+		case selector.Empty():
+			// only the default AKODeploymentConfig has an empty selector, it
+			// is the last resort
+			continue
+		case !selector.Matches(labels.Set(cluster.Labels)):
+			s.step(SelectionRuleSelector, adc.Name, false, "the selector %s doesn't match the cluster labels", selector)
+		default:
+			s.step(SelectionRuleSelector, adc.Name, true, "the selector %s matches the cluster labels", selector)
+			s.selectBy(SelectionRuleSelector, adc)
+			return
+		}
+	}
+	s.step(SelectionRuleSelector, "", false, "no AKODeploymentConfig selector matches the cluster labels")
+}
+
+func (s *ClusterSelection) selectByDefault(candidates []*akoov1alpha1.AKODeploymentConfig) {
+	for _, adc := range candidates {
+		if adc.Name != akoov1alpha1.WorkloadClusterAkoDeploymentConfig {
+			continue
+		}
+		if selector, err := metav1.LabelSelectorAsSelector(&adc.Spec.ClusterSelector); err != nil || !selector.Empty() {
+			// a non empty default selector was evaluated with the others
+			return
+		}
+		s.step(SelectionRuleDefault, adc.Name, true, "the default AKODeploymentConfig has an empty selector and selects every cluster")
+		s.selectBy(SelectionRuleDefault, adc)
+		return
+	}
+	s.step(SelectionRuleDefault, akoov1alpha1.WorkloadClusterAkoDeploymentConfig, false,
+		"there is no default AKODeploymentConfig with an empty selector")
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako_operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+const benchADCs = 1000
+
+// countingClient counts the objects the lookups read, the fake client scans
+// every object on an indexed List while the cache only deep copies the
+// objects of the index, which is what the objects/op metric reports
+type countingClient struct {
+	client.Client
+	read int
+}
+
+func (c *countingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.read++
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *countingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	c.read += meta.LenList(list)
+	return nil
+}
+
+func newBenchClient(b *testing.B) *countingClient {
+	scheme := runtime.NewScheme()
+	if err := akoov1alpha1.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+	objs := make([]client.Object, 0, benchADCs+1)
+	objs = append(objs, &akoov1alpha1.AKODeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+	})
+	for i := 0; i < benchADCs; i++ {
+		objs = append(objs, &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("adc-%d", i)},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": fmt.Sprintf("team-%d", i)}},
+			},
+		})
+	}
+	return &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+		WithObjects(objs...).Build()}
+}
+
+// BenchmarkGetAKODeploymentConfigForCluster looks up the AKODeploymentConfig of
+// a cluster among benchADCs AKODeploymentConfigs, through the index like the
+// controllers do and by reading all of them like kubectl-ako explain does
+func BenchmarkGetAKODeploymentConfigForCluster(b *testing.B) {
+	ctx := context.Background()
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+		Name:      "workload-cls",
+		Namespace: "default",
+		Labels:    map[string]string{"team": "team-0", "env": "dev"},
+	}}
+	conditions.MarkTrue(cluster, clusterv1.ReadyCondition)
+
+	b.Run("list", func(b *testing.B) {
+		c := newBenchClient(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var adcs akoov1alpha1.AKODeploymentConfigList
+			if err := c.List(ctx, &adcs); err != nil {
+				b.Fatal(err)
+			}
+			if SelectAKODeploymentConfig(cluster, adcs.Items).AKODeploymentConfig == nil {
+				b.Fatal("the cluster is not selected")
+			}
+		}
+		b.ReportMetric(float64(c.read)/float64(b.N), "objects/op")
+	})
+	b.Run("index", func(b *testing.B) {
+		c := newBenchClient(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			adc, err := GetAKODeploymentConfigForCluster(ctx, c, logr.Discard(), cluster)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if adc == nil {
+				b.Fatal("the cluster is not selected")
+			}
+		}
+		b.ReportMetric(float64(c.read)/float64(b.N), "objects/op")
+	})
+}
@@ -0,0 +1,299 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako_operator
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+func selectionADC(name string, selector map[string]string) akoov1alpha1.AKODeploymentConfig {
+	return akoov1alpha1.AKODeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: akoov1alpha1.AKODeploymentConfigSpec{
+			ClusterSelector: metav1.LabelSelector{MatchLabels: selector},
+		},
+	}
+}
+
+func selectionCluster(namespace string, labels map[string]string) *clusterv1.Cluster {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: namespace, Labels: labels},
+	}
+	conditions.MarkTrue(cluster, clusterv1.ReadyCondition)
+	return cluster
+}
+
+func notReady(cluster *clusterv1.Cluster) *clusterv1.Cluster {
+	conditions.MarkFalse(cluster, clusterv1.ReadyCondition, "Provisioning", clusterv1.ConditionSeverityInfo, "")
+	return cluster
+}
+
+func deleting(cluster *clusterv1.Cluster) *clusterv1.Cluster {
+	cluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	return notReady(cluster)
+}
+
+// selectionClient returns a client of the AKODeploymentConfigs with the index
+// GetAKODeploymentConfigSelection reads them through
+func selectionClient(adcs []akoov1alpha1.AKODeploymentConfig) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(akoov1alpha1.AddToScheme(scheme)).To(Succeed())
+	objs := make([]client.Object, 0, len(adcs))
+	for i := range adcs {
+		objs = append(objs, adcs[i].DeepCopy())
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+		WithObjects(objs...).Build()
+}
+
+var _ = Describe("AKODeploymentConfig selection", func() {
+	mgmtLabel := map[string]string{"cluster-role.tkg.tanzu.vmware.com/management": ""}
+	defaultADC := selectionADC(akoov1alpha1.WorkloadClusterAkoDeploymentConfig, nil)
+	mgmtADC := selectionADC(akoov1alpha1.ManagementClusterAkoDeploymentConfig, mgmtLabel)
+	teamA := selectionADC("team-a", map[string]string{"team": "a"})
+	teamB := selectionADC("team-b", map[string]string{"team": "a", "env": "dev"})
+	allADCs := []akoov1alpha1.AKODeploymentConfig{teamB, defaultADC, mgmtADC, teamA}
+
+	for _, tc := range []struct {
+		name      string
+		cluster   *clusterv1.Cluster
+		adcs      []akoov1alpha1.AKODeploymentConfig
+		bootstrap bool
+		expected  string
+		rule      SelectionRule
+		skipped   bool
+		unmanaged bool
+		trace     []SelectionRule
+	}{
+		{
+			name:    "no AKODeploymentConfig",
+			cluster: selectionCluster("default", nil),
+			trace:   []SelectionRule{SelectionRuleSelector, SelectionRuleDefault},
+		},
+		{
+			name:     "management cluster selected by the management AKODeploymentConfig",
+			cluster:  selectionCluster(akoov1alpha1.TKGSystemNamespace, mgmtLabel),
+			adcs:     allADCs,
+			expected: akoov1alpha1.ManagementClusterAkoDeploymentConfig,
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleSelector},
+		},
+		{
+			name:      "management cluster selected but not managed by the default AKODeploymentConfig",
+			cluster:   selectionCluster(akoov1alpha1.TKGSystemNamespace, nil),
+			adcs:      []akoov1alpha1.AKODeploymentConfig{defaultADC},
+			expected:  akoov1alpha1.WorkloadClusterAkoDeploymentConfig,
+			rule:      SelectionRuleDefault,
+			unmanaged: true,
+			trace:     []SelectionRule{SelectionRuleSelector, SelectionRuleDefault, SelectionRuleManagementCluster},
+		},
+		{
+			name:      "management cluster selected but not managed by a matching workload AKODeploymentConfig",
+			cluster:   selectionCluster(akoov1alpha1.TKGSystemNamespace, map[string]string{"team": "a"}),
+			adcs:      allADCs,
+			expected:  "team-a",
+			rule:      SelectionRuleSelector,
+			unmanaged: true,
+			trace:     []SelectionRule{SelectionRuleSelector, SelectionRuleSelector, SelectionRuleManagementCluster},
+		},
+		{
+			name:      "management cluster labeled with a workload AKODeploymentConfig",
+			cluster:   selectionCluster(akoov1alpha1.TKGSystemNamespace, map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: "team-a"}),
+			adcs:      allADCs,
+			expected:  "team-a",
+			rule:      SelectionRuleClusterLabel,
+			unmanaged: true,
+			trace:     []SelectionRule{SelectionRuleClusterLabel, SelectionRuleManagementCluster},
+		},
+		{
+			name:     "workload cluster with the management labels",
+			cluster:  selectionCluster("default", mgmtLabel),
+			adcs:     allADCs,
+			expected: akoov1alpha1.ManagementClusterAkoDeploymentConfig,
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleSelector},
+		},
+		{
+			name:     "first matching selector by name",
+			cluster:  selectionCluster("default", map[string]string{"team": "a", "env": "dev"}),
+			adcs:     allADCs,
+			expected: "team-a",
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleSelector, SelectionRuleSelector},
+		},
+		{
+			name:     "label kept while its selector matches",
+			cluster:  selectionCluster("default", map[string]string{"team": "a", "env": "dev", akoov1alpha1.AviClusterLabel: "team-b"}),
+			adcs:     allADCs,
+			expected: "team-b",
+			rule:     SelectionRuleClusterLabel,
+			trace:    []SelectionRule{SelectionRuleClusterLabel},
+		},
+		{
+			name:      "label left until relabeled once its selector no longer matches",
+			cluster:   selectionCluster("default", map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: "team-b"}),
+			adcs:      allADCs,
+			expected:  "team-a",
+			rule:      SelectionRuleSelector,
+			unmanaged: true,
+			trace:     []SelectionRule{SelectionRuleClusterLabel, SelectionRuleSelector, SelectionRuleSelector, SelectionRuleClusterLabel},
+		},
+		{
+			name:      "label of a missing AKODeploymentConfig",
+			cluster:   selectionCluster("default", map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: "deleted"}),
+			adcs:      allADCs,
+			expected:  "team-a",
+			rule:      SelectionRuleSelector,
+			unmanaged: true,
+			trace:     []SelectionRule{SelectionRuleClusterLabel, SelectionRuleSelector, SelectionRuleSelector, SelectionRuleClusterLabel},
+		},
+		{
+			name:     "label of the default AKODeploymentConfig overridden by a selector",
+			cluster:  selectionCluster("default", map[string]string{"team": "a", akoov1alpha1.AviClusterLabel: akoov1alpha1.WorkloadClusterAkoDeploymentConfig}),
+			adcs:     allADCs,
+			expected: "team-a",
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleClusterLabel, SelectionRuleSelector, SelectionRuleSelector},
+		},
+		{
+			name:     "default AKODeploymentConfig with an empty selector",
+			cluster:  selectionCluster("default", map[string]string{"team": "c"}),
+			adcs:     allADCs,
+			expected: akoov1alpha1.WorkloadClusterAkoDeploymentConfig,
+			rule:     SelectionRuleDefault,
+			trace:    []SelectionRule{SelectionRuleSelector, SelectionRuleSelector, SelectionRuleSelector, SelectionRuleSelector, SelectionRuleDefault},
+		},
+		{
+			name:     "default AKODeploymentConfig with a matching selector",
+			cluster:  selectionCluster("default", map[string]string{"team": "c"}),
+			adcs:     []akoov1alpha1.AKODeploymentConfig{selectionADC(akoov1alpha1.WorkloadClusterAkoDeploymentConfig, map[string]string{"team": "c"})},
+			expected: akoov1alpha1.WorkloadClusterAkoDeploymentConfig,
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleSelector},
+		},
+		{
+			name:    "default AKODeploymentConfig with a selector not matching",
+			cluster: selectionCluster("default", map[string]string{"team": "d"}),
+			adcs:    []akoov1alpha1.AKODeploymentConfig{selectionADC(akoov1alpha1.WorkloadClusterAkoDeploymentConfig, map[string]string{"team": "c"})},
+			trace:   []SelectionRule{SelectionRuleSelector, SelectionRuleSelector},
+		},
+		{
+			name:    "no default AKODeploymentConfig",
+			cluster: selectionCluster("default", map[string]string{"team": "c"}),
+			adcs:    []akoov1alpha1.AKODeploymentConfig{teamA},
+			trace:   []SelectionRule{SelectionRuleSelector, SelectionRuleSelector, SelectionRuleDefault},
+		},
+		{
+			name:     "cluster not ready",
+			cluster:  notReady(selectionCluster("default", map[string]string{"team": "a"})),
+			adcs:     allADCs,
+			expected: "team-a",
+			rule:     SelectionRuleSelector,
+			skipped:  true,
+			trace:    []SelectionRule{SelectionRuleSelector, SelectionRuleSelector, SelectionRuleClusterNotReady},
+		},
+		{
+			name:     "cluster not ready being deleted",
+			cluster:  deleting(selectionCluster("default", map[string]string{"team": "a"})),
+			adcs:     allADCs,
+			expected: "team-a",
+			rule:     SelectionRuleSelector,
+			trace:    []SelectionRule{SelectionRuleSelector, SelectionRuleSelector},
+		},
+		{
+			name:      "cluster not ready in the bootstrap cluster",
+			cluster:   notReady(selectionCluster("default", map[string]string{"team": "a"})),
+			adcs:      allADCs,
+			bootstrap: true,
+			expected:  "team-a",
+			rule:      SelectionRuleSelector,
+			trace:     []SelectionRule{SelectionRuleSelector, SelectionRuleSelector},
+		},
+	} {
+		tc := tc
+		It("should decide the selection of "+tc.name, func() {
+			c := &config.OperatorConfiguration{BootstrapCluster: tc.bootstrap}
+			c.Default()
+			config.Set(c)
+			defer config.Set(nil)
+
+			s := SelectAKODeploymentConfig(tc.cluster, tc.adcs)
+			if tc.expected == "" {
+				Expect(s.AKODeploymentConfig).To(BeNil())
+			} else {
+				Expect(s.AKODeploymentConfig).NotTo(BeNil())
+				Expect(s.AKODeploymentConfig.Name).To(Equal(tc.expected))
+				Expect(s.Rule).To(Equal(tc.rule))
+				Expect(s.Manages(s.AKODeploymentConfig)).To(Equal(!tc.skipped && !tc.unmanaged))
+			}
+			Expect(s.Skipped).To(Equal(tc.skipped))
+
+			var rules []SelectionRule
+			for _, step := range s.Trace {
+				Expect(step.Message).NotTo(BeEmpty())
+				rules = append(rules, step.Rule)
+			}
+			Expect(rules).To(Equal(tc.trace))
+
+			var recorded []SelectionStep
+			Expect(json.Unmarshal([]byte(s.TraceJSON()), &recorded)).To(Succeed())
+			Expect(recorded).To(Equal(s.Trace))
+
+			// reading only the AKODeploymentConfigs which may select the cluster
+			// through the index comes to the same decision
+			indexed, err := GetAKODeploymentConfigSelection(context.Background(), selectionClient(tc.adcs), tc.cluster)
+			Expect(err).NotTo(HaveOccurred())
+			if tc.expected == "" {
+				Expect(indexed.AKODeploymentConfig).To(BeNil())
+			} else {
+				Expect(indexed.AKODeploymentConfig).NotTo(BeNil())
+				Expect(indexed.AKODeploymentConfig.Name).To(Equal(tc.expected))
+				Expect(indexed.Rule).To(Equal(tc.rule))
+				Expect(indexed.Manages(indexed.AKODeploymentConfig)).To(Equal(!tc.skipped && !tc.unmanaged))
+			}
+			Expect(indexed.Skipped).To(Equal(tc.skipped))
+		})
+	}
+
+	It("should let every AKODeploymentConfig which can manage the cluster manage it", func() {
+		cluster := selectionCluster("default", map[string]string{"team": "a", "env": "dev", akoov1alpha1.AviClusterLabel: akoov1alpha1.WorkloadClusterAkoDeploymentConfig})
+		s := SelectAKODeploymentConfig(cluster, allADCs)
+		Expect(s.Manages(&teamA)).To(BeTrue())
+		Expect(s.Manages(&teamB)).To(BeTrue())
+		Expect(s.Manages(&defaultADC)).To(BeTrue())
+		Expect(s.Manages(&mgmtADC)).To(BeFalse())
+
+		cluster.Labels[akoov1alpha1.AviClusterLabel] = "team-b"
+		s = SelectAKODeploymentConfig(cluster, allADCs)
+		Expect(s.Manages(&teamA)).To(BeFalse())
+		Expect(s.Manages(&teamB)).To(BeTrue())
+
+		mgmt := selectionCluster(akoov1alpha1.TKGSystemNamespace, mgmtLabel)
+		s = SelectAKODeploymentConfig(mgmt, allADCs)
+		Expect(s.Manages(&mgmtADC)).To(BeTrue())
+		Expect(s.Manages(&defaultADC)).To(BeFalse())
+	})
+
+	It("should not depend on the order of the AKODeploymentConfigs", func() {
+		cluster := selectionCluster("default", map[string]string{"team": "a", "env": "dev"})
+		reversed := []akoov1alpha1.AKODeploymentConfig{teamA, mgmtADC, defaultADC, teamB}
+		Expect(SelectAKODeploymentConfig(cluster, reversed).Trace).To(Equal(SelectAKODeploymentConfig(cluster, allADCs).Trace))
+	})
+})
@@ -0,0 +1,128 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako_operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+// AviClientFingerprint identifies the avi controller of the AKODeploymentConfig
+// together with the content of its credentials and certificate authority
+// secrets, the avi clients built for it are reused while it doesn't change
+func AviClientFingerprint(ctx context.Context, c client.Client, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(obj.Spec.Controller))
+	for _, ref := range []*akoov1alpha1.SecretRef{obj.Spec.AdminCredentialRef, obj.Spec.CertificateAuthorityRef} {
+		if ref == nil {
+			return "", aviclient.ErrEmptyInput
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				log.Info("Cannot find referenced secret " + ref.Namespace + "/" + ref.Name + " requeue the request")
+			} else {
+				log.Error(err, "Failed to find referenced "+ref.Namespace+"/"+ref.Name+" Secret")
+			}
+			return "", err
+		}
+		keys := make([]string, 0, len(secret.Data))
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "\x00%s/%s\x00%s\x00", ref.Namespace, ref.Name, k)
+			h.Write(secret.Data[k])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AviClients caches the avi client of every AKODeploymentConfig by name, a
+// client is built again once the avi controller, credentials or certificate
+// authority of its AKODeploymentConfig change
+type AviClients struct {
+	// NewClient builds the avi client of an AKODeploymentConfig, NewAviClient
+	// when nil, it's replaced in the tests
+	NewClient func(ctx context.Context, c client.Client, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error)
+
+	lock    sync.Mutex
+	clients map[string]cachedAviClient
+}
+
+// cachedAviClient is the avi client built for an AKODeploymentConfig
+type cachedAviClient struct {
+	fingerprint string
+	aviClient   aviclient.Client
+}
+
+// Get returns the avi client of the AKODeploymentConfig
+func (a *AviClients) Get(ctx context.Context, c client.Client, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+	fingerprint, err := AviClientFingerprint(ctx, c, log, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if aviClient, ok := a.cached(obj.Name, fingerprint); ok {
+		return aviClient, nil
+	}
+
+	// logging in to the avi controller goes over the network, the client is
+	// built without holding the lock so other AKODeploymentConfigs aren't
+	// blocked meanwhile
+	newClient := a.NewClient
+	if newClient == nil {
+		newClient = NewAviClient
+	}
+	aviClient, err := newClient(ctx, c, log, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	// another reconcile may have cached a client for the same secrets meanwhile
+	if cached, ok := a.clients[obj.Name]; ok && cached.fingerprint == fingerprint {
+		return cached.aviClient, nil
+	}
+	if a.clients == nil {
+		a.clients = make(map[string]cachedAviClient)
+	}
+	a.clients[obj.Name] = cachedAviClient{fingerprint: fingerprint, aviClient: aviClient}
+	return aviClient, nil
+}
+
+// cached returns the avi client built for the AKODeploymentConfig while its
+// fingerprint didn't change
+func (a *AviClients) cached(name, fingerprint string) (aviclient.Client, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	cached, ok := a.clients[name]
+	if !ok || cached.fingerprint != fingerprint {
+		return nil, false
+	}
+	return cached.aviClient, true
+}
+
+// NewAviClient builds the avi client of the AKODeploymentConfig from its
+// secrets
+func NewAviClient(ctx context.Context, c client.Client, log logr.Logger, obj *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+	return aviclient.NewAviClientFromSecrets(c, ctx, log, obj.Spec.Controller,
+		obj.Spec.AdminCredentialRef.Name, obj.Spec.AdminCredentialRef.Namespace,
+		obj.Spec.CertificateAuthorityRef.Name, obj.Spec.CertificateAuthorityRef.Namespace,
+		obj.Spec.ControllerVersion)
+}
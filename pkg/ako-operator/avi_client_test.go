@@ -0,0 +1,140 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako_operator
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+var _ = Describe("AviClientFingerprint", func() {
+	var (
+		ctx         context.Context
+		c           client.Client
+		adc         *akoov1alpha1.AKODeploymentConfig
+		fingerprint string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-adc"},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				Controller:              "10.0.0.1",
+				AdminCredentialRef:      &akoov1alpha1.SecretRef{Name: "controller-credentials", Namespace: "default"},
+				CertificateAuthorityRef: &akoov1alpha1.SecretRef{Name: "controller-ca", Namespace: "default"},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "controller-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("password")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "controller-ca", Namespace: "default"},
+				Data:       map[string][]byte{"certificateAuthorityData": []byte("ca")},
+			},
+		).Build()
+		var err error
+		fingerprint, err = AviClientFingerprint(ctx, c, logr.Discard(), adc)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should not change while the controller and secrets don't", func() {
+		Expect(AviClientFingerprint(ctx, c, logr.Discard(), adc)).Should(Equal(fingerprint))
+	})
+
+	It("should change with the avi controller", func() {
+		adc.Spec.Controller = "10.0.0.2"
+		Expect(AviClientFingerprint(ctx, c, logr.Discard(), adc)).ShouldNot(Equal(fingerprint))
+	})
+
+	It("should change with the credentials", func() {
+		credentials := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "controller-credentials", Namespace: "default"}, credentials)).To(Succeed())
+		credentials.Data["password"] = []byte("rotated")
+		Expect(c.Update(ctx, credentials)).To(Succeed())
+		Expect(AviClientFingerprint(ctx, c, logr.Discard(), adc)).ShouldNot(Equal(fingerprint))
+	})
+
+	It("should fail when a secret is missing", func() {
+		adc.Spec.CertificateAuthorityRef.Name = "missing"
+		_, err := AviClientFingerprint(ctx, c, logr.Discard(), adc)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	When("the avi clients are cached", func() {
+		var (
+			aviClients *AviClients
+			built      int
+		)
+
+		BeforeEach(func() {
+			built = 0
+			aviClients = &AviClients{
+				NewClient: func(context.Context, client.Client, logr.Logger, *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+					built++
+					return aviclient.NewFakeAviClient(), nil
+				},
+			}
+			_, err := aviClients.Get(ctx, c, logr.Discard(), adc)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should reuse the client while the controller and secrets don't change", func() {
+			_, err := aviClients.Get(ctx, c, logr.Discard(), adc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(built).Should(Equal(1))
+		})
+
+		It("should build the client again once the credentials change", func() {
+			credentials := &corev1.Secret{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: "controller-credentials", Namespace: "default"}, credentials)).To(Succeed())
+			credentials.Data["password"] = []byte("rotated")
+			Expect(c.Update(ctx, credentials)).To(Succeed())
+			_, err := aviClients.Get(ctx, c, logr.Discard(), adc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(built).Should(Equal(2))
+		})
+
+		It("should return the cached client while building the client of another AKODeploymentConfig", func() {
+			building, release := make(chan struct{}), make(chan struct{})
+			aviClients.NewClient = func(context.Context, client.Client, logr.Logger, *akoov1alpha1.AKODeploymentConfig) (aviclient.Client, error) {
+				close(building)
+				<-release
+				return aviclient.NewFakeAviClient(), nil
+			}
+			other := adc.DeepCopy()
+			other.Name = "other-adc"
+			otherDone := make(chan error, 1)
+			go func() {
+				_, err := aviClients.Get(ctx, c, logr.Discard(), other)
+				otherDone <- err
+			}()
+			<-building
+
+			cachedDone := make(chan error, 1)
+			go func() {
+				_, err := aviClients.Get(ctx, c, logr.Discard(), adc)
+				cachedDone <- err
+			}()
+			Eventually(cachedDone).Should(Receive(BeNil()))
+			close(release)
+			Eventually(otherDone).Should(Receive(BeNil()))
+		})
+	})
+})
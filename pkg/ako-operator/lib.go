@@ -4,35 +4,45 @@
 package ako_operator
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
-	"strconv"
+	"strings"
+	"text/template"
 
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 )
 
 // Legacy cluster environment variables
 const (
 	// DeployInBootstrapCluster - defines if ako operator is deployed in bootstrap cluster
-	DeployInBootstrapCluster = "bootstrap_cluster"
+	DeployInBootstrapCluster = config.BootstrapClusterEnv
 
 	// IsControlPlaneHAProvider - defines if ako operator is going to provide control plane HA
-	IsControlPlaneHAProvider = "avi_control_plane_ha_provider"
+	IsControlPlaneHAProvider = config.ControlPlaneHAProviderEnv
 
 	// ClusterControlPlaneAnnotations - defines cluster control plane endpoint
 	ClusterControlPlaneAnnotations = "tkg.tanzu.vmware.com/cluster-controlplane-endpoint"
 
 	// ControlPlaneEndpointPort - defines the control plane endpoint port
-	ControlPlaneEndpointPort = "control_plane_endpoint_port"
+	ControlPlaneEndpointPort = config.ControlPlaneEndpointPortEnv
 )
 
+// ControlPlanePortName is the name of the API server port in the HA service and
+// endpoints when the control plane exposes extra ports
+const ControlPlanePortName = "apiserver"
+
 // ClusterClass Env variables
 const (
 	// ClusterClassEnabled - helps check if cluster is classy based cluster when no cluster object create yet.
-	ClusterClassEnabled = "cluster_class_enabled"
+	ClusterClassEnabled = config.ClusterClassEnabledEnv
 
 	// KubeVipLoadBalancerProvider - defines if cluster using kube-vip to implement load balancer
 	// type of service
@@ -46,14 +56,36 @@ const (
 
 	// ApiServerPort - defines the control plane endpoint port
 	ApiServerPort = "apiServerPort"
+
+	// ApiServerTargetPort - defines the port the API server listens on in the
+	// control plane machines
+	ApiServerTargetPort = "apiServerTargetPort"
+
+	// ApiServerExtraPorts - defines the extra control plane ports exposed on
+	// the control plane endpoint, e.g. konnectivity
+	ApiServerExtraPorts = "apiServerExtraPorts"
 )
 
+// ControlPlanePort is an extra port of the control plane machines exposed on the
+// control plane endpoint
+type ControlPlanePort struct {
+	// Name is the name of the port in the HA service and endpoints
+	Name string `json:"name"`
+	// Port is the port exposed on the control plane endpoint
+	Port int32 `json:"port"`
+	// TargetPort is the port in the control plane machines, default value is
+	// Port
+	TargetPort int32 `json:"targetPort,omitempty"`
+	// Protocol is TCP, UDP or SCTP, default value is TCP
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+}
+
 func IsBootStrapCluster() bool {
-	return os.Getenv(DeployInBootstrapCluster) == "True"
+	return config.Get().BootstrapCluster
 }
 
 func IsClusterClassEnabled() bool {
-	return os.Getenv(ClusterClassEnabled) == "True"
+	return config.Get().ClusterClassEnabled
 }
 
 // IsClusterClassBasedCluster checks if a cluster is cluster class based cluster
@@ -64,8 +96,15 @@ func IsClusterClassBasedCluster(cluster *clusterv1.Cluster) bool {
 	return false
 }
 
-// IsControlPlaneVIPProvider checks if NSX Advanced Load Balancer is cluster's endpoint VIP provider
+// IsControlPlaneVIPProvider checks if NSX Advanced Load Balancer is cluster's endpoint VIP provider,
+// the clusters migrating or migrated from kube-vip to Avi are
 func IsControlPlaneVIPProvider(cluster *clusterv1.Cluster) (bool, error) {
+	if cluster != nil {
+		switch cluster.Annotations[akoov1alpha1.HAMigrationAnnotation] {
+		case akoov1alpha1.HAMigrationInProgress, akoov1alpha1.HAMigrationCompleted:
+			return true, nil
+		}
+	}
 	if IsClusterClassBasedCluster(cluster) {
 		for _, clusterVariable := range cluster.Spec.Topology.Variables {
 			if clusterVariable.Name == AviAPIServerHAProvider {
@@ -77,7 +116,7 @@ func IsControlPlaneVIPProvider(cluster *clusterv1.Cluster) (bool, error) {
 			}
 		}
 	}
-	return os.Getenv(IsControlPlaneHAProvider) == "True", nil
+	return config.Get().ControlPlaneHAProvider, nil
 }
 
 // IsLoadBalancerProvider checks if NSX Advanced Load Balancer is cluster's load balancer implementation
@@ -161,17 +200,124 @@ func GetControlPlaneEndpointPort(cluster *clusterv1.Cluster) (int32, error) {
 		}
 		return int32(apiServerPort), nil
 	} else {
-		if port, ok := os.LookupEnv(ControlPlaneEndpointPort); ok {
-			apiServerPort, err := strconv.Atoi(port)
-			if err != nil {
-				return 6443, err
+		c, err := config.Current()
+		if err != nil {
+			return 6443, err
+		}
+		return c.ControlPlaneEndpointPort, nil
+	}
+}
+
+// GetControlPlaneTargetPort returns the port the API server listens on in the
+// control plane machines
+// default value is 6443
+func GetControlPlaneTargetPort(cluster *clusterv1.Cluster) (int32, error) {
+	if IsClusterClassBasedCluster(cluster) {
+		for _, clusterVariable := range cluster.Spec.Topology.Variables {
+			if clusterVariable.Name == ApiServerTargetPort {
+				var targetPort int
+				if err := json.Unmarshal(clusterVariable.Value.Raw, &targetPort); err != nil {
+					return 6443, err
+				}
+				if !validatePortNumber(targetPort) {
+					return 6443, fmt.Errorf("target port number %d is not in valid range [1,65535]", targetPort)
+				}
+				return int32(targetPort), nil
 			}
-			if !validatePortNumber(apiServerPort) {
-				return 6443, fmt.Errorf("port number %d is not in valid range [1,65535]", apiServerPort)
+		}
+	}
+	return 6443, nil
+}
+
+// GetControlPlaneExtraPorts returns the extra control plane ports exposed on the
+// control plane endpoint, with their defaults set
+func GetControlPlaneExtraPorts(cluster *clusterv1.Cluster) ([]ControlPlanePort, error) {
+	if !IsClusterClassBasedCluster(cluster) {
+		return nil, nil
+	}
+	var ports []ControlPlanePort
+	for _, clusterVariable := range cluster.Spec.Topology.Variables {
+		if clusterVariable.Name == ApiServerExtraPorts {
+			if err := json.Unmarshal(clusterVariable.Value.Raw, &ports); err != nil {
+				return nil, err
 			}
-			return int32(apiServerPort), nil
-		} else {
-			return 6443, nil
+			break
+		}
+	}
+	apiServerPort, err := GetControlPlaneEndpointPort(cluster)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	exposed := map[string]bool{fmt.Sprintf("%s/%d", corev1.ProtocolTCP, apiServerPort): true}
+	for i := range ports {
+		port := &ports[i]
+		if errs := validation.IsValidPortName(port.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("extra port name %q is invalid: %s", port.Name, strings.Join(errs, ", "))
+		}
+		if port.Name == ControlPlanePortName || names[port.Name] {
+			return nil, fmt.Errorf("extra port name %q is already used", port.Name)
+		}
+		names[port.Name] = true
+		if port.TargetPort == 0 {
+			port.TargetPort = port.Port
+		}
+		if !validatePortNumber(int(port.Port)) || !validatePortNumber(int(port.TargetPort)) {
+			return nil, fmt.Errorf("extra port %s numbers %d:%d are not in valid range [1,65535]", port.Name, port.Port, port.TargetPort)
+		}
+		switch port.Protocol {
+		case "":
+			port.Protocol = corev1.ProtocolTCP
+		case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+		default:
+			return nil, fmt.Errorf("extra port %s protocol %s is not one of TCP, UDP or SCTP", port.Name, port.Protocol)
+		}
+		key := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+		if exposed[key] {
+			return nil, fmt.Errorf("extra port %s exposes %s which is already exposed", port.Name, key)
 		}
+		exposed[key] = true
+	}
+	return ports, nil
+}
+
+// IsPerClusterAviTenant checks if the AVI tenant is derived from each cluster
+// instead of being shared by all the clusters selected by the AKODeploymentConfig
+func IsPerClusterAviTenant(obj *akoov1alpha1.AKODeploymentConfig) bool {
+	return obj.Spec.Tenant.Mode == akoov1alpha1.AVITenantModeNamespace ||
+		obj.Spec.Tenant.Mode == akoov1alpha1.AVITenantModeLabelTemplate
+}
+
+// GetClusterAviTenant returns the name of the AVI tenant the cluster's AKO user
+// and AKO instance live in, according to the AKODeploymentConfig tenant mode.
+// The management cluster always uses the tenant set in the AKODeploymentConfig.
+func GetClusterAviTenant(cluster *clusterv1.Cluster, obj *akoov1alpha1.AKODeploymentConfig) (string, error) {
+	if !IsPerClusterAviTenant(obj) || cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
+		return obj.Spec.Tenant.Name, nil
+	}
+	if obj.Spec.Tenant.Mode == akoov1alpha1.AVITenantModeNamespace {
+		return cluster.Namespace, nil
+	}
+
+	tmpl, err := template.New("tenant").Option("missingkey=error").Parse(obj.Spec.Tenant.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant name template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Name      string
+		Namespace string
+		Labels    map[string]string
+	}{
+		Name:      cluster.Name,
+		Namespace: cluster.Namespace,
+		Labels:    cluster.Labels,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render tenant name for cluster %s/%s: %w", cluster.Namespace, cluster.Name, err)
+	}
+	tenant := strings.TrimSpace(buf.String())
+	if tenant == "" {
+		return "", fmt.Errorf("tenant name rendered for cluster %s/%s is empty", cluster.Namespace, cluster.Name)
 	}
+	return tenant, nil
 }
@@ -12,6 +12,8 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 )
 
 var _ = Describe("AKO Operator lib unit test", func() {
@@ -207,6 +209,19 @@ var _ = Describe("AKO Operator lib unit test", func() {
 					Expect(isVIPProvider).Should(Equal(false))
 					Expect(err).ShouldNot(HaveOccurred())
 				})
+				It("should return true once the cluster migrates to Avi", func() {
+					for phase, expected := range map[string]bool{
+						akoov1alpha1.HAMigrationRequested:  false,
+						akoov1alpha1.HAMigrationInProgress: true,
+						akoov1alpha1.HAMigrationCompleted:  true,
+						akoov1alpha1.HAMigrationRolledBack: false,
+					} {
+						cluster.Annotations = map[string]string{akoov1alpha1.HAMigrationAnnotation: phase}
+						isVIPProvider, err := IsControlPlaneVIPProvider(cluster)
+						Expect(isVIPProvider).Should(Equal(expected), phase)
+						Expect(err).ShouldNot(HaveOccurred())
+					}
+				})
 			})
 
 			When("ako operator doesn't provide control plane HA", func() {
@@ -555,4 +570,122 @@ var _ = Describe("AKO Operator lib unit test", func() {
 			})
 		})
 	})
+
+	Context("Get control plane target and extra ports", func() {
+		withVariables := func(variables map[string]string) *clusterv1.Cluster {
+			cluster := clusterClassCluster.DeepCopy()
+			for name, value := range variables {
+				cluster.Spec.Topology.Variables = append(cluster.Spec.Topology.Variables, clusterv1.ClusterVariable{
+					Name:  name,
+					Value: apiextensionsv1.JSON{Raw: []byte(value)},
+				})
+			}
+			return cluster
+		}
+
+		It("should default the target port to 6443", func() {
+			Expect(GetControlPlaneTargetPort(legacyCluster)).Should(Equal(int32(6443)))
+			Expect(GetControlPlaneTargetPort(clusterClassCluster)).Should(Equal(int32(6443)))
+		})
+
+		It("should return the target port of the cluster variable", func() {
+			Expect(GetControlPlaneTargetPort(withVariables(map[string]string{ApiServerTargetPort: "8443"}))).Should(Equal(int32(8443)))
+			_, err := GetControlPlaneTargetPort(withVariables(map[string]string{ApiServerTargetPort: "70000"}))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should return no extra ports by default", func() {
+			Expect(GetControlPlaneExtraPorts(legacyCluster)).Should(BeEmpty())
+			Expect(GetControlPlaneExtraPorts(clusterClassCluster)).Should(BeEmpty())
+		})
+
+		It("should default the extra ports", func() {
+			ports, err := GetControlPlaneExtraPorts(withVariables(map[string]string{
+				ApiServerExtraPorts: `[{"name":"konnectivity","port":8132},{"name":"metrics","port":9000,"targetPort":9100,"protocol":"UDP"}]`,
+			}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ports).Should(Equal([]ControlPlanePort{
+				{Name: "konnectivity", Port: 8132, TargetPort: 8132, Protocol: "TCP"},
+				{Name: "metrics", Port: 9000, TargetPort: 9100, Protocol: "UDP"},
+			}))
+		})
+
+		for _, extraPorts := range []string{
+			`[{"name":"Konnectivity","port":8132}]`,
+			`[{"name":"apiserver","port":8132}]`,
+			`[{"name":"a","port":8132},{"name":"a","port":8133}]`,
+			`[{"name":"a","port":0}]`,
+			`[{"name":"a","port":8132,"protocol":"HTTP"}]`,
+			`[{"name":"a","port":31005}]`,
+			`{"name":"a"}`,
+		} {
+			extraPorts := extraPorts
+			It("should reject the extra ports "+extraPorts, func() {
+				_, err := GetControlPlaneExtraPorts(withVariables(map[string]string{ApiServerExtraPorts: extraPorts}))
+				Expect(err).Should(HaveOccurred())
+			})
+		}
+	})
+
+	Context("cluster avi tenant", func() {
+		var (
+			cluster *clusterv1.Cluster
+			adc     *akoov1alpha1.AKODeploymentConfig
+		)
+		BeforeEach(func() {
+			cluster = legacyCluster.DeepCopy()
+			cluster.Labels = map[string]string{"team": "blue"}
+			adc = &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					Tenant: akoov1alpha1.AVITenant{Name: "shared"},
+				},
+			}
+		})
+		When("tenant mode is not set", func() {
+			It("should return the tenant in AKODeploymentConfig", func() {
+				Expect(IsPerClusterAviTenant(adc)).Should(BeFalse())
+				tenant, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(tenant).Should(Equal("shared"))
+			})
+		})
+		When("tenant mode is Namespace", func() {
+			BeforeEach(func() {
+				adc.Spec.Tenant.Mode = akoov1alpha1.AVITenantModeNamespace
+			})
+			It("should return the cluster namespace", func() {
+				Expect(IsPerClusterAviTenant(adc)).Should(BeTrue())
+				tenant, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(tenant).Should(Equal("default"))
+			})
+			It("should return the tenant in AKODeploymentConfig for management cluster", func() {
+				cluster.Namespace = akoov1alpha1.TKGSystemNamespace
+				tenant, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(tenant).Should(Equal("shared"))
+			})
+		})
+		When("tenant mode is LabelTemplate", func() {
+			BeforeEach(func() {
+				adc.Spec.Tenant.Mode = akoov1alpha1.AVITenantModeLabelTemplate
+			})
+			It("should render the tenant from cluster labels", func() {
+				adc.Spec.Tenant.NameTemplate = "{{ .Namespace }}-{{ .Labels.team }}"
+				tenant, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(tenant).Should(Equal("default-blue"))
+			})
+			It("should throw error if the label is missing", func() {
+				adc.Spec.Tenant.NameTemplate = "{{ .Labels.owner }}"
+				_, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).Should(HaveOccurred())
+			})
+			It("should throw error if the rendered tenant is empty", func() {
+				adc.Spec.Tenant.NameTemplate = "{{ index .Labels \"owner\" }}"
+				_, err := GetClusterAviTenant(cluster, adc)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
 })
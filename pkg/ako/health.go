@@ -0,0 +1,141 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako
+
+import (
+	"context"
+	"fmt"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+// Health is the state of AKO in a workload cluster
+type Health struct {
+	// Ready tells AKO is running and serving the cluster
+	Ready bool
+	// Reason is the AKOReady condition reason when AKO is not ready
+	Reason string
+	// Message describes the state of AKO
+	Message string
+	// ReadyReplicas is the number of ready AKO pods
+	ReadyReplicas int32
+	// Replicas is the number of expected AKO pods
+	Replicas int32
+	// Restarts is the total number of restarts of the AKO containers
+	Restarts int32
+}
+
+// GetHealth probes AKO in the workload cluster: its StatefulSet and pods, the
+// object deletion status AKO annotates its StatefulSet with and the
+// deleteConfig flag of its ConfigMap
+func GetHealth(ctx context.Context, remoteClient client.Client) (*Health, error) {
+	ss := &appv1.StatefulSet{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{
+		Name:      akoStatefulSetName,
+		Namespace: akoov1alpha1.AviNamespace,
+	}, ss); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &Health{
+				Reason:  akoov1alpha1.AKONotDeployedReason,
+				Message: fmt.Sprintf("StatefulSet %s/%s not found", akoov1alpha1.AviNamespace, akoStatefulSetName),
+			}, nil
+		}
+		return nil, err
+	}
+
+	health := &Health{Replicas: 1}
+	if ss.Spec.Replicas != nil {
+		health.Replicas = *ss.Spec.Replicas
+	}
+	if err := podsHealth(ctx, remoteClient, ss, health); err != nil {
+		return nil, err
+	}
+
+	switch ss.Annotations[akoCleanUpAnnotationKey] {
+	case akoCleanUpInProgressStatus:
+		health.Reason = akoov1alpha1.AKOObjectDeletionReason
+		health.Message = "AKO is deleting its avi objects"
+		return health, nil
+	case akoCleanUpFinishedStatus, akoCleanUpTimeoutStatus:
+		health.Reason = akoov1alpha1.AKOObjectDeletionReason
+		health.Message = "AKO deleted its avi objects"
+		return health, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{
+		Name:      akoov1alpha1.AKOConfigMapName,
+		Namespace: akoov1alpha1.AviNamespace,
+	}, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		health.Reason = akoov1alpha1.AKONotDeployedReason
+		health.Message = fmt.Sprintf("ConfigMap %s/%s not found", akoov1alpha1.AviNamespace, akoov1alpha1.AKOConfigMapName)
+		return health, nil
+	}
+	if cm.Data["deleteConfig"] == "true" {
+		health.Reason = akoov1alpha1.AKODisabledReason
+		health.Message = "deleteConfig is set in the AKO ConfigMap"
+		return health, nil
+	}
+
+	if health.ReadyReplicas < health.Replicas {
+		health.Reason = akoov1alpha1.AKOPodNotReadyReason
+		if health.Message == "" {
+			health.Message = fmt.Sprintf("%d of %d AKO pods are ready", health.ReadyReplicas, health.Replicas)
+		}
+		return health, nil
+	}
+	health.Ready = true
+	if health.Restarts != 0 {
+		health.Message = fmt.Sprintf("AKO restarted %d times", health.Restarts)
+	}
+	return health, nil
+}
+
+// podsHealth counts the ready AKO pods and their restarts, the message tells
+// why the first pod which isn't ready is waiting
+func podsHealth(ctx context.Context, remoteClient client.Client, ss *appv1.StatefulSet, health *Health) error {
+	if ss.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ss.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	pods := &corev1.PodList{}
+	if err := remoteClient.List(ctx, pods, client.InNamespace(ss.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		ready := podReady(pod)
+		if ready {
+			health.ReadyReplicas++
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			health.Restarts += status.RestartCount
+			if !ready && health.Message == "" && status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+				health.Message = fmt.Sprintf("pod %s is waiting: %s", pod.Name, status.State.Waiting.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
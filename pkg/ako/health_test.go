@@ -0,0 +1,139 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ako
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+var _ = Describe("AKO health", func() {
+	var (
+		ctx    context.Context
+		objs   []client.Object
+		ss     *appv1.StatefulSet
+		pod    *corev1.Pod
+		cm     *corev1.ConfigMap
+		health *Health
+		err    error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ss = &appv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      akoStatefulSetName,
+				Namespace: akoov1alpha1.AviNamespace,
+			},
+			Spec: appv1.StatefulSetSpec{
+				Replicas: ptr.To(int32(1)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "ako"}},
+			},
+		}
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ako-0",
+				Namespace: akoov1alpha1.AviNamespace,
+				Labels:    map[string]string{"app.kubernetes.io/name": "ako"},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         "ako",
+					RestartCount: 2,
+				}},
+			},
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      akoov1alpha1.AKOConfigMapName,
+				Namespace: akoov1alpha1.AviNamespace,
+			},
+			Data: map[string]string{"deleteConfig": "false"},
+		}
+		objs = nil
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(appv1.AddToScheme(scheme)).Should(Succeed())
+		Expect(corev1.AddToScheme(scheme)).Should(Succeed())
+		fclient := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		health, err = GetHealth(ctx, fclient)
+	})
+
+	When("AKO is running", func() {
+		BeforeEach(func() {
+			objs = []client.Object{ss, pod, cm}
+		})
+
+		It("should be ready and count the restarts", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(health.Ready).Should(BeTrue())
+			Expect(health.ReadyReplicas).Should(Equal(int32(1)))
+			Expect(health.Restarts).Should(Equal(int32(2)))
+			Expect(health.Message).Should(Equal("AKO restarted 2 times"))
+		})
+	})
+
+	When("the StatefulSet does not exist", func() {
+		It("should not be deployed", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(health.Ready).Should(BeFalse())
+			Expect(health.Reason).Should(Equal(akoov1alpha1.AKONotDeployedReason))
+		})
+	})
+
+	When("the AKO pod is crashing", func() {
+		BeforeEach(func() {
+			pod.Status.Conditions[0].Status = corev1.ConditionFalse
+			pod.Status.ContainerStatuses[0].State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}
+			objs = []client.Object{ss, pod, cm}
+		})
+
+		It("should not be ready", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(health.Ready).Should(BeFalse())
+			Expect(health.Reason).Should(Equal(akoov1alpha1.AKOPodNotReadyReason))
+			Expect(health.Message).Should(Equal("pod ako-0 is waiting: CrashLoopBackOff"))
+		})
+	})
+
+	When("AKO is deleting its avi objects", func() {
+		BeforeEach(func() {
+			ss.Annotations = map[string]string{akoCleanUpAnnotationKey: akoCleanUpInProgressStatus}
+			objs = []client.Object{ss, pod, cm}
+		})
+
+		It("should not be ready", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(health.Ready).Should(BeFalse())
+			Expect(health.Reason).Should(Equal(akoov1alpha1.AKOObjectDeletionReason))
+		})
+	})
+
+	When("deleteConfig is set", func() {
+		BeforeEach(func() {
+			cm.Data["deleteConfig"] = "true"
+			objs = []client.Object{ss, pod, cm}
+		})
+
+		It("should be disabled", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(health.Ready).Should(BeFalse())
+			Expect(health.Reason).Should(Equal(akoov1alpha1.AKODisabledReason))
+		})
+	})
+})
@@ -176,6 +176,16 @@ func IsAviUserNonExistentError(err error) bool {
 	return err == nil && matched
 }
 
+// IsAviTenantNonExistentError returns if an error is Tenant doesn't exist error
+// by matching error message
+func IsAviTenantNonExistentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	matched, err := regexp.Match(`No object of type tenant with name .*is found`, []byte(err.Error()))
+	return err == nil && matched
+}
+
 // IsAviRoleNonExistentError returns if an error is User role doesn't exist error
 // by matching error message
 func IsAviRoleNonExistentError(err error) bool {
@@ -186,6 +196,26 @@ func IsAviRoleNonExistentError(err error) bool {
 	return err == nil && matched
 }
 
+// IsAviVirtualServiceNonExistentError returns if an error is Virtual Service
+// doesn't exist error by matching error message
+func IsAviVirtualServiceNonExistentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	matched, err := regexp.Match(`No object of type virtualservice with name .*is found`, []byte(err.Error()))
+	return err == nil && matched
+}
+
+// IsAviPoolNonExistentError returns if an error is Pool doesn't exist error
+// by matching error message
+func IsAviPoolNonExistentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	matched, err := regexp.Match(`No object of type pool with name .*is found`, []byte(err.Error()))
+	return err == nil && matched
+}
+
 func (r *realAviClient) GetControllerVersion() (string, error) {
 	return r.AviSession.GetControllerVersion()
 }
@@ -266,29 +296,65 @@ func (r *realAviClient) UserUpdate(obj *models.User, options ...session.ApiOptio
 }
 
 func (r *realAviClient) TenantGet(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
-	return r.Tenant.Get(uuid)
+	return r.Tenant.Get(uuid, options...)
 }
 
+func (r *realAviClient) TenantGetByName(name string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return r.Tenant.GetByName(name, options...)
+}
+
+func (r *realAviClient) TenantCreate(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return r.Tenant.Create(obj, options...)
+}
+
+func (r *realAviClient) TenantDeleteByName(name string, options ...session.ApiOptionsParams) error {
+	return r.Tenant.DeleteByName(name, options...)
+}
+
+// Role operations pass the options through so the role can be scoped to a
+// tenant with session.SetOptTenant
 func (r *realAviClient) RoleGetByName(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.GetByName(name)
+	return r.Role.GetByName(name, options...)
 }
 
 func (r *realAviClient) RoleCreate(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.Create(obj)
+	return r.Role.Create(obj, options...)
 }
 
 func (r *realAviClient) RoleUpdate(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.Update(obj)
+	return r.Role.Update(obj, options...)
+}
+
+func (r *realAviClient) VirtualServiceGet(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+	return r.VirtualService.Get(uuid, options...)
 }
 
 func (r *realAviClient) VirtualServiceGetByName(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
 	return r.VirtualService.GetByName(name)
 }
 
+func (r *realAviClient) VirtualServiceGetAll(options ...session.ApiOptionsParams) ([]*models.VirtualService, error) {
+	return r.VirtualService.GetAll(options...)
+}
+
+func (r *realAviClient) PoolGet(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+	return r.Pool.Get(uuid, options...)
+}
+
 func (r *realAviClient) PoolGetByName(name string, options ...session.ApiOptionsParams) (*models.Pool, error) {
 	return r.Pool.GetByName(name)
 }
 
+// The inventories carry the runtime state of the virtual services and pools,
+// their operational status and how many of their servers are up
+func (r *realAviClient) VsInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error) {
+	return r.VsInventory.Get(uuid, options...)
+}
+
+func (r *realAviClient) PoolInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error) {
+	return r.PoolInventory.Get(uuid, options...)
+}
+
 func (r *realAviClient) AviCertificateConfig() (string, error) {
 	return r.config.CA, nil
 }
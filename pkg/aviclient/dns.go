@@ -0,0 +1,75 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aviclient
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/alb-sdk/go/models"
+)
+
+// CloudDNSProviderProfile returns the DNS profile of the cloud
+func CloudDNSProviderProfile(client Client, cloudName string) (*models.IPAMDNSProviderProfile, error) {
+	cloud, err := client.CloudGetByName(cloudName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find cloud %s", cloudName)
+	}
+	if cloud.DNSProviderRef == nil {
+		return nil, errors.Errorf("no DNS profile is registered for cloud %s", cloudName)
+	}
+	profile, err := client.IPAMDNSProviderProfileGet(GetUUIDFromRef(*cloud.DNSProviderRef))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the DNS profile of cloud %s", cloudName)
+	}
+	return profile, nil
+}
+
+// EnsureDNSServiceDomain adds the domain to the service domains of the Avi DNS
+// profile of the cloud, so that the DNS virtual service answers the FQDNs of the
+// virtual services in it. It tells if the profile was updated.
+func EnsureDNSServiceDomain(client Client, cloudName, domain string) (bool, error) {
+	profile, err := CloudDNSProviderProfile(client, cloudName)
+	if err != nil {
+		return false, err
+	}
+	if profile.InternalProfile == nil {
+		return false, errors.Errorf("the DNS profile of cloud %s is not an Avi DNS profile", cloudName)
+	}
+	for _, serviceDomain := range profile.InternalProfile.DNSServiceDomain {
+		if serviceDomain.DomainName != nil && *serviceDomain.DomainName == domain {
+			return false, nil
+		}
+	}
+	profile.InternalProfile.DNSServiceDomain = append(profile.InternalProfile.DNSServiceDomain,
+		&models.DNSServiceDomain{DomainName: &domain})
+	if _, err := client.IPAMDNSProviderProfileUpdate(profile); err != nil {
+		return false, errors.Wrapf(err, "failed to add domain %s to the DNS profile of cloud %s", domain, cloudName)
+	}
+	return true, nil
+}
+
+// RemoveDNSServiceDomain removes the domain from the service domains of the
+// Avi DNS profile of the cloud. It tells if the profile was updated.
+func RemoveDNSServiceDomain(client Client, cloudName, domain string) (bool, error) {
+	profile, err := CloudDNSProviderProfile(client, cloudName)
+	if err != nil {
+		return false, err
+	}
+	if profile.InternalProfile == nil {
+		return false, nil
+	}
+	serviceDomains := make([]*models.DNSServiceDomain, 0, len(profile.InternalProfile.DNSServiceDomain))
+	for _, serviceDomain := range profile.InternalProfile.DNSServiceDomain {
+		if serviceDomain.DomainName == nil || *serviceDomain.DomainName != domain {
+			serviceDomains = append(serviceDomains, serviceDomain)
+		}
+	}
+	if len(serviceDomains) == len(profile.InternalProfile.DNSServiceDomain) {
+		return false, nil
+	}
+	profile.InternalProfile.DNSServiceDomain = serviceDomains
+	if _, err := client.IPAMDNSProviderProfileUpdate(profile); err != nil {
+		return false, errors.Wrapf(err, "failed to remove domain %s from the DNS profile of cloud %s", domain, cloudName)
+	}
+	return true, nil
+}
@@ -21,6 +21,8 @@ type FakeAviClient struct {
 	Role                   *RoleClient
 	VirtualService         *VirtualServiceClient
 	Pool                   *PoolClient
+	VsInventory            *VsInventoryClient
+	PoolInventory          *PoolInventoryClient
 }
 
 func NewFakeAviClient() *FakeAviClient {
@@ -32,6 +34,10 @@ func NewFakeAviClient() *FakeAviClient {
 		User:                   &UserClient{},
 		Tenant:                 &TenantClient{},
 		Role:                   &RoleClient{},
+		VirtualService:         &VirtualServiceClient{},
+		Pool:                   &PoolClient{},
+		VsInventory:            &VsInventoryClient{},
+		PoolInventory:          &PoolInventoryClient{},
 	}
 }
 
@@ -108,26 +114,58 @@ func (r *FakeAviClient) TenantGet(uuid string, options ...session.ApiOptionsPara
 	return r.Tenant.Get(uuid)
 }
 
+func (r *FakeAviClient) TenantGetByName(name string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return r.Tenant.GetByName(name)
+}
+
+func (r *FakeAviClient) TenantCreate(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return r.Tenant.Create(obj)
+}
+
+func (r *FakeAviClient) TenantDeleteByName(name string, options ...session.ApiOptionsParams) error {
+	return r.Tenant.DeleteByName(name)
+}
+
 func (r *FakeAviClient) RoleGetByName(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.GetByName(name)
+	return r.Role.GetByName(name, options...)
 }
 
 func (r *FakeAviClient) RoleCreate(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.Create(obj)
+	return r.Role.Create(obj, options...)
 }
 
 func (r *FakeAviClient) RoleUpdate(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return r.Role.Update(obj)
+	return r.Role.Update(obj, options...)
+}
+
+func (r *FakeAviClient) VirtualServiceGet(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+	return r.VirtualService.Get(uuid)
 }
 
 func (r *FakeAviClient) VirtualServiceGetByName(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
 	return r.VirtualService.GetByName(name)
 }
 
+func (r *FakeAviClient) VirtualServiceGetAll(options ...session.ApiOptionsParams) ([]*models.VirtualService, error) {
+	return r.VirtualService.GetAll(options...)
+}
+
+func (r *FakeAviClient) PoolGet(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+	return r.Pool.Get(uuid)
+}
+
 func (r *FakeAviClient) PoolGetByName(name string, options ...session.ApiOptionsParams) (*models.Pool, error) {
 	return r.Pool.GetByName(name)
 }
 
+func (r *FakeAviClient) VsInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error) {
+	return r.VsInventory.Get(uuid)
+}
+
+func (r *FakeAviClient) PoolInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error) {
+	return r.PoolInventory.Get(uuid)
+}
+
 func (r *FakeAviClient) AviCertificateConfig() (string, error) {
 	return "", nil
 }
@@ -263,19 +301,49 @@ func (client *UserClient) Update(obj *models.User, options ...session.ApiOptions
 
 // Tenant Client
 type TenantClient struct {
-	getTenantFn GetTenantFunc
+	getTenantFn          GetTenantFunc
+	getByNameTenantFn    GetByNameTenantFunc
+	createTenantFn       CreateTenantFunc
+	deleteByNameTenantFn DeleteByNameTenantFunc
 }
 
 type GetTenantFunc func(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error)
+type GetByNameTenantFunc func(name string, options ...session.ApiOptionsParams) (*models.Tenant, error)
+type CreateTenantFunc func(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error)
+type DeleteByNameTenantFunc func(name string, options ...session.ApiOptionsParams) error
 
 func (client *TenantClient) SetGetTenantFunc(fn GetTenantFunc) {
 	client.getTenantFn = fn
 }
 
+func (client *TenantClient) SetGetByNameTenantFunc(fn GetByNameTenantFunc) {
+	client.getByNameTenantFn = fn
+}
+
+func (client *TenantClient) SetCreateTenantFunc(fn CreateTenantFunc) {
+	client.createTenantFn = fn
+}
+
+func (client *TenantClient) SetDeleteByNameTenantFunc(fn DeleteByNameTenantFunc) {
+	client.deleteByNameTenantFn = fn
+}
+
 func (client *TenantClient) Get(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
 	return client.getTenantFn(uuid)
 }
 
+func (client *TenantClient) GetByName(name string, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return client.getByNameTenantFn(name)
+}
+
+func (client *TenantClient) Create(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error) {
+	return client.createTenantFn(obj)
+}
+
+func (client *TenantClient) DeleteByName(name string, options ...session.ApiOptionsParams) error {
+	return client.deleteByNameTenantFn(name)
+}
+
 // Role Client
 type RoleClient struct {
 	getByNameRoleFn GetByNameRoleFunc
@@ -300,24 +368,35 @@ func (client *RoleClient) SetUpdateRoleFunc(fn UpdateRoleFunc) {
 }
 
 func (client *RoleClient) GetByName(name string, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return client.getByNameRoleFn(name)
+	return client.getByNameRoleFn(name, options...)
 }
 
 func (client *RoleClient) Create(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return client.createRoleFunc(obj)
+	return client.createRoleFunc(obj, options...)
 }
 
 func (client *RoleClient) Update(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error) {
-	return client.updateRoleFunc(obj)
+	return client.updateRoleFunc(obj, options...)
 }
 
 // Pool Client
 type PoolClient struct {
+	getFn       GetPoolFunc
 	getByNameFn GetByNamePoolFunc
 }
 
+type GetPoolFunc func(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error)
+
 type GetByNamePoolFunc func(name string, options ...session.ApiOptionsParams) (*models.Pool, error)
 
+func (client *PoolClient) SetGetFn(fn GetPoolFunc) {
+	client.getFn = fn
+}
+
+func (client *PoolClient) Get(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+	return client.getFn(uuid)
+}
+
 func (client *PoolClient) SetGetByNameFn(fn GetByNamePoolFunc) {
 	client.getByNameFn = fn
 }
@@ -328,11 +407,25 @@ func (client *PoolClient) GetByName(name string, options ...session.ApiOptionsPa
 
 // VirtualService Client
 type VirtualServiceClient struct {
+	getFn       GetVSFunc
 	getByNameFn GetByNameVSFunc
+	getAllFn    GetAllVSFunc
 }
 
+type GetVSFunc func(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error)
+
 type GetByNameVSFunc func(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error)
 
+type GetAllVSFunc func(options ...session.ApiOptionsParams) ([]*models.VirtualService, error)
+
+func (client *VirtualServiceClient) SetGetFn(fn GetVSFunc) {
+	client.getFn = fn
+}
+
+func (client *VirtualServiceClient) Get(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+	return client.getFn(uuid)
+}
+
 func (client *VirtualServiceClient) SetGetByNameFn(fn GetByNameVSFunc) {
 	client.getByNameFn = fn
 }
@@ -340,3 +433,41 @@ func (client *VirtualServiceClient) SetGetByNameFn(fn GetByNameVSFunc) {
 func (client *VirtualServiceClient) GetByName(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
 	return client.getByNameFn(name)
 }
+
+func (client *VirtualServiceClient) SetGetAllFn(fn GetAllVSFunc) {
+	client.getAllFn = fn
+}
+
+func (client *VirtualServiceClient) GetAll(options ...session.ApiOptionsParams) ([]*models.VirtualService, error) {
+	return client.getAllFn(options...)
+}
+
+// VsInventory Client
+type VsInventoryClient struct {
+	getFn GetVsInventoryFunc
+}
+
+type GetVsInventoryFunc func(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error)
+
+func (client *VsInventoryClient) SetGetFn(fn GetVsInventoryFunc) {
+	client.getFn = fn
+}
+
+func (client *VsInventoryClient) Get(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error) {
+	return client.getFn(uuid)
+}
+
+// PoolInventory Client
+type PoolInventoryClient struct {
+	getFn GetPoolInventoryFunc
+}
+
+type GetPoolInventoryFunc func(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error)
+
+func (client *PoolInventoryClient) SetGetFn(fn GetPoolInventoryFunc) {
+	client.getFn = fn
+}
+
+func (client *PoolInventoryClient) Get(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error) {
+	return client.getFn(uuid)
+}
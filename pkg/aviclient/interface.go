@@ -24,6 +24,9 @@ type Client interface {
 	UserUpdate(obj *models.User, options ...session.ApiOptionsParams) (*models.User, error)
 
 	TenantGet(uuid string, options ...session.ApiOptionsParams) (*models.Tenant, error)
+	TenantGetByName(name string, options ...session.ApiOptionsParams) (*models.Tenant, error)
+	TenantCreate(obj *models.Tenant, options ...session.ApiOptionsParams) (*models.Tenant, error)
+	TenantDeleteByName(name string, options ...session.ApiOptionsParams) error
 
 	RoleGetByName(name string, options ...session.ApiOptionsParams) (*models.Role, error)
 	RoleCreate(obj *models.Role, options ...session.ApiOptionsParams) (*models.Role, error)
@@ -32,10 +35,16 @@ type Client interface {
 	IPAMDNSProviderProfileGet(uuid string, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error)
 	IPAMDNSProviderProfileUpdate(obj *models.IPAMDNSProviderProfile, options ...session.ApiOptionsParams) (*models.IPAMDNSProviderProfile, error)
 
+	VirtualServiceGet(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error)
 	VirtualServiceGetByName(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error)
+	VirtualServiceGetAll(options ...session.ApiOptionsParams) ([]*models.VirtualService, error)
 
+	PoolGet(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error)
 	PoolGetByName(name string, options ...session.ApiOptionsParams) (*models.Pool, error)
 
+	VsInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error)
+	PoolInventoryGet(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error)
+
 	AviCertificateConfig() (string, error)
 
 	GetControllerVersion() (string, error)
@@ -0,0 +1,174 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+)
+
+// Legacy environment variables, they configure the operator when it's started
+// without a configuration file
+const (
+	BootstrapClusterEnv         = "bootstrap_cluster"
+	ClusterClassEnabledEnv      = "cluster_class_enabled"
+	ControlPlaneHAProviderEnv   = "avi_control_plane_ha_provider"
+	ControlPlaneEndpointPortEnv = "control_plane_endpoint_port"
+)
+
+// DefaultReloadInterval is how often the configuration file is checked for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// current is the configuration loaded from the configuration file, it's nil
+// when the operator is configured with environment variables
+var current atomic.Pointer[OperatorConfiguration]
+
+// Get returns the current operator configuration. Without a configuration file it
+// is read from the legacy environment variables, invalid values fall back to
+// their defaults.
+func Get() *OperatorConfiguration {
+	c, _ := Current()
+	return c
+}
+
+// Current returns the current operator configuration like Get, together with the
+// error found in the legacy environment variables, if any
+func Current() (*OperatorConfiguration, error) {
+	if c := current.Load(); c != nil {
+		return c, nil
+	}
+	return FromEnvironment()
+}
+
+// Set makes c the current operator configuration, a nil c goes back to the
+// legacy environment variables
+func Set(c *OperatorConfiguration) {
+	current.Store(c)
+}
+
+// FromEnvironment builds the operator configuration from the legacy environment
+// variables. The defaulted configuration is returned together with the error of an
+// invalid control plane endpoint port.
+func FromEnvironment() (*OperatorConfiguration, error) {
+	c := &OperatorConfiguration{
+		BootstrapCluster:       os.Getenv(BootstrapClusterEnv) == "True",
+		ClusterClassEnabled:    os.Getenv(ClusterClassEnabledEnv) == "True",
+		ControlPlaneHAProvider: os.Getenv(ControlPlaneHAProviderEnv) == "True",
+	}
+	c.Default()
+
+	port, ok := os.LookupEnv(ControlPlaneEndpointPortEnv)
+	if !ok {
+		return c, nil
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return c, err
+	}
+	if n < 1 || n > 65535 {
+		return c, fmt.Errorf("port number %d is not in valid range [1,65535]", n)
+	}
+	c.ControlPlaneEndpointPort = int32(n)
+	return c, nil
+}
+
+// Load reads, defaults and validates the operator configuration file
+func Load(path string) (*OperatorConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes, defaults and validates an operator configuration document
+func Parse(data []byte) (*OperatorConfiguration, error) {
+	c := &OperatorConfiguration{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse operator configuration: %w", err)
+	}
+	c.Default()
+	if errs := c.Validate(); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid operator configuration: %w", errs.ToAggregate())
+	}
+	return c, nil
+}
+
+// Reloader watches the configuration file and applies the changes which don't
+// need a restart, the other changes are only logged. It is a manager Runnable.
+type Reloader struct {
+	Path     string
+	Interval time.Duration
+	Log      logr.Logger
+
+	data []byte
+}
+
+// NewReloader returns a Reloader for the configuration file loaded at startup
+func NewReloader(path string, log logr.Logger) (*Reloader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader{Path: path, Interval: DefaultReloadInterval, Log: log, data: data}, nil
+}
+
+// Start polls the configuration file until ctx is done. Polling rather than file
+// events also catches the symlink swaps of ConfigMap volumes.
+func (r *Reloader) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+// NeedLeaderElection lets every replica reload its own configuration
+func (r *Reloader) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *Reloader) reload() {
+	data, err := os.ReadFile(r.Path)
+	if err != nil {
+		r.Log.Error(err, "Failed to read operator configuration, keep the current one")
+		return
+	}
+	if bytes.Equal(data, r.data) {
+		return
+	}
+	r.data = data
+
+	c, err := Parse(data)
+	if err != nil {
+		r.Log.Error(err, "Invalid operator configuration, keep the current one")
+		return
+	}
+	old := Get()
+	if changed := c.restartRequiredChanges(old); len(changed) != 0 {
+		r.Log.Info("Operator configuration changes require a restart to take effect", "fields", changed)
+	}
+	// only the fields which are read on every reconcile are taken from the new file
+	reloaded := old.DeepCopy()
+	reloaded.Requeue = c.Requeue
+	reloaded.Cleanup = c.Cleanup
+	reloaded.DriftDetection = c.DriftDetection
+	reloaded.HealthCheck = c.HealthCheck
+	reloaded.Concurrency.ClusterCleanup = c.Concurrency.ClusterCleanup
+	Set(reloaded)
+	r.Log.Info("Reloaded operator configuration")
+}
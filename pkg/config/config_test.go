@@ -0,0 +1,228 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Operator configuration", func() {
+	AfterEach(func() {
+		Set(nil)
+	})
+
+	When("the configuration file is parsed", func() {
+		It("should default the empty fields", func() {
+			c, err := Parse([]byte(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+`))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(c.ControlPlaneEndpointPort).Should(Equal(int32(DefaultControlPlaneEndpointPort)))
+			Expect(c.WebhookValidationMode).Should(Equal("online"))
+			Expect(c.Concurrency).Should(Equal(ConcurrencyConfiguration{AKODeploymentConfig: 1, Cluster: 1, Machine: 1, ClusterCleanup: 1}))
+			Expect(c.Requeue.AKODeletion.Duration).Should(Equal(DefaultAKODeletionRequeueInterval))
+			Expect(c.Cleanup.AKODeletionTimeout.Duration).Should(BeZero())
+			Expect(c.Cleanup.PreDrainTimeout.Duration).Should(Equal(DefaultPreDrainTimeout))
+			Expect(c.DriftDetection.Interval.Duration).Should(Equal(DefaultDriftDetectionInterval))
+			Expect(c.DriftDetection.Reapply).Should(BeFalse())
+			Expect(c.HealthCheck.Interval.Duration).Should(Equal(DefaultHealthCheckInterval))
+			Expect(c.DNS).Should(Equal(DNSConfiguration{ResolveInterval: metav1.Duration{Duration: DefaultDNSResolveInterval}}))
+			Expect(c.Enabled(ConfigHotReload)).Should(BeTrue())
+			Expect(c.Enabled(AKODriftDetection)).Should(BeTrue())
+			Expect(c.Enabled(AKOHealthCheck)).Should(BeTrue())
+			Expect(c.Enabled(HAEndpointReadiness)).Should(BeFalse())
+		})
+
+		It("should keep the configured fields", func() {
+			c, err := Parse([]byte(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+controlPlaneHAProvider: true
+controlPlaneEndpointPort: 8443
+webhookValidationMode: offline
+permissionMatrixConfigMap: tkg-system/ako-permissions
+haServiceNamespace: tkg-ha-services
+concurrency:
+  cluster: 5
+requeue:
+  akoDeletion: 5s
+cleanup:
+  akoDeletionTimeout: 10m
+driftDetection:
+  interval: 1h
+  reapply: true
+healthCheck:
+  interval: 30s
+dns:
+  server: 10.0.0.10:53
+  resolveInterval: 1m
+featureGates:
+  ConfigHotReload: false
+`))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(c.ControlPlaneHAProvider).Should(BeTrue())
+			Expect(c.ControlPlaneEndpointPort).Should(Equal(int32(8443)))
+			Expect(c.WebhookValidationMode).Should(Equal("offline"))
+			Expect(c.PermissionMatrixConfigMap).Should(Equal("tkg-system/ako-permissions"))
+			Expect(c.HAServiceNamespace).Should(Equal("tkg-ha-services"))
+			Expect(c.Concurrency).Should(Equal(ConcurrencyConfiguration{AKODeploymentConfig: 1, Cluster: 5, Machine: 1, ClusterCleanup: 1}))
+			Expect(c.Requeue.AKODeletion.Duration).Should(Equal(5 * time.Second))
+			Expect(c.Cleanup.AKODeletionTimeout.Duration).Should(Equal(10 * time.Minute))
+			Expect(c.DriftDetection).Should(Equal(DriftDetectionConfiguration{Interval: metav1.Duration{Duration: time.Hour}, Reapply: true}))
+			Expect(c.HealthCheck.Interval.Duration).Should(Equal(30 * time.Second))
+			Expect(c.DNS).Should(Equal(DNSConfiguration{Server: "10.0.0.10:53", ResolveInterval: metav1.Duration{Duration: time.Minute}}))
+			Expect(c.Enabled(ConfigHotReload)).Should(BeFalse())
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := Parse([]byte(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+bootstrapClusters: true
+`))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should reject invalid fields", func() {
+			_, err := Parse([]byte(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha2
+kind: OperatorConfiguration
+controlPlaneEndpointPort: 70000
+webhookValidationMode: strict
+permissionMatrixConfigMap: ako-permissions
+haServiceNamespace: TKG_HA
+concurrency:
+  machine: -1
+cleanup:
+  akoDeletionTimeout: -1s
+  preDrainTimeout: -1s
+driftDetection:
+  interval: -1m
+healthCheck:
+  interval: -1m
+dns:
+  server: 10.0.0.10
+  resolveInterval: -1m
+featureGates:
+  Unknown: true
+`))
+			Expect(err).Should(HaveOccurred())
+			for _, field := range []string{"apiVersion", "controlPlaneEndpointPort", "webhookValidationMode",
+				"permissionMatrixConfigMap", "haServiceNamespace", "concurrency.machine", "cleanup.akoDeletionTimeout", "cleanup.preDrainTimeout", "driftDetection.interval", "healthCheck.interval",
+				"dns.server", "dns.resolveInterval", "featureGates[Unknown]"} {
+				Expect(err.Error()).Should(ContainSubstring(field))
+			}
+		})
+	})
+
+	When("there is no configuration file", func() {
+		BeforeEach(func() {
+			os.Setenv(ControlPlaneHAProviderEnv, "True")
+			os.Setenv(ControlPlaneEndpointPortEnv, "8443")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(ControlPlaneHAProviderEnv)
+			os.Unsetenv(ControlPlaneEndpointPortEnv)
+		})
+
+		It("should read the legacy environment variables", func() {
+			c, err := Current()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(c.ControlPlaneHAProvider).Should(BeTrue())
+			Expect(c.BootstrapCluster).Should(BeFalse())
+			Expect(c.ControlPlaneEndpointPort).Should(Equal(int32(8443)))
+		})
+
+		It("should fall back to the default port when it is invalid", func() {
+			os.Setenv(ControlPlaneEndpointPortEnv, "0")
+			c, err := Current()
+			Expect(err).Should(HaveOccurred())
+			Expect(c.ControlPlaneEndpointPort).Should(Equal(int32(DefaultControlPlaneEndpointPort)))
+		})
+
+		It("should prefer the configuration set", func() {
+			c := &OperatorConfiguration{}
+			c.Default()
+			Set(c)
+			Expect(Get().ControlPlaneHAProvider).Should(BeFalse())
+		})
+	})
+
+	When("the configuration file changes", func() {
+		var (
+			dir      string
+			path     string
+			reloader *Reloader
+		)
+
+		write := func(data string) {
+			Expect(os.WriteFile(path, []byte(data), 0600)).Should(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "akoo-config")
+			Expect(err).ShouldNot(HaveOccurred())
+			path = filepath.Join(dir, "config.yaml")
+			write(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+`)
+			c, err := Load(path)
+			Expect(err).ShouldNot(HaveOccurred())
+			Set(c)
+			reloader, err = NewReloader(path, logr.Discard())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).Should(Succeed())
+		})
+
+		It("should only apply the fields which don't need a restart", func() {
+			write(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+controlPlaneHAProvider: true
+concurrency:
+  cluster: 5
+  clusterCleanup: 8
+requeue:
+  akoDeletion: 5s
+cleanup:
+  akoDeletionTimeout: 10m
+driftDetection:
+  reapply: true
+`)
+			reloader.reload()
+			c := Get()
+			Expect(c.Requeue.AKODeletion.Duration).Should(Equal(5 * time.Second))
+			Expect(c.Cleanup.AKODeletionTimeout.Duration).Should(Equal(10 * time.Minute))
+			Expect(c.ControlPlaneHAProvider).Should(BeFalse())
+			Expect(c.Concurrency.Cluster).Should(Equal(1))
+			Expect(c.Concurrency.ClusterCleanup).Should(Equal(8))
+			Expect(c.DriftDetection.Reapply).Should(BeTrue())
+		})
+
+		It("should keep the current configuration when the new one is invalid", func() {
+			write(`
+apiVersion: config.networking.tkg.tanzu.vmware.com/v1alpha1
+kind: OperatorConfiguration
+requeue:
+  akoDeletion: -5s
+`)
+			reloader.reload()
+			Expect(Get().Requeue.AKODeletion.Duration).Should(Equal(DefaultAKODeletionRequeueInterval))
+		})
+	})
+})
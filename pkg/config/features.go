@@ -0,0 +1,58 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import "sort"
+
+// Feature is the name of an operator feature which can be turned on or off with
+// the featureGates of the operator configuration
+type Feature string
+
+const (
+	// ConfigHotReload reloads the operator configuration file when it changes,
+	// applying the fields which don't need a restart
+	ConfigHotReload Feature = "ConfigHotReload"
+
+	// AKODriftDetection periodically compares the AKO configuration running in
+	// the workload clusters with the expected one
+	AKODriftDetection Feature = "AKODriftDetection"
+
+	// AKOHealthCheck periodically probes AKO in the workload clusters and
+	// reports its health in the AKOReady condition of the Cluster
+	AKOHealthCheck Feature = "AKOHealthCheck"
+
+	// HAEndpointReadiness only sends the control plane VIP traffic to the
+	// control plane machines whose node, API server and etcd member are healthy
+	HAEndpointReadiness Feature = "HAEndpointReadiness"
+)
+
+// defaultFeatureGates lists every known feature with its default state
+var defaultFeatureGates = map[string]bool{
+	string(ConfigHotReload):     true,
+	string(AKODriftDetection):   true,
+	string(AKOHealthCheck):      true,
+	string(HAEndpointReadiness): false,
+}
+
+// Enabled checks if the feature is turned on in the configuration
+func (c *OperatorConfiguration) Enabled(f Feature) bool {
+	if enabled, ok := c.FeatureGates[string(f)]; ok {
+		return enabled
+	}
+	return defaultFeatureGates[string(f)]
+}
+
+// Enabled checks if the feature is turned on in the current configuration
+func Enabled(f Feature) bool {
+	return Get().Enabled(f)
+}
+
+func knownFeatureGates() []string {
+	features := make([]string, 0, len(defaultFeatureGates))
+	for f := range defaultFeatureGates {
+		features = append(features, f)
+	}
+	sort.Strings(features)
+	return features
+}
@@ -0,0 +1,360 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// GroupVersion is the apiVersion of the operator configuration file
+	GroupVersion = "config.networking.tkg.tanzu.vmware.com/v1alpha1"
+	// Kind is the kind of the operator configuration file
+	Kind = "OperatorConfiguration"
+
+	// DefaultControlPlaneEndpointPort is the API server port of legacy clusters
+	DefaultControlPlaneEndpointPort = 6443
+	// DefaultAKODeletionRequeueInterval is how often a deleted cluster is checked
+	// for the AKO cleanup to finish
+	DefaultAKODeletionRequeueInterval = time.Second
+	// DefaultDriftDetectionInterval is how often the AKO configuration of a
+	// workload cluster is compared with the expected one
+	DefaultDriftDetectionInterval = 10 * time.Minute
+	// DefaultHealthCheckInterval is how often AKO is probed in a workload cluster
+	DefaultHealthCheckInterval = time.Minute
+	// DefaultDNSResolveInterval is how often the FQDN control plane endpoints
+	// are resolved again
+	DefaultDNSResolveInterval = 5 * time.Minute
+	// DefaultPreDrainTimeout is how long the drain of a deleted control plane
+	// machine is held for the Avi pool to stop sending it traffic
+	DefaultPreDrainTimeout = 10 * time.Minute
+)
+
+// OperatorConfiguration configures the load balancer operator. It is read from
+// the file passed with --config, every field left empty falls back to its
+// default value.
+type OperatorConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// BootstrapCluster tells the operator runs in the bootstrap kind cluster
+	// instead of the management cluster. Requires a restart.
+	// +optional
+	BootstrapCluster bool `json:"bootstrapCluster,omitempty"`
+
+	// ClusterClassEnabled tells the workload clusters are ClusterClass based
+	// before any Cluster object exists. Requires a restart.
+	// +optional
+	ClusterClassEnabled bool `json:"clusterClassEnabled,omitempty"`
+
+	// ControlPlaneHAProvider makes NSX Advanced Load Balancer the control plane
+	// endpoint VIP provider of legacy clusters. Requires a restart.
+	// +optional
+	ControlPlaneHAProvider bool `json:"controlPlaneHAProvider,omitempty"`
+
+	// ControlPlaneEndpointPort is the API server port of legacy clusters, it is
+	// 6443 by default. Requires a restart.
+	// +optional
+	ControlPlaneEndpointPort int32 `json:"controlPlaneEndpointPort,omitempty"`
+
+	// WebhookValidationMode is the default AKODeploymentConfig webhook validation
+	// mode, either online or offline. Requires a restart.
+	// +optional
+	WebhookValidationMode string `json:"webhookValidationMode,omitempty"`
+
+	// PermissionMatrixConfigMap is the namespace/name of the ConfigMap overriding
+	// the embedded AKO user role permission matrix. Requires a restart.
+	// +optional
+	PermissionMatrixConfigMap string `json:"permissionMatrixConfigMap,omitempty"`
+
+	// HAServiceNamespace is the namespace hosting the control plane HA services
+	// of the workload clusters, by default each one lives in the namespace of
+	// its cluster. The existing services are moved when it changes. Requires a
+	// restart.
+	// +optional
+	HAServiceNamespace string `json:"haServiceNamespace,omitempty"`
+
+	// Concurrency configures how many objects each controller reconciles in
+	// parallel. Requires a restart.
+	// +optional
+	Concurrency ConcurrencyConfiguration `json:"concurrency,omitempty"`
+
+	// Requeue configures the requeue intervals of the controllers. Reloaded
+	// without a restart.
+	// +optional
+	Requeue RequeueConfiguration `json:"requeue,omitempty"`
+
+	// Cleanup configures the cleanup of deleted clusters and machines. Reloaded
+	// without a restart.
+	// +optional
+	Cleanup CleanupConfiguration `json:"cleanup,omitempty"`
+
+	// DriftDetection configures how the AKO configuration running in the workload
+	// clusters is checked. Reloaded without a restart.
+	// +optional
+	DriftDetection DriftDetectionConfiguration `json:"driftDetection,omitempty"`
+
+	// HealthCheck configures how AKO is probed in the workload clusters.
+	// Reloaded without a restart.
+	// +optional
+	HealthCheck HealthCheckConfiguration `json:"healthCheck,omitempty"`
+
+	// DNS configures how the FQDN control plane endpoints are resolved.
+	// Reloaded without a restart.
+	// +optional
+	DNS DNSConfiguration `json:"dns,omitempty"`
+
+	// FeatureGates enables or disables operator features by name. Requires a
+	// restart.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// ConcurrencyConfiguration sets the number of concurrent reconciles per controller
+type ConcurrencyConfiguration struct {
+	// AKODeploymentConfig is the number of AKODeploymentConfigs reconciled in
+	// parallel, it is 1 by default.
+	// +optional
+	AKODeploymentConfig int `json:"akoDeploymentConfig,omitempty"`
+
+	// Cluster is the number of Clusters reconciled in parallel, it is 1 by default.
+	// +optional
+	Cluster int `json:"cluster,omitempty"`
+
+	// Machine is the number of Machines reconciled in parallel, it is 1 by default.
+	// +optional
+	Machine int `json:"machine,omitempty"`
+
+	// ClusterCleanup is the number of clusters an AKODeploymentConfig being deleted
+	// cleans up in parallel, it is 1 by default. The clusters of the other
+	// AKODeploymentConfigs are reconciled by the cluster controller, see Cluster.
+	// Unlike the other fields it is reloaded without a restart.
+	// +optional
+	ClusterCleanup int `json:"clusterCleanup,omitempty"`
+}
+
+// RequeueConfiguration sets how long the controllers wait before checking an
+// object again
+type RequeueConfiguration struct {
+	// AKODeletion is how often a deleted cluster is checked for the AKO cleanup
+	// to finish, it is 1s by default.
+	// +optional
+	AKODeletion metav1.Duration `json:"akoDeletion,omitempty"`
+}
+
+// CleanupConfiguration sets how the avi resources of deleted clusters and
+// machines are cleaned up
+type CleanupConfiguration struct {
+	// AKODeletionTimeout is how long a deleted cluster waits for AKO to clean up
+	// its avi resources. Once it passes the cluster deletion goes on and the
+	// remaining resources are left in the avi controller. Zero, the default,
+	// waits forever.
+	// +optional
+	AKODeletionTimeout metav1.Duration `json:"akoDeletionTimeout,omitempty"`
+
+	// PreDrainTimeout is how long the drain of a deleted control plane machine
+	// is held for the Avi pool of the control plane VIP to stop sending it
+	// traffic. Once it passes the pre-drain hook is released anyway, it is 10m
+	// by default.
+	// +optional
+	PreDrainTimeout metav1.Duration `json:"preDrainTimeout,omitempty"`
+}
+
+// DriftDetectionConfiguration sets how the AKO configuration running in the
+// workload clusters is compared with the one rendered from their AKODeploymentConfig
+type DriftDetectionConfiguration struct {
+	// Interval is how often a workload cluster is checked, it is 10m by default.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// Reapply writes the expected configuration back to the workload cluster
+	// when it drifted. Drift is only reported by default.
+	// +optional
+	Reapply bool `json:"reapply,omitempty"`
+}
+
+// HealthCheckConfiguration sets how AKO is probed in the workload clusters
+type HealthCheckConfiguration struct {
+	// Interval is how often AKO is probed in a workload cluster, it is 1m by
+	// default.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// DNSConfiguration sets how the FQDN control plane endpoints of the clusters
+// using NSX Advanced Load Balancer as HA provider are resolved
+type DNSConfiguration struct {
+	// Server is the host:port of the DNS server resolving the FQDN control plane
+	// endpoints, the system resolver is used by default.
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// ResolveInterval is how often the FQDN control plane endpoints are resolved
+	// again to follow the DNS changes, it is 5m by default.
+	// +optional
+	ResolveInterval metav1.Duration `json:"resolveInterval,omitempty"`
+}
+
+// Default sets the default value of every empty field
+func (c *OperatorConfiguration) Default() {
+	if c.APIVersion == "" {
+		c.APIVersion = GroupVersion
+	}
+	if c.Kind == "" {
+		c.Kind = Kind
+	}
+	if c.ControlPlaneEndpointPort == 0 {
+		c.ControlPlaneEndpointPort = DefaultControlPlaneEndpointPort
+	}
+	if c.WebhookValidationMode == "" {
+		c.WebhookValidationMode = "online"
+	}
+	if c.Concurrency.AKODeploymentConfig == 0 {
+		c.Concurrency.AKODeploymentConfig = 1
+	}
+	if c.Concurrency.Cluster == 0 {
+		c.Concurrency.Cluster = 1
+	}
+	if c.Concurrency.Machine == 0 {
+		c.Concurrency.Machine = 1
+	}
+	if c.Concurrency.ClusterCleanup == 0 {
+		c.Concurrency.ClusterCleanup = 1
+	}
+	if c.Requeue.AKODeletion.Duration == 0 {
+		c.Requeue.AKODeletion.Duration = DefaultAKODeletionRequeueInterval
+	}
+	if c.Cleanup.PreDrainTimeout.Duration == 0 {
+		c.Cleanup.PreDrainTimeout.Duration = DefaultPreDrainTimeout
+	}
+	if c.DriftDetection.Interval.Duration == 0 {
+		c.DriftDetection.Interval.Duration = DefaultDriftDetectionInterval
+	}
+	if c.HealthCheck.Interval.Duration == 0 {
+		c.HealthCheck.Interval.Duration = DefaultHealthCheckInterval
+	}
+	if c.DNS.ResolveInterval.Duration == 0 {
+		c.DNS.ResolveInterval.Duration = DefaultDNSResolveInterval
+	}
+}
+
+// Validate checks the configuration, it expects the defaults to be set
+func (c *OperatorConfiguration) Validate() field.ErrorList {
+	var allErrs field.ErrorList
+	if c.APIVersion != GroupVersion {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{GroupVersion}))
+	}
+	if c.Kind != Kind {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+	if c.ControlPlaneEndpointPort < 1 || c.ControlPlaneEndpointPort > 65535 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("controlPlaneEndpointPort"), c.ControlPlaneEndpointPort,
+			"port number should be in range [1,65535]"))
+	}
+	if c.WebhookValidationMode != "online" && c.WebhookValidationMode != "offline" {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("webhookValidationMode"), c.WebhookValidationMode,
+			[]string{"online", "offline"}))
+	}
+	if c.PermissionMatrixConfigMap != "" {
+		if namespace, name, ok := strings.Cut(c.PermissionMatrixConfigMap, "/"); !ok || namespace == "" || name == "" {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("permissionMatrixConfigMap"), c.PermissionMatrixConfigMap,
+				"should be namespace/name"))
+		}
+	}
+	if c.HAServiceNamespace != "" {
+		for _, msg := range validation.IsDNS1123Label(c.HAServiceNamespace) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("haServiceNamespace"), c.HAServiceNamespace, msg))
+		}
+	}
+	concurrency := field.NewPath("concurrency")
+	for _, workers := range []struct {
+		name string
+		n    int
+	}{
+		{"akoDeploymentConfig", c.Concurrency.AKODeploymentConfig},
+		{"cluster", c.Concurrency.Cluster},
+		{"machine", c.Concurrency.Machine},
+		{"clusterCleanup", c.Concurrency.ClusterCleanup},
+	} {
+		if workers.n < 1 {
+			allErrs = append(allErrs, field.Invalid(concurrency.Child(workers.name), workers.n, "should be at least 1"))
+		}
+	}
+	if c.Requeue.AKODeletion.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("requeue", "akoDeletion"), c.Requeue.AKODeletion.Duration.String(),
+			"should not be negative"))
+	}
+	if c.Cleanup.AKODeletionTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("cleanup", "akoDeletionTimeout"), c.Cleanup.AKODeletionTimeout.Duration.String(),
+			"should not be negative"))
+	}
+	if c.Cleanup.PreDrainTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("cleanup", "preDrainTimeout"), c.Cleanup.PreDrainTimeout.Duration.String(),
+			"should not be negative"))
+	}
+	if c.DriftDetection.Interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("driftDetection", "interval"), c.DriftDetection.Interval.Duration.String(),
+			"should not be negative"))
+	}
+	if c.HealthCheck.Interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("healthCheck", "interval"), c.HealthCheck.Interval.Duration.String(),
+			"should not be negative"))
+	}
+	if c.DNS.Server != "" {
+		if _, _, err := net.SplitHostPort(c.DNS.Server); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("dns", "server"), c.DNS.Server, "should be host:port"))
+		}
+	}
+	if c.DNS.ResolveInterval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("dns", "resolveInterval"), c.DNS.ResolveInterval.Duration.String(),
+			"should not be negative"))
+	}
+	for name := range c.FeatureGates {
+		if _, ok := defaultFeatureGates[name]; !ok {
+			allErrs = append(allErrs, field.NotSupported(field.NewPath("featureGates").Key(name), name, knownFeatureGates()))
+		}
+	}
+	return allErrs
+}
+
+// restartRequiredChanges lists the fields which changed from old to c but are only
+// read when the operator starts
+func (c *OperatorConfiguration) restartRequiredChanges(old *OperatorConfiguration) []string {
+	var changed []string
+	check := func(name string, a, b interface{}) {
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			changed = append(changed, name)
+		}
+	}
+	check("bootstrapCluster", old.BootstrapCluster, c.BootstrapCluster)
+	check("clusterClassEnabled", old.ClusterClassEnabled, c.ClusterClassEnabled)
+	check("controlPlaneHAProvider", old.ControlPlaneHAProvider, c.ControlPlaneHAProvider)
+	check("controlPlaneEndpointPort", old.ControlPlaneEndpointPort, c.ControlPlaneEndpointPort)
+	check("webhookValidationMode", old.WebhookValidationMode, c.WebhookValidationMode)
+	check("permissionMatrixConfigMap", old.PermissionMatrixConfigMap, c.PermissionMatrixConfigMap)
+	check("haServiceNamespace", old.HAServiceNamespace, c.HAServiceNamespace)
+	oldConcurrency, concurrency := old.Concurrency, c.Concurrency
+	oldConcurrency.ClusterCleanup, concurrency.ClusterCleanup = 0, 0
+	check("concurrency", oldConcurrency, concurrency)
+	check("featureGates", old.FeatureGates, c.FeatureGates)
+	return changed
+}
+
+// DeepCopy returns a copy of the configuration which shares nothing with c
+func (c *OperatorConfiguration) DeepCopy() *OperatorConfiguration {
+	out := *c
+	if c.FeatureGates != nil {
+		out.FeatureGates = make(map[string]bool, len(c.FeatureGates))
+		for name, enabled := range c.FeatureGates {
+			out.FeatureGates[name] = enabled
+		}
+	}
+	return &out
+}
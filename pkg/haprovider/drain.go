@@ -0,0 +1,140 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/vmware/alb-sdk/go/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+// HAVirtualServiceName returns the name of the Avi virtual service of the HA
+// service of the cluster. The HA services live in the management cluster, the
+// virtual service is named by its AKO, which prefixes the names with the
+// management cluster namespace and name.
+func HAVirtualServiceName(managementCluster *clusterv1.Cluster, service *corev1.Service) string {
+	return managementCluster.Namespace + "-" + managementCluster.Name + "--" + service.Namespace + "-" + service.Name
+}
+
+// getManagementCluster returns the management cluster, nil when it is not
+// found, e.g. before the bootstrap cluster pivots.
+func (r *HAProvider) getManagementCluster(ctx context.Context) (*clusterv1.Cluster, error) {
+	clusters := &clusterv1.ClusterList{}
+	if err := r.List(ctx, clusters, client.InNamespace(akoov1alpha1.TKGSystemNamespace),
+		client.HasLabels{akoov1alpha1.TKGManagememtClusterRoleLabel}); err != nil {
+		return nil, err
+	}
+	if len(clusters.Items) == 0 {
+		return nil, nil
+	}
+	return &clusters.Items[0], nil
+}
+
+// haVirtualService returns the Avi virtual service of the HA service, nil when
+// it isn't found. It's the one AKO records on the service, the one named like
+// AKO names them when the service has no FQDN to be recorded under.
+func (r *HAProvider) haVirtualService(ctx context.Context, aviClient aviclient.Client, service *corev1.Service) (*models.VirtualService, error) {
+	if value, ok := service.Annotations[akoov1alpha1.AkoVirtualServiceAnnotation]; ok {
+		virtualServices := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &virtualServices); err != nil {
+			r.log.Info("can't parse the virtual services of " + service.Name + ": " + err.Error())
+		}
+		for _, uuid := range virtualServices {
+			if uuid != "" {
+				return aviClient.VirtualServiceGet(uuid)
+			}
+		}
+	}
+	managementCluster, err := r.getManagementCluster(ctx)
+	if err != nil || managementCluster == nil {
+		return nil, err
+	}
+	vs, err := aviClient.VirtualServiceGetByName(HAVirtualServiceName(managementCluster, service))
+	if aviclient.IsAviVirtualServiceNonExistentError(err) {
+		return nil, nil
+	}
+	return vs, err
+}
+
+// haPoolRef returns the ref of the pool the virtual service sends the traffic
+// of the port to
+func haPoolRef(vs *models.VirtualService, port int32) string {
+	for _, selector := range vs.ServicePoolSelect {
+		if selector.ServicePort != nil && int32(*selector.ServicePort) == port && selector.ServicePoolRef != nil {
+			return *selector.ServicePoolRef
+		}
+	}
+	if vs.PoolRef != nil {
+		return *vs.PoolRef
+	}
+	return ""
+}
+
+// MachineDrained tells if the Avi pool of the HA service of the cluster no
+// longer sends traffic to the control plane machine: the pool member of the
+// machine is removed or disabled. The pool is the one the virtual service of
+// the HA service sends the API server port to; while the virtual service or
+// the pool can't be found the machine isn't drained. A cluster without HA
+// service has nothing to drain.
+func (r *HAProvider) MachineDrained(ctx context.Context, aviClient aviclient.Client, machine *clusterv1.Machine, cluster *clusterv1.Cluster) (bool, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.getHAServiceName(cluster), Namespace: HAServiceNamespace(cluster)}, service); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.log.Info("HA service of " + cluster.Name + " not found, nothing to drain")
+			return true, nil
+		}
+		return false, err
+	}
+	vs, err := r.haVirtualService(ctx, aviClient, service)
+	if err != nil {
+		return false, err
+	}
+	if vs == nil {
+		r.log.Info("virtual service of " + service.Name + " not found, can't tell if " + machine.Name + " is drained")
+		return false, nil
+	}
+	port, err := ako_operator.GetControlPlaneEndpointPort(cluster)
+	if err != nil {
+		return false, err
+	}
+	poolRef := haPoolRef(vs, port)
+	if poolRef == "" {
+		r.log.Info("virtual service " + ptr.Deref(vs.Name, "") + " has no pool for the API server port, can't tell if " + machine.Name + " is drained")
+		return false, nil
+	}
+	pool, err := aviClient.PoolGet(aviclient.GetUUIDFromRef(poolRef))
+	if err != nil {
+		return false, err
+	}
+	return poolMemberDrained(pool, machine), nil
+}
+
+// poolMemberDrained tells if none of the enabled servers of the pool is an
+// address of the machine
+func poolMemberDrained(pool *models.Pool, machine *clusterv1.Machine) bool {
+	for _, server := range pool.Servers {
+		if server.IP == nil || server.IP.Addr == nil {
+			continue
+		}
+		if server.Enabled != nil && !*server.Enabled {
+			continue
+		}
+		for _, machineAddress := range machine.Status.Addresses {
+			if machineAddress.Type == clusterv1.MachineExternalIP && machineAddress.Address == *server.IP.Addr {
+				return false
+			}
+		}
+	}
+	return true
+}
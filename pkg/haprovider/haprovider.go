@@ -5,8 +5,10 @@ package haprovider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net"
-	"sync"
+	"strings"
 
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
 
@@ -18,12 +20,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
 	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
 	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
 )
 
@@ -31,40 +37,98 @@ const (
 	IPv4IpFamily = "IPv4"
 	IPv6IpFamily = "IPv6"
 	IPv6IpType   = "V6"
+
+	// haServiceNameHashLength is the length of the hash making the truncated
+	// HA service names unique
+	haServiceNameHashLength = 8
 )
 
+// Resolver returns the addresses of an FQDN in an IP family
+type Resolver func(ctx context.Context, fqdn string, ipFamily string) ([]string, error)
+
 type HAProvider struct {
 	client.Client
-	log logr.Logger
+	recorder record.EventRecorder
+	log      logr.Logger
+	resolve  Resolver
+	clock    clock.PassiveClock
 }
 
-var (
-	instance  *HAProvider
-	once      sync.Once
-	QueryFQDN = queryFQDNEndpoint
-)
+// NewProvider returns an HAProvider resolving the FQDNs through the DNS and
+// reading the system time. Each controller owns its provider, the tests replace
+// the resolver and clock.
+func NewProvider(c client.Client, recorder record.EventRecorder, log logr.Logger) *HAProvider {
+	return &HAProvider{
+		Client:   c,
+		recorder: recorder,
+		log:      log,
+		resolve:  ResolveFQDN,
+		clock:    clock.RealClock{},
+	}
+}
 
-// NewProvider make HAProvider as a singleton
-func NewProvider(c client.Client, log logr.Logger) *HAProvider {
-	once.Do(func() {
-		instance = &HAProvider{
-			Client: c,
-			log:    log,
-		}
-	})
-	return instance
+// WithResolver sets the resolver of the control plane endpoint FQDNs
+func (r *HAProvider) WithResolver(resolve Resolver) *HAProvider {
+	r.resolve = resolve
+	return r
+}
+
+// WithClock sets the clock timing the migrations from kube-vip
+func (r *HAProvider) WithClock(c clock.PassiveClock) *HAProvider {
+	r.clock = c
+	return r
+}
+
+// ResolvesFQDN tells if the control plane endpoint of the cluster is an FQDN,
+// which is resolved again periodically to follow the DNS changes
+func ResolvesFQDN(cluster *clusterv1.Cluster) bool {
+	host := cluster.Spec.ControlPlaneEndpoint.Host
+	return host != "" && net.ParseIP(host) == nil
+}
+
+// HAServiceName returns the name of the control plane load balancer type of
+// service of the cluster. The names which don't fit in a DNS label are
+// truncated and made unique again with a hash of the full name.
+func HAServiceName(cluster *clusterv1.Cluster) string {
+	prefix := cluster.Namespace + "-" + cluster.Name
+	suffix := "-" + akoov1alpha1.HAServiceName
+	if len(prefix)+len(suffix) <= validation.DNS1123LabelMaxLength {
+		return prefix + suffix
+	}
+	hash := sha256.Sum256([]byte(prefix))
+	suffix = "-" + hex.EncodeToString(hash[:])[:haServiceNameHashLength] + suffix
+	return strings.TrimRight(prefix[:validation.DNS1123LabelMaxLength-len(suffix)], "-.") + suffix
+}
+
+// HAServiceNamespace returns the namespace of the HA service of the cluster,
+// the namespace of the operator configuration for the workload clusters when
+// it's set, the cluster namespace otherwise
+func HAServiceNamespace(cluster *clusterv1.Cluster) string {
+	if namespace := config.Get().HAServiceNamespace; namespace != "" && cluster.Namespace != akoov1alpha1.TKGSystemNamespace {
+		return namespace
+	}
+	return cluster.Namespace
 }
 
 func (r *HAProvider) getHAServiceName(cluster *clusterv1.Cluster) string {
-	return cluster.Namespace + "-" + cluster.Name + "-" + akoov1alpha1.HAServiceName
+	return HAServiceName(cluster)
 }
 
 func (r *HAProvider) CreateOrUpdateHAService(ctx context.Context, cluster *clusterv1.Cluster) error {
 	serviceName := r.getHAServiceName(cluster)
+	adcForCluster, err := r.getADCForCluster(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	ensureHAServiceFinalizer(cluster, adcForCluster)
+	if err := r.moveHAService(ctx, cluster); err != nil {
+		return err
+	}
 	service := &corev1.Service{}
+	created := false
 	if err := r.Client.Get(ctx, client.ObjectKey{
 		Name:      serviceName,
-		Namespace: cluster.Namespace,
+		Namespace: HAServiceNamespace(cluster),
 	}, service); err != nil {
 		if apierrors.IsNotFound(err) {
 			r.log.Info(serviceName + " service doesn't exist, start creating it...")
@@ -72,24 +136,77 @@ func (r *HAProvider) CreateOrUpdateHAService(ctx context.Context, cluster *clust
 			if err != nil {
 				return err
 			}
+			created = true
 		} else {
 			return err
 		}
 	}
+	servicePorts, endpointPorts, err := r.controlPlanePorts(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if syncServicePorts(service, servicePorts) {
+		// the service is updated with the control plane endpoint below
+		r.log.Info("control plane ports changed", "service", serviceName, "ports", service.Spec.Ports)
+	}
+	if err := r.reconcileHealthMonitor(ctx, adcForCluster, service); err != nil {
+		return err
+	}
+	// a new service already references the AviInfraSetting it was created with
+	unusedAviInfraSetting := false
+	if !created {
+		if unusedAviInfraSetting, err = r.syncAviInfraSetting(ctx, cluster, adcForCluster, service); err != nil {
+			return err
+		}
+	}
+
 	if err := r.updateClusterControlPlaneEndpoint(cluster, service); err != nil {
 		return err
 	}
+	reserveVIP(cluster, service)
 
 	if err := r.updateControlPlaneEndpointToService(ctx, cluster, service); err != nil {
 		return err
 	}
+	// the HA service doesn't reference it anymore
+	if unusedAviInfraSetting {
+		if err := r.deleteClusterAviInfraSetting(ctx, cluster); err != nil {
+			return err
+		}
+	}
 
-	if _, err := r.ensureEndpoints(ctx, serviceName, service.Namespace); err != nil {
+	endpoints, err := r.ensureEndpoints(ctx, serviceName, service.Namespace)
+	if err != nil {
 		return err
 	}
+	if syncEndpointsPorts(endpoints, endpointPorts) {
+		if err := r.Update(ctx, endpoints); err != nil {
+			return errors.Wrapf(err, "Failed to update the ports of endpoints <%s>\n", endpoints.Name)
+		}
+	}
+	// the HA services and AviInfraSetting outside of the cluster namespace are
+	// gone, the cluster controller doesn't need to clean them up
+	if !needsHAServiceFinalizer(cluster, adcForCluster) && cluster.DeletionTimestamp.IsZero() {
+		ctrlutil.RemoveFinalizer(cluster, akoov1alpha1.HAServiceFinalizer)
+	}
 	return nil
 }
 
+// needsHAServiceFinalizer tells if the HA service or the AviInfraSetting of the
+// cluster live outside of its namespace, the cluster can't own them
+func needsHAServiceFinalizer(cluster *clusterv1.Cluster, adc *akoov1alpha1.AKODeploymentConfig) bool {
+	return HAServiceNamespace(cluster) != cluster.Namespace || (adc != nil && adc.Spec.ControlPlaneAviInfraSetting != nil)
+}
+
+// ensureHAServiceFinalizer adds the HA service finalizer to the cluster before
+// its HA service or AviInfraSetting is created outside of its namespace, they
+// are deleted with it once the cluster api is done with the cluster
+func ensureHAServiceFinalizer(cluster *clusterv1.Cluster, adc *akoov1alpha1.AKODeploymentConfig) {
+	if needsHAServiceFinalizer(cluster, adc) && cluster.DeletionTimestamp.IsZero() {
+		ctrlutil.AddFinalizer(cluster, akoov1alpha1.HAServiceFinalizer)
+	}
+}
+
 func (r *HAProvider) createService(
 	ctx context.Context,
 	cluster *clusterv1.Cluster,
@@ -101,16 +218,17 @@ func (r *HAProvider) createService(
 		return nil, err
 	}
 
-	port, err := ako_operator.GetControlPlaneEndpointPort(cluster)
+	servicePorts, _, err := r.controlPlanePorts(ctx, cluster)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get cluster primary ip family, which is used for HA service
-	primaryIPFamily, err := utils.GetPrimaryIPFamily(cluster)
+	ipFamily, err := utils.GetPrimaryIPFamily(cluster)
 	if err != nil {
 		return nil, err
 	}
+	primaryIPFamily := ipFamily
 	if primaryIPFamily == IPv6IpType {
 		primaryIPFamily = IPv6IpFamily
 	} else {
@@ -124,26 +242,22 @@ func (r *HAProvider) createService(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        serviceName,
-			Namespace:   cluster.Namespace,
+			Namespace:   HAServiceNamespace(cluster),
 			Annotations: serviceAnnotations,
 		},
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeLoadBalancer,
 			// TODO:(chenlin) Add two ip families after AKO fully supports dual-stack load balancer type of service
 			IPFamilies: []corev1.IPFamily{corev1.IPFamily(primaryIPFamily)},
-			Ports: []corev1.ServicePort{
-				{
-					Protocol:   "TCP",
-					Port:       port,
-					TargetPort: intstr.FromInt(int(6443)),
-				},
-			},
+			Ports:      servicePorts,
 		},
 	}
 	// Add Finalizer on Management Cluster's service to avoid being deleted.
+	// The services in another namespace than their cluster are deleted with
+	// the cluster finalizer instead of an owner reference.
 	if cluster.Namespace == akoov1alpha1.TKGSystemNamespace {
 		ctrlutil.AddFinalizer(service, akoov1alpha1.HAServiceBootstrapClusterFinalizer)
-	} else {
+	} else if service.Namespace == cluster.Namespace {
 		service.OwnerReferences = []metav1.OwnerReference{
 			{
 				APIVersion: cluster.APIVersion,
@@ -154,23 +268,40 @@ func (r *HAProvider) createService(
 		}
 	}
 
+	vip, existing := "", false
 	if endpoint, err := ako_operator.GetControlPlaneEndpoint(cluster); err != nil {
 		r.log.Error(err, "can't unmarshal cluster variables ", "endpoint", endpoint)
 		return nil, err
-	} else if endpoint != "" {
+	} else if endpoint != "" && endpoint != serviceAnnotations[akoov1alpha1.HAExternalDNSHostnameAnnotation] {
 		// "endpoint" can be ipv4/ipv6 or hostname, add ipv4/ipv6 or hostname as annotation: ako.vmware.com/load-balancer-ip:<ip>
 		// doesn't support ipv6 endpoint because of AKO limitation: https://avinetworks.com/docs/ako/1.10/support-for-ipv6-in-ako/
+		// a control plane already serving on its endpoint can't move to another one
+		existing = endpoint == cluster.Spec.ControlPlaneEndpoint.Host &&
+			conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
 		if net.ParseIP(endpoint) == nil {
-			endpoint, err = QueryFQDN(endpoint)
+			resolved, err := r.resolveVIP(ctx, endpoint, ipFamily, "")
 			if err != nil {
-				r.log.Error(err, "Failed to resolve control plane endpoint ", "endpoint", endpoint)
 				return nil, err
 			}
+			endpoint = resolved
+		}
+		vip = endpoint
+	} else if reserved := cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation]; reserved != "" {
+		// the service is recreated, request the VIP Avi served before
+		vip = reserved
+	}
+	if vip != "" {
+		adcForCluster, err := r.getADCForCluster(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.validateRequestedVIP(cluster, adcForCluster, vip, existing); err != nil {
+			return nil, err
 		}
 		// update the load balancer ip spec & annotation as intermediate plan to
 		// tolerant older and newer version TKr
-		service.Spec.LoadBalancerIP = endpoint
-		service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation] = endpoint
+		service.Spec.LoadBalancerIP = vip
+		service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation] = vip
 	}
 	r.log.Info("Creating " + serviceName + " Service")
 	err = r.Create(ctx, service)
@@ -197,10 +328,17 @@ func (r *HAProvider) annotateService(ctx context.Context, cluster *clusterv1.Clu
 		return serviceAnnotation, nil
 	}
 
-	aviInfraSetting, err := r.getAviInfraSettingFromAdc(ctx, adcForCluster)
+	// the AviInfraSetting of the cluster takes precedence over the one of the
+	// control plane network
+	aviInfraSetting, err := r.reconcileClusterAviInfraSetting(ctx, cluster, adcForCluster)
 	if err != nil {
 		return serviceAnnotation, err
 	}
+	if aviInfraSetting == nil {
+		if aviInfraSetting, err = r.getAviInfraSettingFromAdc(ctx, adcForCluster); err != nil {
+			return serviceAnnotation, err
+		}
+	}
 
 	if _, ok := cluster.Labels[akoov1alpha1.TKGManagememtClusterRoleLabel]; ok {
 		if adcForCluster.Spec.ControlPlaneNetwork.CIDR != "" && adcForCluster.Spec.ControlPlaneNetwork.CIDR != adcForCluster.Spec.DataNetwork.CIDR {
@@ -213,9 +351,31 @@ func (r *HAProvider) annotateService(ctx context.Context, cluster *clusterv1.Clu
 		// add AVIInfraSetting annotation when creating HA svc
 		serviceAnnotation[akoov1alpha1.HAAVIInfraSettingAnnotationsKey] = aviInfraSetting.Name
 	}
+	if fqdn := ControlPlaneFQDN(cluster, adcForCluster); fqdn != "" {
+		// the FQDN is only published for the clusters which have no control
+		// plane endpoint of their own, or whose endpoint it already is
+		if endpoint, _ := ako_operator.GetControlPlaneEndpoint(cluster); endpoint == "" || endpoint == fqdn {
+			serviceAnnotation[akoov1alpha1.HAExternalDNSHostnameAnnotation] = fqdn
+		}
+	}
 	return serviceAnnotation, nil
 }
 
+// ControlPlaneFQDN returns the FQDN the control plane VIP of the cluster is
+// published as, empty when the AKODeploymentConfig doesn't publish it. The
+// namespace is part of it since clusters of different namespaces can share a
+// name, and it's empty as well when that isn't a valid DNS name.
+func ControlPlaneFQDN(cluster *clusterv1.Cluster, adc *akoov1alpha1.AKODeploymentConfig) string {
+	if adc == nil || adc.Spec.ControlPlaneDNS == nil || adc.Spec.ControlPlaneDNS.Domain == "" {
+		return ""
+	}
+	fqdn := cluster.Name + "." + cluster.Namespace + "." + strings.TrimSuffix(adc.Spec.ControlPlaneDNS.Domain, ".")
+	if len(validation.IsDNS1123Subdomain(fqdn)) != 0 {
+		return ""
+	}
+	return fqdn
+}
+
 func (r *HAProvider) getADCForCluster(ctx context.Context, cluster *clusterv1.Cluster) (*akoov1alpha1.AKODeploymentConfig, error) {
 	adcForCluster, err := ako_operator.GetAKODeploymentConfigForCluster(ctx, r.Client, r.log, cluster)
 	if err != nil {
@@ -250,7 +410,11 @@ func (r *HAProvider) updateClusterControlPlaneEndpoint(cluster *clusterv1.Cluste
 	// Dakar Limitation: customers ensure the service engine is running
 	ingress := service.Status.LoadBalancer.Ingress
 	if len(ingress) > 0 && net.ParseIP(ingress[0].IP) != nil {
-		if endpoint != "" && net.ParseIP(endpoint) == nil {
+		if fqdn := service.Annotations[akoov1alpha1.HAExternalDNSHostnameAnnotation]; endpoint == "" && fqdn != "" {
+			// the VIP is published in DNS, the FQDN becomes the endpoint
+			cluster.Spec.ControlPlaneEndpoint.Host = fqdn
+			ako_operator.SetControlPlaneEndpoint(cluster, fqdn)
+		} else if endpoint != "" && net.ParseIP(endpoint) == nil {
 			cluster.Spec.ControlPlaneEndpoint.Host = endpoint
 		} else {
 			cluster.Spec.ControlPlaneEndpoint.Host = service.Status.LoadBalancer.Ingress[0].IP
@@ -265,13 +429,27 @@ func (r *HAProvider) updateClusterControlPlaneEndpoint(cluster *clusterv1.Cluste
 
 func (r *HAProvider) updateControlPlaneEndpointToService(ctx context.Context, cluster *clusterv1.Cluster, service *corev1.Service) error {
 	host := cluster.Spec.ControlPlaneEndpoint.Host
-	var err error
-	if net.ParseIP(host) == nil {
-		host, err = QueryFQDN(host)
+	current := service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]
+	ingress := service.Status.LoadBalancer.Ingress
+	if fqdn := service.Annotations[akoov1alpha1.HAExternalDNSHostnameAnnotation]; fqdn != "" && host == fqdn && len(ingress) > 0 {
+		// the FQDN published for the VIP may not be resolvable yet, keep
+		// the VIP Avi allocated
+		host = ingress[0].IP
+	} else if net.ParseIP(host) == nil {
+		ipFamily, err := utils.GetPrimaryIPFamily(cluster)
+		if err != nil {
+			return err
+		}
+		vip, err := r.resolveVIP(ctx, host, ipFamily, current)
 		if err != nil {
-			r.log.Error(err, "Failed to resolve control plane endpoint ", "endpoint", host)
 			return err
 		}
+		if current != "" && vip != current {
+			r.log.Info("control plane endpoint resolves to a new VIP", "endpoint", host, "vip", vip, "previous", current)
+			r.recorder.Eventf(cluster, corev1.EventTypeNormal, ControlPlaneVIPChangedReason,
+				"Control plane endpoint %s resolves to %s instead of %s", host, vip, current)
+		}
+		host = vip
 	}
 	service.Spec.LoadBalancerIP = host
 	if service.Annotations == nil {
@@ -319,41 +497,97 @@ func (r *HAProvider) removeMachineIpFromEndpoints(endpoints *corev1.Endpoints, m
 		r.log.Info("currentEndpoints.Subsets is already empty, skip")
 		return
 	}
+	endpoints.Subsets[0].Addresses = withoutMachineAddress(endpoints.Subsets[0].Addresses, machine)
+	endpoints.Subsets[0].NotReadyAddresses = withoutMachineAddress(endpoints.Subsets[0].NotReadyAddresses, machine)
+	// remove the Subset if both "Addresses" and "NotReadyAddresses" are emtpy
+	if len(endpoints.Subsets[0].Addresses) == 0 && len(endpoints.Subsets[0].NotReadyAddresses) == 0 {
+		endpoints.Subsets = nil
+	}
+}
+
+// hasOtherReadyAddress tells if another machine than this one gets the traffic
+func hasOtherReadyAddress(endpoints *corev1.Endpoints, machine *clusterv1.Machine) bool {
+	if len(endpoints.Subsets) == 0 {
+		return false
+	}
+	return len(withoutMachineAddress(endpoints.Subsets[0].Addresses, machine)) != 0
+}
+
+// publishNotReadyAddressesWhenNoneReady moves the not ready addresses to the
+// addresses when none is ready, the control plane VIP is better served by the
+// machines which aren't ready than by none
+func (r *HAProvider) publishNotReadyAddressesWhenNoneReady(endpoints *corev1.Endpoints) {
+	if len(endpoints.Subsets) == 0 || len(endpoints.Subsets[0].Addresses) != 0 || len(endpoints.Subsets[0].NotReadyAddresses) == 0 {
+		return
+	}
+	r.log.Info("no machine is ready, publish every machine in the addresses of " + endpoints.Name)
+	endpoints.Subsets[0].Addresses = endpoints.Subsets[0].NotReadyAddresses
+	endpoints.Subsets[0].NotReadyAddresses = nil
+}
+
+func withoutMachineAddress(addresses []corev1.EndpointAddress, machine *clusterv1.Machine) []corev1.EndpointAddress {
 	newAddresses := make([]corev1.EndpointAddress, 0)
-	for _, address := range endpoints.Subsets[0].Addresses {
+	for _, address := range addresses {
 		// skip the machine should be deleted
 		if address.NodeName != nil && *address.NodeName == machine.Name {
 			continue
 		}
 		newAddresses = append(newAddresses, address)
 	}
-	endpoints.Subsets[0].Addresses = newAddresses
-	// remove the Subset if "Addresses" is emtpy
-	if len(endpoints.Subsets[0].Addresses) == 0 {
-		endpoints.Subsets = nil
+	if len(newAddresses) == 0 {
+		return nil
 	}
+	return newAddresses
 }
 
-func (r *HAProvider) addMachineIpToEndpoints(endpoints *corev1.Endpoints, machine *clusterv1.Machine, ipFamily string) {
+// addMachineIpToEndpoints publishes the machine in the addresses of the
+// endpoints when it's ready, in the not ready addresses otherwise
+func (r *HAProvider) addMachineIpToEndpoints(endpoints *corev1.Endpoints, machine *clusterv1.Machine, ipFamily string, ports []corev1.EndpointPort, ready bool) {
 	if endpoints.Subsets == nil {
 		// create a Subset if Endpoint doesn't have one
 		endpoints.Subsets = []corev1.EndpointSubset{{
 			Addresses: make([]corev1.EndpointAddress, 0),
-			Ports: []corev1.EndpointPort{{
-				Port:     6443,
-				Protocol: "TCP",
-			}},
+			Ports:     ports,
 		}}
 	} else {
 		// check if machine has already been added to Endpoints
-		for i, address := range endpoints.Subsets[0].Addresses {
+		subset := &endpoints.Subsets[0]
+		for i, address := range subset.Addresses {
 			if address.NodeName != nil && *address.NodeName == machine.Name {
 				r.log.Info("machine is in Endpoints Object")
-				endpoints.Subsets[0].Addresses[i] = r.syncEndpointMachineIP(address, machine)
+				address = r.syncEndpointMachineIP(address, machine)
+				if ready {
+					subset.Addresses[i] = address
+				} else {
+					r.log.Info("machine " + machine.Name + " is not ready, move it to the not ready addresses")
+					subset.Addresses = append(subset.Addresses[:i], subset.Addresses[i+1:]...)
+					subset.NotReadyAddresses = append(subset.NotReadyAddresses, address)
+				}
+				return
+			}
+		}
+		for i, address := range subset.NotReadyAddresses {
+			if address.NodeName != nil && *address.NodeName == machine.Name {
+				r.log.Info("machine is in the not ready addresses of Endpoints Object")
+				address = r.syncEndpointMachineIP(address, machine)
+				if !ready {
+					subset.NotReadyAddresses[i] = address
+				} else {
+					r.log.Info("machine " + machine.Name + " is ready, move it to the addresses")
+					subset.NotReadyAddresses = append(subset.NotReadyAddresses[:i], subset.NotReadyAddresses[i+1:]...)
+					if len(subset.NotReadyAddresses) == 0 {
+						subset.NotReadyAddresses = nil
+					}
+					subset.Addresses = append(subset.Addresses, address)
+				}
 				return
 			}
 		}
 	}
+	addresses := &endpoints.Subsets[0].Addresses
+	if !ready {
+		addresses = &endpoints.Subsets[0].NotReadyAddresses
+	}
 	// add a new machine to Endpoints
 	for _, machineAddress := range machine.Status.Addresses {
 		if machineAddress.Type != clusterv1.MachineExternalIP {
@@ -369,12 +603,12 @@ func (r *HAProvider) addMachineIpToEndpoints(endpoints *corev1.Endpoints, machin
 			// Validate MachineIP before adding to Endpoint
 			if ipFamily == "V6" {
 				if net.ParseIP(machineAddress.Address).To4() == nil {
-					endpoints.Subsets[0].Addresses = append(endpoints.Subsets[0].Addresses, newAddress)
+					*addresses = append(*addresses, newAddress)
 					break
 				}
 			} else if ipFamily == "V4" {
 				if net.ParseIP(machineAddress.Address).To4() != nil {
-					endpoints.Subsets[0].Addresses = append(endpoints.Subsets[0].Addresses, newAddress)
+					*addresses = append(*addresses, newAddress)
 					break
 				}
 			}
@@ -401,7 +635,7 @@ func (r *HAProvider) CreateOrUpdateHAEndpoints(ctx context.Context, machine *clu
 		return err
 	}
 
-	endpoints, err := r.ensureEndpoints(ctx, r.getHAServiceName(cluster), cluster.Namespace)
+	endpoints, err := r.ensureEndpoints(ctx, r.getHAServiceName(cluster), HAServiceNamespace(cluster))
 	if err != nil {
 		r.log.Error(err, "Failed to get the Endpoints object of current cluster HA Service")
 		return err
@@ -417,14 +651,24 @@ func (r *HAProvider) CreateOrUpdateHAEndpoints(ctx context.Context, machine *clu
 	if adcForCluster != nil && adcForCluster.Spec.ExtraConfigs.IpFamily != "" {
 		ipFamily = adcForCluster.Spec.ExtraConfigs.IpFamily
 	}
+	_, ports, err := r.controlPlanePorts(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	syncEndpointsPorts(endpoints, ports)
 	if !machine.DeletionTimestamp.IsZero() {
 		r.log.Info("machine" + machine.Name + " is being deleted, remove the endpoint of the machine from " + r.getHAServiceName(cluster) + " Endpoints")
 		r.removeMachineIpFromEndpoints(endpoints, machine)
 	} else {
-		// Add machine ip to the Endpoints object no matter it's ready or not
-		// Because avi controller checks the status of machine. If it's not ready, avi won't use it as an endpoint
-		r.addMachineIpToEndpoints(endpoints, machine, ipFamily)
-	}
+		// Add machine ip to the Endpoints object, the machines which are not
+		// ready yet, e.g. whose etcd member didn't join during a rolling
+		// upgrade, are published in the not ready addresses and get no traffic.
+		// The only ready machine is kept, e.g. the first one of the cluster
+		// whose node can't be healthy before the API server is reachable.
+		ready := MachineReady(machine) || !hasOtherReadyAddress(endpoints, machine)
+		r.addMachineIpToEndpoints(endpoints, machine, ipFamily, ports, ready)
+	}
+	r.publishNotReadyAddressesWhenNoneReady(endpoints)
 	if err := r.Update(ctx, endpoints); err != nil {
 		return errors.Wrapf(err, "Failed to update endpoints <%s>, control plane machine IP doesn't get allocated yet\n", endpoints.Name)
 	}
@@ -463,11 +707,3 @@ func (r *HAProvider) ensureEndpoints(ctx context.Context, serviceName, serviceNa
 func GetAviInfraSettingName(adc *akoov1alpha1.AKODeploymentConfig) string {
 	return adc.Name + "-ais"
 }
-
-func queryFQDNEndpoint(fqdn string) (string, error) {
-	ips, err := net.LookupIP(fqdn)
-	if err == nil {
-		return ips[0].String(), err
-	}
-	return "", err
-}
@@ -5,6 +5,8 @@ package haprovider
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,17 +14,32 @@ import (
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clocktesting "k8s.io/utils/clock/testing"
 	"k8s.io/utils/ptr"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+	"github.com/vmware/alb-sdk/go/models"
+	"github.com/vmware/alb-sdk/go/session"
+	akov1alpha2 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1alpha2"
+	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
 )
 
 var _ = Describe("Control Plane HA provider", func() {
@@ -38,9 +55,11 @@ var _ = Describe("Control Plane HA provider", func() {
 		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
 		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
 		log.SetLogger(zap.New())
-		fc := fakeClient.NewClientBuilder().WithScheme(scheme).Build()
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).Build()
 		logger := log.Log
-		haProvider = *NewProvider(fc, logger)
+		haProvider = *NewProvider(fc, record.NewFakeRecorder(10), logger)
 	})
 
 	Context("Test_CreateOrUpdateHAService", func() {
@@ -156,8 +175,8 @@ var _ = Describe("Control Plane HA provider", func() {
 				}
 				key = client.ObjectKey{Name: haProvider.getHAServiceName(cluster), Namespace: cluster.Namespace}
 				Expect(haProvider.Client.Create(ctx, svc)).ShouldNot(HaveOccurred())
-				QueryFQDN = func(fqdn string) (string, error) {
-					return "3.3.3.3", nil
+				haProvider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+					return []string{"3.3.3.3"}, nil
 				}
 			})
 
@@ -241,8 +260,8 @@ var _ = Describe("Control Plane HA provider", func() {
 					},
 					Spec: clusterv1.ClusterSpec{},
 				}
-				QueryFQDN = func(fqdn string) (string, error) {
-					return "3.3.3.3", nil
+				haProvider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+					return []string{"3.3.3.3"}, nil
 				}
 			})
 			It("should create service successfully", func() {
@@ -264,8 +283,8 @@ var _ = Describe("Control Plane HA provider", func() {
 					},
 					Spec: clusterv1.ClusterSpec{},
 				}
-				QueryFQDN = func(fqdn string) (string, error) {
-					return "", errors.New("Unable to resolve fqdn")
+				haProvider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+					return nil, errors.New("Unable to resolve fqdn")
 				}
 			})
 			It("should fail and can't resolve fqdn", func() {
@@ -274,6 +293,29 @@ var _ = Describe("Control Plane HA provider", func() {
 			})
 		})
 
+		When("cluster FQDN control plane endpoint resolves to no address", func() {
+			BeforeEach(func() {
+				cluster = &clusterv1.Cluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:        "test-cluster",
+						Namespace:   "default",
+						Annotations: map[string]string{"tkg.tanzu.vmware.com/cluster-controlplane-endpoint": "empty.fqdn"},
+					},
+					Spec: clusterv1.ClusterSpec{},
+				}
+				haProvider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+					return nil, nil
+				}
+			})
+			It("should fail without choosing a VIP", func() {
+				_, err = haProvider.createService(ctx, cluster)
+				Expect(err).Should(MatchError(ContainSubstring("empty.fqdn doesn't resolve to any")))
+
+				cluster.Spec.ControlPlaneEndpoint.Host = "empty.fqdn"
+				Expect(haProvider.updateControlPlaneEndpointToService(ctx, cluster, &corev1.Service{})).Should(MatchError(ContainSubstring("doesn't resolve")))
+			})
+		})
+
 		When("cluster is dual-stack IPv4 Primary", func() {
 			BeforeEach(func() {
 				cluster = &clusterv1.Cluster{
@@ -521,6 +563,97 @@ var _ = Describe("Control Plane HA provider", func() {
 					Expect(len(ep.Subsets)).Should(Equal(0))
 				})
 
+				When("the HAEndpointReadiness feature is enabled", func() {
+					var mc2 *clusterv1.Machine
+
+					BeforeEach(func() {
+						c := &config.OperatorConfiguration{FeatureGates: map[string]bool{string(config.HAEndpointReadiness): true}}
+						c.Default()
+						config.Set(c)
+						mc2 = mc.DeepCopy()
+						mc2.Name = "test-mc-2"
+						mc2.Status.Addresses = clusterv1.MachineAddresses{
+							clusterv1.MachineAddress{
+								Type:    clusterv1.MachineExternalIP,
+								Address: "1.1.1.2",
+							},
+						}
+					})
+
+					AfterEach(func() {
+						config.Set(nil)
+					})
+
+					It("should publish the first machine of the cluster before its node is healthy", func() {
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].Addresses[0].IP).Should(Equal("1.1.1.1"))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(BeEmpty())
+
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].NotReadyAddresses[0].IP).Should(Equal("1.1.1.2"))
+					})
+
+					It("should publish a machine whose etcd member is not healthy in the not ready addresses", func() {
+						conditions.MarkTrue(mc2, clusterv1.MachineNodeHealthyCondition)
+						conditions.MarkFalse(mc2, controlplanev1.MachineEtcdMemberHealthyCondition, "Joining", clusterv1.ConditionSeverityWarning, "")
+
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].NotReadyAddresses[0].IP).Should(Equal("1.1.1.2"))
+
+						conditions.MarkTrue(mc2, controlplanev1.MachineEtcdMemberHealthyCondition)
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(2))
+						Expect(ep.Subsets[0].Addresses[1].IP).Should(Equal("1.1.1.2"))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(BeEmpty())
+					})
+
+					It("should move a machine whose node is no longer healthy to the not ready addresses", func() {
+						conditions.MarkTrue(mc2, clusterv1.MachineNodeHealthyCondition)
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+
+						conditions.MarkFalse(mc, clusterv1.MachineNodeHealthyCondition, "NodeConditionsFailed", clusterv1.ConditionSeverityWarning, "")
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc)).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].Addresses[0].IP).Should(Equal("1.1.1.2"))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(HaveLen(1))
+
+						// the machines which are not ready are used rather than none
+						time := v1.Now()
+						mc2.DeletionTimestamp = &time
+						Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+						Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+						Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+						Expect(ep.Subsets[0].Addresses[0].IP).Should(Equal("1.1.1.1"))
+						Expect(ep.Subsets[0].NotReadyAddresses).Should(BeEmpty())
+					})
+				})
+
+				It("should publish every machine in the addresses without the HAEndpointReadiness feature", func() {
+					mc2 := mc.DeepCopy()
+					mc2.Name = "test-mc-2"
+					mc2.Status.Addresses = clusterv1.MachineAddresses{
+						clusterv1.MachineAddress{
+							Type:    clusterv1.MachineExternalIP,
+							Address: "1.1.1.2",
+						},
+					}
+					mc2.Status.Conditions = nil
+
+					Expect(haProvider.CreateOrUpdateHAEndpoints(ctx, mc2)).ShouldNot(HaveOccurred())
+					Expect(haProvider.Client.Get(ctx, key, ep)).ShouldNot(HaveOccurred())
+					Expect(ep.Subsets[0].Addresses).Should(HaveLen(2))
+				})
+
 				It("Support IPv4 and IPv6 now", func() {
 					mc2 := mc.DeepCopy()
 					mc2.Name = "test-mc-2"
@@ -540,3 +673,899 @@ var _ = Describe("Control Plane HA provider", func() {
 		})
 	})
 })
+
+var _ = Describe("Control plane DNS", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		cluster    *clusterv1.Cluster
+		adc        *akoov1alpha1.AKODeploymentConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1beta1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: v1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ControlPlaneDNS: &akoov1alpha1.ControlPlaneDNS{Domain: "k8s.example.com."},
+			},
+		}
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).WithObjects(adc).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+	})
+
+	It("should build the FQDN from the cluster name, its namespace and the domain", func() {
+		Expect(ControlPlaneFQDN(cluster, adc)).Should(Equal("test-cluster.default.k8s.example.com"))
+		other := cluster.DeepCopy()
+		other.Namespace = "other"
+		Expect(ControlPlaneFQDN(other, adc)).Should(Equal("test-cluster.other.k8s.example.com"))
+		Expect(ControlPlaneFQDN(cluster, nil)).Should(BeEmpty())
+		adc.Spec.ControlPlaneDNS = nil
+		Expect(ControlPlaneFQDN(cluster, adc)).Should(BeEmpty())
+	})
+
+	It("should publish the VIP of a cluster without control plane endpoint", func() {
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Annotations[akoov1alpha1.HAExternalDNSHostnameAnnotation]).Should(Equal("test-cluster.default.k8s.example.com"))
+		Expect(svc.Spec.LoadBalancerIP).Should(BeEmpty())
+
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.10"}}
+		Expect(haProvider.updateClusterControlPlaneEndpoint(cluster, svc)).Should(Succeed())
+		Expect(cluster.Spec.ControlPlaneEndpoint.Host).Should(Equal("test-cluster.default.k8s.example.com"))
+
+		haProvider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+			return nil, errors.New("not published yet")
+		}
+		Expect(haProvider.updateControlPlaneEndpointToService(ctx, cluster, svc)).Should(Succeed())
+		Expect(svc.Spec.LoadBalancerIP).Should(Equal("10.0.0.10"))
+		Expect(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.0.0.10"))
+	})
+
+	It("should not publish the VIP of a cluster with its own control plane endpoint", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "2.2.2.2"
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Annotations).ShouldNot(HaveKey(akoov1alpha1.HAExternalDNSHostnameAnnotation))
+		Expect(svc.Spec.LoadBalancerIP).Should(Equal("2.2.2.2"))
+	})
+})
+
+var _ = Describe("Control plane ports", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		cluster    *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(controlplanev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "1.1.1.1", Port: 6443},
+				Topology: &clusterv1.Topology{
+					Variables: []clusterv1.ClusterVariable{
+						{Name: ako_operator.ApiServerTargetPort, Value: apiextensionsv1.JSON{Raw: []byte("8443")}},
+						{Name: ako_operator.ApiServerExtraPorts, Value: apiextensionsv1.JSON{Raw: []byte(`[{"name":"konnectivity","port":8132}]`)}},
+					},
+				},
+			},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).WithObjects(cluster).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+	})
+
+	It("should expose the target port and the extra ports on the service", func() {
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Spec.Ports).Should(Equal([]corev1.ServicePort{
+			{Name: ako_operator.ControlPlanePortName, Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(8443)},
+			{Name: "konnectivity", Protocol: corev1.ProtocolTCP, Port: 8132, TargetPort: intstr.FromInt(8132)},
+		}))
+	})
+
+	It("should update the ports of an existing service and its endpoints", func() {
+		svc := &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Name: haProvider.getHAServiceName(cluster), Namespace: cluster.Namespace},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(6443), NodePort: 30000}},
+			},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+			},
+		}
+		Expect(haProvider.Client.Create(ctx, svc)).ShouldNot(HaveOccurred())
+		ep := &corev1.Endpoints{
+			ObjectMeta: v1.ObjectMeta{Name: svc.Name, Namespace: svc.Namespace},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+				Ports:     []corev1.EndpointPort{{Port: 6443, Protocol: corev1.ProtocolTCP}},
+			}},
+		}
+		Expect(haProvider.Client.Create(ctx, ep)).ShouldNot(HaveOccurred())
+
+		Expect(haProvider.CreateOrUpdateHAService(ctx, cluster)).ShouldNot(HaveOccurred())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), svc)).ShouldNot(HaveOccurred())
+		Expect(svc.Spec.Ports).Should(HaveLen(2))
+		Expect(svc.Spec.Ports[0].NodePort).Should(Equal(int32(30000)))
+		Expect(svc.Spec.Ports[0].TargetPort).Should(Equal(intstr.FromInt(8443)))
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(ep), ep)).ShouldNot(HaveOccurred())
+		Expect(ep.Subsets[0].Addresses).Should(HaveLen(1))
+		Expect(ep.Subsets[0].Ports).Should(Equal([]corev1.EndpointPort{
+			{Name: ako_operator.ControlPlanePortName, Protocol: corev1.ProtocolTCP, Port: 8443},
+			{Name: "konnectivity", Protocol: corev1.ProtocolTCP, Port: 8132},
+		}))
+	})
+
+	It("should keep the node port of an unchanged port", func() {
+		svc := &corev1.Service{Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(6443), NodePort: 30000}},
+		}}
+		Expect(syncServicePorts(svc, []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(6443)}})).Should(BeFalse())
+		Expect(svc.Spec.Ports[0].NodePort).Should(Equal(int32(30000)))
+	})
+
+	It("should not give the node port of a port to two ports", func() {
+		svc := &corev1.Service{Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: ako_operator.ControlPlanePortName, Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(6443), NodePort: 30000},
+			},
+		}}
+		Expect(syncServicePorts(svc, []corev1.ServicePort{
+			{Name: ako_operator.ControlPlanePortName, Protocol: corev1.ProtocolTCP, Port: 8443, TargetPort: intstr.FromInt(8443)},
+			{Name: "konnectivity", Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(8132), NodePort: 30001},
+		})).Should(BeTrue())
+		Expect(svc.Spec.Ports[0].NodePort).Should(Equal(int32(30000)))
+		Expect(svc.Spec.Ports[1].NodePort).Should(BeZero())
+	})
+
+	When("the cluster is not based on a ClusterClass", func() {
+		var kcp *controlplanev1.KubeadmControlPlane
+
+		BeforeEach(func() {
+			kcp = &controlplanev1.KubeadmControlPlane{
+				ObjectMeta: v1.ObjectMeta{Name: "test-cluster-control-plane", Namespace: cluster.Namespace},
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+						InitConfiguration: &bootstrapv1.InitConfiguration{
+							LocalAPIEndpoint: bootstrapv1.APIEndpoint{BindPort: 7443},
+						},
+					},
+				},
+			}
+			cluster.Spec.Topology = nil
+			cluster.Spec.ControlPlaneRef = &corev1.ObjectReference{
+				APIVersion: controlplanev1.GroupVersion.String(),
+				Kind:       "KubeadmControlPlane",
+				Name:       kcp.Name,
+			}
+		})
+
+		It("should target the bind port of the KubeadmControlPlane", func() {
+			Expect(haProvider.Client.Create(ctx, kcp)).ShouldNot(HaveOccurred())
+			svc, err := haProvider.createService(ctx, cluster)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(svc.Spec.Ports).Should(Equal([]corev1.ServicePort{
+				{Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(7443)},
+			}))
+		})
+
+		It("should target the secure port of the API server of the KubeadmControlPlane", func() {
+			kcp.Spec.KubeadmConfigSpec.ClusterConfiguration = &bootstrapv1.ClusterConfiguration{
+				APIServer: bootstrapv1.APIServer{
+					ControlPlaneComponent: bootstrapv1.ControlPlaneComponent{ExtraArgs: map[string]string{"secure-port": "9443"}},
+				},
+			}
+			Expect(haProvider.Client.Create(ctx, kcp)).ShouldNot(HaveOccurred())
+			svc, err := haProvider.createService(ctx, cluster)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(svc.Spec.Ports[0].TargetPort).Should(Equal(intstr.FromInt(9443)))
+		})
+
+		It("should target the default port without a KubeadmControlPlane", func() {
+			svc, err := haProvider.createService(ctx, cluster)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(svc.Spec.Ports[0].TargetPort).Should(Equal(intstr.FromInt(6443)))
+		})
+	})
+})
+
+var _ = Describe("Control plane health monitor", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		adc        *akoov1alpha1.AKODeploymentConfig
+		svc        *corev1.Service
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1alpha2.AddToScheme(scheme)).NotTo(HaveOccurred())
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: v1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+			Spec:       akoov1alpha1.AKODeploymentConfigSpec{ControlPlaneHealthMonitor: "apiserver-readyz"},
+		}
+		svc = &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Name: "default-test-cluster-control-plane", Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 6443, TargetPort: intstr.FromInt(6443)}},
+			},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(svc).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+	})
+
+	It("should attach the health monitor through an L4Rule and detach it once unset", func() {
+		Expect(haProvider.reconcileHealthMonitor(ctx, adc, svc)).Should(Succeed())
+		Expect(svc.Annotations[akoov1alpha1.HAL4RuleAnnotation]).Should(Equal(svc.Name))
+		l4Rule := &akov1alpha2.L4Rule{}
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), l4Rule)).Should(Succeed())
+		Expect(l4Rule.Spec.BackendProperties).Should(HaveLen(1))
+		Expect(*l4Rule.Spec.BackendProperties[0].Port).Should(Equal(6443))
+		Expect(l4Rule.Spec.BackendProperties[0].HealthMonitorRefs).Should(Equal([]string{"apiserver-readyz"}))
+		Expect(l4Rule.OwnerReferences[0].Name).Should(Equal(svc.Name))
+
+		adc.Spec.ControlPlaneHealthMonitor = "System-TCP"
+		Expect(haProvider.reconcileHealthMonitor(ctx, adc, svc)).Should(Succeed())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), l4Rule)).Should(Succeed())
+		Expect(l4Rule.Spec.BackendProperties[0].HealthMonitorRefs).Should(Equal([]string{"System-TCP"}))
+
+		adc.Spec.ControlPlaneHealthMonitor = ""
+		Expect(haProvider.reconcileHealthMonitor(ctx, adc, svc)).Should(Succeed())
+		Expect(svc.Annotations).ShouldNot(HaveKey(akoov1alpha1.HAL4RuleAnnotation))
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), l4Rule))).Should(BeTrue())
+	})
+
+	It("should not touch the service without health monitor", func() {
+		Expect(haProvider.reconcileHealthMonitor(ctx, nil, svc)).Should(Succeed())
+		Expect(svc.Annotations).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("Control plane machine drain", func() {
+	var (
+		ctx           context.Context
+		haProvider    *HAProvider
+		fakeAviClient *aviclient.FakeAviClient
+		cluster       *clusterv1.Cluster
+		machine       *clusterv1.Machine
+		service       *corev1.Service
+		vsName        string
+		vs            *models.VirtualService
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		managementCluster := &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "mgmt",
+				Namespace: akoov1alpha1.TKGSystemNamespace,
+				Labels:    map[string]string{akoov1alpha1.TKGManagememtClusterRoleLabel: ""},
+			},
+		}
+		cluster = &clusterv1.Cluster{ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"}}
+		machine = &clusterv1.Machine{
+			ObjectMeta: v1.ObjectMeta{Name: "test-machine", Namespace: "default"},
+			Status: clusterv1.MachineStatus{
+				Addresses: clusterv1.MachineAddresses{{Type: clusterv1.MachineExternalIP, Address: "1.1.1.1"}},
+			},
+		}
+		service = &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Name: "default-test-cluster-control-plane", Namespace: "default"},
+		}
+		vsName = "tkg-system-mgmt--default-default-test-cluster-control-plane"
+		vs = &models.VirtualService{
+			Name: ptr.To(vsName),
+			ServicePoolSelect: []*models.ServicePoolSelector{
+				{ServicePort: ptr.To(uint32(443)), ServicePoolRef: ptr.To("https://avi/api/pool/pool-443")},
+				{ServicePort: ptr.To(uint32(6443)), ServicePoolRef: ptr.To("https://avi/api/pool/pool-6443")},
+			},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(managementCluster, service).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+		fakeAviClient = aviclient.NewFakeAviClient()
+		fakeAviClient.VirtualService.SetGetByNameFn(func(name string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+			if name != vsName {
+				return nil, errors.New("No object of type virtualservice with name " + name + " is found")
+			}
+			return vs, nil
+		})
+	})
+
+	poolWith := func(servers ...*models.Server) aviclient.GetPoolFunc {
+		return func(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+			Expect(uuid).Should(Equal("pool-6443"))
+			return &models.Pool{Name: ptr.To(uuid), Servers: servers}, nil
+		}
+	}
+
+	It("should hold the drain while the machine is an enabled pool member", func() {
+		fakeAviClient.Pool.SetGetFn(poolWith(
+			&models.Server{IP: &models.IPAddr{Addr: ptr.To("1.1.1.1")}, Enabled: ptr.To(true)},
+			&models.Server{IP: &models.IPAddr{Addr: ptr.To("1.1.1.2")}},
+		))
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeFalse())
+	})
+
+	It("should release the drain once the pool member is disabled or removed", func() {
+		fakeAviClient.Pool.SetGetFn(poolWith(
+			&models.Server{IP: &models.IPAddr{Addr: ptr.To("1.1.1.1")}, Enabled: ptr.To(false)},
+		))
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeTrue())
+		fakeAviClient.Pool.SetGetFn(poolWith(
+			&models.Server{IP: &models.IPAddr{Addr: ptr.To("1.1.1.2")}},
+		))
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeTrue())
+	})
+
+	It("should use the virtual service AKO recorded on the HA service", func() {
+		service.Annotations = map[string]string{akoov1alpha1.AkoVirtualServiceAnnotation: `{"test-cluster.k8s.example.com":"vs-uuid"}`}
+		Expect(haProvider.Client.Update(ctx, service)).To(Succeed())
+		vsName = "unused"
+		fakeAviClient.VirtualService.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+			Expect(uuid).Should(Equal("vs-uuid"))
+			return vs, nil
+		})
+		fakeAviClient.Pool.SetGetFn(poolWith(
+			&models.Server{IP: &models.IPAddr{Addr: ptr.To("1.1.1.1")}},
+		))
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeFalse())
+	})
+
+	It("should hold the drain when the virtual service or its pool can't be found", func() {
+		vsName = "another-vs"
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeFalse())
+		vsName = "tkg-system-mgmt--default-default-test-cluster-control-plane"
+		vs.ServicePoolSelect = vs.ServicePoolSelect[:1]
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeFalse())
+	})
+
+	It("should release the drain when the HA service doesn't exist", func() {
+		Expect(haProvider.Client.Delete(ctx, service)).To(Succeed())
+		Expect(haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)).Should(BeTrue())
+	})
+
+	It("should fail on the other Avi errors", func() {
+		fakeAviClient.Pool.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.Pool, error) {
+			return nil, errors.New("Rest request error, returning to caller")
+		})
+		_, err := haProvider.MachineDrained(ctx, fakeAviClient, machine, cluster)
+		Expect(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("Control plane VIP reservation", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		recorder   *record.FakeRecorder
+		cluster    *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1beta1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		adc := &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: v1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ControlPlaneNetwork: akoov1alpha1.ControlPlaneNetwork{Name: "cp-network", CIDR: "10.10.0.0/16"},
+				DataNetwork: akoov1alpha1.DataNetwork{
+					Name:    "data-network",
+					CIDR:    "10.10.1.0/24",
+					IPPools: []akoov1alpha1.IPPool{{Start: "10.10.1.10", End: "10.10.1.50", Type: "V4"}},
+				},
+			},
+		}
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        "test-cluster",
+				Namespace:   "default",
+				Annotations: map[string]string{akoov1alpha1.HAReservedVIPAnnotation: "10.10.2.5"},
+			},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).WithObjects(adc).Build()
+		recorder = record.NewFakeRecorder(10)
+		haProvider = NewProvider(fc, recorder, log.Log)
+	})
+
+	It("should record the VIP Avi serves", func() {
+		svc := &corev1.Service{}
+		reserveVIP(cluster, svc)
+		Expect(cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation]).Should(Equal("10.10.2.5"))
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.10.2.6"}}
+		reserveVIP(cluster, svc)
+		Expect(cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation]).Should(Equal("10.10.2.6"))
+	})
+
+	It("should request the reserved VIP when the service is recreated", func() {
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Spec.LoadBalancerIP).Should(Equal("10.10.2.5"))
+		Expect(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.10.2.5"))
+	})
+
+	It("should prefer the control plane endpoint of the cluster", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "10.10.3.5"
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.10.3.5"))
+	})
+
+	It("should refuse a VIP out of the control plane network", func() {
+		cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation] = "10.20.0.5"
+		_, err := haProvider.createService(ctx, cluster)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("not in the control plane network"))
+		Expect(errors.Is(err, ErrControlPlaneVIPInvalid)).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ControlPlaneVIPInvalidReason)))
+	})
+
+	It("should refuse a control plane endpoint out of the control plane network", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "10.20.0.5"
+		_, err := haProvider.createService(ctx, cluster)
+		Expect(errors.Is(err, ErrControlPlaneVIPInvalid)).Should(BeTrue())
+		Expect(err.Error()).Should(ContainSubstring("not in the control plane network"))
+	})
+
+	It("should refuse a VIP in the data network pools", func() {
+		cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation] = "10.10.1.20"
+		_, err := haProvider.createService(ctx, cluster)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("data network pool"))
+	})
+
+	It("should keep the control plane endpoint the cluster already serves on in the data network pools", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "10.10.1.20"
+		conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Spec.LoadBalancerIP).Should(Equal("10.10.1.20"))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ControlPlaneVIPInPoolReason)))
+	})
+
+	It("should refuse a control plane endpoint in the data network pools before the control plane is initialized", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "10.10.1.20"
+		_, err := haProvider.createService(ctx, cluster)
+		Expect(errors.Is(err, ErrControlPlaneVIPInvalid)).Should(BeTrue())
+		Expect(err.Error()).Should(ContainSubstring("data network pool"))
+	})
+
+	It("should check the data network without a dedicated control plane network", func() {
+		adc := &akoov1alpha1.AKODeploymentConfig{
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ControlPlaneNetwork: akoov1alpha1.ControlPlaneNetwork{Name: "data-network", CIDR: "10.10.1.0/24"},
+				DataNetwork: akoov1alpha1.DataNetwork{
+					Name:    "data-network",
+					CIDR:    "10.10.1.0/24",
+					IPPools: []akoov1alpha1.IPPool{{Start: "10.10.1.10", End: "10.10.1.50", Type: "V4"}},
+				},
+			},
+		}
+		Expect(validateVIP("10.10.1.5", "", adc)).Should(Succeed())
+		Expect(validateVIP("10.10.2.5", "", adc)).Should(MatchError(ContainSubstring("not in the data network")))
+		Expect(validateVIP("10.10.1.20", "", adc)).Should(MatchError(ContainSubstring("data network pool")))
+		// Avi allocated that VIP to the cluster from the pool, it's requested back
+		Expect(validateVIP("10.10.1.20", "10.10.1.20", adc)).Should(Succeed())
+		adc.Spec.ControlPlaneNetwork = akoov1alpha1.ControlPlaneNetwork{}
+		Expect(validateVIP("10.10.1.20", "", adc)).Should(MatchError(ContainSubstring("data network pool")))
+	})
+
+	It("should only check the VIP is an IP without AKODeploymentConfig", func() {
+		Expect(validateVIP("10.10.1.20", "", nil)).Should(Succeed())
+		Expect(validateVIP("cluster.example.com", "", nil)).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("Cluster AviInfraSetting", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		cluster    *clusterv1.Cluster
+		adc        *akoov1alpha1.AKODeploymentConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1beta1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		adc = &akoov1alpha1.AKODeploymentConfig{
+			ObjectMeta: v1.ObjectMeta{Name: akoov1alpha1.WorkloadClusterAkoDeploymentConfig},
+			Spec: akoov1alpha1.AKODeploymentConfigSpec{
+				ServiceEngineGroup: "Default-Group",
+				DataNetwork:        akoov1alpha1.DataNetwork{Name: "data-network", CIDR: "10.10.1.0/24"},
+				ControlPlaneAviInfraSetting: &akoov1alpha1.ControlPlaneAviInfraSetting{
+					ServiceEngineGroup: "control-plane-group",
+					EnableRHI:          ptr.To(true),
+					BGPPeerLabels:      []string{"peer-a"},
+					EnablePublicIP:     ptr.To(false),
+				},
+			},
+		}
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+		}
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).WithObjects(adc).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+	})
+
+	createReadyHAService := func() {
+		cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "10.10.1.10", Port: 6443}
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.10.1.10"}}
+		Expect(haProvider.Client.Status().Update(ctx, svc)).Should(Succeed())
+	}
+
+	It("should reference an AviInfraSetting of the cluster from the HA service", func() {
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Annotations[akoov1alpha1.HAAVIInfraSettingAnnotationsKey]).Should(Equal("default-test-cluster-control-plane-ais"))
+
+		ais := &akov1beta1.AviInfraSetting{}
+		Expect(haProvider.Client.Get(ctx, client.ObjectKey{Name: ClusterAviInfraSettingName(cluster)}, ais)).Should(Succeed())
+		Expect(ais.Spec.SeGroup.Name).Should(Equal("control-plane-group"))
+		Expect(ais.Spec.Network.VipNetworks).Should(Equal([]akov1beta1.AviInfraSettingVipNetwork{{NetworkName: "data-network", Cidr: "10.10.1.0/24"}}))
+		Expect(ais.Spec.Network.EnableRhi).Should(Equal(ptr.To(true)))
+		Expect(ais.Spec.Network.BgpPeerLabels).Should(Equal([]string{"peer-a"}))
+		Expect(ais.Spec.Network.EnablePublicIP).Should(Equal(ptr.To(false)))
+		Expect(ais.Annotations[akoov1alpha1.TKGClusterNameLabel]).Should(Equal(cluster.Name))
+
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKey{Name: ClusterAviInfraSettingName(cluster)}, ais))).Should(BeTrue())
+	})
+
+	It("should default the service engine group and use the control plane network", func() {
+		adc.Spec.ControlPlaneAviInfraSetting.ServiceEngineGroup = ""
+		adc.Spec.ControlPlaneNetwork = akoov1alpha1.ControlPlaneNetwork{Name: "cp-network", CIDR: "fd00::/64"}
+		spec := clusterAviInfraSettingSpec(adc)
+		Expect(spec.SeGroup.Name).Should(Equal("Default-Group"))
+		Expect(spec.Network.VipNetworks).Should(Equal([]akov1beta1.AviInfraSettingVipNetwork{{NetworkName: "cp-network", V6Cidr: "fd00::/64"}}))
+	})
+
+	It("should keep the HA service finalizer while the cluster has an AviInfraSetting", func() {
+		createReadyHAService()
+		Expect(haProvider.CreateOrUpdateHAService(ctx, cluster)).Should(Succeed())
+		Expect(cluster.Finalizers).Should(ContainElement(akoov1alpha1.HAServiceFinalizer))
+		ais := &akov1beta1.AviInfraSetting{}
+		Expect(haProvider.Client.Get(ctx, client.ObjectKey{Name: ClusterAviInfraSettingName(cluster)}, ais)).Should(Succeed())
+
+		adc.Spec.ControlPlaneAviInfraSetting = nil
+		Expect(haProvider.Client.Update(ctx, adc)).Should(Succeed())
+		Expect(haProvider.CreateOrUpdateHAService(ctx, cluster)).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKey{Name: ClusterAviInfraSettingName(cluster)}, ais))).Should(BeTrue())
+		Expect(cluster.Finalizers).ShouldNot(ContainElement(akoov1alpha1.HAServiceFinalizer))
+	})
+
+	It("should remove the HA service finalizer once the HA service is deleted", func() {
+		createReadyHAService()
+		Expect(haProvider.CreateOrUpdateHAService(ctx, cluster)).Should(Succeed())
+		Expect(cluster.Finalizers).Should(ContainElement(akoov1alpha1.HAServiceFinalizer))
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+		Expect(cluster.Finalizers).ShouldNot(ContainElement(akoov1alpha1.HAServiceFinalizer))
+	})
+
+	It("should not add the HA service finalizer without an AviInfraSetting of the cluster", func() {
+		createReadyHAService()
+		adc.Spec.ControlPlaneAviInfraSetting = nil
+		Expect(haProvider.Client.Update(ctx, adc)).Should(Succeed())
+		Expect(haProvider.CreateOrUpdateHAService(ctx, cluster)).Should(Succeed())
+		Expect(cluster.Finalizers).ShouldNot(ContainElement(akoov1alpha1.HAServiceFinalizer))
+	})
+
+	It("should move the HA service back to the AviInfraSetting of the AKODeploymentConfig", func() {
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		adc.Spec.ControlPlaneAviInfraSetting = nil
+		Expect(haProvider.Client.Create(ctx, &akov1beta1.AviInfraSetting{ObjectMeta: v1.ObjectMeta{Name: GetAviInfraSettingName(adc)}})).Should(Succeed())
+		unused, err := haProvider.syncAviInfraSetting(ctx, cluster, adc, svc)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unused).Should(BeTrue())
+		Expect(svc.Annotations[akoov1alpha1.HAAVIInfraSettingAnnotationsKey]).Should(Equal(GetAviInfraSettingName(adc)))
+
+		unused, err = haProvider.syncAviInfraSetting(ctx, cluster, adc, svc)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unused).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Control plane migration from kube-vip", func() {
+	var (
+		ctx           context.Context
+		haProvider    *HAProvider
+		cluster       *clusterv1.Cluster
+		svc           *corev1.Service
+		fakeAviClient *aviclient.FakeAviClient
+		vsState       string
+		poolState     string
+		serversUp     int64
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443},
+			},
+		}
+		svc = &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      HAServiceName(cluster),
+				Namespace: "default",
+				Annotations: map[string]string{
+					akoov1alpha1.AkoPreferredIPAnnotation:    "10.0.0.10",
+					akoov1alpha1.AkoVirtualServiceAnnotation: `{"test-cluster.default.k8s.example.com":"vs-uuid"}`,
+					akoov1alpha1.HAServiceAnnotationsKey:     "true",
+					akoov1alpha1.TKGClusterNameLabel:         cluster.Name,
+					akoov1alpha1.TKGClusterNameSpaceLabel:    cluster.Namespace,
+				},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		}
+		vsState, poolState, serversUp = "OPER_UP", "OPER_UP", 1
+		fakeAviClient = aviclient.NewFakeAviClient()
+		fakeAviClient.VirtualService.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.VirtualService, error) {
+			Expect(uuid).Should(Equal("vs-uuid"))
+			return &models.VirtualService{
+				UUID:    ptr.To(uuid),
+				PoolRef: ptr.To("https://avi/api/pool/pool-6443"),
+			}, nil
+		})
+		fakeAviClient.VsInventory.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.VsInventory, error) {
+			Expect(uuid).Should(Equal("vs-uuid"))
+			return &models.VsInventory{
+				Runtime: &models.VsRuntimeSummary{OperStatus: &models.OperationalStatus{State: ptr.To(vsState)}},
+			}, nil
+		})
+		fakeAviClient.PoolInventory.SetGetFn(func(uuid string, options ...session.ApiOptionsParams) (*models.PoolInventory, error) {
+			Expect(uuid).Should(Equal("pool-6443"))
+			return &models.PoolInventory{
+				Runtime: &models.PoolRuntimeSummary{
+					NumServersUp: ptr.To(serversUp),
+					OperStatus:   &models.OperationalStatus{State: ptr.To(poolState)},
+				},
+			}, nil
+		})
+	})
+
+	build := func(objs ...client.Object) {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1beta1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).WithObjects(objs...).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log).
+			WithClock(clocktesting.NewFakePassiveClock(time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)))
+	}
+
+	It("should time the migration with the provider clock", func() {
+		build()
+		cluster.Annotations = map[string]string{}
+		haProvider.StartHAMigration(cluster)
+		Expect(cluster.Annotations[akoov1alpha1.HAMigrationStartedAnnotation]).Should(Equal("2024-03-01T12:00:00Z"))
+		Expect(haProvider.HAMigrationExpired(cluster, time.Minute)).Should(BeFalse())
+		haProvider.WithClock(clocktesting.NewFakePassiveClock(time.Date(2024, time.March, 1, 12, 2, 0, 0, time.UTC)))
+		Expect(haProvider.HAMigrationExpired(cluster, time.Minute)).Should(BeTrue())
+	})
+
+	It("should create the HA service with the control plane endpoint as VIP", func() {
+		build()
+		Expect(haProvider.MigrateHAService(ctx, fakeAviClient, cluster)).Should(BeFalse())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), svc)).Should(Succeed())
+		Expect(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.0.0.10"))
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), &corev1.Endpoints{})).Should(Succeed())
+		Expect(cluster.Spec.ControlPlaneEndpoint.Host).Should(Equal("10.0.0.10"))
+	})
+
+	It("should complete once the virtual service and its pool are up", func() {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.10"}}
+		build(svc)
+		Expect(haProvider.MigrateHAService(ctx, fakeAviClient, cluster)).Should(BeTrue())
+	})
+
+	It("should wait while the virtual service is down", func() {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.10"}}
+		build(svc)
+		vsState = "OPER_DOWN"
+		Expect(haProvider.MigrateHAService(ctx, fakeAviClient, cluster)).Should(BeFalse())
+	})
+
+	It("should wait while no pool member is up", func() {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.10"}}
+		build(svc)
+		serversUp = 0
+		Expect(haProvider.MigrateHAService(ctx, fakeAviClient, cluster)).Should(BeFalse())
+		serversUp, poolState = 1, "OPER_DOWN"
+		Expect(haProvider.MigrateHAService(ctx, fakeAviClient, cluster)).Should(BeFalse())
+	})
+
+	It("should fail when Avi allocates another VIP", func() {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.11"}}
+		build(svc)
+		_, err := haProvider.MigrateHAService(ctx, fakeAviClient, cluster)
+		Expect(errors.Is(err, ErrHAMigrationVIPMismatch)).Should(BeTrue())
+	})
+
+	It("should delete the HA service and its endpoints on rollback", func() {
+		build(svc, &corev1.Endpoints{ObjectMeta: v1.ObjectMeta{Name: svc.Name, Namespace: svc.Namespace}})
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), &corev1.Service{}))).Should(BeTrue())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(svc), &corev1.Endpoints{}))).Should(BeTrue())
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+	})
+})
+
+var _ = Describe("HA service placement", func() {
+	var (
+		ctx        context.Context
+		haProvider *HAProvider
+		cluster    *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default", UID: "test-uid"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443},
+			},
+		}
+		c := &config.OperatorConfiguration{HAServiceNamespace: "tkg-ha-services"}
+		c.Default()
+		config.Set(c)
+	})
+
+	AfterEach(func() {
+		config.Set(nil)
+	})
+
+	build := func(objs ...client.Object) {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akoov1alpha1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		Expect(akov1beta1.AddToScheme(scheme)).NotTo(HaveOccurred())
+		fc := fakeClient.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigSelectorField, index.AKODeploymentConfigBySelector).
+			WithIndex(&corev1.Service{}, index.ServiceClusterField, index.ServiceByCluster).
+			WithObjects(objs...).Build()
+		haProvider = NewProvider(fc, record.NewFakeRecorder(10), log.Log)
+	}
+
+	It("should keep the short names and truncate the long ones with a hash", func() {
+		Expect(HAServiceName(cluster)).Should(Equal("default-test-cluster-control-plane"))
+
+		cluster.Name = strings.Repeat("a", 60)
+		name := HAServiceName(cluster)
+		Expect(name).Should(HaveLen(63))
+		Expect(name).Should(HavePrefix("default-aaaa"))
+		Expect(name).Should(HaveSuffix("-" + akoov1alpha1.HAServiceName))
+		Expect(HAServiceName(cluster)).Should(Equal(name))
+
+		other := cluster.DeepCopy()
+		other.Name = strings.Repeat("a", 59) + "b"
+		Expect(HAServiceName(other)).ShouldNot(Equal(name))
+		Expect(HAServiceName(other)).Should(HaveLen(63))
+	})
+
+	It("should host the workload cluster services in the configured namespace", func() {
+		build()
+		Expect(HAServiceNamespace(cluster)).Should(Equal("tkg-ha-services"))
+		svc, err := haProvider.createService(ctx, cluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(svc.Namespace).Should(Equal("tkg-ha-services"))
+		Expect(svc.OwnerReferences).Should(BeEmpty())
+
+		management := cluster.DeepCopy()
+		management.Namespace = akoov1alpha1.TKGSystemNamespace
+		Expect(HAServiceNamespace(management)).Should(Equal(akoov1alpha1.TKGSystemNamespace))
+	})
+
+	newService := func(name, namespace string, annotations map[string]string) *corev1.Service {
+		svc := &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Annotations: map[string]string{
+					akoov1alpha1.TKGClusterNameLabel:      cluster.Name,
+					akoov1alpha1.TKGClusterNameSpaceLabel: cluster.Namespace,
+				},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		}
+		for k, v := range annotations {
+			svc.Annotations[k] = v
+		}
+		return svc
+	}
+
+	It("should move the existing services with their endpoints", func() {
+		legacy := newService(HAServiceName(cluster), cluster.Namespace, map[string]string{akoov1alpha1.HAServiceAnnotationsKey: "true"})
+		endpoints := &corev1.Endpoints{
+			ObjectMeta: v1.ObjectMeta{Name: legacy.Name, Namespace: legacy.Namespace},
+			Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "1.1.1.1"}}}},
+		}
+		build(cluster, legacy, endpoints)
+
+		Expect(haProvider.moveHAService(ctx, cluster)).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(legacy), &corev1.Service{}))).Should(BeTrue())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(legacy), &corev1.Endpoints{}))).Should(BeTrue())
+		moved := &corev1.Endpoints{}
+		key := client.ObjectKey{Namespace: "tkg-ha-services", Name: legacy.Name}
+		Expect(haProvider.Client.Get(ctx, key, moved)).Should(Succeed())
+		Expect(moved.Subsets).Should(Equal(endpoints.Subsets))
+
+		svc := &corev1.Service{}
+		Expect(haProvider.Client.Get(ctx, key, svc)).Should(Succeed())
+		Expect(svc.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.0.0.10"))
+		Expect(haProvider.moveHAService(ctx, cluster)).Should(Succeed())
+		Expect(haProvider.Client.Get(ctx, key, &corev1.Service{})).Should(Succeed())
+
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, key, &corev1.Service{}))).Should(BeTrue())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, key, &corev1.Endpoints{}))).Should(BeTrue())
+	})
+
+	It("should keep the old service when the new one can't be created", func() {
+		cluster.Spec.ControlPlaneEndpoint.Host = "cluster.example.com"
+		legacy := newService(HAServiceName(cluster), cluster.Namespace, map[string]string{akoov1alpha1.HAServiceAnnotationsKey: "true"})
+		build(cluster, legacy)
+		haProvider.WithResolver(func(context.Context, string, string) ([]string, error) {
+			return nil, errors.New("no such host")
+		})
+
+		Expect(haProvider.moveHAService(ctx, cluster)).ShouldNot(Succeed())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(legacy), &corev1.Service{})).Should(Succeed())
+	})
+
+	It("should leave the other services of the cluster alone", func() {
+		user := newService("user-lb", cluster.Namespace, nil)
+		unannotated := newService(HAServiceName(cluster), cluster.Namespace, nil)
+		unannotated.Namespace = "other"
+		build(cluster, user, unannotated)
+
+		Expect(haProvider.moveHAService(ctx, cluster)).Should(Succeed())
+		Expect(haProvider.DeleteHAService(ctx, cluster)).Should(Succeed())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(user), &corev1.Service{})).Should(Succeed())
+		Expect(haProvider.Client.Get(ctx, client.ObjectKeyFromObject(unannotated), &corev1.Service{})).Should(Succeed())
+		Expect(apierrors.IsNotFound(haProvider.Client.Get(ctx, client.ObjectKey{Namespace: "tkg-ha-services", Name: HAServiceName(cluster)}, &corev1.Service{}))).Should(BeTrue())
+	})
+})
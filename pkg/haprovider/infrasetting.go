@@ -0,0 +1,154 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
+	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
+)
+
+// ClusterAviInfraSettingName returns the name of the AviInfraSetting of the
+// control plane VIP of the cluster
+func ClusterAviInfraSettingName(cluster *clusterv1.Cluster) string {
+	return HAServiceName(cluster) + "-ais"
+}
+
+// reconcileClusterAviInfraSetting creates or updates the AviInfraSetting of the
+// cluster when its AKODeploymentConfig asks for one, it returns nil otherwise.
+// The AviInfraSetting isn't namespaced so it can't be owned by the cluster,
+// the HA service finalizer added beforehand deletes it with the cluster.
+func (r *HAProvider) reconcileClusterAviInfraSetting(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	adc *akoov1alpha1.AKODeploymentConfig,
+) (*akov1beta1.AviInfraSetting, error) {
+	if adc == nil || adc.Spec.ControlPlaneAviInfraSetting == nil {
+		return nil, nil
+	}
+	aviInfraSetting := &akov1beta1.AviInfraSetting{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterAviInfraSettingName(cluster)},
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		// it's deleted with the HA service once the cluster is gone
+		return aviInfraSetting, nil
+	}
+	op, err := ctrlutil.CreateOrUpdate(ctx, r.Client, aviInfraSetting, func() error {
+		if aviInfraSetting.Annotations == nil {
+			aviInfraSetting.Annotations = make(map[string]string)
+		}
+		aviInfraSetting.Annotations[akoov1alpha1.TKGClusterNameLabel] = cluster.Name
+		aviInfraSetting.Annotations[akoov1alpha1.TKGClusterNameSpaceLabel] = cluster.Namespace
+		aviInfraSetting.Spec = clusterAviInfraSettingSpec(adc)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to reconcile AviInfraSetting <%s>\n", aviInfraSetting.Name)
+	}
+	if op != ctrlutil.OperationResultNone {
+		r.log.Info("AviInfraSetting of the cluster "+string(op), "aviInfraSetting", aviInfraSetting.Name)
+	}
+	return aviInfraSetting, nil
+}
+
+// clusterAviInfraSettingSpec places the control plane VIP in the control plane
+// network of the AKODeploymentConfig, in its data network when it has none
+func clusterAviInfraSettingSpec(adc *akoov1alpha1.AKODeploymentConfig) akov1beta1.AviInfraSettingSpec {
+	settings := adc.Spec.ControlPlaneAviInfraSetting
+	vipNetwork := akov1beta1.AviInfraSettingVipNetwork{
+		NetworkName: adc.Spec.ControlPlaneNetwork.Name,
+		Cidr:        adc.Spec.ControlPlaneNetwork.CIDR,
+	}
+	if vipNetwork.NetworkName == "" {
+		vipNetwork.NetworkName = adc.Spec.DataNetwork.Name
+		vipNetwork.Cidr = adc.Spec.DataNetwork.CIDR
+	}
+	if utils.GetIPFamilyFromCidr(vipNetwork.Cidr) == "V6" {
+		vipNetwork.V6Cidr, vipNetwork.Cidr = vipNetwork.Cidr, ""
+	}
+	seGroup := settings.ServiceEngineGroup
+	if seGroup == "" {
+		seGroup = adc.Spec.ServiceEngineGroup
+	}
+	return akov1beta1.AviInfraSettingSpec{
+		SeGroup: akov1beta1.AviInfraSettingSeGroup{
+			Name: seGroup,
+		},
+		Network: akov1beta1.AviInfraSettingNetwork{
+			VipNetworks:    []akov1beta1.AviInfraSettingVipNetwork{vipNetwork},
+			EnableRhi:      settings.EnableRHI,
+			BgpPeerLabels:  settings.BGPPeerLabels,
+			EnablePublicIP: settings.EnablePublicIP,
+		},
+		// T1LR value is required like in the AviInfraSetting of the
+		// AKODeploymentConfig
+		NSXSettings: akov1beta1.AviInfraNSXSettings{
+			T1LR: ptr.To(adc.Spec.ExtraConfigs.NetworksConfig.NsxtT1LR),
+		},
+	}
+}
+
+// syncAviInfraSetting points the HA service to the AviInfraSetting of the
+// cluster, or back to the one of the AKODeploymentConfig once the cluster has
+// none anymore, and tells if the AviInfraSetting of the cluster can be deleted
+func (r *HAProvider) syncAviInfraSetting(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	adc *akoov1alpha1.AKODeploymentConfig,
+	service *corev1.Service,
+) (bool, error) {
+	aviInfraSetting, err := r.reconcileClusterAviInfraSetting(ctx, cluster, adc)
+	if err != nil {
+		return false, err
+	}
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	current := service.Annotations[akoov1alpha1.HAAVIInfraSettingAnnotationsKey]
+	if aviInfraSetting != nil {
+		if current != aviInfraSetting.Name {
+			r.log.Info("HA service moves to the AviInfraSetting of the cluster", "service", service.Name, "aviInfraSetting", aviInfraSetting.Name)
+			service.Annotations[akoov1alpha1.HAAVIInfraSettingAnnotationsKey] = aviInfraSetting.Name
+		}
+		return false, nil
+	}
+	if current != ClusterAviInfraSettingName(cluster) {
+		return false, nil
+	}
+	delete(service.Annotations, akoov1alpha1.HAAVIInfraSettingAnnotationsKey)
+	if adc != nil {
+		adcAviInfraSetting, err := r.getAviInfraSettingFromAdc(ctx, adc)
+		if err != nil {
+			return false, err
+		}
+		if adcAviInfraSetting != nil {
+			service.Annotations[akoov1alpha1.HAAVIInfraSettingAnnotationsKey] = adcAviInfraSetting.Name
+		}
+	}
+	r.log.Info("HA service leaves the AviInfraSetting of the cluster", "service", service.Name, "aviInfraSetting", current)
+	return true, nil
+}
+
+// deleteClusterAviInfraSetting deletes the AviInfraSetting of the cluster, it
+// has none when AKO isn't installed in the management cluster
+func (r *HAProvider) deleteClusterAviInfraSetting(ctx context.Context, cluster *clusterv1.Cluster) error {
+	aviInfraSetting := &akov1beta1.AviInfraSetting{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterAviInfraSettingName(cluster)},
+	}
+	if err := r.Delete(ctx, aviInfraSetting); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return errors.Wrapf(err, "Failed to delete AviInfraSetting <%s>\n", aviInfraSetting.Name)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/alb-sdk/go/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+)
+
+// The reasons of the events emitted on the cluster at the end of its migration
+// from kube-vip to Avi
+const (
+	KubeVipRemovableReason      = "KubeVipRemovable"
+	HAMigrationRolledBackReason = "HAMigrationRolledBack"
+)
+
+// aviOperUp is the state of the Avi virtual services and pools serving traffic
+const aviOperUp = "OPER_UP"
+
+// ErrHAMigrationVIPMismatch is returned when Avi allocates another VIP than the
+// control plane endpoint of the migrated cluster, the migration can't succeed
+var ErrHAMigrationVIPMismatch = errors.New("Avi allocated another VIP than the control plane endpoint")
+
+// operUp tells if an Avi operational status is up
+func operUp(status *models.OperationalStatus) bool {
+	return status != nil && ptr.Deref(status.State, "") == aviOperUp
+}
+
+// MigrateHAService creates the HA service of a cluster moving from kube-vip to
+// Avi, with the current control plane endpoint as the preferred VIP, and tells
+// if Avi serves that VIP: its virtual service is up and the pool of the API
+// server port has servers up. kube-vip still holds the VIP until it's removed,
+// dialing it would reach kube-vip rather than Avi, so the health is read from
+// the Avi controller. The control plane endpoint of the cluster is left
+// untouched.
+func (r *HAProvider) MigrateHAService(ctx context.Context, aviClient aviclient.Client, cluster *clusterv1.Cluster) (bool, error) {
+	serviceName := r.getHAServiceName(cluster)
+	service := &corev1.Service{}
+	if err := r.Get(ctx, client.ObjectKey{Name: serviceName, Namespace: HAServiceNamespace(cluster)}, service); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		r.log.Info("migrating to Avi, creating " + serviceName + " service with the control plane endpoint as VIP")
+		adc, err := r.getADCForCluster(ctx, cluster)
+		if err != nil {
+			return false, err
+		}
+		ensureHAServiceFinalizer(cluster, adc)
+		if service, err = r.createService(ctx, cluster); err != nil {
+			return false, err
+		}
+	}
+	_, endpointPorts, err := r.controlPlanePorts(ctx, cluster)
+	if err != nil {
+		return false, err
+	}
+	endpoints, err := r.ensureEndpoints(ctx, serviceName, service.Namespace)
+	if err != nil {
+		return false, err
+	}
+	if syncEndpointsPorts(endpoints, endpointPorts) {
+		if err := r.Update(ctx, endpoints); err != nil {
+			return false, errors.Wrapf(err, "Failed to update the ports of endpoints <%s>\n", endpoints.Name)
+		}
+	}
+
+	ingress := service.Status.LoadBalancer.Ingress
+	if len(ingress) == 0 || ingress[0].IP == "" {
+		r.log.Info(serviceName + " service external ip is not ready")
+		return false, nil
+	}
+	vip := service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]
+	if ingress[0].IP != vip {
+		return false, errors.Wrapf(ErrHAMigrationVIPMismatch, "%s instead of %s", ingress[0].IP, vip)
+	}
+
+	vs, err := r.haVirtualService(ctx, aviClient, service)
+	if err != nil {
+		return false, err
+	}
+	if vs == nil {
+		r.log.Info("virtual service of " + serviceName + " not found yet")
+		return false, nil
+	}
+	vsInventory, err := aviClient.VsInventoryGet(ptr.Deref(vs.UUID, ""))
+	if err != nil {
+		return false, err
+	}
+	if vsInventory.Runtime == nil || !operUp(vsInventory.Runtime.OperStatus) {
+		r.log.Info("virtual service " + ptr.Deref(vs.Name, "") + " is not up yet")
+		return false, nil
+	}
+	port, err := ako_operator.GetControlPlaneEndpointPort(cluster)
+	if err != nil {
+		return false, err
+	}
+	poolRef := haPoolRef(vs, port)
+	if poolRef == "" {
+		r.log.Info("virtual service " + ptr.Deref(vs.Name, "") + " has no pool for the API server port yet")
+		return false, nil
+	}
+	poolInventory, err := aviClient.PoolInventoryGet(aviclient.GetUUIDFromRef(poolRef))
+	if err != nil {
+		return false, err
+	}
+	if poolInventory.Runtime == nil || !operUp(poolInventory.Runtime.OperStatus) ||
+		ptr.Deref(poolInventory.Runtime.NumServersUp, 0) == 0 {
+		r.log.Info("pool of virtual service " + ptr.Deref(vs.Name, "") + " has no API server up yet")
+		return false, nil
+	}
+	return true, nil
+}
+
+// StartHAMigration stamps the start time of the migration of the cluster
+func (r *HAProvider) StartHAMigration(cluster *clusterv1.Cluster) {
+	cluster.Annotations[akoov1alpha1.HAMigrationStartedAnnotation] = r.clock.Now().UTC().Format(time.RFC3339)
+}
+
+// HAMigrationExpired tells if the migration of the cluster started more than
+// timeout ago
+func (r *HAProvider) HAMigrationExpired(cluster *clusterv1.Cluster, timeout time.Duration) bool {
+	started, err := time.Parse(time.RFC3339, cluster.Annotations[akoov1alpha1.HAMigrationStartedAnnotation])
+	return err == nil && r.clock.Since(started) > timeout
+}
+
+// DeleteHAService deletes the HA services of the cluster and their endpoints,
+// wherever they are, and the AviInfraSetting of the cluster. It rolls back a
+// failed migration from kube-vip to Avi and cleans up the resources which are
+// not owned by their cluster, the HA service finalizer is removed once they are
+// gone.
+func (r *HAProvider) DeleteHAService(ctx context.Context, cluster *clusterv1.Cluster) error {
+	services, err := r.haServicesForCluster(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	for i := range services {
+		if err := r.deleteHAService(ctx, &services[i]); err != nil {
+			return err
+		}
+	}
+	if err := r.deleteClusterAviInfraSetting(ctx, cluster); err != nil {
+		return err
+	}
+	ctrlutil.RemoveFinalizer(cluster, akoov1alpha1.HAServiceFinalizer)
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+// HAServiceMovedReason is the reason of the event emitted on the cluster when
+// its HA service is moved to another namespace or name
+const HAServiceMovedReason = "HAServiceMoved"
+
+// isHAServiceOf tells if the service is an HA service created for the cluster:
+// a load balancer service annotated by the operator and named after the
+// cluster, as it is now or before the names were truncated. The other services
+// annotated with the cluster are left alone.
+func isHAServiceOf(service *corev1.Service, cluster *clusterv1.Cluster) bool {
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer || service.Annotations[akoov1alpha1.HAServiceAnnotationsKey] != "true" {
+		return false
+	}
+	return service.Name == HAServiceName(cluster) ||
+		service.Name == cluster.Namespace+"-"+cluster.Name+"-"+akoov1alpha1.HAServiceName
+}
+
+// haServicesForCluster lists the HA services created for the cluster, in any
+// namespace
+func (r *HAProvider) haServicesForCluster(ctx context.Context, cluster *clusterv1.Cluster) ([]corev1.Service, error) {
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.MatchingFields{
+		index.ServiceClusterField: index.Key(cluster.Namespace, cluster.Name),
+	}); err != nil {
+		return nil, err
+	}
+	var haServices []corev1.Service
+	for _, service := range services.Items {
+		if isHAServiceOf(&service, cluster) {
+			haServices = append(haServices, service)
+		}
+	}
+	return haServices, nil
+}
+
+// moveHAService moves the HA services of the cluster which are not where they
+// belong, e.g. once the HA services namespace of the operator configuration
+// changes. Their endpoints are copied to the new location, which the new
+// service is then created in with the control plane endpoint as preferred VIP,
+// so that the cluster keeps its VIP. The old service is only deleted once the
+// new one exists, Avi hands the VIP over to it when the old one releases it.
+func (r *HAProvider) moveHAService(ctx context.Context, cluster *clusterv1.Cluster) error {
+	services, err := r.haServicesForCluster(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKey{Namespace: HAServiceNamespace(cluster), Name: r.getHAServiceName(cluster)}
+	for i := range services {
+		service := &services[i]
+		from := client.ObjectKeyFromObject(service)
+		if from == key {
+			continue
+		}
+		endpoints := &corev1.Endpoints{}
+		if err := r.Get(ctx, from, endpoints); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		moved := &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Subsets:    endpoints.Subsets,
+		}
+		if err := r.Create(ctx, moved); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "Failed to create endpoints <%s>\n", key)
+		}
+		if err := r.Get(ctx, key, &corev1.Service{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			r.log.Info("moving HA service", "from", from.String(), "to", key.String())
+			if _, err := r.createService(ctx, cluster); err != nil {
+				return errors.Wrapf(err, "Failed to create service <%s>\n", key)
+			}
+			if err := r.Get(ctx, key, &corev1.Service{}); err != nil {
+				return errors.Wrapf(err, "Failed to get service <%s>\n", key)
+			}
+		}
+		if err := r.deleteHAService(ctx, service); err != nil {
+			return err
+		}
+		r.recorder.Eventf(cluster, corev1.EventTypeNormal, HAServiceMovedReason, "HA service moved from %s to %s", from, key)
+	}
+	return nil
+}
+
+// deleteHAService deletes an HA service and its endpoints, the finalizer of the
+// management cluster service is removed first
+func (r *HAProvider) deleteHAService(ctx context.Context, service *corev1.Service) error {
+	if ctrlutil.RemoveFinalizer(service, akoov1alpha1.HAServiceBootstrapClusterFinalizer) {
+		if err := r.Update(ctx, service); err != nil {
+			return errors.Wrapf(err, "Failed to remove the finalizer of service <%s>\n", service.Name)
+		}
+	}
+	meta := metav1.ObjectMeta{Name: service.Name, Namespace: service.Namespace}
+	for _, obj := range []client.Object{&corev1.Service{ObjectMeta: meta}, &corev1.Endpoints{ObjectMeta: meta}} {
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Failed to delete <%s>\n", meta.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ako_operator "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/ako-operator"
+)
+
+// controlPlanePorts returns the ports of the HA service of the cluster and the
+// matching ports of its endpoints. The API server port is only named when the
+// cluster exposes extra ports, so that the single port services don't change.
+func (r *HAProvider) controlPlanePorts(ctx context.Context, cluster *clusterv1.Cluster) ([]corev1.ServicePort, []corev1.EndpointPort, error) {
+	port, err := ako_operator.GetControlPlaneEndpointPort(cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetPort, err := r.controlPlaneTargetPort(ctx, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	extraPorts, err := ako_operator.GetControlPlaneExtraPorts(cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := ""
+	if len(extraPorts) > 0 {
+		name = ako_operator.ControlPlanePortName
+	}
+	servicePorts := []corev1.ServicePort{{
+		Name:       name,
+		Protocol:   corev1.ProtocolTCP,
+		Port:       port,
+		TargetPort: intstr.FromInt(int(targetPort)),
+	}}
+	endpointPorts := []corev1.EndpointPort{{
+		Name:     name,
+		Protocol: corev1.ProtocolTCP,
+		Port:     targetPort,
+	}}
+	for _, extra := range extraPorts {
+		servicePorts = append(servicePorts, corev1.ServicePort{
+			Name:       extra.Name,
+			Protocol:   extra.Protocol,
+			Port:       extra.Port,
+			TargetPort: intstr.FromInt(int(extra.TargetPort)),
+		})
+		endpointPorts = append(endpointPorts, corev1.EndpointPort{
+			Name:     extra.Name,
+			Protocol: extra.Protocol,
+			Port:     extra.TargetPort,
+		})
+	}
+	return servicePorts, endpointPorts, nil
+}
+
+// controlPlaneTargetPort returns the port the API server listens on in the
+// control plane machines. A ClusterClass based cluster sets it in its variables,
+// the other clusters in the kubeadm configuration of their KubeadmControlPlane:
+// the secure-port argument of the API server, or the bind port of its local
+// endpoint.
+func (r *HAProvider) controlPlaneTargetPort(ctx context.Context, cluster *clusterv1.Cluster) (int32, error) {
+	ref := cluster.Spec.ControlPlaneRef
+	if ako_operator.IsClusterClassBasedCluster(cluster) || ref == nil ||
+		ref.Kind != "KubeadmControlPlane" || ref.GroupVersionKind().Group != controlplanev1.GroupVersion.Group {
+		return ako_operator.GetControlPlaneTargetPort(cluster)
+	}
+	kcp := &controlplanev1.KubeadmControlPlane{}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, kcp); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ako_operator.GetControlPlaneTargetPort(cluster)
+		}
+		return 0, errors.Wrapf(err, "Failed to get KubeadmControlPlane <%s>\n", ref.Name)
+	}
+	spec := kcp.Spec.KubeadmConfigSpec
+	if spec.ClusterConfiguration != nil {
+		if securePort, ok := spec.ClusterConfiguration.APIServer.ExtraArgs["secure-port"]; ok {
+			port, err := strconv.ParseInt(securePort, 10, 32)
+			if err != nil || port < 1 || port > 65535 {
+				return 0, errors.Errorf("secure-port %s of KubeadmControlPlane %s is not a valid port", securePort, kcp.Name)
+			}
+			return int32(port), nil
+		}
+	}
+	if spec.InitConfiguration != nil && spec.InitConfiguration.LocalAPIEndpoint.BindPort != 0 {
+		return spec.InitConfiguration.LocalAPIEndpoint.BindPort, nil
+	}
+	return ako_operator.GetControlPlaneTargetPort(cluster)
+}
+
+// syncServicePorts sets the ports of the service, the node ports allocated to
+// the ports which are kept, or renamed, are preserved. Every current port is
+// matched once, by name first then by port, so two ports never share a node
+// port; the node ports of the new ports are left to the API server to
+// allocate. It tells if the ports changed.
+func syncServicePorts(service *corev1.Service, ports []corev1.ServicePort) bool {
+	desired := make([]corev1.ServicePort, len(ports))
+	copy(desired, ports)
+	matched := make([]bool, len(service.Spec.Ports))
+	match := func(port corev1.ServicePort, same func(current corev1.ServicePort) bool) int {
+		for j, current := range service.Spec.Ports {
+			if !matched[j] && current.Protocol == port.Protocol && same(current) {
+				return j
+			}
+		}
+		return -1
+	}
+	for i := range desired {
+		desired[i].NodePort = 0
+		j := match(desired[i], func(current corev1.ServicePort) bool { return current.Name == desired[i].Name })
+		if j < 0 {
+			j = match(desired[i], func(current corev1.ServicePort) bool { return current.Port == desired[i].Port })
+		}
+		if j < 0 {
+			continue
+		}
+		matched[j] = true
+		desired[i].NodePort = service.Spec.Ports[j].NodePort
+		desired[i].AppProtocol = service.Spec.Ports[j].AppProtocol
+	}
+	if reflect.DeepEqual(service.Spec.Ports, desired) {
+		return false
+	}
+	service.Spec.Ports = desired
+	return true
+}
+
+// syncEndpointsPorts sets the ports of the endpoints subset. It tells if the
+// ports changed.
+func syncEndpointsPorts(endpoints *corev1.Endpoints, ports []corev1.EndpointPort) bool {
+	changed := false
+	for i := range endpoints.Subsets {
+		if !reflect.DeepEqual(endpoints.Subsets[i].Ports, ports) {
+			endpoints.Subsets[i].Ports = ports
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	akov1alpha2 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1alpha2"
+)
+
+// MachineReady tells if the control plane machine can receive the API server
+// traffic: its node is healthy and, when the KubeadmControlPlane reports them,
+// its API server pod and etcd member are healthy too. Without the
+// HAEndpointReadiness feature, off by default, every machine is ready.
+func MachineReady(machine *clusterv1.Machine) bool {
+	if !config.Enabled(config.HAEndpointReadiness) {
+		return true
+	}
+	if !conditions.IsTrue(machine, clusterv1.MachineNodeHealthyCondition) {
+		return false
+	}
+	for _, condition := range []clusterv1.ConditionType{
+		controlplanev1.MachineAPIServerPodHealthyCondition,
+		controlplanev1.MachineEtcdMemberHealthyCondition,
+	} {
+		if conditions.Has(machine, condition) && !conditions.IsTrue(machine, condition) {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileHealthMonitor attaches the Avi health monitor of the
+// AKODeploymentConfig to the pool of the API server port through an L4Rule
+// named after the HA service, and detaches it once the health monitor is unset.
+// The service annotation is saved with the other service changes.
+func (r *HAProvider) reconcileHealthMonitor(ctx context.Context, adc *akoov1alpha1.AKODeploymentConfig, service *corev1.Service) error {
+	l4Rule := &akov1alpha2.L4Rule{
+		ObjectMeta: metav1.ObjectMeta{Name: service.Name, Namespace: service.Namespace},
+	}
+	if adc == nil || adc.Spec.ControlPlaneHealthMonitor == "" {
+		if _, ok := service.Annotations[akoov1alpha1.HAL4RuleAnnotation]; !ok {
+			return nil
+		}
+		delete(service.Annotations, akoov1alpha1.HAL4RuleAnnotation)
+		if err := r.Delete(ctx, l4Rule); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Failed to delete L4Rule <%s>\n", l4Rule.Name)
+		}
+		return nil
+	}
+
+	if len(service.Spec.Ports) == 0 {
+		return nil
+	}
+	backend := &akov1alpha2.BackendProperties{
+		Port:              ptr.To(int(service.Spec.Ports[0].Port)),
+		Protocol:          ptr.To(string(corev1.ProtocolTCP)),
+		HealthMonitorRefs: []string{adc.Spec.ControlPlaneHealthMonitor},
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(l4Rule), l4Rule); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		l4Rule.Spec.BackendProperties = []*akov1alpha2.BackendProperties{backend}
+		// the L4Rule goes away with the HA service
+		l4Rule.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Name:       service.Name,
+			UID:        service.UID,
+		}}
+		r.log.Info("Creating L4Rule attaching health monitor "+adc.Spec.ControlPlaneHealthMonitor, "service", service.Name)
+		if err := r.Create(ctx, l4Rule); err != nil {
+			return errors.Wrapf(err, "Failed to create L4Rule <%s>\n", l4Rule.Name)
+		}
+	} else if len(l4Rule.Spec.BackendProperties) != 1 ||
+		ptr.Deref(l4Rule.Spec.BackendProperties[0].Port, 0) != *backend.Port ||
+		len(l4Rule.Spec.BackendProperties[0].HealthMonitorRefs) != 1 ||
+		l4Rule.Spec.BackendProperties[0].HealthMonitorRefs[0] != adc.Spec.ControlPlaneHealthMonitor {
+		l4Rule.Spec.BackendProperties = []*akov1alpha2.BackendProperties{backend}
+		if err := r.Update(ctx, l4Rule); err != nil {
+			return errors.Wrapf(err, "Failed to update L4Rule <%s>\n", l4Rule.Name)
+		}
+	}
+
+	if service.Annotations[akoov1alpha1.HAL4RuleAnnotation] == l4Rule.Name {
+		return nil
+	}
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	service.Annotations[akoov1alpha1.HAL4RuleAnnotation] = l4Rule.Name
+	return nil
+}
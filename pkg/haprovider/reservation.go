@@ -0,0 +1,110 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+// ControlPlaneVIPInvalidReason is the reason of the event emitted on the
+// cluster when its control plane VIP can't be requested from Avi
+const ControlPlaneVIPInvalidReason = "ControlPlaneVIPInvalid"
+
+// ControlPlaneVIPInPoolReason is the reason of the event emitted on the cluster
+// when the control plane endpoint it already uses is in the data network pools
+const ControlPlaneVIPInPoolReason = "ControlPlaneVIPInPool"
+
+// ErrControlPlaneVIPInvalid is returned when the VIP the HA service of a
+// cluster would request is out of the networks Avi can serve it from
+var ErrControlPlaneVIPInvalid = errors.New("control plane VIP can't be requested")
+
+// reserveVIP records the VIP Avi serves for the HA service on the cluster
+func reserveVIP(cluster *clusterv1.Cluster, service *corev1.Service) {
+	ingress := service.Status.LoadBalancer.Ingress
+	if len(ingress) == 0 || net.ParseIP(ingress[0].IP) == nil {
+		return
+	}
+	if cluster.Annotations == nil {
+		cluster.Annotations = make(map[string]string)
+	}
+	cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation] = ingress[0].IP
+}
+
+// vipInPoolError is returned by validateVIP when the VIP is in a static pool of
+// the data network
+type vipInPoolError struct {
+	pool akoov1alpha1.IPPool
+}
+
+func (e *vipInPoolError) Error() string {
+	return "in the data network pool [" + e.pool.Start + "," + e.pool.End + "]"
+}
+
+// validateRequestedVIP checks the VIP the HA service of the cluster requests,
+// its control plane endpoint or the VIP reserved for it, and emits an event on
+// the cluster when it can't be requested. A control plane endpoint the cluster
+// already uses is kept even in the data network pools, only a warning is
+// emitted since the cluster can't move to another one.
+func (r *HAProvider) validateRequestedVIP(cluster *clusterv1.Cluster, adc *akoov1alpha1.AKODeploymentConfig, vip string, existing bool) error {
+	err := validateVIP(vip, cluster.Annotations[akoov1alpha1.HAReservedVIPAnnotation], adc)
+	var inPool *vipInPoolError
+	if existing && errors.As(err, &inPool) {
+		r.recorder.Eventf(cluster, corev1.EventTypeWarning, ControlPlaneVIPInPoolReason,
+			"Control plane endpoint %s of the cluster is %v, Avi may allocate it to another service", vip, err)
+		return nil
+	}
+	if err != nil {
+		r.recorder.Eventf(cluster, corev1.EventTypeWarning, ControlPlaneVIPInvalidReason,
+			"Control plane VIP %s can't be requested: %v", vip, err)
+		return errors.Wrapf(ErrControlPlaneVIPInvalid, "%s of cluster %s/%s: %v", vip, cluster.Namespace, cluster.Name, err)
+	}
+	return nil
+}
+
+// validateVIP checks the VIP is in the network Avi serves the control plane
+// VIPs from: the control plane network of the AKODeploymentConfig, the data
+// network without a dedicated one. It must be out of the static pools of the
+// data network, Avi allocates them to the other services; only the VIP Avi
+// allocated to the cluster from the pools of a shared network, the reserved
+// one, is requested back.
+func validateVIP(vip, reserved string, adc *akoov1alpha1.AKODeploymentConfig) error {
+	ip := net.ParseIP(vip)
+	if ip == nil {
+		return errors.New("not an IP address")
+	}
+	if adc == nil {
+		return nil
+	}
+	dedicated := adc.Spec.ControlPlaneNetwork.CIDR != "" && adc.Spec.ControlPlaneNetwork.CIDR != adc.Spec.DataNetwork.CIDR
+	network, cidr := "data network", adc.Spec.DataNetwork.CIDR
+	if dedicated {
+		network, cidr = "control plane network", adc.Spec.ControlPlaneNetwork.CIDR
+	}
+	if cidr != "" {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		if !ipNet.Contains(ip) {
+			return errors.Errorf("not in the %s %s", network, cidr)
+		}
+	}
+	if !dedicated && vip == reserved {
+		return nil
+	}
+	for _, pool := range adc.Spec.DataNetwork.IPPools {
+		start, end := net.ParseIP(pool.Start), net.ParseIP(pool.End)
+		if start != nil && end != nil && bytes.Compare(ip.To16(), start.To16()) >= 0 && bytes.Compare(ip.To16(), end.To16()) <= 0 {
+			return &vipInPoolError{pool: pool}
+		}
+	}
+	return nil
+}
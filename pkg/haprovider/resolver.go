@@ -0,0 +1,82 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/utils"
+)
+
+// ControlPlaneVIPChangedReason is the reason of the event emitted on the cluster
+// when its FQDN control plane endpoint resolves to another VIP
+const ControlPlaneVIPChangedReason = "ControlPlaneVIPChanged"
+
+// ResolveFQDN returns the addresses of the IP family, V4 or V6, the fqdn resolves
+// to, sorted so that the same records always give the same VIP. The fqdn is
+// resolved by the DNS server of the operator configuration, or by the system
+// resolver when there is none.
+func ResolveFQDN(ctx context.Context, fqdn string, ipFamily string) ([]string, error) {
+	resolver := net.DefaultResolver
+	if server := config.Get().DNS.Server; server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (ipFamily != utils.IPv6IpFamily) {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s doesn't resolve to any %s address", fqdn, ipFamily)
+	}
+	sort.Slice(ips, func(i, j int) bool { return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0 })
+	resolved := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		resolved = append(resolved, ip.String())
+	}
+	return resolved, nil
+}
+
+// resolveVIP resolves the FQDN control plane endpoint into the VIP to request,
+// keeping the current one as long as the FQDN still resolves to it
+func (r *HAProvider) resolveVIP(ctx context.Context, fqdn, ipFamily, current string) (string, error) {
+	resolved, err := r.resolve(ctx, fqdn, ipFamily)
+	if err != nil {
+		r.log.Error(err, "Failed to resolve control plane endpoint ", "endpoint", fqdn)
+		return "", err
+	}
+	if len(resolved) == 0 {
+		err := fmt.Errorf("%s doesn't resolve to any %s address", fqdn, ipFamily)
+		r.log.Error(err, "Failed to resolve control plane endpoint ", "endpoint", fqdn)
+		return "", err
+	}
+	return chooseVIP(resolved, current), nil
+}
+
+// chooseVIP keeps the current VIP as long as the FQDN still resolves to it, so
+// that round robin records don't move the control plane endpoint around
+func chooseVIP(resolved []string, current string) string {
+	for _, ip := range resolved {
+		if ip == current {
+			return current
+		}
+	}
+	return resolved[0]
+}
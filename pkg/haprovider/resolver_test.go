@@ -0,0 +1,170 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package haprovider
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/config"
+)
+
+// serveDNS answers the A and AAAA queries of the records until the connection
+// is closed, the other names don't exist
+func serveDNS(conn net.PacketConn, records map[string][]net.IP) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			continue
+		}
+		q := query.Questions[0]
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeSuccess},
+			Questions: query.Questions,
+		}
+		ips, ok := records[q.Name.String()]
+		if !ok {
+			reply.RCode = dnsmessage.RCodeNameError
+		}
+		for _, ip := range ips {
+			h := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+			switch {
+			case ip.To4() != nil && q.Type == dnsmessage.TypeA:
+				h.Type = dnsmessage.TypeA
+				a := dnsmessage.AResource{}
+				copy(a.A[:], ip.To4())
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h, Body: &a})
+			case ip.To4() == nil && q.Type == dnsmessage.TypeAAAA:
+				h.Type = dnsmessage.TypeAAAA
+				aaaa := dnsmessage.AAAAResource{}
+				copy(aaaa.AAAA[:], ip.To16())
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h, Body: &aaaa})
+			}
+		}
+		out, err := reply.Pack()
+		if err != nil {
+			continue
+		}
+		_, _ = conn.WriteTo(out, addr)
+	}
+}
+
+var _ = Describe("FQDN control plane endpoint resolver", func() {
+	Context("ResolveFQDN", func() {
+		var conn net.PacketConn
+
+		BeforeEach(func() {
+			var err error
+			conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			go serveDNS(conn, map[string][]net.IP{
+				"vip.example.com.":    {net.ParseIP("10.0.0.2"), net.ParseIP("fd00::1"), net.ParseIP("10.0.0.1")},
+				"v4only.example.com.": {net.ParseIP("10.0.0.3")},
+			})
+			c := &config.OperatorConfiguration{DNS: config.DNSConfiguration{Server: conn.LocalAddr().String()}}
+			c.Default()
+			config.Set(c)
+		})
+
+		AfterEach(func() {
+			config.Set(nil)
+			Expect(conn.Close()).Should(Succeed())
+		})
+
+		It("should return the sorted IPv4 addresses of an IPv4 cluster", func() {
+			Expect(ResolveFQDN(context.Background(), "vip.example.com.", "V4")).Should(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		})
+
+		It("should return the IPv6 addresses of an IPv6 cluster", func() {
+			Expect(ResolveFQDN(context.Background(), "vip.example.com.", "V6")).Should(Equal([]string{"fd00::1"}))
+		})
+
+		It("should fail when the FQDN has no address of the cluster IP family", func() {
+			_, err := ResolveFQDN(context.Background(), "v4only.example.com.", "V6")
+			Expect(err).Should(MatchError(ContainSubstring("doesn't resolve to any V6 address")))
+		})
+
+		It("should fail when the FQDN doesn't exist", func() {
+			_, err := ResolveFQDN(context.Background(), "missing.example.com.", "V4")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("chooseVIP", func() {
+		It("should keep the current VIP while the FQDN resolves to it", func() {
+			Expect(chooseVIP([]string{"10.0.0.1", "10.0.0.2"}, "10.0.0.2")).Should(Equal("10.0.0.2"))
+		})
+
+		It("should move to the first address once the current VIP is gone", func() {
+			Expect(chooseVIP([]string{"10.0.0.1", "10.0.0.2"}, "10.0.0.3")).Should(Equal("10.0.0.1"))
+			Expect(chooseVIP([]string{"10.0.0.1", "10.0.0.2"}, "")).Should(Equal("10.0.0.1"))
+		})
+	})
+
+	Context("updateControlPlaneEndpointToService", func() {
+		var (
+			provider *HAProvider
+			recorder *record.FakeRecorder
+			cluster  *clusterv1.Cluster
+			service  *corev1.Service
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).NotTo(HaveOccurred())
+			Expect(clusterv1.AddToScheme(scheme)).NotTo(HaveOccurred())
+			recorder = record.NewFakeRecorder(10)
+			service = &corev1.Service{
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "default-test-cluster-control-plane",
+					Namespace:   "default",
+					Annotations: map[string]string{akoov1alpha1.AkoPreferredIPAnnotation: "10.0.0.1"},
+				},
+			}
+			provider = NewProvider(fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(service).Build(), recorder, log.Log)
+			cluster = &clusterv1.Cluster{
+				ObjectMeta: v1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+				Spec: clusterv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "vip.example.com", Port: 6443},
+				},
+			}
+		})
+
+		It("should emit an event when the FQDN resolves to another VIP", func() {
+			provider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+				return []string{"10.0.0.5"}, nil
+			}
+			Expect(provider.updateControlPlaneEndpointToService(context.Background(), cluster, service)).Should(Succeed())
+			Expect(service.Spec.LoadBalancerIP).Should(Equal("10.0.0.5"))
+			Expect(service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.0.0.5"))
+			Expect(recorder.Events).Should(Receive(ContainSubstring(ControlPlaneVIPChangedReason)))
+		})
+
+		It("should keep the VIP quietly while the FQDN still resolves to it", func() {
+			provider.resolve = func(_ context.Context, fqdn, ipFamily string) ([]string, error) {
+				return []string{"10.0.0.0", "10.0.0.1"}, nil
+			}
+			Expect(provider.updateControlPlaneEndpointToService(context.Background(), cluster, service)).Should(Succeed())
+			Expect(service.Annotations[akoov1alpha1.AkoPreferredIPAnnotation]).Should(Equal("10.0.0.1"))
+			Expect(recorder.Events).ShouldNot(Receive())
+		})
+	})
+})
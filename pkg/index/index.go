@@ -0,0 +1,142 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package index registers the cache field indexers the controllers use to look
+// up related objects without listing every object of a kind.
+package index
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+const (
+	// AKODeploymentConfigSecretField indexes the AKODeploymentConfigs by the
+	// namespace/name of the avi controller credentials and CA secrets they reference
+	AKODeploymentConfigSecretField = "spec.secretRefs"
+
+	// AKODeploymentConfigConfigMapField indexes the AKODeploymentConfigs by the
+	// namespace/name of the role permission profile ConfigMap they reference
+	AKODeploymentConfigConfigMapField = "spec.rolePermissionProfileRef"
+
+	// AKODeploymentConfigSelectorField indexes the AKODeploymentConfigs by a
+	// key=value label their cluster selector requires, or AnyClusterLabel when
+	// it requires none
+	AKODeploymentConfigSelectorField = "spec.clusterSelector"
+
+	// ClusterAviLabelField indexes the Clusters by the AKODeploymentConfig in their
+	// networking.tkg.tanzu.vmware.com/avi label
+	ClusterAviLabelField = "metadata.labels.avi"
+
+	// ServiceClusterField indexes the Services by the namespace/name of the Cluster
+	// in their tkg cluster annotations
+	ServiceClusterField = "metadata.annotations.cluster"
+)
+
+// AnyClusterLabel is the AKODeploymentConfigSelectorField value of the
+// AKODeploymentConfigs whose cluster selector has no match labels, they may
+// select a cluster whatever its labels
+const AnyClusterLabel = "*"
+
+// Key returns the index value of a namespaced object reference
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// ClusterLabel returns the AKODeploymentConfigSelectorField value of a cluster label
+func ClusterLabel(key, value string) string {
+	return key + "=" + value
+}
+
+// AddDefaultIndexes registers every field indexer with the manager's cache
+func AddDefaultIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(ctx, &akoov1alpha1.AKODeploymentConfig{}, AKODeploymentConfigSecretField, AKODeploymentConfigBySecret); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &akoov1alpha1.AKODeploymentConfig{}, AKODeploymentConfigConfigMapField, AKODeploymentConfigByConfigMap); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &akoov1alpha1.AKODeploymentConfig{}, AKODeploymentConfigSelectorField, AKODeploymentConfigBySelector); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &clusterv1.Cluster{}, ClusterAviLabelField, ClusterByAviLabel); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, &corev1.Service{}, ServiceClusterField, ServiceByCluster)
+}
+
+// AKODeploymentConfigBySecret returns the secrets an AKODeploymentConfig reads the avi
+// controller credentials and CA from
+func AKODeploymentConfigBySecret(o client.Object) []string {
+	adc, ok := o.(*akoov1alpha1.AKODeploymentConfig)
+	if !ok {
+		return nil
+	}
+	var secrets []string
+	for _, ref := range []*akoov1alpha1.SecretRef{adc.Spec.AdminCredentialRef, adc.Spec.CertificateAuthorityRef} {
+		if ref != nil && ref.Name != "" {
+			secrets = append(secrets, Key(ref.Namespace, ref.Name))
+		}
+	}
+	return secrets
+}
+
+// AKODeploymentConfigByConfigMap returns the role permission profile ConfigMap an
+// AKODeploymentConfig builds the ako user role from
+func AKODeploymentConfigByConfigMap(o client.Object) []string {
+	adc, ok := o.(*akoov1alpha1.AKODeploymentConfig)
+	if !ok {
+		return nil
+	}
+	ref := adc.Spec.RolePermissionProfileRef
+	if ref == nil || ref.Name == "" {
+		return nil
+	}
+	return []string{Key(ref.Namespace, ref.Name)}
+}
+
+// AKODeploymentConfigBySelector returns a cluster label the cluster selector of an
+// AKODeploymentConfig requires, the one with the first key is enough since a
+// selected cluster has all of them
+func AKODeploymentConfigBySelector(o client.Object) []string {
+	adc, ok := o.(*akoov1alpha1.AKODeploymentConfig)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(adc.Spec.ClusterSelector.MatchLabels))
+	for key := range adc.Spec.ClusterSelector.MatchLabels {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return []string{AnyClusterLabel}
+	}
+	sort.Strings(keys)
+	return []string{ClusterLabel(keys[0], adc.Spec.ClusterSelector.MatchLabels[keys[0]])}
+}
+
+// ClusterByAviLabel returns the AKODeploymentConfig which selected the Cluster
+func ClusterByAviLabel(o client.Object) []string {
+	adcName, ok := o.GetLabels()[akoov1alpha1.AviClusterLabel]
+	if !ok {
+		return nil
+	}
+	return []string{adcName}
+}
+
+// ServiceByCluster returns the Cluster a Service, like the control plane HA
+// service, is created for
+func ServiceByCluster(o client.Object) []string {
+	name, ok := o.GetAnnotations()[akoov1alpha1.TKGClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	return []string{Key(o.GetAnnotations()[akoov1alpha1.TKGClusterNameSpaceLabel], name)}
+}
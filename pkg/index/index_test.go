@@ -0,0 +1,111 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package index
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+)
+
+var _ = Describe("Field indexers", func() {
+	When("an AKODeploymentConfig is indexed", func() {
+		It("should return the referenced secrets", func() {
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					AdminCredentialRef:      &akoov1alpha1.SecretRef{Name: "avi-credentials", Namespace: "tkg-system-networking"},
+					CertificateAuthorityRef: &akoov1alpha1.SecretRef{Name: "avi-ca", Namespace: "tkg-system-networking"},
+				},
+			}
+			Expect(AKODeploymentConfigBySecret(adc)).Should(Equal([]string{
+				"tkg-system-networking/avi-credentials",
+				"tkg-system-networking/avi-ca",
+			}))
+		})
+
+		It("should skip the empty references", func() {
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					CertificateAuthorityRef: &akoov1alpha1.SecretRef{},
+				},
+			}
+			Expect(AKODeploymentConfigBySecret(adc)).Should(BeEmpty())
+		})
+
+		It("should ignore the other kinds", func() {
+			Expect(AKODeploymentConfigBySecret(&corev1.Secret{})).Should(BeNil())
+		})
+	})
+
+	When("an AKODeploymentConfig with a role permission profile is indexed", func() {
+		It("should return the referenced ConfigMap", func() {
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					RolePermissionProfileRef: &akoov1alpha1.ConfigMapRef{Name: "ako-role-profile", Namespace: "tkg-system-networking"},
+				},
+			}
+			Expect(AKODeploymentConfigByConfigMap(adc)).Should(Equal([]string{"tkg-system-networking/ako-role-profile"}))
+		})
+
+		It("should skip the AKODeploymentConfigs without profile", func() {
+			Expect(AKODeploymentConfigByConfigMap(&akoov1alpha1.AKODeploymentConfig{})).Should(BeNil())
+		})
+	})
+
+	When("an AKODeploymentConfig with a cluster selector is indexed", func() {
+		It("should return the match label with the first key", func() {
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a", "env": "prod"}},
+				},
+			}
+			Expect(AKODeploymentConfigBySelector(adc)).Should(Equal([]string{"env=prod"}))
+		})
+
+		It("should match any cluster without match labels", func() {
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					ClusterSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: metav1.LabelSelectorOpExists},
+					}},
+				},
+			}
+			Expect(AKODeploymentConfigBySelector(adc)).Should(Equal([]string{AnyClusterLabel}))
+			Expect(AKODeploymentConfigBySelector(&akoov1alpha1.AKODeploymentConfig{})).Should(Equal([]string{AnyClusterLabel}))
+		})
+	})
+
+	When("a Cluster is indexed", func() {
+		It("should return the AKODeploymentConfig in its avi label", func() {
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{akoov1alpha1.AviClusterLabel: "install-ako-for-all"},
+			}}
+			Expect(ClusterByAviLabel(cluster)).Should(Equal([]string{"install-ako-for-all"}))
+		})
+
+		It("should skip the clusters without avi label", func() {
+			Expect(ClusterByAviLabel(&clusterv1.Cluster{})).Should(BeNil())
+		})
+	})
+
+	When("a Service is indexed", func() {
+		It("should return the cluster in its annotations", func() {
+			service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					akoov1alpha1.TKGClusterNameLabel:      "workload-cls",
+					akoov1alpha1.TKGClusterNameSpaceLabel: "default",
+				},
+			}}
+			Expect(ServiceByCluster(service)).Should(Equal([]string{"default/workload-cls"}))
+		})
+
+		It("should skip the services without cluster annotations", func() {
+			Expect(ServiceByCluster(&corev1.Service{})).Should(BeNil())
+		})
+	})
+})
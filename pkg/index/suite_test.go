@@ -0,0 +1,16 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package index
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIndex(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Field Indexers Suite")
+}
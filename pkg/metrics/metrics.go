@@ -0,0 +1,41 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics registers the operator metrics with the controller-runtime
+// registry, they are served on the manager's metrics endpoint
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "ako_operator"
+
+var (
+	// AKOConfigurationDrift is the number of AKO settings of a workload cluster
+	// which differ from the ones rendered from its AKODeploymentConfig
+	AKOConfigurationDrift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ako_configuration_drift",
+		Help:      "Number of AKO settings in a workload cluster which differ from the expected ones",
+	}, []string{"namespace", "cluster"})
+
+	// AKOConfigurationReapplied counts how many times the expected AKO
+	// configuration was written back to a workload cluster
+	AKOConfigurationReapplied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ako_configuration_reapplied_total",
+		Help:      "Number of times the expected AKO configuration was re-applied to a workload cluster",
+	}, []string{"namespace", "cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(AKOConfigurationDrift, AKOConfigurationReapplied)
+}
+
+// ForgetCluster drops the series of a deleted cluster
+func ForgetCluster(namespace, name string) {
+	AKOConfigurationDrift.DeleteLabelValues(namespace, name)
+	AKOConfigurationReapplied.DeleteLabelValues(namespace, name)
+}
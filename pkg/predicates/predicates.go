@@ -0,0 +1,110 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package predicates filters the watch events the controllers don't act on
+package predicates
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+// SecretDataChanged filters out the Secret updates which leave its data untouched,
+// like the periodic resyncs and the metadata only changes
+func SecretDataChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret, ok := e.ObjectOld.(*corev1.Secret)
+			if !ok {
+				return true
+			}
+			newSecret, ok := e.ObjectNew.(*corev1.Secret)
+			if !ok {
+				return true
+			}
+			return oldSecret.Type != newSecret.Type || !reflect.DeepEqual(oldSecret.Data, newSecret.Data)
+		},
+	}
+}
+
+// ConfigMapReferenced filters out the events of every ConfigMap but the role
+// permission profiles referenced by an AKODeploymentConfig and the ConfigMaps in
+// always, like the permission matrix override. The AKODeploymentConfigs are
+// looked up with the index.AKODeploymentConfigConfigMapField index.
+func ConfigMapReferenced(c client.Reader, always ...client.ObjectKey) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		if _, ok := o.(*corev1.ConfigMap); !ok {
+			return false
+		}
+		key := client.ObjectKeyFromObject(o)
+		for _, k := range always {
+			if k == key {
+				return true
+			}
+		}
+		var adcs akoov1alpha1.AKODeploymentConfigList
+		if err := c.List(context.Background(), &adcs, client.MatchingFields{
+			index.AKODeploymentConfigConfigMapField: index.Key(key.Namespace, key.Name),
+		}); err != nil {
+			// let the handler decide rather than dropping a change
+			return true
+		}
+		return len(adcs.Items) != 0
+	})
+}
+
+// HAService filters out the events of every Service but the control plane HA
+// services of the clusters
+func HAService() predicate.Predicate {
+	return predicate.NewPredicateFuncs(IsHAService)
+}
+
+// IsHAService checks if the object is the control plane HA service of a cluster
+func IsHAService(o client.Object) bool {
+	service, ok := o.(*corev1.Service)
+	return ok && service.Spec.Type == corev1.ServiceTypeLoadBalancer &&
+		strings.Contains(service.Name, akoov1alpha1.HAServiceName)
+}
+
+// ClusterChanged filters out the Cluster updates which only change what AKO
+// doesn't depend on, like the status written by the cluster api controllers.
+// The updates of the spec, labels, annotations, finalizers, deletion timestamp
+// and Ready condition go through.
+func ClusterChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCluster, ok := e.ObjectOld.(*clusterv1.Cluster)
+			if !ok {
+				return true
+			}
+			newCluster, ok := e.ObjectNew.(*clusterv1.Cluster)
+			if !ok {
+				return true
+			}
+			return oldCluster.Generation != newCluster.Generation ||
+				!reflect.DeepEqual(oldCluster.Labels, newCluster.Labels) ||
+				!reflect.DeepEqual(oldCluster.Annotations, newCluster.Annotations) ||
+				!reflect.DeepEqual(oldCluster.Finalizers, newCluster.Finalizers) ||
+				!oldCluster.DeletionTimestamp.Equal(newCluster.DeletionTimestamp) ||
+				readyStatus(oldCluster) != readyStatus(newCluster)
+		},
+	}
+}
+
+func readyStatus(cluster *clusterv1.Cluster) corev1.ConditionStatus {
+	if c := conditions.Get(cluster, clusterv1.ReadyCondition); c != nil {
+		return c.Status
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package predicates
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	akoov1alpha1 "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/api/v1alpha1"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
+)
+
+var _ = Describe("Predicates", func() {
+	When("a Secret is updated", func() {
+		var secret *corev1.Secret
+
+		BeforeEach(func() {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "avi-credentials", Namespace: "tkg-system-networking"},
+				Data:       map[string][]byte{"username": []byte("admin")},
+			}
+		})
+
+		It("should filter out the resyncs and metadata changes", func() {
+			updated := secret.DeepCopy()
+			updated.ResourceVersion = "2"
+			updated.Labels = map[string]string{"foo": "bar"}
+			Expect(SecretDataChanged().Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: updated})).Should(BeFalse())
+		})
+
+		It("should keep the data changes", func() {
+			updated := secret.DeepCopy()
+			updated.Data["password"] = []byte("password")
+			Expect(SecretDataChanged().Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: updated})).Should(BeTrue())
+		})
+
+		It("should keep the create and delete events", func() {
+			Expect(SecretDataChanged().Create(event.CreateEvent{Object: secret})).Should(BeTrue())
+			Expect(SecretDataChanged().Delete(event.DeleteEvent{Object: secret})).Should(BeTrue())
+		})
+	})
+
+	When("a Service event is received", func() {
+		It("should keep the control plane HA services", func() {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "default-workload-cls-" + akoov1alpha1.HAServiceName},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			}
+			Expect(HAService().Create(event.CreateEvent{Object: service})).Should(BeTrue())
+		})
+
+		It("should filter out the other services", func() {
+			lb := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			}
+			clusterIP := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "default-workload-cls-" + akoov1alpha1.HAServiceName},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+			}
+			Expect(HAService().Create(event.CreateEvent{Object: lb})).Should(BeFalse())
+			Expect(HAService().Update(event.UpdateEvent{ObjectOld: clusterIP, ObjectNew: clusterIP})).Should(BeFalse())
+		})
+	})
+
+	When("a ConfigMap event is received", func() {
+		var referenced predicate.Predicate
+
+		configMap := func(namespace, name string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		}
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(akoov1alpha1.AddToScheme(scheme)).Should(Succeed())
+			adc := &akoov1alpha1.AKODeploymentConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "install-ako-for-all"},
+				Spec: akoov1alpha1.AKODeploymentConfigSpec{
+					RolePermissionProfileRef: &akoov1alpha1.ConfigMapRef{Name: "ako-role-profile", Namespace: "tkg-system-networking"},
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(adc).
+				WithIndex(&akoov1alpha1.AKODeploymentConfig{}, index.AKODeploymentConfigConfigMapField, index.AKODeploymentConfigByConfigMap).
+				Build()
+			referenced = ConfigMapReferenced(c, client.ObjectKey{Namespace: "tkg-system", Name: "ako-permissions"})
+		})
+
+		It("should keep the referenced role permission profiles", func() {
+			Expect(referenced.Create(event.CreateEvent{Object: configMap("tkg-system-networking", "ako-role-profile")})).Should(BeTrue())
+		})
+
+		It("should keep the permission matrix override", func() {
+			Expect(referenced.Create(event.CreateEvent{Object: configMap("tkg-system", "ako-permissions")})).Should(BeTrue())
+		})
+
+		It("should filter out the other ConfigMaps", func() {
+			cm := configMap("kube-system", "kubeadm-config")
+			Expect(referenced.Create(event.CreateEvent{Object: cm})).Should(BeFalse())
+			Expect(referenced.Update(event.UpdateEvent{ObjectOld: cm, ObjectNew: cm})).Should(BeFalse())
+		})
+	})
+
+	When("a Cluster is updated", func() {
+		var cluster *clusterv1.Cluster
+
+		BeforeEach(func() {
+			cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:       "workload-cls",
+				Namespace:  "default",
+				Generation: 1,
+				Labels:     map[string]string{"foo": "bar"},
+			}}
+			conditions.MarkFalse(cluster, clusterv1.ReadyCondition, "Provisioning", clusterv1.ConditionSeverityInfo, "")
+		})
+
+		It("should filter out the status only changes", func() {
+			updated := cluster.DeepCopy()
+			updated.ResourceVersion = "2"
+			updated.Status.Phase = string(clusterv1.ClusterPhaseProvisioned)
+			conditions.MarkTrue(updated, clusterv1.ControlPlaneInitializedCondition)
+			Expect(ClusterChanged().Update(event.UpdateEvent{ObjectOld: cluster, ObjectNew: updated})).Should(BeFalse())
+		})
+
+		It("should keep the changes AKO depends on", func() {
+			for _, update := range []func(*clusterv1.Cluster){
+				func(c *clusterv1.Cluster) { c.Generation = 2 },
+				func(c *clusterv1.Cluster) { c.Labels[akoov1alpha1.AviClusterLabel] = "install-ako-for-all" },
+				func(c *clusterv1.Cluster) { c.Annotations = map[string]string{"foo": "bar"} },
+				func(c *clusterv1.Cluster) { c.Finalizers = []string{akoov1alpha1.ClusterFinalizer} },
+				func(c *clusterv1.Cluster) { c.DeletionTimestamp = &metav1.Time{Time: time.Now()} },
+				func(c *clusterv1.Cluster) { conditions.MarkTrue(c, clusterv1.ReadyCondition) },
+			} {
+				updated := cluster.DeepCopy()
+				update(updated)
+				Expect(ClusterChanged().Update(event.UpdateEvent{ObjectOld: cluster, ObjectNew: updated})).Should(BeTrue())
+			}
+		})
+	})
+})
@@ -4,6 +4,8 @@
 package funcs
 
 import (
+	"context"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -16,7 +18,10 @@ import (
 	adccluster "github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/akodeploymentconfig/cluster"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/controllers/cluster"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/aviclient"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/haprovider"
+	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/index"
 	"github.com/vmware-tanzu/load-balancer-operator-for-kubernetes/pkg/test/builder"
+	akov1alpha2 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1alpha2"
 	akov1beta1 "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/apis/ako/v1beta1"
 )
 
@@ -33,6 +38,10 @@ var AddAllToSchemeFunc builder.AddToSchemeFunc = func(scheme *runtime.Scheme) (e
 	if err != nil {
 		return err
 	}
+	err = akov1alpha2.AddToScheme(scheme)
+	if err != nil {
+		return err
+	}
 	err = corev1.AddToScheme(scheme)
 	if err != nil {
 		return err
@@ -45,6 +54,9 @@ var AddAllToSchemeFunc builder.AddToSchemeFunc = func(scheme *runtime.Scheme) (e
 }
 
 var AddAKODeploymentConfigAndClusterControllerToMgrFunc builder.AddToManagerFunc = func(mgr ctrlmgr.Manager) error {
+	if err := index.AddDefaultIndexes(context.Background(), mgr); err != nil {
+		return err
+	}
 	rec := &akodeploymentconfig.AKODeploymentConfigReconciler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("AKODeploymentConfig"),
@@ -60,12 +72,19 @@ var AddAKODeploymentConfigAndClusterControllerToMgrFunc builder.AddToManagerFunc
 	if err := rec.SetupWithManager(mgr); err != nil {
 		return err
 	}
+	if err := (&akodeploymentconfig.AKOClusterReconciler{AKODeploymentConfigReconciler: rec}).SetupWithManager(mgr); err != nil {
+		return err
+	}
 
 	// involve the cluster controller as well for the resetting skip-default-adc label test
+	clusterLog := ctrl.Log.WithName("controllers").WithName("Cluster")
+	clusterRecorder := mgr.GetEventRecorderFor("cluster-controller")
 	if err := (&cluster.ClusterReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Cluster"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        clusterLog,
+		Scheme:     mgr.GetScheme(),
+		Recorder:   clusterRecorder,
+		Haprovider: haprovider.NewProvider(mgr.GetClient(), clusterRecorder, clusterLog),
 	}).SetupWithManager(mgr); err != nil {
 		return err
 	}